@@ -0,0 +1,168 @@
+package apis
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/scitix/aegis/api"
+	"github.com/scitix/aegis/api/models"
+	"github.com/scitix/aegis/pkg/archive"
+	"github.com/scitix/aegis/pkg/metrics"
+	"k8s.io/klog/v2"
+)
+
+var archiveStore *archive.Store
+
+func init() {
+	api.RegisterHandler("/archive/records", archiveRecordsHandler)
+	api.RegisterHandler("/archive/record", archiveRecordHandler)
+}
+
+// SetArchiveStore enables the archive query api
+func SetArchiveStore(store *archive.Store) {
+	archiveStore = store
+}
+
+type archiveRecordsResponse struct {
+	api.CommonResponse
+	Items    []*archive.Record `json:"items,omitempty"`
+	Continue string            `json:"continue,omitempty"`
+}
+
+type archiveRecordResponse struct {
+	api.CommonResponse
+	Record *archive.Record `json:"record,omitempty"`
+}
+
+// archiveRecordsHandler lists the archived records, most recent first:
+// GET /archive/records?kind=&type=&node=&outcome=&since=&until=&limit=&continue=
+// since and until are RFC3339 times bounding the finish time. Objects are only
+// returned with full=true.
+func archiveRecordsHandler(rw http.ResponseWriter, r *http.Request, callback func(ctx context.Context, alert *models.Alert) error,
+	metrics *metrics.MetricsController,
+) {
+	if errStatus, errResponse := checkArchiveRequest(r); errResponse != nil {
+		api.EncodeResponseWithStatus(rw, errStatus, errResponse)
+		return
+	}
+
+	query, err := parseArchiveQuery(r)
+	if err != nil {
+		api.EncodeResponseWithStatus(rw, http.StatusBadRequest, api.CommonResponse{
+			Code:    api.RequestParamError,
+			Message: err.Error(),
+		})
+		return
+	}
+
+	records, next, err := archiveStore.List(*query)
+	if err != nil {
+		klog.Errorf("fail to list archive: %v", err)
+		api.EncodeResponseWithStatus(rw, http.StatusInternalServerError, api.CommonResponse{
+			Code:    api.ServerError,
+			Message: err.Error(),
+		})
+		return
+	}
+
+	if full, _ := strconv.ParseBool(r.URL.Query().Get("full")); !full {
+		for _, record := range records {
+			record.Object = nil
+		}
+	}
+	api.EncodeResponseWithStatus(rw, http.StatusOK, archiveRecordsResponse{
+		CommonResponse: api.CommonResponse{Code: api.OK},
+		Items:          records,
+		Continue:       next,
+	})
+}
+
+// archiveRecordHandler returns an archived record with its object:
+// GET /archive/record?uid=
+func archiveRecordHandler(rw http.ResponseWriter, r *http.Request, callback func(ctx context.Context, alert *models.Alert) error,
+	metrics *metrics.MetricsController,
+) {
+	if errStatus, errResponse := checkArchiveRequest(r); errResponse != nil {
+		api.EncodeResponseWithStatus(rw, errStatus, errResponse)
+		return
+	}
+
+	uid := r.URL.Query().Get("uid")
+	if len(uid) == 0 {
+		api.EncodeResponseWithStatus(rw, http.StatusBadRequest, api.CommonResponse{
+			Code:    api.RequestParamError,
+			Message: "uid is required",
+		})
+		return
+	}
+
+	record, err := archiveStore.Get(uid)
+	if err != nil {
+		klog.Errorf("fail to get archive record %s: %v", uid, err)
+		api.EncodeResponseWithStatus(rw, http.StatusInternalServerError, api.CommonResponse{
+			Code:    api.ServerError,
+			Message: err.Error(),
+		})
+		return
+	}
+	if record == nil {
+		api.EncodeResponseWithStatus(rw, http.StatusNotFound, api.CommonResponse{
+			Code:    api.NotFoundError,
+			Message: fmt.Sprintf("record %s not found", uid),
+		})
+		return
+	}
+	api.EncodeResponseWithStatus(rw, http.StatusOK, archiveRecordResponse{
+		CommonResponse: api.CommonResponse{Code: api.OK},
+		Record:         record,
+	})
+}
+
+func checkArchiveRequest(r *http.Request) (int, *api.CommonResponse) {
+	if r.Method != http.MethodGet {
+		return http.StatusMethodNotAllowed, &api.CommonResponse{
+			Code:    api.RequestParamError,
+			Message: fmt.Sprintf("method %s not allowed", r.Method),
+		}
+	}
+
+	if archiveStore == nil {
+		return http.StatusServiceUnavailable, &api.CommonResponse{
+			Code:    api.ServerError,
+			Message: "archive is not enabled",
+		}
+	}
+	return 0, nil
+}
+
+func parseArchiveQuery(r *http.Request) (*archive.Query, error) {
+	values := r.URL.Query()
+	query := &archive.Query{
+		Kind:     values.Get("kind"),
+		Type:     values.Get("type"),
+		Node:     values.Get("node"),
+		Outcome:  values.Get("outcome"),
+		Continue: values.Get("continue"),
+	}
+
+	var err error
+	if since := values.Get("since"); len(since) > 0 {
+		if query.Since, err = time.Parse(time.RFC3339, since); err != nil {
+			return nil, fmt.Errorf("invalid since %q: %v", since, err)
+		}
+	}
+	if until := values.Get("until"); len(until) > 0 {
+		if query.Until, err = time.Parse(time.RFC3339, until); err != nil {
+			return nil, fmt.Errorf("invalid until %q: %v", until, err)
+		}
+	}
+	if limit := values.Get("limit"); len(limit) > 0 {
+		if query.Limit, err = strconv.Atoi(limit); err != nil || query.Limit < 0 {
+			return nil, fmt.Errorf("invalid limit %q", limit)
+		}
+	}
+	return query, nil
+}
@@ -0,0 +1,86 @@
+package apis
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/scitix/aegis/api/models"
+	"github.com/scitix/aegis/pkg/archive"
+)
+
+func newArchiveTestServer() *http.ServeMux {
+	mux := http.NewServeMux()
+	callback := func(ctx context.Context, alert *models.Alert) error { return nil }
+	mux.HandleFunc("/archive/records", func(rw http.ResponseWriter, r *http.Request) {
+		archiveRecordsHandler(rw, r, callback, nil)
+	})
+	mux.HandleFunc("/archive/record", func(rw http.ResponseWriter, r *http.Request) {
+		archiveRecordHandler(rw, r, callback, nil)
+	})
+	return mux
+}
+
+func TestArchiveHandlers(t *testing.T) {
+	mux := newArchiveTestServer()
+	if rw := getV1(t, mux, "/archive/records", nil, nil); rw.Code != http.StatusServiceUnavailable {
+		t.Errorf("expected disabled archive unavailable, got %d", rw.Code)
+	}
+
+	store, err := archive.Open(filepath.Join(t.TempDir(), "archive.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer store.Close()
+	SetArchiveStore(store)
+	defer SetArchiveStore(nil)
+
+	record := &archive.Record{
+		Kind:       archive.KindAlert,
+		Namespace:  "monitoring",
+		Name:       "alert-1",
+		UID:        "uid-1",
+		Outcome:    "Failed",
+		FinishTime: time.Date(2024, 5, 20, 8, 0, 0, 0, time.UTC),
+		Object:     []byte(`{"kind":"AegisAlert"}`),
+	}
+	if err := store.Put(record); err != nil {
+		t.Fatal(err)
+	}
+
+	var records archiveRecordsResponse
+	if rw := getV1(t, mux, "/archive/records?kind=AegisAlert", nil, &records); rw.Code != http.StatusOK {
+		t.Fatalf("expected ok, got %d: %s", rw.Code, rw.Body.String())
+	}
+	if len(records.Items) != 1 || records.Items[0].UID != "uid-1" || records.Items[0].Object != nil {
+		t.Errorf("expected the record without its object, got %+v", records.Items)
+	}
+
+	var response archiveRecordResponse
+	if rw := getV1(t, mux, "/archive/record?uid=uid-1", nil, &response); rw.Code != http.StatusOK {
+		t.Fatalf("expected ok, got %d: %s", rw.Code, rw.Body.String())
+	}
+	if response.Record == nil || string(response.Record.Object) != `{"kind":"AegisAlert"}` {
+		t.Errorf("expected the record with its object, got %+v", response.Record)
+	}
+
+	for path, status := range map[string]int{
+		"/archive/records?since=yesterday": http.StatusBadRequest,
+		"/archive/records?limit=-1":        http.StatusBadRequest,
+		"/archive/record":                  http.StatusBadRequest,
+		"/archive/record?uid=missing":      http.StatusNotFound,
+	} {
+		if rw := getV1(t, mux, path, nil, nil); rw.Code != status {
+			t.Errorf("expected %s answered %d, got %d", path, status, rw.Code)
+		}
+	}
+
+	rw := httptest.NewRecorder()
+	mux.ServeHTTP(rw, httptest.NewRequest(http.MethodPost, "/archive/records", nil))
+	if rw.Code != http.StatusMethodNotAllowed {
+		t.Errorf("expected post not allowed, got %d", rw.Code)
+	}
+}
@@ -0,0 +1,531 @@
+package apis
+
+import (
+	"context"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/fields"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/klog/v2"
+
+	"github.com/scitix/aegis/api"
+	"github.com/scitix/aegis/api/models"
+	deviceaware "github.com/scitix/aegis/internal/device_aware"
+	alertv1alpha1 "github.com/scitix/aegis/pkg/apis/alert/v1alpha1"
+	clustercheckv1alpha1 "github.com/scitix/aegis/pkg/apis/clustercheck/v1alpha1"
+	diagnosisv1alpha1 "github.com/scitix/aegis/pkg/apis/diagnosis/v1alpha1"
+	nodecheckv1alpha1 "github.com/scitix/aegis/pkg/apis/nodecheck/v1alpha1"
+	"github.com/scitix/aegis/pkg/metrics"
+	"github.com/scitix/aegis/pkg/nodeticket"
+)
+
+const (
+	defaultV1Limit = 100
+	maxV1Limit     = 1000
+)
+
+// V1Informers are the informers whose caches the /api/v1 endpoints are served
+// from, nil for the objects of disabled controllers
+type V1Informers struct {
+	Alerts        cache.SharedIndexInformer
+	Rules         cache.SharedIndexInformer
+	Templates     cache.SharedIndexInformer
+	Diagnoses     cache.SharedIndexInformer
+	NodeChecks    cache.SharedIndexInformer
+	ClusterChecks cache.SharedIndexInformer
+	Nodes         cache.SharedIndexInformer
+}
+
+var v1Informers *V1Informers
+
+// v1Token is the bearer token required by the /api/v1 endpoints, empty if
+// served without authentication
+var v1Token string
+
+// v1Resource is a list endpoint of /api/v1
+type v1Resource struct {
+	name     string
+	informer func(*V1Informers) cache.SharedIndexInformer
+	// object is an empty object of the resource to list its fields
+	object interface{}
+	// fields returns the field selector fields of an object besides
+	// metadata.name and metadata.namespace
+	fields func(obj interface{}) fields.Set
+}
+
+var v1Resources = []v1Resource{
+	{
+		name:     "alerts",
+		informer: func(i *V1Informers) cache.SharedIndexInformer { return i.Alerts },
+		object:   &alertv1alpha1.AegisAlert{},
+		fields: func(obj interface{}) fields.Set {
+			alert := obj.(*alertv1alpha1.AegisAlert)
+			return fields.Set{
+				"spec.source":                    alert.Spec.Source,
+				"spec.type":                      alert.Spec.Type,
+				"spec.severity":                  alert.Spec.Severity,
+				"spec.status":                    string(alert.Spec.Status),
+				"spec.involvedObject.kind":       string(alert.Spec.InvolvedObject.Kind),
+				"spec.involvedObject.name":       alert.Spec.InvolvedObject.Name,
+				"spec.involvedObject.namespace":  alert.Spec.InvolvedObject.Namespace,
+				"spec.involvedObject.node":       alert.Spec.InvolvedObject.Node,
+				"status.opsStatus.status":        string(alert.Status.OpsStatus.Status),
+				"status.opsStatus.triggerStatus": string(alert.Status.OpsStatus.TriggerStatus),
+			}
+		},
+	},
+	{
+		name:     "rules",
+		informer: func(i *V1Informers) cache.SharedIndexInformer { return i.Rules },
+	},
+	{
+		name:     "templates",
+		informer: func(i *V1Informers) cache.SharedIndexInformer { return i.Templates },
+	},
+	{
+		name:     "diagnoses",
+		informer: func(i *V1Informers) cache.SharedIndexInformer { return i.Diagnoses },
+		object:   &diagnosisv1alpha1.AegisDiagnosis{},
+		fields: func(obj interface{}) fields.Set {
+			diagnosis := obj.(*diagnosisv1alpha1.AegisDiagnosis)
+			return fields.Set{
+				"spec.object.kind":      string(diagnosis.Spec.Object.Kind),
+				"spec.object.name":      diagnosis.Spec.Object.Name,
+				"spec.object.namespace": diagnosis.Spec.Object.Namespace,
+				"spec.object.node":      diagnosis.Spec.Object.Node,
+				"status.phase":          string(diagnosis.Status.Phase),
+			}
+		},
+	},
+	{
+		name:     "nodechecks",
+		informer: func(i *V1Informers) cache.SharedIndexInformer { return i.NodeChecks },
+		object:   &nodecheckv1alpha1.AegisNodeHealthCheck{},
+		fields: func(obj interface{}) fields.Set {
+			check := obj.(*nodecheckv1alpha1.AegisNodeHealthCheck)
+			return fields.Set{
+				"spec.node":     check.Spec.Node,
+				"status.status": string(check.Status.Status),
+			}
+		},
+	},
+	{
+		name:     "clusterchecks",
+		informer: func(i *V1Informers) cache.SharedIndexInformer { return i.ClusterChecks },
+		object:   &clustercheckv1alpha1.AegisClusterHealthCheck{},
+		fields: func(obj interface{}) fields.Set {
+			check := obj.(*clustercheckv1alpha1.AegisClusterHealthCheck)
+			return fields.Set{
+				"status.phase": string(check.Status.Phase),
+			}
+		},
+	},
+}
+
+func init() {
+	for _, resource := range v1Resources {
+		api.RegisterHandler("/api/v1/"+resource.name, newV1ListHandler(resource))
+	}
+	api.RegisterHandler("/api/v1/nodes/{name}/aegis", v1NodeHandler)
+}
+
+// SetV1Informers enables the /api/v1 endpoints
+func SetV1Informers(informers *V1Informers) {
+	v1Informers = informers
+}
+
+// SetV1Token requires the bearer token on the /api/v1 requests, empty to
+// serve them without authentication
+func SetV1Token(token string) {
+	v1Token = token
+}
+
+type v1ListResponse struct {
+	api.CommonResponse
+	Items []interface{} `json:"items"`
+	// Total is the number of objects matching the selectors, on all pages
+	Total    int    `json:"total"`
+	Continue string `json:"continue,omitempty"`
+}
+
+// v1Query selects the objects of a list endpoint:
+// ?namespace=&labelSelector=&fieldSelector=&limit=&continue=
+type v1Query struct {
+	namespace string
+	labels    labels.Selector
+	fields    fields.Selector
+	limit     int
+	// after is the key of the last object of the previous page
+	after string
+}
+
+// newV1ListHandler lists the cached objects of a resource sorted by
+// namespace/name, with the ETag of the page.
+func newV1ListHandler(resource v1Resource) api.HandlerWithMetrics {
+	return func(rw http.ResponseWriter, r *http.Request, callback func(ctx context.Context, alert *models.Alert) error,
+		metrics *metrics.MetricsController,
+	) {
+		informer, errStatus, errResponse := checkV1Request(r, resource.informer)
+		if errResponse != nil {
+			api.EncodeResponseWithStatus(rw, errStatus, errResponse)
+			return
+		}
+
+		query, err := parseV1Query(r, resource)
+		if err != nil {
+			api.EncodeResponseWithStatus(rw, http.StatusBadRequest, api.CommonResponse{
+				Code:    api.RequestParamError,
+				Message: err.Error(),
+			})
+			return
+		}
+
+		items, total, next, err := listV1Objects(informer.GetStore(), resource, query)
+		if err != nil {
+			klog.Errorf("fail to list %s: %v", resource.name, err)
+			api.EncodeResponseWithStatus(rw, http.StatusInternalServerError, api.CommonResponse{
+				Code:    api.ServerError,
+				Message: err.Error(),
+			})
+			return
+		}
+
+		encodeV1Response(rw, r, v1ETag(items, next), v1ListResponse{
+			CommonResponse: api.CommonResponse{Code: api.OK},
+			Items:          items,
+			Total:          total,
+			Continue:       next,
+		})
+	}
+}
+
+func checkV1Request(r *http.Request, informer func(*V1Informers) cache.SharedIndexInformer) (cache.SharedIndexInformer, int, *api.CommonResponse) {
+	if r.Method != http.MethodGet {
+		return nil, http.StatusMethodNotAllowed, &api.CommonResponse{
+			Code:    api.RequestParamError,
+			Message: fmt.Sprintf("method %s not allowed", r.Method),
+		}
+	}
+
+	if len(v1Token) > 0 {
+		token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if !ok || subtle.ConstantTimeCompare([]byte(token), []byte(v1Token)) != 1 {
+			return nil, http.StatusUnauthorized, &api.CommonResponse{
+				Code:    api.RequestParamError,
+				Message: "invalid bearer token",
+			}
+		}
+	}
+
+	var i cache.SharedIndexInformer
+	if v1Informers != nil {
+		i = informer(v1Informers)
+	}
+	if i == nil {
+		return nil, http.StatusServiceUnavailable, &api.CommonResponse{
+			Code:    api.ServerError,
+			Message: "api is not enabled",
+		}
+	}
+	if !i.HasSynced() {
+		return nil, http.StatusServiceUnavailable, &api.CommonResponse{
+			Code:    api.ServerError,
+			Message: "cache is not synced yet",
+		}
+	}
+	return i, 0, nil
+}
+
+func parseV1Query(r *http.Request, resource v1Resource) (*v1Query, error) {
+	values := r.URL.Query()
+	query := &v1Query{
+		namespace: values.Get("namespace"),
+		labels:    labels.Everything(),
+		fields:    fields.Everything(),
+		limit:     defaultV1Limit,
+	}
+
+	var err error
+	if selector := values.Get("labelSelector"); len(selector) > 0 {
+		if query.labels, err = labels.Parse(selector); err != nil {
+			return nil, fmt.Errorf("invalid labelSelector %q: %v", selector, err)
+		}
+	}
+
+	if selector := values.Get("fieldSelector"); len(selector) > 0 {
+		if query.fields, err = fields.ParseSelector(selector); err != nil {
+			return nil, fmt.Errorf("invalid fieldSelector %q: %v", selector, err)
+		}
+		supported := sets.New("metadata.name", "metadata.namespace")
+		if resource.fields != nil {
+			for field := range resource.fields(resource.object) {
+				supported.Insert(field)
+			}
+		}
+		for _, requirement := range query.fields.Requirements() {
+			if !supported.Has(requirement.Field) {
+				return nil, fmt.Errorf("unsupported field %q of %s, must be one of %s", requirement.Field, resource.name, strings.Join(sets.List(supported), ", "))
+			}
+		}
+	}
+
+	if limit := values.Get("limit"); len(limit) > 0 {
+		if query.limit, err = strconv.Atoi(limit); err != nil || query.limit <= 0 {
+			return nil, fmt.Errorf("invalid limit %q", limit)
+		}
+		if query.limit > maxV1Limit {
+			query.limit = maxV1Limit
+		}
+	}
+
+	if token := values.Get("continue"); len(token) > 0 {
+		after, err := base64.RawURLEncoding.DecodeString(token)
+		if err != nil || len(after) == 0 {
+			return nil, fmt.Errorf("invalid continue %q", token)
+		}
+		query.after = string(after)
+	}
+	return query, nil
+}
+
+// listV1Objects returns a page of the objects matching the query, the number
+// of matching objects and the continue token of the next page, if any.
+func listV1Objects(store cache.Store, resource v1Resource, query *v1Query) ([]interface{}, int, string, error) {
+	type keyedObject struct {
+		key string
+		obj interface{}
+	}
+
+	matched := make([]keyedObject, 0)
+	for _, obj := range store.List() {
+		accessor, err := meta.Accessor(obj)
+		if err != nil {
+			return nil, 0, "", err
+		}
+		if len(query.namespace) > 0 && accessor.GetNamespace() != query.namespace {
+			continue
+		}
+		if !query.labels.Matches(labels.Set(accessor.GetLabels())) {
+			continue
+		}
+		if !query.fields.Empty() {
+			set := fields.Set{
+				"metadata.name":      accessor.GetName(),
+				"metadata.namespace": accessor.GetNamespace(),
+			}
+			if resource.fields != nil {
+				for field, value := range resource.fields(obj) {
+					set[field] = value
+				}
+			}
+			if !query.fields.Matches(set) {
+				continue
+			}
+		}
+
+		key, err := cache.MetaNamespaceKeyFunc(obj)
+		if err != nil {
+			return nil, 0, "", err
+		}
+		matched = append(matched, keyedObject{key: key, obj: obj})
+	}
+	sort.Slice(matched, func(i, j int) bool {
+		return matched[i].key < matched[j].key
+	})
+
+	// the page starts after the last key of the previous one, so objects
+	// created or deleted in between do not shift it
+	start := sort.Search(len(matched), func(i int) bool {
+		return matched[i].key > query.after
+	})
+	end := start + query.limit
+	next := ""
+	if end < len(matched) {
+		next = base64.RawURLEncoding.EncodeToString([]byte(matched[end-1].key))
+	} else {
+		end = len(matched)
+	}
+
+	items := make([]interface{}, 0, end-start)
+	for _, object := range matched[start:end] {
+		items = append(items, object.obj)
+	}
+	return items, len(matched), next, nil
+}
+
+// v1ETag changes whenever an object of the response is created, updated or deleted
+func v1ETag(objs []interface{}, extra ...string) string {
+	hash := sha256.New()
+	for _, obj := range objs {
+		if accessor, err := meta.Accessor(obj); err == nil {
+			fmt.Fprintf(hash, "%s/%s;", accessor.GetUID(), accessor.GetResourceVersion())
+		}
+	}
+	for _, s := range extra {
+		fmt.Fprintf(hash, "%s;", s)
+	}
+	return `"` + hex.EncodeToString(hash.Sum(nil)[:16]) + `"`
+}
+
+// encodeV1Response writes the response with its ETag, or only the ETag if
+// it matches the If-None-Match header of the request
+func encodeV1Response(rw http.ResponseWriter, r *http.Request, etag string, response interface{}) {
+	rw.Header().Set("ETag", etag)
+	rw.Header().Set("Cache-Control", "no-cache")
+	for _, match := range strings.Split(r.Header.Get("If-None-Match"), ",") {
+		match = strings.TrimPrefix(strings.TrimSpace(match), "W/")
+		if match == etag || match == "*" {
+			rw.WriteHeader(http.StatusNotModified)
+			return
+		}
+	}
+	api.EncodeResponseWithStatus(rw, http.StatusOK, response)
+}
+
+type v1NodeTicket struct {
+	Condition  string                 `json:"condition,omitempty"`
+	Reason     string                 `json:"reason,omitempty"`
+	Supervisor string                 `json:"supervisor,omitempty"`
+	Status     string                 `json:"status,omitempty"`
+	Workflows  []v1NodeTicketWorkflow `json:"workflows,omitempty"`
+	CreatedAt  string                 `json:"creationTime,omitempty"`
+}
+
+type v1NodeTicketWorkflow struct {
+	Action string `json:"action,omitempty"`
+	Status string `json:"status,omitempty"`
+}
+
+type v1Node struct {
+	Name          string `json:"name"`
+	Unschedulable bool   `json:"unschedulable"`
+	LoadAffected  bool   `json:"loadAffected"`
+	// DeviceErrors of the node by device type, reported by device aware
+	DeviceErrors map[string]string `json:"deviceErrors,omitempty"`
+	// Ticket of the node, if any
+	Ticket *v1NodeTicket `json:"ticket,omitempty"`
+	// Alerts firing on the node, or on its pods
+	Alerts []*alertv1alpha1.AegisAlert `json:"alerts"`
+}
+
+type v1NodeResponse struct {
+	api.CommonResponse
+	Node *v1Node `json:"node,omitempty"`
+}
+
+// v1NodeHandler returns the aegis state of a node: GET /api/v1/nodes/{name}/aegis
+func v1NodeHandler(rw http.ResponseWriter, r *http.Request, callback func(ctx context.Context, alert *models.Alert) error,
+	metrics *metrics.MetricsController,
+) {
+	informer, errStatus, errResponse := checkV1Request(r, func(i *V1Informers) cache.SharedIndexInformer { return i.Nodes })
+	if errResponse != nil {
+		api.EncodeResponseWithStatus(rw, errStatus, errResponse)
+		return
+	}
+
+	name := r.PathValue("name")
+	obj, exists, err := informer.GetStore().GetByKey(name)
+	if err != nil {
+		klog.Errorf("fail to get node %s: %v", name, err)
+		api.EncodeResponseWithStatus(rw, http.StatusInternalServerError, api.CommonResponse{
+			Code:    api.ServerError,
+			Message: err.Error(),
+		})
+		return
+	}
+	if !exists {
+		api.EncodeResponseWithStatus(rw, http.StatusNotFound, api.CommonResponse{
+			Code:    api.NotFoundError,
+			Message: fmt.Sprintf("node %s not found", name),
+		})
+		return
+	}
+
+	node, err := newV1Node(obj.(*corev1.Node))
+	if err != nil {
+		klog.Errorf("fail to read aegis state of node %s: %v", name, err)
+		api.EncodeResponseWithStatus(rw, http.StatusInternalServerError, api.CommonResponse{
+			Code:    api.ServerError,
+			Message: err.Error(),
+		})
+		return
+	}
+
+	objs := []interface{}{obj}
+	if v1Informers.Alerts != nil {
+		for _, obj := range v1Informers.Alerts.GetStore().List() {
+			alert := obj.(*alertv1alpha1.AegisAlert)
+			if alert.Spec.Status == alertv1alpha1.AlertStatusFiring && alertOnNode(alert, name) {
+				node.Alerts = append(node.Alerts, alert)
+			}
+		}
+	}
+	sort.Slice(node.Alerts, func(i, j int) bool {
+		return node.Alerts[i].CreationTimestamp.Before(&node.Alerts[j].CreationTimestamp)
+	})
+	for _, alert := range node.Alerts {
+		objs = append(objs, alert)
+	}
+
+	encodeV1Response(rw, r, v1ETag(objs), v1NodeResponse{
+		CommonResponse: api.CommonResponse{Code: api.OK},
+		Node:           node,
+	})
+}
+
+func newV1Node(node *corev1.Node) (*v1Node, error) {
+	n := &v1Node{
+		Name:          node.Name,
+		Unschedulable: node.Spec.Unschedulable,
+		LoadAffected:  node.Labels[deviceaware.AEGIS_LOAD_AFFECTED_LABEL] == "true",
+		Alerts:        make([]*alertv1alpha1.AegisAlert, 0),
+	}
+
+	if value, ok := node.Annotations[deviceaware.AEGIS_DEVICE_ANNOTATION]; ok {
+		if err := json.Unmarshal([]byte(value), &n.DeviceErrors); err != nil {
+			return nil, fmt.Errorf("invalid device errors: %v", err)
+		}
+	}
+
+	ticket, err := nodeticket.ReadNodeTicketFromAnnotation(node)
+	if err != nil {
+		return nil, err
+	}
+	if ticket != nil {
+		n.Ticket = &v1NodeTicket{
+			Condition:  ticket.Condition,
+			Reason:     ticket.Reason,
+			Supervisor: ticket.Supervisor,
+			Status:     string(ticket.Status),
+		}
+		if !ticket.CreatedAt.IsZero() {
+			n.Ticket.CreatedAt = ticket.CreatedAt.UTC().Format(time.RFC3339)
+		}
+		for _, workflow := range ticket.Workflows {
+			n.Ticket.Workflows = append(n.Ticket.Workflows, v1NodeTicketWorkflow{
+				Action: string(workflow.Action),
+				Status: string(workflow.Status),
+			})
+		}
+	}
+	return n, nil
+}
+
+func alertOnNode(alert *alertv1alpha1.AegisAlert, node string) bool {
+	object := alert.Spec.InvolvedObject
+	return object.Node == node || (object.Kind == alertv1alpha1.NodeKind && object.Name == node)
+}
@@ -0,0 +1,238 @@
+package apis
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/cache"
+
+	"github.com/scitix/aegis/api/models"
+	deviceaware "github.com/scitix/aegis/internal/device_aware"
+	alertv1alpha1 "github.com/scitix/aegis/pkg/apis/alert/v1alpha1"
+	"github.com/scitix/aegis/pkg/nodeticket"
+)
+
+// fakeInformer serves a store which is always synced
+type fakeInformer struct {
+	cache.SharedIndexInformer
+	store cache.Store
+}
+
+func (f *fakeInformer) GetStore() cache.Store { return f.store }
+func (f *fakeInformer) HasSynced() bool       { return true }
+
+func newFakeInformer(objs ...interface{}) *fakeInformer {
+	store := cache.NewStore(cache.MetaNamespaceKeyFunc)
+	for _, obj := range objs {
+		store.Add(obj)
+	}
+	return &fakeInformer{store: store}
+}
+
+func newV1TestAlert(name, node string, status alertv1alpha1.AlertStatusType) *alertv1alpha1.AegisAlert {
+	return &alertv1alpha1.AegisAlert{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:            name,
+			Namespace:       "monitoring",
+			UID:             types.UID(name),
+			ResourceVersion: "1",
+			Labels:          map[string]string{"node": node},
+		},
+		Spec: alertv1alpha1.AegisAlertSpec{
+			Type:   "NodeNotReady",
+			Status: status,
+			InvolvedObject: alertv1alpha1.AegisAlertObject{
+				Kind: alertv1alpha1.NodeKind,
+				Name: node,
+			},
+		},
+	}
+}
+
+func newV1TestServer() *http.ServeMux {
+	mux := http.NewServeMux()
+	for _, resource := range v1Resources {
+		handler := newV1ListHandler(resource)
+		mux.HandleFunc("/api/v1/"+resource.name, func(rw http.ResponseWriter, r *http.Request) {
+			handler(rw, r, func(ctx context.Context, alert *models.Alert) error { return nil }, nil)
+		})
+	}
+	mux.HandleFunc("/api/v1/nodes/{name}/aegis", func(rw http.ResponseWriter, r *http.Request) {
+		v1NodeHandler(rw, r, func(ctx context.Context, alert *models.Alert) error { return nil }, nil)
+	})
+	return mux
+}
+
+func getV1(t *testing.T, mux *http.ServeMux, path string, header http.Header, response interface{}) *httptest.ResponseRecorder {
+	t.Helper()
+	r := httptest.NewRequest(http.MethodGet, path, nil)
+	for key, values := range header {
+		r.Header[key] = values
+	}
+	rw := httptest.NewRecorder()
+	mux.ServeHTTP(rw, r)
+	if rw.Code == http.StatusOK && response != nil {
+		if err := json.Unmarshal(rw.Body.Bytes(), response); err != nil {
+			t.Fatalf("decode %s: %v", path, err)
+		}
+	}
+	return rw
+}
+
+type v1TestListResponse struct {
+	Items []struct {
+		Metadata metav1.ObjectMeta `json:"metadata"`
+	} `json:"items"`
+	Total    int    `json:"total"`
+	Continue string `json:"continue"`
+}
+
+func TestV1ListAlerts(t *testing.T) {
+	alerts := newFakeInformer(
+		newV1TestAlert("c", "node1", alertv1alpha1.AlertStatusFiring),
+		newV1TestAlert("a", "node1", alertv1alpha1.AlertStatusFiring),
+		newV1TestAlert("b", "node1", alertv1alpha1.AlertStatusResolved),
+		newV1TestAlert("d", "node2", alertv1alpha1.AlertStatusFiring),
+	)
+	SetV1Informers(&V1Informers{Alerts: alerts})
+	defer SetV1Informers(nil)
+	mux := newV1TestServer()
+
+	// pages of firing alerts of node1, sorted by name
+	var names []string
+	token := ""
+	for pages := 0; pages < 3; pages++ {
+		query := url.Values{
+			"labelSelector": {"node=node1"},
+			"fieldSelector": {"spec.status=Firing"},
+			"limit":         {"1"},
+			"continue":      {token},
+		}
+		var response v1TestListResponse
+		if rw := getV1(t, mux, "/api/v1/alerts?"+query.Encode(), nil, &response); rw.Code != http.StatusOK {
+			t.Fatalf("expected ok, got %d: %s", rw.Code, rw.Body.String())
+		}
+		if response.Total != 2 {
+			t.Errorf("expected 2 matching alerts, got %d", response.Total)
+		}
+		for _, item := range response.Items {
+			names = append(names, item.Metadata.Name)
+		}
+		if token = response.Continue; len(token) == 0 {
+			break
+		}
+	}
+	if fmt.Sprint(names) != "[a c]" {
+		t.Errorf("expected alerts [a c], got %v", names)
+	}
+
+	if rw := getV1(t, mux, "/api/v1/alerts?fieldSelector=spec.unknown=x", nil, nil); rw.Code != http.StatusBadRequest {
+		t.Errorf("expected unsupported field rejected, got %d", rw.Code)
+	}
+	if rw := getV1(t, mux, "/api/v1/diagnoses", nil, nil); rw.Code != http.StatusServiceUnavailable {
+		t.Errorf("expected disabled diagnoses unavailable, got %d", rw.Code)
+	}
+}
+
+func TestV1Token(t *testing.T) {
+	SetV1Informers(&V1Informers{Alerts: newFakeInformer(newV1TestAlert("a", "node1", alertv1alpha1.AlertStatusFiring))})
+	defer SetV1Informers(nil)
+	SetV1Token("secret")
+	defer SetV1Token("")
+	mux := newV1TestServer()
+
+	for _, header := range []http.Header{nil, {"Authorization": {"Bearer wrong"}}, {"Authorization": {"secret"}}} {
+		if rw := getV1(t, mux, "/api/v1/alerts", header, nil); rw.Code != http.StatusUnauthorized {
+			t.Errorf("expected %v unauthorized, got %d", header, rw.Code)
+		}
+	}
+	if rw := getV1(t, mux, "/api/v1/nodes/node1/aegis", nil, nil); rw.Code != http.StatusUnauthorized {
+		t.Errorf("expected node state unauthorized, got %d", rw.Code)
+	}
+
+	var response v1TestListResponse
+	header := http.Header{"Authorization": {"Bearer secret"}}
+	if rw := getV1(t, mux, "/api/v1/alerts", header, &response); rw.Code != http.StatusOK || len(response.Items) != 1 {
+		t.Errorf("expected the alert with the token, got %d", rw.Code)
+	}
+}
+
+func TestV1ETag(t *testing.T) {
+	alerts := newFakeInformer(newV1TestAlert("a", "node1", alertv1alpha1.AlertStatusFiring))
+	SetV1Informers(&V1Informers{Alerts: alerts})
+	defer SetV1Informers(nil)
+	mux := newV1TestServer()
+
+	rw := getV1(t, mux, "/api/v1/alerts", nil, nil)
+	etag := rw.Header().Get("ETag")
+	if rw.Code != http.StatusOK || len(etag) == 0 {
+		t.Fatalf("expected ok with an ETag, got %d %q", rw.Code, etag)
+	}
+
+	header := http.Header{"If-None-Match": {etag}}
+	if rw := getV1(t, mux, "/api/v1/alerts", header, nil); rw.Code != http.StatusNotModified || rw.Body.Len() > 0 {
+		t.Errorf("expected not modified without body, got %d", rw.Code)
+	}
+
+	updated := newV1TestAlert("a", "node1", alertv1alpha1.AlertStatusResolved)
+	updated.ResourceVersion = "2"
+	alerts.store.Update(updated)
+	if rw := getV1(t, mux, "/api/v1/alerts", header, nil); rw.Code != http.StatusOK || rw.Header().Get("ETag") == etag {
+		t.Errorf("expected new ETag of the updated alert, got %d", rw.Code)
+	}
+}
+
+func TestV1Node(t *testing.T) {
+	ticket, err := nodeticket.MarshalNodeTicket(&nodeticket.NodeTicket{Condition: "GpuDown", Supervisor: "sre"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	node := &corev1.Node{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:   "node1",
+			Labels: map[string]string{deviceaware.AEGIS_LOAD_AFFECTED_LABEL: "true"},
+			Annotations: map[string]string{
+				deviceaware.AEGIS_DEVICE_ANNOTATION: `{"gpu":"GpuDown"}`,
+				nodeticket.TicketAnnotationKey:      ticket,
+			},
+		},
+		Spec: corev1.NodeSpec{Unschedulable: true},
+	}
+	SetV1Informers(&V1Informers{
+		Nodes: newFakeInformer(node),
+		Alerts: newFakeInformer(
+			newV1TestAlert("a", "node1", alertv1alpha1.AlertStatusFiring),
+			newV1TestAlert("b", "node1", alertv1alpha1.AlertStatusResolved),
+			newV1TestAlert("c", "node2", alertv1alpha1.AlertStatusFiring),
+		),
+	})
+	defer SetV1Informers(nil)
+	mux := newV1TestServer()
+
+	var response v1NodeResponse
+	if rw := getV1(t, mux, "/api/v1/nodes/node1/aegis", nil, &response); rw.Code != http.StatusOK {
+		t.Fatalf("expected ok, got %d: %s", rw.Code, rw.Body.String())
+	}
+	got := response.Node
+	if !got.Unschedulable || !got.LoadAffected || got.DeviceErrors["gpu"] != "GpuDown" {
+		t.Errorf("unexpected node state %+v", got)
+	}
+	if got.Ticket == nil || got.Ticket.Condition != "GpuDown" || got.Ticket.Supervisor != "sre" {
+		t.Errorf("unexpected ticket %+v", got.Ticket)
+	}
+	if len(got.Alerts) != 1 || got.Alerts[0].Name != "a" {
+		t.Errorf("expected the firing alert of the node only, got %d alerts", len(got.Alerts))
+	}
+
+	if rw := getV1(t, mux, "/api/v1/nodes/node3/aegis", nil, nil); rw.Code != http.StatusNotFound {
+		t.Errorf("expected unknown node not found, got %d", rw.Code)
+	}
+}
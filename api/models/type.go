@@ -6,6 +6,7 @@ const (
 	DefaultAlertSource      AlertSourceType = "Default"
 	AlertManagerAlertSource AlertSourceType = "Alertmanager"
 	AIAlertSource           AlertSourceType = "AI"
+	DiagnosisAlertSource    AlertSourceType = "Diagnosis"
 )
 
 const (
@@ -8,6 +8,7 @@ import (
 	"github.com/prometheus/client_golang/prometheus"
 	"github.com/prometheus/client_golang/prometheus/collectors"
 	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"go.opentelemetry.io/contrib/instrumentation/net/http/otelhttp"
 
 	"github.com/scitix/aegis/api/models"
 	"github.com/scitix/aegis/pkg/metrics"
@@ -40,10 +41,10 @@ func RunHttpServer(port, routePrefix string,
 
 	for path, handler := range handlerMap {
 		func(path string, handler interface{}) {
-			mux.HandleFunc(routePrefix+path, func(rw http.ResponseWriter, r *http.Request) {
+			mux.Handle(routePrefix+path, otelhttp.NewHandler(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
 				h := handler.(HandlerWithMetrics)
 				h(rw, r, createAlertHandler, metrics)
-			})
+			}), routePrefix+path))
 		}(path, handler)
 	}
 
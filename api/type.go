@@ -15,12 +15,20 @@ func EncodeResponse(w http.ResponseWriter, response interface{}) error {
 	return json.NewEncoder(w).Encode(response)
 }
 
+// EncodeResponseWithStatus writes the response with the http status code
+func EncodeResponseWithStatus(w http.ResponseWriter, status int, response interface{}) error {
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.WriteHeader(status)
+	return json.NewEncoder(w).Encode(response)
+}
+
 // Code define
 const (
 	OK                int32 = 200
 	UnknownError      int32 = 2000
 	RequestParamError int32 = 2001
 	ServerError       int32 = 2002
+	NotFoundError     int32 = 2003
 )
 
 // CodeMap is a mapping for code and error info
@@ -29,6 +37,7 @@ var CodeMap = map[int32]string{
 	UnknownError:      "Unknown error",
 	ServerError:       "Server error",
 	RequestParamError: "Request params error",
+	NotFoundError:     "Not found",
 }
 
 type Error struct {
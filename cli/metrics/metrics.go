@@ -0,0 +1,21 @@
+package metrics
+
+import (
+	"github.com/spf13/cobra"
+)
+
+func NewCommand() *cobra.Command {
+	c := &cobra.Command{
+		Use:   "metrics",
+		Short: "Tooling of aegis metrics",
+		Long:  "Tooling of aegis metrics",
+		// nothing to do with the cluster
+		PersistentPreRun: func(cmd *cobra.Command, args []string) {},
+	}
+
+	c.AddCommand(
+		NewRulesCmd("rules"),
+	)
+
+	return c
+}
@@ -0,0 +1,88 @@
+package metrics
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/spf13/cobra"
+	"gopkg.in/yaml.v3"
+	"k8s.io/klog/v2"
+
+	aegismetrics "github.com/scitix/aegis/pkg/metrics"
+)
+
+func NewRulesCmd(use string) *cobra.Command {
+	o := &rulesOption{}
+
+	c := &cobra.Command{
+		Use:   use,
+		Short: "Generate the recording rules of the remediation SLOs",
+		Run: func(cmd *cobra.Command, args []string) {
+			if err := o.run(cmd.OutOrStdout()); err != nil {
+				klog.Fatalf("Generate recording rules failed: %v", err)
+			}
+		},
+		Example: `aegiscli metrics rules --windows 1h,1d,7d -o aegis-rules.yaml
+aegiscli metrics rules --prometheus-rule --namespace monitoring | kubectl apply -f -`,
+	}
+
+	c.Flags().StringSliceVar(&o.windows, "windows", aegismetrics.DefaultRuleWindows, "Windows the rules are computed over")
+	c.Flags().Float64SliceVar(&o.quantiles, "quantiles", aegismetrics.DefaultRuleQuantiles, "Quantiles of the remediation durations")
+	c.Flags().BoolVar(&o.prometheusRule, "prometheus-rule", false, "Wrap the rules in a prometheus-operator PrometheusRule")
+	c.Flags().StringVar(&o.name, "name", "aegis-remediation-slo", "Name of the PrometheusRule")
+	c.Flags().StringVarP(&o.namespace, "namespace", "n", "monitoring", "Namespace of the PrometheusRule")
+	c.Flags().StringVarP(&o.output, "output", "o", "", "File to write the rules to, stdout if empty")
+
+	return c
+}
+
+type rulesOption struct {
+	windows        []string
+	quantiles      []float64
+	prometheusRule bool
+	name           string
+	namespace      string
+	output         string
+}
+
+type prometheusRule struct {
+	APIVersion string                  `yaml:"apiVersion"`
+	Kind       string                  `yaml:"kind"`
+	Metadata   map[string]string       `yaml:"metadata"`
+	Spec       aegismetrics.RuleGroups `yaml:"spec"`
+}
+
+func (o *rulesOption) run(stdout io.Writer) error {
+	groups, err := aegismetrics.NewRecordingRules(o.windows, o.quantiles)
+	if err != nil {
+		return err
+	}
+
+	var content interface{} = groups
+	if o.prometheusRule {
+		content = &prometheusRule{
+			APIVersion: "monitoring.coreos.com/v1",
+			Kind:       "PrometheusRule",
+			Metadata: map[string]string{
+				"name":      o.name,
+				"namespace": o.namespace,
+			},
+			Spec: *groups,
+		}
+	}
+
+	var buf bytes.Buffer
+	encoder := yaml.NewEncoder(&buf)
+	encoder.SetIndent(2)
+	if err := encoder.Encode(content); err != nil {
+		return fmt.Errorf("marshal rules: %v", err)
+	}
+
+	if len(o.output) == 0 {
+		_, err = stdout.Write(buf.Bytes())
+		return err
+	}
+	return os.WriteFile(o.output, buf.Bytes(), 0644)
+}
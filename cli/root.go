@@ -8,6 +8,7 @@ import (
 
 	"github.com/scitix/aegis/cli/auth"
 	"github.com/scitix/aegis/cli/config"
+	"github.com/scitix/aegis/cli/metrics"
 	"github.com/scitix/aegis/cli/rule"
 )
 
@@ -31,6 +32,7 @@ func NewCommand(name string) *cobra.Command {
 	c.AddCommand(
 		auth.NewCommand("aegis", "auth"),
 		rule.NewCommand(f),
+		metrics.NewCommand(),
 	)
 
 	// init add the klog flags
@@ -15,7 +15,11 @@ import (
 	"github.com/scitix/aegis/internal/k8s"
 	analyzercommon "github.com/scitix/aegis/pkg/analyzer/common"
 	"github.com/scitix/aegis/pkg/ai"
+	"github.com/scitix/aegis/pkg/controller/alert"
+	"github.com/scitix/aegis/pkg/controller/diagnosis"
+	"github.com/scitix/aegis/pkg/explaincache"
 	"github.com/scitix/aegis/pkg/metrics"
+	"github.com/scitix/aegis/pkg/tracing"
 	"github.com/scitix/aegis/tools"
 	"github.com/scitix/aegis/version"
 	"github.com/spf13/pflag"
@@ -47,6 +51,12 @@ func main() {
 		klog.Fatal(err)
 	}
 
+	shutdownTracing, err := tracing.Setup(context.Background(), conf.Tracing)
+	if err != nil {
+		klog.Fatalf("Failed to setup tracing: %v", err)
+	}
+	defer shutdownTracing(context.Background())
+
 	cfg, kubeClient, err := k8s.CreateApiserverClient(apiserverHost, kubeConfigFile)
 	if err != nil {
 		handleFatalInitError(err)
@@ -78,6 +88,16 @@ func main() {
 	})
 
 	// run api
+	if store := aegisController.ArchiveStore(); store != nil {
+		apis.SetArchiveStore(store)
+	}
+	if conf.EnableReadAPI {
+		if len(conf.ReadAPIToken) == 0 {
+			klog.Warningf("The read-only /api/v1 is served without authentication, set read-api.token to require a bearer token")
+		}
+		apis.SetV1Token(conf.ReadAPIToken)
+		apis.SetV1Informers(aegisController.StartReadCaches(ctx))
+	}
 	metricsController := metrics.NewMetricsController()
 	if port > 0 {
 		go api.RunHttpServer(strconv.Itoa(port), routePrefix, aegisController.CreateOrUpdateAlert, metricsController)
@@ -132,6 +152,8 @@ func parse() (bool, *controller.Configuration, error) {
 	flags.IntVar(&port, "http-port", 80, "Port to use for http server")
 	flags.StringVar(&routePrefix, "web.route-prefix", "/", "Prefix for API and UI endpoints")
 	flags.IntVar(&gracePeriod, "grace-period", 5, "Graceful shutdown period")
+	flags.Bool("read-api.enable", false, "Serve the read-only /api/v1 from the informer caches, also on followers")
+	flags.String("read-api.token", "", "Bearer token required by the read-only /api/v1, empty to serve it without authentication")
 
 	flags.Duration("sync-period", 30*time.Minute, "Period at which the controller forces the local object store.")
 	flags.Int("workers", 2, "Workers for workqueue.")
@@ -143,6 +165,14 @@ func parse() (bool, *controller.Configuration, error) {
 	flags.Int32("alert.ttl-after-succeed", 2*24*60*60, "clean ttl after alert ops succeed")
 	flags.Int32("alert.ttl-after-failed", 4*24*60*60, "clean ttl after alert ops failed")
 	flags.Int32("alert.ttl-after-noops", 1*24*60*60, "clean ttl after alert ops no-ops")
+	flags.Int("alert.history-size", 20, "number of status history entries kept per alert")
+	flags.Bool("alert.orphan-gc.enable", true, "garbage collect ops workflows whose alert is gone")
+	flags.Duration("alert.orphan-gc.period", 10*time.Minute, "period between two orphan workflow scans")
+	flags.Duration("alert.orphan-gc.grace-period", time.Hour, "keep orphan workflows for this long after they finished before deletion")
+	flags.Bool("alert.orphan-gc.terminate-running", false, "terminate orphan workflows that are still running")
+	flags.Bool("alert.orphan-gc.dry-run", false, "only log and count the orphan workflow actions")
+	flags.String("alert.escalation.ticket-system", "Node", "ticket system of the CreateTicket escalation action: None, Node, Scitix or UCP")
+	flags.Duration("alert.ops-slo", time.Hour, "report alerts whose ops run longer than this, 0 to disable")
 
 	// prometheus flags stay on stdlib flag so existing env-var / helm overrides work
 	promEndpoint := flag.String("prometheus.endpoint", "", "Prometheus server endpoint, e.g. http://localhost:9090")
@@ -160,6 +190,17 @@ func parse() (bool, *controller.Configuration, error) {
 	flags.Int("diagnosis.pod-log.fetch-lines", 0, "number of log lines to fetch per container (0 = default 1000)")
 	flags.StringSlice("diagnosis.pod-log.keywords", nil, "case-insensitive keywords to filter pod log lines (empty = no filtering)")
 	flags.Int("diagnosis.pod-log.max-output-lines", 0, "max log lines forwarded to LLM (0 = default 60)")
+	flags.Int("diagnosis.agent.max-steps", 0, "max tool calls of an agent mode diagnosis (0 = default 8)")
+	flags.Int("diagnosis.agent.max-tokens", 0, "max LLM tokens of an agent mode diagnosis (0 = default 32000)")
+	flags.Duration("diagnosis.agent.timeout", 0, "wall time of the tool calls of an agent mode diagnosis (0 = default 2m)")
+	flags.StringSlice("diagnosis.agent.namespaces", nil, "namespaces the agent tools may read besides the one of the diagnosed object")
+	flags.Int("diagnosis.remediation.min-confidence", diagnosis.DefaultRemediationMinConfidence, "confidence of the analysis to raise its alert in auto remediation")
+	flags.String("diagnosis.explain-cache.backend", "memory", "backend persisting the explain cache, support memory/configmap/bbolt")
+	flags.Duration("diagnosis.explain-cache.ttl", 0, "TTL of the cached explains (0 = default 24h)")
+	flags.Int("diagnosis.explain-cache.max-entries", 0, "max cached explains of each tier (0 = default 1000)")
+	flags.Int("diagnosis.explain-cache.max-entry-size", 0, "max size in bytes of a cached explain (0 = default 65536)")
+	flags.String("diagnosis.explain-cache.path", "", "path of the bbolt explain cache (empty = default /aegis/cache/explain.db)")
+	flags.String("diagnosis.explain-cache.namespace", "", "namespace of the explain cache ConfigMaps (empty = the aegis namespace)")
 
 	flags.String("ai.provider", "openai", "backend AI provider name")
 
@@ -202,10 +243,15 @@ func parse() (bool, *controller.Configuration, error) {
 		SyncWorkers:               viper.GetInt("workers"),
 		EnableLeaderElection:      viper.GetBool("enable-leader-election"),
 		ElectionID:                viper.GetString("election-id"),
+		EnableReadAPI:             viper.GetBool("read-api.enable"),
+		ReadAPIToken:              viper.GetString("read-api.token"),
 		EnableAlert:               viper.GetBool("alert.enable"),
 		DefaultTTLAfterOpsSucceed: viper.GetInt32("alert.ttl-after-succeed"),
 		DefaultTTLAfterOpsFailed:  viper.GetInt32("alert.ttl-after-failed"),
 		DefaultTTLAfterNoOps:      viper.GetInt32("alert.ttl-after-noops"),
+		AlertHistorySize:          viper.GetInt("alert.history-size"),
+		EscalationTicketSystem:    viper.GetString("alert.escalation.ticket-system"),
+		AlertOpsSLO:               viper.GetDuration("alert.ops-slo"),
 		PromEndpoint:              *promEndpoint,
 		PromToken:                 *promToken,
 		EnableHealthcheck:         viper.GetBool("healthcheck.enable"),
@@ -223,7 +269,22 @@ func parse() (bool, *controller.Configuration, error) {
 			Keywords:       viper.GetStringSlice("diagnosis.pod-log.keywords"),
 			MaxOutputLines: viper.GetInt("diagnosis.pod-log.max-output-lines"),
 		},
-		EnableNodePoller:          viper.GetBool("node-poller.enable"),
+		AgentConfig: &diagnosis.AgentConfig{
+			MaxSteps:   viper.GetInt("diagnosis.agent.max-steps"),
+			MaxTokens:  viper.GetInt("diagnosis.agent.max-tokens"),
+			Timeout:    viper.GetDuration("diagnosis.agent.timeout"),
+			Namespaces: viper.GetStringSlice("diagnosis.agent.namespaces"),
+		},
+		ExplainCacheConfig: &explaincache.Config{
+			Backend:      viper.GetString("diagnosis.explain-cache.backend"),
+			TTL:          viper.GetDuration("diagnosis.explain-cache.ttl"),
+			MaxEntries:   viper.GetInt("diagnosis.explain-cache.max-entries"),
+			MaxEntrySize: viper.GetInt("diagnosis.explain-cache.max-entry-size"),
+			Path:         viper.GetString("diagnosis.explain-cache.path"),
+			Namespace:    viper.GetString("diagnosis.explain-cache.namespace"),
+		},
+		RemediationMinConfidence: viper.GetInt("diagnosis.remediation.min-confidence"),
+		EnableNodePoller:         viper.GetBool("node-poller.enable"),
 		NodePoller: nodepoller.PollerConfig{
 			PollInterval:         viper.GetDuration("node-poller.poll-interval"),
 			ResyncInterval:       viper.GetDuration("node-poller.resync-interval"),
@@ -233,6 +294,37 @@ func parse() (bool, *controller.Configuration, error) {
 			PriorityNamespace:    viper.GetString("node-poller.priority-namespace"),
 			PriorityConfigKey:    viper.GetString("node-poller.priority-configkey"),
 		},
+		OrphanGC: alert.OrphanGCConfig{
+			Enable:           viper.GetBool("alert.orphan-gc.enable"),
+			Period:           viper.GetDuration("alert.orphan-gc.period"),
+			GracePeriod:      viper.GetDuration("alert.orphan-gc.grace-period"),
+			TerminateRunning: viper.GetBool("alert.orphan-gc.terminate-running"),
+			DryRun:           viper.GetBool("alert.orphan-gc.dry-run"),
+		},
+	}
+
+	if err := viper.UnmarshalKey("notifiers", &config.Notifiers); err != nil {
+		return false, nil, fmt.Errorf("invalid notifiers config: %v", err)
+	}
+
+	if err := viper.UnmarshalKey("cloudevents", &config.CloudEvents); err != nil {
+		return false, nil, fmt.Errorf("invalid cloudevents config: %v", err)
+	}
+
+	if err := viper.UnmarshalKey("archive", &config.Archive); err != nil {
+		return false, nil, fmt.Errorf("invalid archive config: %v", err)
+	}
+
+	if err := viper.UnmarshalKey("diagnosis.redaction", &config.Redaction); err != nil {
+		return false, nil, fmt.Errorf("invalid diagnosis redaction config: %v", err)
+	}
+
+	if err := viper.UnmarshalKey("tracing", &config.Tracing); err != nil {
+		return false, nil, fmt.Errorf("invalid tracing config: %v", err)
+	}
+
+	if config.ExplainCacheConfig.Namespace == "" {
+		config.ExplainCacheConfig.Namespace = os.Getenv("POD_NAMESPACE")
 	}
 
 	return false, config, nil
@@ -2,19 +2,21 @@ package controller
 
 import (
 	"context"
-	"encoding/json"
 	"fmt"
 	"regexp"
 	"strings"
 	"sync"
 	"time"
 
+	"github.com/scitix/aegis/api/apis"
 	"github.com/scitix/aegis/api/models"
 	deviceaware "github.com/scitix/aegis/internal/device_aware"
 	"github.com/scitix/aegis/internal/controller/nodepoller"
 	"github.com/scitix/aegis/internal/k8s"
 	analyzercommon "github.com/scitix/aegis/pkg/analyzer/common"
+	"github.com/scitix/aegis/pkg/cloudevents"
 	"github.com/scitix/aegis/pkg/apis/alert/v1alpha1"
+	"github.com/scitix/aegis/pkg/archive"
 	"github.com/scitix/aegis/pkg/controller"
 	"github.com/scitix/aegis/pkg/controller/alert"
 	"github.com/scitix/aegis/pkg/controller/clustercheck"
@@ -22,21 +24,28 @@ import (
 	"github.com/scitix/aegis/pkg/controller/nodecheck"
 	"github.com/scitix/aegis/pkg/controller/rule"
 	"github.com/scitix/aegis/pkg/controller/template"
+	"github.com/scitix/aegis/pkg/explaincache"
 	"github.com/scitix/aegis/pkg/metrics"
+	"github.com/scitix/aegis/pkg/notifier"
 	"github.com/scitix/aegis/pkg/prom"
+	"github.com/scitix/aegis/pkg/redact"
+	"github.com/scitix/aegis/pkg/tracing"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
-	"k8s.io/apimachinery/pkg/labels"
-	"k8s.io/apimachinery/pkg/selection"
 	"k8s.io/client-go/informers"
 	clientset "k8s.io/client-go/kubernetes"
 	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/cache"
 	"k8s.io/client-go/tools/leaderelection"
 	"k8s.io/client-go/tools/leaderelection/resourcelock"
+	"k8s.io/client-go/util/workqueue"
 	"k8s.io/klog/v2"
 
 	alertv1alpha1 "github.com/scitix/aegis/pkg/apis/alert/v1alpha1"
 	alertclientset "github.com/scitix/aegis/pkg/generated/alert/clientset/versioned"
 	alertInformers "github.com/scitix/aegis/pkg/generated/alert/informers/externalversions"
+	alertlisters "github.com/scitix/aegis/pkg/generated/alert/listers/alert/v1alpha1"
 
 	ruleclientset "github.com/scitix/aegis/pkg/generated/rule/clientset/versioned"
 	ruleInformers "github.com/scitix/aegis/pkg/generated/rule/informers/externalversions"
@@ -72,6 +81,10 @@ type Configuration struct {
 	DefaultTTLAfterOpsSucceed int32
 	DefaultTTLAfterOpsFailed  int32
 	DefaultTTLAfterNoOps      int32
+	AlertHistorySize          int
+	OrphanGC                  alert.OrphanGCConfig
+	EscalationTicketSystem    string        // ticket system of the CreateTicket escalation action
+	AlertOpsSLO               time.Duration // ops running longer are reported, 0 disables it
 
 	// prom
 	PromEndpoint string
@@ -97,10 +110,35 @@ type Configuration struct {
 
 	// pod log keyword filtering for diagnosis; nil → legacy 60-line behaviour
 	PodLogConfig *analyzercommon.PodLogConfig
+	AgentConfig  *diagnosis.AgentConfig
+
+	// explain cache of the diagnoses, memory only by default
+	ExplainCacheConfig *explaincache.Config
+	// redaction of the prompts sent to the AI providers
+	Redaction redact.Config
+	// confidence of the analysis to raise its alert in auto remediation
+	RemediationMinConfidence int
 
 	// enable node active polling
 	EnableNodePoller bool
 	NodePoller       nodepoller.PollerConfig
+
+	// notification sinks of alert lifecycle events
+	Notifiers []notifier.Config
+
+	// cloudevents emission of state transitions
+	CloudEvents cloudevents.Config
+
+	// archive of finished objects before their ttl cleanup
+	Archive archive.Config
+
+	// tracing of the alert pipeline
+	Tracing tracing.Config
+
+	// serve the read-only /api/v1 from the informer caches
+	EnableReadAPI bool
+	// bearer token required by the read-only /api/v1, empty if not required
+	ReadAPIToken string
 }
 
 type AegisController struct {
@@ -109,6 +147,11 @@ type AegisController struct {
 	// alert interface
 	alertInterface controller.AlertControllerInterface
 
+	// webhook alert dedup, served by every replica
+	alertLister  alertlisters.AegisAlertLister
+	alertsSynced cache.InformerSynced
+	resolveQueue workqueue.RateLimitingInterface
+
 	// informer
 	alertInformer        alertInformers.SharedInformerFactory
 	workflowInformer     wfInformers.SharedInformerFactory
@@ -142,6 +185,10 @@ type AegisController struct {
 
 	// node active polling
 	nodeStatusPoller *nodepoller.NodeStatusPoller
+
+	// archive of finished objects, nil if disabled
+	archiveStore *archive.Store
+	archiver     *archive.Archiver
 }
 
 func NewAegisController(cfg *Configuration) (*AegisController, error) {
@@ -163,6 +210,11 @@ func NewAegisController(cfg *Configuration) (*AegisController, error) {
 	}
 	alertInformer := alertInformers.NewSharedInformerFactory(alertclientInterface, cfg.ResyncPeriod)
 	aInformer := alertInformer.Aegis().V1alpha1().AegisAlerts()
+	if err := aInformer.Informer().AddIndexers(cache.Indexers{
+		controller.AlertFingerprintIndex: controller.AlertFingerprintIndexFunc,
+	}); err != nil {
+		return nil, fmt.Errorf("fail to add alert fingerprint index: %v", err)
+	}
 
 	workflowclientset, err := wfclientset.NewForConfig(cfg.Config)
 	if err != nil {
@@ -198,19 +250,42 @@ func NewAegisController(cfg *Configuration) (*AegisController, error) {
 	metricsController := metrics.NewMetricsController()
 
 	lifecycle.register("metrics", metricsController)
+	if err := metricsController.RegisterUnmatchedAlerts(aInformer.Lister()); err != nil {
+		klog.Warningf("fail to register unmatched alerts metrics: %v", err)
+	}
+
+	if len(cfg.Notifiers) > 0 {
+		notifyController, err := notifier.NewNotifyController(cfg.Notifiers)
+		if err != nil {
+			return nil, fmt.Errorf("fail to create notifiers: %v", err)
+		}
+		lifecycle.register("notifier", notifyController)
+	}
+
+	// the remediation raises the alerts once the aegis controller is created
+	remediation := &diagnosisRemediation{}
+	diagnosisCallback := diagnosisCallbacks{}
+	if cfg.CloudEvents.Enable {
+		emitter, err := cloudevents.NewEmitter(cfg.CloudEvents)
+		if err != nil {
+			return nil, fmt.Errorf("fail to create cloudevents emitter: %v", err)
+		}
+		lifecycle.register("cloudevents", emitter)
+		diagnosisCallback = append(diagnosisCallback, emitter)
+	}
 
 	// promethues api client
 	prometheus := prom.CreatePromClient(cfg.PromEndpoint, cfg.PromToken)
 
 	// create template controller
 	templateController := template.NewController(cfg.Client, templateclientset, templateInformer.Aegis().V1alpha1().AegisOpsTemplates())
-	ruleController := rule.NewController(cfg.Client, ruleclientInterface, templateclientset, ruleInformer.Aegis().V1alpha1().AegisAlertOpsRules(), templateInformer.Aegis().V1alpha1().AegisOpsTemplates())
-	diagnosisController, err := diagnosis.NewController(cfg.Client, diagnosisclientset, diagnosisInformer.Aegis().V1alpha1().AegisDiagnosises(), 300*time.Second, cfg.AiBackend, cfg.DiagnosisLanguage, cfg.CollectorImage, cfg.EnableProm, prometheus, cfg.DiagnosisEnableExplain, !cfg.DiagnosisEnableCache, cfg.PodLogConfig)
+	ruleController := rule.NewController(cfg.Client, ruleclientInterface, templateclientset, ruleInformer.Aegis().V1alpha1().AegisAlertOpsRules(), templateInformer.Aegis().V1alpha1().AegisOpsTemplates(), time.Duration(cfg.DefaultTTLAfterOpsFailed)*time.Second)
+	diagnosisController, err := diagnosis.NewController(cfg.Client, diagnosisclientset, diagnosisInformer.Aegis().V1alpha1().AegisDiagnosises(), 300*time.Second, cfg.AiBackend, cfg.DiagnosisLanguage, cfg.CollectorImage, cfg.EnableProm, prometheus, cfg.DiagnosisEnableExplain, !cfg.DiagnosisEnableCache, cfg.PodLogConfig, cfg.AgentConfig, cfg.ExplainCacheConfig, &cfg.Redaction, diagnosisCallback, remediation, cfg.RemediationMinConfidence)
 	if err != nil {
 		return nil, fmt.Errorf("fail to create diagnosis controller: %v", err)
 	}
 
-	alertController := alert.NewController(cfg.Client, alertclientInterface, workflowclientset, ruleController, workflowInformer.Argoproj().V1alpha1().Workflows(), aInformer, lifecycle)
+	alertController := alert.NewController(cfg.Client, alertclientInterface, workflowclientset, ruleController, workflowInformer.Argoproj().V1alpha1().Workflows(), aInformer, lifecycle, prometheus, cfg.AlertHistorySize, cfg.OrphanGC, metricsController, newTicketManagerFunc(cfg), cfg.AlertOpsSLO, metricsController)
 	nodecheckController := nodecheck.NewController(cfg.Client, nodecheckclientset, nodecheckInformer.Aegis().V1alpha1().AegisNodeHealthChecks(), podInformer, cmInformer, nodeInformer, lifecycle, cfg.EnableFireNodeEvent)
 	clustercheckController := clustercheck.NewController(cfg.Client, clustercheckclientset, clustercheckInformer.Aegis().V1alpha1().AegisClusterHealthChecks(), nodecheckclientset, nodecheckInformer.Aegis().V1alpha1().AegisNodeHealthChecks())
 
@@ -224,8 +299,23 @@ func NewAegisController(cfg *Configuration) (*AegisController, error) {
 	}
 
 	alertInterface := &controller.RealAlertController{
-		AlertClient: alertclientInterface,
-		AlertLister: aInformer.Lister(),
+		AlertClient:  alertclientInterface,
+		AlertLister:  aInformer.Lister(),
+		AlertIndexer: aInformer.Informer().GetIndexer(),
+	}
+
+	var archiveStore *archive.Store
+	var archiver *archive.Archiver
+	if cfg.Archive.Enable {
+		archiveStore, err = archive.Open(cfg.Archive.Path)
+		if err != nil {
+			return nil, fmt.Errorf("fail to open archive: %v", err)
+		}
+		archiver = archive.NewArchiver(archiveStore, cfg.Archive.Retention,
+			aInformer.Informer(),
+			diagnosisInformer.Aegis().V1alpha1().AegisDiagnosises().Informer(),
+			nodecheckInformer.Aegis().V1alpha1().AegisNodeHealthChecks().Informer(),
+			clustercheckInformer.Aegis().V1alpha1().AegisClusterHealthChecks().Informer())
 	}
 
 	pollerCfg := cfg.NodePoller
@@ -247,6 +337,9 @@ func NewAegisController(cfg *Configuration) (*AegisController, error) {
 	n := &AegisController{
 		cfg:                    cfg,
 		alertInterface:         alertInterface,
+		alertLister:            aInformer.Lister(),
+		alertsSynced:           aInformer.Informer().HasSynced,
+		resolveQueue:           workqueue.NewNamedRateLimitingQueue(workqueue.DefaultControllerRateLimiter(), "alert_resolve"),
 		sharedInformer:         sharedInformers,
 		alertInformer:          alertInformer,
 		workflowInformer:       workflowInformer,
@@ -263,11 +356,49 @@ func NewAegisController(cfg *Configuration) (*AegisController, error) {
 		clustercheckController: clustercheckController,
 		deviceawareController:  deviceawareController,
 		nodeStatusPoller:       nodePoller,
+		archiveStore:           archiveStore,
+		archiver:               archiver,
 	}
+	remediation.controller = n
 	return n, nil
 }
 
+// ArchiveStore returns the archive of finished objects, nil if disabled
+func (c *AegisController) ArchiveStore() *archive.Store {
+	return c.archiveStore
+}
+
+// StartReadCaches starts the informers of the enabled controllers, also on
+// followers, and returns them for the read-only api
+func (c *AegisController) StartReadCaches(ctx context.Context) *apis.V1Informers {
+	informers := &apis.V1Informers{
+		Nodes: c.sharedInformer.Core().V1().Nodes().Informer(),
+	}
+	if c.cfg.EnableAlert {
+		informers.Alerts = c.alertInformer.Aegis().V1alpha1().AegisAlerts().Informer()
+		informers.Rules = c.ruleInformer.Aegis().V1alpha1().AegisAlertOpsRules().Informer()
+		informers.Templates = c.templateInformer.Aegis().V1alpha1().AegisOpsTemplates().Informer()
+		c.alertInformer.Start(ctx.Done())
+		c.ruleInformer.Start(ctx.Done())
+		c.templateInformer.Start(ctx.Done())
+	}
+	if c.cfg.EnableDiagnosis {
+		informers.Diagnoses = c.diagnosisInfomer.Aegis().V1alpha1().AegisDiagnosises().Informer()
+		c.diagnosisInfomer.Start(ctx.Done())
+	}
+	if c.cfg.EnableHealthcheck {
+		informers.NodeChecks = c.nodecheckInformer.Aegis().V1alpha1().AegisNodeHealthChecks().Informer()
+		informers.ClusterChecks = c.clustercheckInformer.Aegis().V1alpha1().AegisClusterHealthChecks().Informer()
+		c.nodecheckInformer.Start(ctx.Done())
+		c.clustercheckInformer.Start(ctx.Done())
+	}
+	c.sharedInformer.Start(ctx.Done())
+	return informers
+}
+
 func (c *AegisController) Run(ctx context.Context) error {
+	c.startDedup(ctx, c.cfg.SyncWorkers)
+
 	var err error
 	if c.cfg.EnableLeaderElection {
 		lock := &resourcelock.LeaseLock{
@@ -319,9 +450,9 @@ func (c *AegisController) run(ctx context.Context) error {
 	c.sharedInformer.Start(ctx.Done())
 
 	var wg sync.WaitGroup
-	wg.Add(8)
+	wg.Add(9)
 
-	errChan := make(chan error, 8)
+	errChan := make(chan error, 9)
 
 	go func() {
 		defer wg.Done()
@@ -426,6 +557,16 @@ func (c *AegisController) run(ctx context.Context) error {
 		}
 	}()
 
+	go func() {
+		defer wg.Done()
+
+		if c.archiver == nil {
+			return
+		}
+
+		c.archiver.Run(ctx)
+	}()
+
 	wg.Wait()
 
 	// close chan
@@ -501,11 +642,25 @@ func (c *AegisController) filterLabels(labels map[string]string) map[string]stri
 	return filters
 }
 
-func (c *AegisController) CreateOrUpdateAlert(ctx context.Context, _alert *models.Alert) error {
+func (c *AegisController) CreateOrUpdateAlert(ctx context.Context, _alert *models.Alert) (err error) {
 	if _alert == nil {
 		return fmt.Errorf("empty alert entity")
 	}
 
+	ctx, span := tracing.Tracer().Start(ctx, "CreateOrUpdateAlert", trace.WithAttributes(
+		attribute.String("aegis.alert.type", string(_alert.Type)),
+		attribute.String("aegis.alert.status", string(_alert.Status)),
+		attribute.String("aegis.alert.fingerprint", _alert.FingerPrint),
+	))
+	defer func() {
+		tracing.End(span, err)
+	}()
+
+	// dedup lookups are served from the fingerprint index of the alert cache
+	if !cache.WaitForCacheSync(ctx.Done(), c.alertsSynced) {
+		return fmt.Errorf("alert cache not synced")
+	}
+
 	if _alert.Status == models.AlertStatusResolved {
 		return c.tryPatchAlertStatus(ctx, _alert)
 	} else {
@@ -517,7 +672,7 @@ func (c *AegisController) CreateOrUpdateAlert(ctx context.Context, _alert *model
 
 		if len(todos) > 0 {
 			klog.V(2).Infof("found %d alert(s) for fingerprint: %s, skipping alert creation", len(todos), _alert.FingerPrint)
-			return c.incurAlertCount(ctx, todos[0])
+			return c.incurAlertCount(ctx, todos[0], _alert.Status)
 		}
 	}
 
@@ -537,10 +692,17 @@ func (c *AegisController) CreateOrUpdateAlert(ctx context.Context, _alert *model
 
 	// severity
 	severity := labels["severity"]
+
+	// the alert sync continues the trace
+	annotations := make(map[string]string, len(c.cfg.SystemParas))
+	for key, value := range c.cfg.SystemParas {
+		annotations[key] = value
+	}
+
 	alert := &alertv1alpha1.AegisAlert{
 		ObjectMeta: metav1.ObjectMeta{
 			Labels:      labels,
-			Annotations: c.cfg.SystemParas,
+			Annotations: tracing.InjectAnnotations(ctx, annotations),
 		},
 		Spec: alertv1alpha1.AegisAlertSpec{
 			TTLStrategy: &v1alpha1.TTLStrategy{
@@ -570,7 +732,12 @@ func (c *AegisController) CreateOrUpdateAlert(ctx context.Context, _alert *model
 	}
 
 	generateName := getGeneratename(_alert)
-	if err := c.alertInterface.CreateAlertWithGenerateName(ctx, c.cfg.PublishNamespace, alert, generateName); err != nil {
+	if len(_alert.FingerPrint) > 0 {
+		err = c.createFingerprintAlert(ctx, alert, generateName, _alert.FingerPrint)
+	} else {
+		err = c.alertInterface.CreateAlertWithGenerateName(ctx, c.cfg.PublishNamespace, alert, generateName)
+	}
+	if err != nil {
 		klog.Errorf("fail to create alert %v: %v", _alert, err)
 		return err
 	}
@@ -583,76 +750,3 @@ type patchStatusValue struct {
 	Path  string `json:"path"`
 	Value string `json:"value"`
 }
-
-type patchCountValue struct {
-	Op    string `json:"op"`
-	Path  string `json:"path"`
-	Value int32  `json:"value"`
-}
-
-func (c *AegisController) tryPatchAlertStatus(ctx context.Context, _alert *models.Alert) error {
-	fingerprint := _alert.FingerPrint
-	if len(fingerprint) == 0 {
-		return nil
-	}
-
-	req, _ := labels.NewRequirement("fingerprint", selection.Equals, []string{fingerprint})
-	selector := labels.NewSelector()
-	selector = selector.Add(*req)
-
-	patches := []patchStatusValue{{
-		Op:    "replace",
-		Path:  "/status/status",
-		Value: _alert.Status,
-	}}
-	patchBytes, _ := json.Marshal(patches)
-	if err := c.alertInterface.PatchAlertWithLabelSelector(ctx, c.cfg.PublishNamespace, selector, patchBytes); err != nil {
-		klog.Errorf("fail to patch alert %v: %v", _alert, err)
-		return err
-	}
-
-	return nil
-}
-
-func (c *AegisController) tryFetchInCompletedAlerts(ctx context.Context, _alert *models.Alert) ([]*alertv1alpha1.AegisAlert, error) {
-	fingerprint := _alert.FingerPrint
-	if len(fingerprint) == 0 {
-		return nil, nil
-	}
-
-	req, _ := labels.NewRequirement("fingerprint", selection.Equals, []string{fingerprint})
-	selector := labels.NewSelector()
-	selector = selector.Add(*req)
-
-	alerts, err := c.alertInterface.ListAlertWithLabelSelector(ctx, c.cfg.PublishNamespace, selector)
-	if err != nil {
-		klog.Errorf("fail to list alert with label selector %v: %v", selector, err)
-		return nil, err
-	}
-
-	todos := make([]*alertv1alpha1.AegisAlert, 0)
-	for _, alert := range alerts {
-		if alert.Status.OpsStatus.Status != alertv1alpha1.OpsStatusSucceeded && alert.Status.OpsStatus.Status != alertv1alpha1.OpsStatusFailed {
-			todos = append(todos, alert)
-		}
-	}
-
-	klog.V(2).Infof("list %d todo alerts for fingerprint %v", len(todos), fingerprint)
-
-	return todos, nil
-}
-
-func (c *AegisController) incurAlertCount(ctx context.Context, todo *alertv1alpha1.AegisAlert) error {
-	patches := []patchCountValue{{
-		Op:    "replace",
-		Path:  "/status/count",
-		Value: todo.Status.Count + 1,
-	}}
-	patchBytes, _ := json.Marshal(patches)
-	if err := c.alertInterface.PatchAlert(ctx, c.cfg.PublishNamespace, todo.Name, patchBytes); err != nil {
-		klog.Errorf("fail to patch alert %v: %v", todo, err)
-		return err
-	}
-
-	return nil
-}
@@ -0,0 +1,251 @@
+package controller
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/scitix/aegis/api/models"
+	alertv1alpha1 "github.com/scitix/aegis/pkg/apis/alert/v1alpha1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/client-go/util/retry"
+	"k8s.io/klog/v2"
+)
+
+const (
+	// maxCreateAttempts bounds the names tried for a new alert of a fingerprint
+	maxCreateAttempts = 5
+
+	// maxResolveRetries bounds the retries of a resolved status patch
+	maxResolveRetries = 5
+)
+
+// startDedup starts the alert informer and the resolved alert workers. The
+// webhook is served by every replica, so they run before leader election.
+func (c *AegisController) startDedup(ctx context.Context, workers int) {
+	c.alertInformer.Start(ctx.Done())
+
+	go func() {
+		defer utilruntime.HandleCrash()
+		defer c.resolveQueue.ShutDown()
+
+		if ok := cache.WaitForNamedCacheSync("alert dedup", ctx.Done(), c.alertsSynced); !ok {
+			return
+		}
+		for i := 0; i < workers; i++ {
+			go wait.UntilWithContext(ctx, c.runResolveWorker, time.Second)
+		}
+		<-ctx.Done()
+	}()
+}
+
+// fingerprintName returns the k-th alert name of the fingerprint. Names are
+// deterministic so replicas receiving the same alert race on create and the
+// loser increments the count instead of creating a duplicate.
+func fingerprintName(generateName, fingerprint string, k int) string {
+	sum := sha256.Sum256([]byte(fingerprint))
+	return fmt.Sprintf("%s%x-%d", generateName, sum[:4], k)
+}
+
+func isCompletedAlert(alert *alertv1alpha1.AegisAlert) bool {
+	return alert.Status.OpsStatus.Status == alertv1alpha1.OpsStatusSucceeded || alert.Status.OpsStatus.Status == alertv1alpha1.OpsStatusFailed
+}
+
+func (c *AegisController) tryFetchInCompletedAlerts(ctx context.Context, _alert *models.Alert) ([]*alertv1alpha1.AegisAlert, error) {
+	fingerprint := _alert.FingerPrint
+	if len(fingerprint) == 0 {
+		return nil, nil
+	}
+
+	alerts, err := c.alertInterface.ListAlertWithFingerprint(ctx, c.cfg.PublishNamespace, fingerprint)
+	if err != nil {
+		klog.Errorf("fail to list alert with fingerprint %s: %v", fingerprint, err)
+		return nil, err
+	}
+
+	todos := make([]*alertv1alpha1.AegisAlert, 0)
+	for _, alert := range alerts {
+		if !isCompletedAlert(alert) {
+			todos = append(todos, alert)
+		}
+	}
+
+	klog.V(2).Infof("list %d todo alerts for fingerprint %v", len(todos), fingerprint)
+
+	return todos, nil
+}
+
+// incurAlertCount increments the count of the alert with optimistic
+// concurrency, refetching the alert on conflict
+func (c *AegisController) incurAlertCount(ctx context.Context, todo *alertv1alpha1.AegisAlert, status string) error {
+	alert := todo
+	err := retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		updated := alert.DeepCopy()
+		// the status of an alert just created by another replica may be unset yet
+		if updated.Status.Count == 0 {
+			updated.Status.Count = 1
+			if len(updated.Status.Status) == 0 {
+				updated.Status.Status = status
+			}
+		}
+		updated.Status.Count++
+
+		err := c.alertInterface.UpdateAlertStatus(ctx, updated)
+		if apierrors.IsConflict(err) {
+			latest, getErr := c.alertInterface.GetAlert(ctx, alert.Namespace, alert.Name)
+			if getErr != nil {
+				return getErr
+			}
+			alert = latest
+		}
+		return err
+	})
+	if err != nil {
+		klog.Errorf("fail to increment count of alert %s/%s: %v", todo.Namespace, todo.Name, err)
+		return err
+	}
+
+	return nil
+}
+
+// createFingerprintAlert creates the alert with the first free name of its
+// fingerprint, or increments the count of an incomplete alert created
+// concurrently under that name
+func (c *AegisController) createFingerprintAlert(ctx context.Context, alert *alertv1alpha1.AegisAlert, generateName, fingerprint string) error {
+	existing, err := c.alertInterface.ListAlertWithFingerprint(ctx, c.cfg.PublishNamespace, fingerprint)
+	if err != nil {
+		return err
+	}
+	taken := make(map[string]bool, len(existing))
+	for _, a := range existing {
+		taken[a.Name] = true
+	}
+
+	k := 0
+	for attempt := 0; attempt < maxCreateAttempts; attempt++ {
+		for taken[fingerprintName(generateName, fingerprint, k)] {
+			k++
+		}
+		name := fingerprintName(generateName, fingerprint, k)
+
+		template := alert.DeepCopy()
+		template.Name = name
+		err := c.alertInterface.CreateAlert(ctx, c.cfg.PublishNamespace, template)
+		if err == nil || apierrors.IsConflict(err) {
+			// a conflict on the status means another replica counted it already
+			return nil
+		}
+		if !apierrors.IsAlreadyExists(err) {
+			return err
+		}
+
+		current, err := c.alertInterface.GetAlert(ctx, c.cfg.PublishNamespace, name)
+		if apierrors.IsNotFound(err) {
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		if !isCompletedAlert(current) {
+			klog.V(2).Infof("alert %s of fingerprint %s created concurrently, increment its count", name, fingerprint)
+			return c.incurAlertCount(ctx, current, alert.Status.Status)
+		}
+		taken[name] = true
+	}
+
+	return fmt.Errorf("no free alert name for fingerprint %s after %d attempts", fingerprint, maxCreateAttempts)
+}
+
+// tryPatchAlertStatus queues the alerts of the fingerprint to be resolved
+func (c *AegisController) tryPatchAlertStatus(ctx context.Context, _alert *models.Alert) error {
+	fingerprint := _alert.FingerPrint
+	if len(fingerprint) == 0 {
+		return nil
+	}
+
+	alerts, err := c.alertInterface.ListAlertWithFingerprint(ctx, c.cfg.PublishNamespace, fingerprint)
+	if err != nil {
+		klog.Errorf("fail to list alert with fingerprint %s: %v", fingerprint, err)
+		return err
+	}
+
+	for _, alert := range alerts {
+		if alert.Status.Status == models.AlertStatusResolved {
+			continue
+		}
+		key, err := cache.MetaNamespaceKeyFunc(alert)
+		if err != nil {
+			utilruntime.HandleError(err)
+			continue
+		}
+		c.resolveQueue.Add(key)
+	}
+
+	return nil
+}
+
+func (c *AegisController) runResolveWorker(ctx context.Context) {
+	for c.processNextResolveItem(ctx) {
+	}
+}
+
+func (c *AegisController) processNextResolveItem(ctx context.Context) bool {
+	obj, shutdown := c.resolveQueue.Get()
+	if shutdown {
+		return false
+	}
+
+	defer c.resolveQueue.Done(obj)
+
+	err := c.syncResolvedAlert(ctx, obj.(string))
+	if err == nil {
+		c.resolveQueue.Forget(obj)
+		return true
+	}
+
+	if c.resolveQueue.NumRequeues(obj) < maxResolveRetries {
+		klog.Warningf("fail to resolve alert %s, retry: %v", obj, err)
+		c.resolveQueue.AddRateLimited(obj)
+		return true
+	}
+
+	utilruntime.HandleError(fmt.Errorf("dropping alert %s out of the resolve queue: %v", obj, err))
+	c.resolveQueue.Forget(obj)
+	return true
+}
+
+// syncResolvedAlert patches the status of a cached alert to resolved
+func (c *AegisController) syncResolvedAlert(ctx context.Context, key string) error {
+	namespace, name, err := cache.SplitMetaNamespaceKey(key)
+	if err != nil {
+		return nil
+	}
+
+	alert, err := c.alertLister.AegisAlerts(namespace).Get(name)
+	if apierrors.IsNotFound(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if alert.Status.Status == models.AlertStatusResolved {
+		return nil
+	}
+
+	patches := []patchStatusValue{{
+		Op:    "replace",
+		Path:  "/status/status",
+		Value: models.AlertStatusResolved,
+	}}
+	patchBytes, _ := json.Marshal(patches)
+	err = c.alertInterface.PatchAlertStatus(ctx, namespace, name, patchBytes)
+	if apierrors.IsNotFound(err) {
+		return nil
+	}
+	return err
+}
@@ -0,0 +1,177 @@
+package controller
+
+import (
+	"context"
+	"testing"
+
+	"github.com/scitix/aegis/api/models"
+	alertv1alpha1 "github.com/scitix/aegis/pkg/apis/alert/v1alpha1"
+	"github.com/scitix/aegis/pkg/controller"
+	"github.com/scitix/aegis/pkg/generated/alert/clientset/versioned/fake"
+	alertlisters "github.com/scitix/aegis/pkg/generated/alert/listers/alert/v1alpha1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	k8stesting "k8s.io/client-go/testing"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/client-go/util/workqueue"
+)
+
+// newDedupTestController serves the dedup lookups from an indexer filled by
+// the test, so it can lag behind the fake apiserver as a real cache does
+func newDedupTestController() (*AegisController, *fake.Clientset, cache.Indexer) {
+	client := fake.NewSimpleClientset()
+	indexer := cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{
+		controller.AlertFingerprintIndex: controller.AlertFingerprintIndexFunc,
+	})
+	lister := alertlisters.NewAegisAlertLister(indexer)
+	c := &AegisController{
+		cfg: &Configuration{PublishNamespace: "monitoring"},
+		alertInterface: &controller.RealAlertController{
+			AlertClient:  client,
+			AlertLister:  lister,
+			AlertIndexer: indexer,
+		},
+		alertLister:  lister,
+		alertsSynced: func() bool { return true },
+		resolveQueue: workqueue.NewNamedRateLimitingQueue(workqueue.DefaultControllerRateLimiter(), "alert_resolve"),
+	}
+	return c, client, indexer
+}
+
+func newDedupTestAlert(status string) *models.Alert {
+	return &models.Alert{
+		AlertSourceType: "prometheus",
+		Type:            "NodeNotReady",
+		Status:          status,
+		InvolvedObject:  models.AlertInvolvedObject{Kind: "Node", Name: "node1"},
+		FingerPrint:     "abc",
+	}
+}
+
+func listTestAlerts(t *testing.T, client *fake.Clientset) []alertv1alpha1.AegisAlert {
+	t.Helper()
+	list, err := client.AegisV1alpha1().AegisAlerts("monitoring").List(context.Background(), metav1.ListOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return list.Items
+}
+
+// syncTestCache copies the alerts of the fake apiserver to the cache
+func syncTestCache(t *testing.T, client *fake.Clientset, indexer cache.Indexer) {
+	t.Helper()
+	alerts := listTestAlerts(t, client)
+	for i := range alerts {
+		if err := indexer.Update(&alerts[i]); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestCreateOrIncrementAlert(t *testing.T) {
+	c, client, indexer := newDedupTestController()
+	ctx := context.Background()
+
+	if err := c.CreateOrUpdateAlert(ctx, newDedupTestAlert(models.AlertStatusFiring)); err != nil {
+		t.Fatal(err)
+	}
+	alerts := listTestAlerts(t, client)
+	if len(alerts) != 1 || alerts[0].Status.Count != 1 {
+		t.Fatalf("expected 1 alert counted once, got %+v", alerts)
+	}
+	name := alerts[0].Name
+	if name != fingerprintName("prometheus-nodenotready-", "abc", 0) {
+		t.Errorf("unexpected alert name %s", name)
+	}
+
+	// the cache lags, the alert of the same name is counted
+	if err := c.CreateOrUpdateAlert(ctx, newDedupTestAlert(models.AlertStatusFiring)); err != nil {
+		t.Fatal(err)
+	}
+	// the alert is found in the cache
+	syncTestCache(t, client, indexer)
+	if err := c.CreateOrUpdateAlert(ctx, newDedupTestAlert(models.AlertStatusFiring)); err != nil {
+		t.Fatal(err)
+	}
+	alerts = listTestAlerts(t, client)
+	if len(alerts) != 1 || alerts[0].Status.Count != 3 {
+		t.Fatalf("expected 1 alert counted 3 times, got %+v", alerts)
+	}
+
+	// a completed alert is not reused
+	completed := alerts[0].DeepCopy()
+	completed.Status.OpsStatus.Status = alertv1alpha1.OpsStatusSucceeded
+	if _, err := client.AegisV1alpha1().AegisAlerts("monitoring").UpdateStatus(ctx, completed, metav1.UpdateOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	syncTestCache(t, client, indexer)
+	if err := c.CreateOrUpdateAlert(ctx, newDedupTestAlert(models.AlertStatusFiring)); err != nil {
+		t.Fatal(err)
+	}
+	if alerts = listTestAlerts(t, client); len(alerts) != 2 || alerts[1].Name != fingerprintName("prometheus-nodenotready-", "abc", 1) {
+		t.Errorf("expected a new alert for the completed one, got %+v", alerts)
+	}
+}
+
+func TestIncrementAlertConflict(t *testing.T) {
+	c, client, indexer := newDedupTestController()
+	ctx := context.Background()
+
+	if err := c.CreateOrUpdateAlert(ctx, newDedupTestAlert(models.AlertStatusFiring)); err != nil {
+		t.Fatal(err)
+	}
+	syncTestCache(t, client, indexer)
+
+	// another replica increments the count first
+	conflicts := 0
+	client.PrependReactor("update", "aegisalerts", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		if action.GetSubresource() != "status" || conflicts > 0 {
+			return false, nil, nil
+		}
+		conflicts++
+		alert := action.(k8stesting.UpdateAction).GetObject().(*alertv1alpha1.AegisAlert).DeepCopy()
+		alert.Status.Count = 5
+		if err := client.Tracker().Update(alertv1alpha1.SchemeGroupVersion.WithResource("aegisalerts"), alert, alert.Namespace); err != nil {
+			return true, nil, err
+		}
+		return true, nil, apierrors.NewConflict(alertv1alpha1.Resource("aegisalerts"), alert.Name, nil)
+	})
+
+	if err := c.CreateOrUpdateAlert(ctx, newDedupTestAlert(models.AlertStatusFiring)); err != nil {
+		t.Fatal(err)
+	}
+	if alerts := listTestAlerts(t, client); alerts[0].Status.Count != 6 {
+		t.Errorf("expected the count incremented after the conflict, got %d", alerts[0].Status.Count)
+	}
+}
+
+func TestResolveAlert(t *testing.T) {
+	c, client, indexer := newDedupTestController()
+	ctx := context.Background()
+
+	if err := c.CreateOrUpdateAlert(ctx, newDedupTestAlert(models.AlertStatusFiring)); err != nil {
+		t.Fatal(err)
+	}
+	syncTestCache(t, client, indexer)
+
+	if err := c.CreateOrUpdateAlert(ctx, newDedupTestAlert(models.AlertStatusResolved)); err != nil {
+		t.Fatal(err)
+	}
+	if c.resolveQueue.Len() != 1 {
+		t.Fatalf("expected 1 alert queued, got %d", c.resolveQueue.Len())
+	}
+	c.processNextResolveItem(ctx)
+	if alerts := listTestAlerts(t, client); alerts[0].Status.Status != models.AlertStatusResolved {
+		t.Errorf("expected alert resolved, got %s", alerts[0].Status.Status)
+	}
+
+	// resolved alerts are not queued again
+	syncTestCache(t, client, indexer)
+	if err := c.CreateOrUpdateAlert(ctx, newDedupTestAlert(models.AlertStatusResolved)); err != nil {
+		t.Fatal(err)
+	}
+	if c.resolveQueue.Len() != 0 {
+		t.Errorf("expected resolved alert skipped, got %d queued", c.resolveQueue.Len())
+	}
+}
@@ -4,6 +4,7 @@ import (
 	"k8s.io/apimachinery/pkg/util/errors"
 
 	alertv1alpha1 "github.com/scitix/aegis/pkg/apis/alert/v1alpha1"
+	diagnosisv1alpha1 "github.com/scitix/aegis/pkg/apis/diagnosis/v1alpha1"
 	nodecheckv1apha1 "github.com/scitix/aegis/pkg/apis/nodecheck/v1alpha1"
 	"github.com/scitix/aegis/pkg/controller"
 	"k8s.io/klog/v2"
@@ -127,6 +128,22 @@ func (l *lifecycle) OnOpsWorkflowFailed(alert *alertv1alpha1.AegisAlert) error {
 	return errors.NewAggregate(errs)
 }
 
+// OnOpsEscalated is dispatched to the callbacks implementing EscalationCallbackInterface
+func (l *lifecycle) OnOpsEscalated(alert *alertv1alpha1.AegisAlert) error {
+	errs := make([]error, 0)
+	for _, callback := range l.callbacks {
+		escalation, ok := callback.(controller.EscalationCallbackInterface)
+		if !ok {
+			continue
+		}
+		err := escalation.OnOpsEscalated(alert)
+		if err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.NewAggregate(errs)
+}
+
 func (l *lifecycle) OnNodeCheckUpdate(nodecheck *nodecheckv1apha1.AegisNodeHealthCheck) error {
 	errs := make([]error, 0)
 	for _, callback := range l.callbacks {
@@ -137,3 +154,17 @@ func (l *lifecycle) OnNodeCheckUpdate(nodecheck *nodecheckv1apha1.AegisNodeHealt
 	}
 	return errors.NewAggregate(errs)
 }
+
+// diagnosisCallbacks dispatches the finished diagnoses to all the callbacks
+type diagnosisCallbacks []controller.DiagnosisCallbackInterface
+
+func (d diagnosisCallbacks) OnDiagnosisFinished(diagnosis *diagnosisv1alpha1.AegisDiagnosis) error {
+	errs := make([]error, 0)
+	for _, callback := range d {
+		err := callback.OnDiagnosisFinished(diagnosis)
+		if err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.NewAggregate(errs)
+}
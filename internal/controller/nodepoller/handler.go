@@ -185,7 +185,7 @@ func (p *NodeStatusPoller) incurAlertCount(ctx context.Context, alert *alertv1al
 		klog.Errorf("nodepoller: failed to marshal patch for alert %s: %v", alert.Name, err)
 		return err
 	}
-	if err := p.alertInterface.PatchAlert(ctx, p.cfg.PublishNamespace, alert.Name, patchBytes); err != nil {
+	if err := p.alertInterface.PatchAlertStatus(ctx, p.cfg.PublishNamespace, alert.Name, patchBytes); err != nil {
 		klog.Errorf("nodepoller: failed to patch alert %s: %v", alert.Name, err)
 		return err
 	}
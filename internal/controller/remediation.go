@@ -0,0 +1,78 @@
+package controller
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/scitix/aegis/api/models"
+	diagnosisv1alpha1 "github.com/scitix/aegis/pkg/apis/diagnosis/v1alpha1"
+	"github.com/scitix/aegis/pkg/controller"
+	"k8s.io/klog/v2"
+)
+
+const remediationTimeout = 30 * time.Second
+
+// diagnosisRemediation raises the alert of the diagnoses in auto remediation,
+// so that the rules and templates act on them
+type diagnosisRemediation struct {
+	// set once the aegis controller is created
+	controller *AegisController
+}
+
+func (r *diagnosisRemediation) RaiseAlert(ctx context.Context, diagnosis *diagnosisv1alpha1.AegisDiagnosis) error {
+	alert := remediationAlert(diagnosis)
+	if alert == nil {
+		return nil
+	}
+
+	if r.controller == nil || !r.controller.cfg.EnableAlert {
+		return fmt.Errorf("%w, skip the remediation of diagnosis %s/%s", controller.ErrAlertDisabled, diagnosis.Namespace, diagnosis.Name)
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, remediationTimeout)
+	defer cancel()
+	if err := r.controller.CreateOrUpdateAlert(ctx, alert); err != nil {
+		return fmt.Errorf("fail to raise alert %s for diagnosis %s/%s: %v", alert.Type, diagnosis.Namespace, diagnosis.Name, err)
+	}
+	klog.Infof("Raised alert %s for diagnosis %s/%s", alert.Type, diagnosis.Namespace, diagnosis.Name)
+	return nil
+}
+
+// remediationAlert is the alert of the diagnosis, nil unless in auto
+// remediation with a recognised category
+func remediationAlert(diagnosis *diagnosisv1alpha1.AegisDiagnosis) *models.Alert {
+	remediation := diagnosis.Status.Remediation
+	if diagnosis.Spec.Remediation != diagnosisv1alpha1.RemediationAuto || remediation == nil {
+		return nil
+	}
+
+	details := map[string]string{
+		"diagnosis":          diagnosis.Name,
+		"diagnosisNamespace": diagnosis.Namespace,
+		"category":           string(remediation.Category),
+		"categorySource":     string(remediation.Source),
+	}
+	if analysis := diagnosis.Status.Analysis; analysis != nil {
+		details["rootCause"] = analysis.RootCause
+		details["confidence"] = strconv.Itoa(int(analysis.Confidence))
+	} else if result := diagnosis.Status.Result; result != nil && len(result.Failures) > 0 {
+		details["rootCause"] = result.Failures[0]
+	}
+
+	object := diagnosis.Spec.Object
+	return &models.Alert{
+		AlertSourceType: models.DiagnosisAlertSource,
+		Type:            remediation.AlertType,
+		Status:          models.AlertStatusFiring,
+		InvolvedObject: models.AlertInvolvedObject{
+			Kind:      string(object.Kind),
+			Name:      object.Name,
+			Namespace: object.Namespace,
+			Node:      object.Node,
+		},
+		Details:     details,
+		FingerPrint: remediation.Fingerprint,
+	}
+}
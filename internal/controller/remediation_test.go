@@ -0,0 +1,46 @@
+package controller
+
+import (
+	"testing"
+
+	"github.com/scitix/aegis/api/models"
+	diagnosisv1alpha1 "github.com/scitix/aegis/pkg/apis/diagnosis/v1alpha1"
+)
+
+func TestRemediationAlert(t *testing.T) {
+	diagnosis := &diagnosisv1alpha1.AegisDiagnosis{
+		Spec: diagnosisv1alpha1.AegisDiagnosisSpec{
+			Object:      diagnosisv1alpha1.AegisDiagnosisObject{Kind: "Pod", Name: "app", Namespace: "default"},
+			Remediation: diagnosisv1alpha1.RemediationSuggest,
+		},
+		Status: diagnosisv1alpha1.AegisDiagnosisStatus{
+			Result: &diagnosisv1alpha1.DiagnosisResult{Failures: []string{"ImagePullBackOff"}},
+			Remediation: &diagnosisv1alpha1.DiagnosisRemediationStatus{
+				Category:    diagnosisv1alpha1.CategoryImage,
+				Source:      diagnosisv1alpha1.CategorySourceSignature,
+				AlertType:   "DiagnosedImageIssue",
+				Fingerprint: "fingerprint",
+			},
+		},
+	}
+	diagnosis.Name = "diagnose-app"
+
+	if alert := remediationAlert(diagnosis); alert != nil {
+		t.Errorf("expected no alert in suggest mode, got %+v", alert)
+	}
+
+	diagnosis.Spec.Remediation = diagnosisv1alpha1.RemediationAuto
+	alert := remediationAlert(diagnosis)
+	if alert == nil {
+		t.Fatal("expected alert in auto mode")
+	}
+	if alert.AlertSourceType != models.DiagnosisAlertSource || alert.Type != "DiagnosedImageIssue" || alert.Status != models.AlertStatusFiring || alert.FingerPrint != "fingerprint" {
+		t.Errorf("unexpected alert %+v", alert)
+	}
+	if alert.InvolvedObject.Kind != "Pod" || alert.InvolvedObject.Name != "app" || alert.InvolvedObject.Namespace != "default" {
+		t.Errorf("unexpected involved object %+v", alert.InvolvedObject)
+	}
+	if alert.Details["diagnosis"] != "diagnose-app" || alert.Details["rootCause"] != "ImagePullBackOff" {
+		t.Errorf("unexpected details %v", alert.Details)
+	}
+}
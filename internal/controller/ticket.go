@@ -0,0 +1,43 @@
+package controller
+
+import (
+	"context"
+
+	selfticket "github.com/scitix/aegis/internal/selfhealing/ticket"
+	"github.com/scitix/aegis/pkg/controller/alert"
+	"github.com/scitix/aegis/pkg/ticketmodel"
+	corev1 "k8s.io/api/core/v1"
+)
+
+const ticketSupervisorAegis = "aegis"
+
+// newTicketManagerFunc returns the factory of the ticket managers used by the
+// CreateTicket escalation, nil if no ticket system is configured.
+func newTicketManagerFunc(cfg *Configuration) alert.TicketManagerFunc {
+	if len(cfg.EscalationTicketSystem) == 0 {
+		return nil
+	}
+
+	system := selfticket.TicketSystem(cfg.EscalationTicketSystem)
+	return func(ctx context.Context, node *corev1.Node) (ticketmodel.TicketManagerInterface, error) {
+		return selfticket.NewTicketManagerBySystem(ctx, system, &ticketmodel.TicketManagerArgs{
+			Client:      cfg.Client,
+			Node:        node,
+			Region:      cfg.SystemParas["region"],
+			ClusterName: cfg.SystemParas["cluster"],
+			OrgName:     cfg.SystemParas["orgname"],
+			NodeName:    node.Name,
+			Ip:          nodeInternalIP(node),
+			User:        ticketSupervisorAegis,
+		})
+	}
+}
+
+func nodeInternalIP(node *corev1.Node) string {
+	for _, address := range node.Status.Addresses {
+		if address.Type == corev1.NodeInternalIP {
+			return address.Address
+		}
+	}
+	return ""
+}
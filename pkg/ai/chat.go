@@ -0,0 +1,62 @@
+package ai
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+
+	kai "github.com/k8sgpt-ai/k8sgpt/pkg/ai"
+	"github.com/k8sgpt-ai/k8sgpt/pkg/util"
+	openai "github.com/sashabaranov/go-openai"
+)
+
+// chatProviders are the providers speaking the OpenAI chat completions API,
+// including the tool calls
+var chatProviders = map[string]bool{
+	"openai":  true,
+	"localai": true,
+}
+
+// ChatClient is a chat completions client with tool calls of a provider
+type ChatClient struct {
+	Client      *openai.Client
+	Model       string
+	Temperature float32
+}
+
+// NewChatClient creates the chat client of the provider, which has to be
+// OpenAI compatible
+func NewChatClient(provider kai.AIProvider, headers []string) (*ChatClient, error) {
+	if !chatProviders[provider.Name] {
+		return nil, fmt.Errorf("AI provider %s does not support tool calls", provider.Name)
+	}
+
+	config := openai.DefaultConfig(provider.Password)
+	if provider.BaseURL != "" {
+		config.BaseURL = provider.BaseURL
+	}
+	if provider.OrganizationId != "" {
+		config.OrgID = provider.OrganizationId
+	}
+
+	transport := &http.Transport{}
+	if provider.ProxyEndpoint != "" {
+		proxyUrl, err := url.Parse(provider.ProxyEndpoint)
+		if err != nil {
+			return nil, err
+		}
+		transport.Proxy = http.ProxyURL(proxyUrl)
+	}
+	config.HTTPClient = &http.Client{
+		Transport: &kai.OpenAIHeaderTransport{
+			Origin:  transport,
+			Headers: util.NewHeaders(headers),
+		},
+	}
+
+	return &ChatClient{
+		Client:      openai.NewClientWithConfig(config),
+		Model:       provider.Model,
+		Temperature: provider.Temperature,
+	}, nil
+}
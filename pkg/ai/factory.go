@@ -16,14 +16,32 @@ type AIProviderFactory interface {
 type DefaultFactory struct{}
 
 func (f *DefaultFactory) Load(name string, headers []string) (kai.IAI, string, error) {
+	aiProvider, err := LoadProvider(name)
+	if err != nil {
+		return nil, "", err
+	}
+
+	aiProvider.CustomHeaders = util.NewHeaders(headers)
+	aiClient := kai.NewClient(aiProvider.Name)
+
+	if err := aiClient.Configure(&aiProvider); err != nil {
+		return nil, "", err
+	}
+
+	return aiClient, aiProvider.Name, nil
+}
+
+// LoadProvider returns the config of the named AI provider, or of the
+// default provider if name is empty
+func LoadProvider(name string) (kai.AIProvider, error) {
 	var configAI kai.AIConfiguration
 	if err := viper.UnmarshalKey("ai", &configAI); err != nil {
-		return nil, "", err
+		return kai.AIProvider{}, err
 	}
 	klog.V(4).Infof("Loaded AI config: %+v", configAI)
 
 	if len(configAI.Providers) == 0 {
-		return nil, "", errors.New("AI providers not defined")
+		return kai.AIProvider{}, errors.New("AI providers not defined")
 	}
 
 	if name == "" && configAI.DefaultProvider != "" {
@@ -42,15 +60,7 @@ func (f *DefaultFactory) Load(name string, headers []string) (kai.IAI, string, e
 	}
 
 	if aiProvider.Name == "" {
-		return nil, "", errors.New("AI provider not found")
+		return kai.AIProvider{}, errors.New("AI provider not found")
 	}
-
-	aiProvider.CustomHeaders = util.NewHeaders(headers)
-	aiClient := kai.NewClient(aiProvider.Name)
-
-	if err := aiClient.Configure(&aiProvider); err != nil {
-		return nil, "", err
-	}
-
-	return aiClient, aiProvider.Name, nil
+	return aiProvider, nil
 }
@@ -71,6 +71,61 @@ var PromptRegistry = map[string]PromptTemplate{
 		Content: pytorchJobPromptTemplate,
 		Render:  RenderTextTemplate,
 	},
+	"TFJob": {
+		Name:    "TFJob",
+		Content: tfJobPromptTemplate,
+		Render:  RenderTextTemplate,
+	},
+	"MPIJob": {
+		Name:    "MPIJob",
+		Content: mpiJobPromptTemplate,
+		Render:  RenderTextTemplate,
+	},
+	"XGBoostJob": {
+		Name:    "XGBoostJob",
+		Content: xgboostJobPromptTemplate,
+		Render:  RenderTextTemplate,
+	},
+	"PaddleJob": {
+		Name:    "PaddleJob",
+		Content: paddleJobPromptTemplate,
+		Render:  RenderTextTemplate,
+	},
+	"Workflow": {
+		Name:    "Workflow",
+		Content: workflowPromptTemplate,
+		Render:  RenderTextTemplate,
+	},
+	"Deployment": {
+		Name:    "Deployment",
+		Content: workloadPromptTemplate,
+		Render:  RenderTextTemplate,
+	},
+	"StatefulSet": {
+		Name:    "StatefulSet",
+		Content: workloadPromptTemplate,
+		Render:  RenderTextTemplate,
+	},
+	"DaemonSet": {
+		Name:    "DaemonSet",
+		Content: daemonSetPromptTemplate,
+		Render:  RenderTextTemplate,
+	},
+	"Job": {
+		Name:    "Job",
+		Content: jobPromptTemplate,
+		Render:  RenderTextTemplate,
+	},
+	"CronJob": {
+		Name:    "CronJob",
+		Content: cronJobPromptTemplate,
+		Render:  RenderTextTemplate,
+	},
+	"Generic": {
+		Name:    "Generic",
+		Content: genericPromptTemplate,
+		Render:  RenderTextTemplate,
+	},
 }
 
 func GetRenderedPrompt(kind string, data PromptData) (string, error) {
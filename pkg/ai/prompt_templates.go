@@ -152,6 +152,198 @@ Analysis: {结合异常摘要、事件和日志分析根因，关键日志片段
 Solution: {最关键的处理建议，不超过 100 字}
 `
 
+const workflowPromptTemplate = `
+你是一个 Kubernetes + Argo Workflows 故障诊断专家，以下是一个 Argo Workflow 的详细信息。请你判断该工作流失败的原因，并用中文给出诊断建议。
+
+该 Workflow 由多个步骤（step / DAG task）组成，每个步骤在独立的 Pod 中运行，任一步骤失败都会导致整个 Workflow 失败。
+
+【工作流基本信息】
+Workflow 名称: {{ index .Metadata "WorkflowName" }}
+Workflow 状态: {{ index .Metadata "WorkflowPhase" }}
+Workflow 消息: {{ index .Metadata "WorkflowMessage" }}
+开始时间: {{ index .Metadata "StartedAt" }}
+结束时间: {{ index .Metadata "FinishedAt" }}
+
+【工作流状态诊断】
+异常摘要（来自 Workflow 及失败步骤的状态字段）: --- {{.ErrorInfo}} ---
+Workflow 历史告警事件： --- {{.EventInfo}} ---
+
+【失败步骤（共 {{ index .Metadata "FailedStepCount" }} 个，按执行顺序）】
+{{ index .Metadata "FailedSteps" }}
+
+【失败步骤容器日志】
+{{- if .Keywords}}
+以下日志已针对关键字 [{{.Keywords}}] 进行过滤：优先收录含上述关键字的行，剩余配额由最新末尾日志补充，整体按时序排列。
+{{- else}}
+以下为失败步骤容器最新日志（末尾若干行）。
+{{- end}}
+--- {{.LogInfo}} ---
+
+请按以下结构化格式返回诊断结论，不超过 1000 字：
+Healthy: {Yes / No，表示是否存在故障，如果 Workflow 仍在运行且没有失败步骤请直接说明}
+Error: {一句话简洁总结最先失败的步骤及其最可能的失败原因}
+Analysis: {结合工作流状态、失败步骤、事件、日志等，简要分析故障根因。请尽可能摘录较关键的日志片段，使用 **markdown 代码块** 包裹}
+Solution: {给出最关键的一句总结，不超过 100 字}
+`
+
+// workloadPromptTemplate is shared by the Deployment and StatefulSet kinds
+const workloadPromptTemplate = `
+你是一个 Kubernetes 集群故障诊断专家，以下是一个 {{ index .Metadata "Kind" }} 工作负载的详细信息。请你判断该工作负载的发布（rollout）及其 Pod 是否存在异常，并用中文给出诊断建议。
+
+【工作负载基本信息】
+类型: {{ index .Metadata "Kind" }}
+名称: {{ index .Metadata "Name" }}
+期望副本数: {{ index .Metadata "Desired" }}
+已更新副本数: {{ index .Metadata "Updated" }}
+就绪副本数: {{ index .Metadata "Ready" }}
+可用副本数: {{ index .Metadata "Available" }}
+不可用副本数: {{ index .Metadata "Unavailable" }}
+{{- with index .Metadata "Revision" }}
+当前版本（revision）: {{ . }}
+{{- end }}
+{{- with index .Metadata "NewReplicaSet" }}
+新 ReplicaSet: {{ . }}
+{{- end }}
+{{- with index .Metadata "UpdateStrategy" }}
+更新策略: {{ . }}
+{{- end }}
+{{- with index .Metadata "CurrentRevision" }}
+当前版本: {{ . }}，目标版本: {{ index $.Metadata "UpdateRevision" }}
+{{- end }}
+{{- with index .Metadata "OutdatedPods" }}
+未更新到目标版本的 Pod: {{ . }}
+{{- end }}
+
+【发布状态诊断】
+异常摘要（来自工作负载状态字段及 Pod 失败原因汇总）: --- {{.ErrorInfo}} ---
+工作负载历史告警事件： --- {{.EventInfo}} ---
+
+【Pod 失败原因汇总（共 {{ index .Metadata "PodCount" }} 个 Pod，其中 {{ index .Metadata "UnhealthyPodCount" }} 个异常）】
+{{ index .Metadata "FailureReasons" }}
+
+【最严重的异常 Pod 诊断】
+{{ index .Metadata "PodDiagnosis" }}
+
+请按以下结构化格式返回诊断结论，不超过 1000 字：
+Healthy: {Yes / No，表示是否存在故障}
+Error: {一句话简洁总结工作负载异常的最可能原因，如发布卡住、镜像拉取失败、容器崩溃、调度失败等}
+Analysis: {结合发布状态、Pod 失败原因汇总、事件及 Pod 诊断，简要分析故障根因；多个 Pod 因同一原因失败时请优先说明该共性原因}
+Solution: {给出最关键的一句总结，不超过 100 字}
+`
+
+const daemonSetPromptTemplate = `
+你是一个 Kubernetes 集群故障诊断专家，以下是一个 DaemonSet 的详细信息。DaemonSet 会在每个符合条件的节点上运行一个 Pod，请你判断该 DaemonSet 是否存在异常，并用中文给出诊断建议。
+
+【DaemonSet 基本信息】
+名称: {{ index .Metadata "Name" }}
+更新策略: {{ index .Metadata "UpdateStrategy" }}
+期望调度节点数: {{ index .Metadata "Desired" }}
+已调度节点数: {{ index .Metadata "Current" }}
+已更新节点数: {{ index .Metadata "Updated" }}
+就绪节点数: {{ index .Metadata "Ready" }}
+可用节点数: {{ index .Metadata "Available" }}
+不可用节点数: {{ index .Metadata "Unavailable" }}
+错误调度节点数: {{ index .Metadata "Misscheduled" }}
+
+【节点分布】
+符合条件但缺少 Pod 的节点: {{ or (index .Metadata "MissingNodes") "无" }}
+Pod 异常的节点: {{ or (index .Metadata "FailingNodes") "无" }}
+
+【发布状态诊断】
+异常摘要（来自 DaemonSet 状态字段及 Pod 失败原因汇总）: --- {{.ErrorInfo}} ---
+DaemonSet 历史告警事件： --- {{.EventInfo}} ---
+
+【Pod 失败原因汇总（共 {{ index .Metadata "PodCount" }} 个 Pod，其中 {{ index .Metadata "UnhealthyPodCount" }} 个异常）】
+{{ index .Metadata "FailureReasons" }}
+
+【最严重的异常 Pod 诊断】
+{{ index .Metadata "PodDiagnosis" }}
+
+请按以下结构化格式返回诊断结论，不超过 1000 字：
+Healthy: {Yes / No，表示是否存在故障}
+Error: {一句话简洁总结 DaemonSet 异常的最可能原因，注意区分是个别节点的问题还是所有节点的共性问题}
+Analysis: {结合节点分布、Pod 失败原因汇总、事件及 Pod 诊断，简要分析故障根因}
+Solution: {给出最关键的一句总结，不超过 100 字}
+`
+
+const jobPromptTemplate = `
+你是一个 Kubernetes 批处理任务故障诊断专家，以下是一个 Job 的详细信息。请你判断该 Job 失败或卡住的原因，并用中文给出诊断建议。
+
+Job 会创建 Pod 运行直到指定数量的 Pod 成功结束；Pod 失败次数超过 backoffLimit、运行时间超过 activeDeadlineSeconds，或命中 podFailurePolicy 的 FailJob 规则时，Job 判定为失败。
+
+【Job 基本信息】
+Job 名称: {{ index .Metadata "Name" }}
+Job 状态: {{ index .Metadata "JobStatus" }}
+完成数 / 并行度: {{ index .Metadata "Completions" }} / {{ index .Metadata "Parallelism" }}
+backoffLimit: {{ index .Metadata "BackoffLimit" }}
+运行中 / 成功 / 失败 Pod 数: {{ index .Metadata "Active" }} / {{ index .Metadata "Succeeded" }} / {{ index .Metadata "Failed" }}
+开始时间: {{ index .Metadata "StartTime" }}
+{{- with index .Metadata "ActiveDeadlineSeconds" }}
+activeDeadlineSeconds: {{ . }}
+{{- end }}
+{{- with index .Metadata "CompletionMode" }}
+完成模式: {{ . }}，已完成索引: {{ index $.Metadata "CompletedIndexes" }}，失败索引: {{ or (index $.Metadata "FailedIndexes") "无" }}
+{{- end }}
+{{- with index .Metadata "PodFailurePolicy" }}
+podFailurePolicy 规则:
+{{ . }}
+{{- end }}
+
+【Job 状态诊断】
+异常摘要（来自 Job 状态字段及最近失败的 Pod）: --- {{.ErrorInfo}} ---
+Job 历史告警事件： --- {{.EventInfo}} ---
+
+【Pod 失败原因汇总（共 {{ index .Metadata "PodCount" }} 个 Pod，其中 {{ index .Metadata "UnhealthyPodCount" }} 个异常）】
+{{ index .Metadata "FailureReasons" }}
+
+【最近失败的 Pod（退出码、信号及 OOM）】
+{{ index .Metadata "FailedPods" }}
+
+【Pod 诊断】
+{{ index .Metadata "PodDiagnosis" }}
+
+请按以下结构化格式返回诊断结论，不超过 1000 字：
+Healthy: {Yes / No，表示是否存在故障}
+Error: {一句话简洁总结 Job 失败的最可能原因，如 OOM、程序异常退出、超时、镜像拉取失败等}
+Analysis: {结合 Job 状态、退出码（137 通常为 SIGKILL / OOMKilled，143 为 SIGTERM）、事件及 Pod 诊断，简要分析故障根因}
+Solution: {给出最关键的一句总结，不超过 100 字}
+`
+
+const cronJobPromptTemplate = `
+你是一个 Kubernetes 批处理任务故障诊断专家，以下是一个 CronJob 的详细信息。请你判断该定时任务是否存在调度遗漏或执行失败，并用中文给出诊断建议。
+
+【CronJob 基本信息】
+CronJob 名称: {{ index .Metadata "Name" }}
+调度表达式: {{ index .Metadata "Schedule" }}{{ with index .Metadata "TimeZone" }}（时区 {{ . }}）{{ end }}
+并发策略: {{ index .Metadata "ConcurrencyPolicy" }}
+{{- with index .Metadata "StartingDeadlineSeconds" }}
+startingDeadlineSeconds: {{ . }}
+{{- end }}
+运行中的 Job 数: {{ index .Metadata "ActiveJobs" }}
+最近调度时间: {{ index .Metadata "LastScheduleTime" }}
+最近成功时间: {{ index .Metadata "LastSuccessfulTime" }}
+下次调度时间: {{ index .Metadata "NextScheduleTime" }}
+{{- with index .Metadata "MissedSchedules" }}
+未执行的调度次数: {{ . }}
+{{- end }}
+
+【CronJob 状态诊断】
+异常摘要（来自调度分析及最近的 Job）: --- {{.ErrorInfo}} ---
+CronJob 历史告警事件： --- {{.EventInfo}} ---
+
+【最近的 Job（共 {{ index .Metadata "JobCount" }} 个，其中 {{ index .Metadata "FailedJobCount" }} 个失败）】
+{{ index .Metadata "RecentJobs" }}
+
+【最近失败的 Job 诊断】
+{{ index .Metadata "LastFailedJob" }}
+
+请按以下结构化格式返回诊断结论，不超过 1000 字：
+Healthy: {Yes / No，表示是否存在故障}
+Error: {一句话简洁总结最可能的原因，区分调度问题（被并发策略跳过、错过 startingDeadlineSeconds 等）和 Job 执行失败}
+Analysis: {结合调度信息、Job 历史、事件及失败 Job 的诊断，简要分析故障根因}
+Solution: {给出最关键的一句总结，不超过 100 字}
+`
+
 const alertToModelPromptTemplate = `
 你是一个 Kubernetes 运维平台的 AI 模块。你接收到来自外部监控系统的一条告警消息，请将它转换为标准结构 models.Alert 的 JSON 格式。
 
@@ -205,3 +397,184 @@ Error: {一句话简洁总结该任务最可能的失败原因}
 Analysis: {结合任务状态、Pod 状态、事件、日志等，简要分析故障根因。请尽可能摘录较关键的日志片段展示出来，使用 **markdown 代码块** 包裹（即用三个反引号包裹日志片段）}
 Solution: {给出最关键的一句总结，不超过 100 字}
 `
+
+const tfJobPromptTemplate = `
+你是一个专业的 Kubernetes + Kubeflow 分布式训练故障诊断专家，以下是一个 TFJob 的详细信息。请你判断该任务是否存在异常，并用中文给出诊断建议。
+
+该 TFJob 是通过 Kubeflow Training Operator 启动的 TensorFlow 分布式训练任务，可能包含以下角色：
+- Chief / Master：负责协调训练、保存 checkpoint 和汇总指标，其退出状态决定整个任务的成败。
+- PS（Parameter Server）：保存并更新模型参数，任一 PS 故障通常会导致所有 Worker 卡住或报错。
+- Worker：执行实际的训练计算。
+- Evaluator：独立评估模型，其失败通常不影响训练本身。
+分析时请注意区分根因角色与被波及的角色（例如 PS 故障引起 Worker 连接报错）。
+
+【任务基本信息】
+Job 名称: {{ index .Metadata "JobName" }}
+Job 状态: {{ index .Metadata "JobStatus" }}
+{{- with index .Metadata "ReplicaStatuses" }}
+各角色副本状态:
+{{ . }}
+{{- end }}
+
+【任务状态诊断】
+异常摘要（来自 TFJob 的 conditions 或状态字段）: --- {{.ErrorInfo}} ---
+Job历史告警事件： --- {{.EventInfo}} ---
+
+【关键组件诊断（基于 Pod 层分析）】
+- Chief Pod 分析摘要（期望 {{ index .Metadata "ChiefExpected" }} 个，已创建 {{ index .Metadata "ChiefCreatedCount" }} 个）:
+{{ index .Metadata "ChiefDiagnosis" }}
+
+- Master Pod 分析摘要（期望 {{ index .Metadata "MasterExpected" }} 个，已创建 {{ index .Metadata "MasterCreatedCount" }} 个）:
+{{ index .Metadata "MasterDiagnosis" }}
+
+- PS Pod 分析摘要（期望 {{ index .Metadata "PSExpected" }} 个，已创建 {{ index .Metadata "PSCreatedCount" }} 个）:
+{{ index .Metadata "PSDiagnosis" }}
+
+- Worker Pod 分析摘要（期望 {{ index .Metadata "WorkerExpected" }} 个，已创建 {{ index .Metadata "WorkerCreatedCount" }} 个）:
+{{ index .Metadata "WorkerDiagnosis" }}
+
+- Evaluator Pod 分析摘要（期望 {{ index .Metadata "EvaluatorExpected" }} 个，已创建 {{ index .Metadata "EvaluatorCreatedCount" }} 个）:
+{{ index .Metadata "EvaluatorDiagnosis" }}
+
+请按以下结构化格式返回诊断结论，不超过 1000 字：
+Healthy: {Yes / No，表示是否存在故障，请优先判断job 状态，如果成功直接说明即可，无需后续解释}
+Error: {一句话简洁总结该任务最可能的失败原因}
+Analysis: {结合任务状态、Pod 状态、事件、日志等，简要分析故障根因。请尽可能摘录较关键的日志片段展示出来，使用 **markdown 代码块** 包裹（即用三个反引号包裹日志片段）}
+Solution: {给出最关键的一句总结，不超过 100 字}
+`
+
+const mpiJobPromptTemplate = `
+你是一个专业的 Kubernetes + Kubeflow 分布式训练故障诊断专家，以下是一个 MPIJob 的详细信息。请你判断该任务是否存在异常，并用中文给出诊断建议。
+
+该 MPIJob 是通过 Kubeflow Training Operator 启动的 MPI 分布式训练任务（如 Horovod、DeepSpeed），包含以下角色：
+- Launcher：运行 mpirun，通过 SSH 或 kubectl exec 在各 Worker 上启动训练进程，其退出状态决定整个任务的成败。任一 rank 异常退出时，mpirun 会终止全部进程，因此 Launcher 日志通常包含真正失败的 rank 及其所在 Worker。
+- Worker：运行各个 rank 的训练进程，本身通常只运行 sshd 或等待命令。
+分析时请优先从 Launcher 日志中定位首个失败的 rank，并区分 Launcher 无法连接 Worker（如 Worker 未就绪、SSH 失败）与训练进程本身的错误。
+
+【任务基本信息】
+Job 名称: {{ index .Metadata "JobName" }}
+Job 状态: {{ index .Metadata "JobStatus" }}
+{{- with index .Metadata "ReplicaStatuses" }}
+各角色副本状态:
+{{ . }}
+{{- end }}
+
+【任务状态诊断】
+异常摘要（来自 MPIJob 的 conditions 或状态字段）: --- {{.ErrorInfo}} ---
+Job历史告警事件： --- {{.EventInfo}} ---
+
+【关键组件诊断（基于 Pod 层分析）】
+- Launcher Pod 分析摘要（期望 {{ index .Metadata "LauncherExpected" }} 个，已创建 {{ index .Metadata "LauncherCreatedCount" }} 个）:
+{{ index .Metadata "LauncherDiagnosis" }}
+
+- Worker Pod 分析摘要（期望 {{ index .Metadata "WorkerExpected" }} 个，已创建 {{ index .Metadata "WorkerCreatedCount" }} 个）:
+{{ index .Metadata "WorkerDiagnosis" }}
+
+请按以下结构化格式返回诊断结论，不超过 1000 字：
+Healthy: {Yes / No，表示是否存在故障，请优先判断job 状态，如果成功直接说明即可，无需后续解释}
+Error: {一句话简洁总结该任务最可能的失败原因}
+Analysis: {结合任务状态、Pod 状态、事件、日志等，简要分析故障根因。请尽可能摘录较关键的日志片段展示出来，使用 **markdown 代码块** 包裹（即用三个反引号包裹日志片段）}
+Solution: {给出最关键的一句总结，不超过 100 字}
+`
+
+const xgboostJobPromptTemplate = `
+你是一个专业的 Kubernetes + Kubeflow 分布式训练故障诊断专家，以下是一个 XGBoostJob 的详细信息。请你判断该任务是否存在异常，并用中文给出诊断建议。
+
+该 XGBoostJob 是通过 Kubeflow Training Operator 启动的 XGBoost 分布式训练任务，包含以下角色：
+- Master：运行 Rabit tracker，协调各 Worker 之间的通信（allreduce），同时参与训练，其退出状态决定整个任务的成败。
+- Worker：连接 Master 上的 tracker 并执行训练，任一 Worker 失败通常会导致 allreduce 中断、整个任务失败。
+分析时请注意 Worker 连接 tracker 失败（如 Master 未就绪、网络不通）与训练数据或内存问题的区别。
+
+【任务基本信息】
+Job 名称: {{ index .Metadata "JobName" }}
+Job 状态: {{ index .Metadata "JobStatus" }}
+{{- with index .Metadata "ReplicaStatuses" }}
+各角色副本状态:
+{{ . }}
+{{- end }}
+
+【任务状态诊断】
+异常摘要（来自 XGBoostJob 的 conditions 或状态字段）: --- {{.ErrorInfo}} ---
+Job历史告警事件： --- {{.EventInfo}} ---
+
+【关键组件诊断（基于 Pod 层分析）】
+- Master Pod 分析摘要（期望 {{ index .Metadata "MasterExpected" }} 个，已创建 {{ index .Metadata "MasterCreatedCount" }} 个）:
+{{ index .Metadata "MasterDiagnosis" }}
+
+- Worker Pod 分析摘要（期望 {{ index .Metadata "WorkerExpected" }} 个，已创建 {{ index .Metadata "WorkerCreatedCount" }} 个）:
+{{ index .Metadata "WorkerDiagnosis" }}
+
+请按以下结构化格式返回诊断结论，不超过 1000 字：
+Healthy: {Yes / No，表示是否存在故障，请优先判断job 状态，如果成功直接说明即可，无需后续解释}
+Error: {一句话简洁总结该任务最可能的失败原因}
+Analysis: {结合任务状态、Pod 状态、事件、日志等，简要分析故障根因。请尽可能摘录较关键的日志片段展示出来，使用 **markdown 代码块** 包裹（即用三个反引号包裹日志片段）}
+Solution: {给出最关键的一句总结，不超过 100 字}
+`
+
+const paddleJobPromptTemplate = `
+你是一个专业的 Kubernetes + Kubeflow 分布式训练故障诊断专家，以下是一个 PaddleJob 的详细信息。请你判断该任务是否存在异常，并用中文给出诊断建议。
+
+该 PaddleJob 是通过 Kubeflow Training Operator 启动的 PaddlePaddle 分布式训练任务，有两种模式：
+- Collective 模式：只有 Worker，各 Worker 之间通过 NCCL 等集合通信进行训练，任一 Worker 故障会导致通信超时、整个任务失败。
+- PS 模式：Master 作为参数服务器（PServer）保存并更新模型参数，Worker 作为训练节点（Trainer），任一 PServer 故障会导致所有 Trainer 报错。
+请先根据是否存在 Master 判断任务模式，并区分根因角色与被波及的角色。
+
+【任务基本信息】
+Job 名称: {{ index .Metadata "JobName" }}
+Job 状态: {{ index .Metadata "JobStatus" }}
+{{- with index .Metadata "ReplicaStatuses" }}
+各角色副本状态:
+{{ . }}
+{{- end }}
+
+【任务状态诊断】
+异常摘要（来自 PaddleJob 的 conditions 或状态字段）: --- {{.ErrorInfo}} ---
+Job历史告警事件： --- {{.EventInfo}} ---
+
+【关键组件诊断（基于 Pod 层分析）】
+- Master（PServer）Pod 分析摘要（期望 {{ index .Metadata "MasterExpected" }} 个，已创建 {{ index .Metadata "MasterCreatedCount" }} 个）:
+{{ index .Metadata "MasterDiagnosis" }}
+
+- Worker Pod 分析摘要（期望 {{ index .Metadata "WorkerExpected" }} 个，已创建 {{ index .Metadata "WorkerCreatedCount" }} 个）:
+{{ index .Metadata "WorkerDiagnosis" }}
+
+请按以下结构化格式返回诊断结论，不超过 1000 字：
+Healthy: {Yes / No，表示是否存在故障，请优先判断job 状态，如果成功直接说明即可，无需后续解释}
+Error: {一句话简洁总结该任务最可能的失败原因}
+Analysis: {结合任务状态、Pod 状态、事件、日志等，简要分析故障根因。请尽可能摘录较关键的日志片段展示出来，使用 **markdown 代码块** 包裹（即用三个反引号包裹日志片段）}
+Solution: {给出最关键的一句总结，不超过 100 字}
+`
+
+const genericPromptTemplate = `
+你是一个 Kubernetes 集群故障诊断专家，以下是一个 {{ index .Metadata "Kind" }}（{{ index .Metadata "APIVersion" }}）对象的详细信息，该类型可能是自定义资源（CRD），其字段由诊断配置提取。请你判断该对象及其 Pod 是否存在异常，并用中文给出诊断建议。
+
+【对象基本信息】
+类型: {{ index .Metadata "Kind" }}
+名称: {{ index .Metadata "Name" }}
+{{- with index .Metadata "Status" }}
+状态: {{ . }}
+{{- end }}
+{{- range $key, $value := .Metadata }}
+{{- if not (or (eq $key "Kind") (eq $key "Name") (eq $key "APIVersion") (eq $key "Status") (eq $key "PodSelector") (eq $key "PodCount") (eq $key "UnhealthyPodCount") (eq $key "FailureReasons") (eq $key "PodDiagnosis")) }}
+{{ $key }}: {{ $value }}
+{{- end }}
+{{- end }}
+
+【对象状态诊断】
+异常摘要（来自健康检查及 Pod 失败原因汇总）: --- {{.ErrorInfo}} ---
+历史告警事件： --- {{.EventInfo}} ---
+{{- with index .Metadata "PodSelector" }}
+
+【Pod 失败原因汇总（选择器 {{ . }}，共 {{ index $.Metadata "PodCount" }} 个 Pod，其中 {{ index $.Metadata "UnhealthyPodCount" }} 个异常）】
+{{ index $.Metadata "FailureReasons" }}
+
+【最严重的异常 Pod 诊断】
+{{ index $.Metadata "PodDiagnosis" }}
+{{- end }}
+
+请按以下结构化格式返回诊断结论，不超过 1000 字：
+Healthy: {Yes / No，表示是否存在故障}
+Error: {一句话简洁总结该对象异常的最可能原因}
+Analysis: {结合对象状态、健康检查、事件及 Pod 诊断，简要分析故障根因；请尽可能摘录较关键的日志片段展示出来，使用 **markdown 代码块** 包裹（即用三个反引号包裹日志片段）}
+Solution: {给出最关键的一句总结，不超过 100 字}
+`
@@ -0,0 +1,239 @@
+package analyzer
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/k8sgpt-ai/k8sgpt/pkg/analyzer"
+	kcommon "github.com/k8sgpt-ai/k8sgpt/pkg/common"
+	"github.com/robfig/cron/v3"
+	"github.com/scitix/aegis/pkg/analyzer/common"
+	"github.com/scitix/aegis/pkg/prom"
+	batchv1 "k8s.io/api/batch/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/klog/v2"
+)
+
+const (
+	// maxMissedSchedules bounds the missed schedules counted, as the cronjob
+	// controller gives up starting the job beyond 100 missed schedules
+	maxMissedSchedules = 100
+
+	// maxListedJobs bounds the recent jobs of a cronjob listed
+	maxListedJobs = 5
+
+	// scheduleTolerance is the lag of the cronjob controller not counted as
+	// a missed schedule
+	scheduleTolerance = time.Minute
+)
+
+type CronJobAnalyzer struct {
+	prometheus *prom.PromAPI
+}
+
+func NewCronJobAnalyzer(prometheus *prom.PromAPI) CronJobAnalyzer {
+	return CronJobAnalyzer{
+		prometheus: prometheus,
+	}
+}
+
+func (c CronJobAnalyzer) Analyze(a common.Analyzer) (*common.Result, error) {
+	kind := "CronJob"
+
+	analyzer.AnalyzerErrorsMetric.DeletePartialMatch(map[string]string{
+		"analyzer_name": kind,
+	})
+
+	cronJob, err := a.Client.GetClient().BatchV1().CronJobs(a.Namespace).Get(a.Context, a.Name, metav1.GetOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("error getting CronJob %s/%s: %w", a.Namespace, a.Name, err)
+	}
+
+	result := &common.Result{
+		Result: kcommon.Result{
+			Kind: kind,
+			Name: cronJob.Name,
+		},
+		Metadata: map[string]string{
+			"Kind":              kind,
+			"Name":              cronJob.Name,
+			"Schedule":          cronJob.Spec.Schedule,
+			"ConcurrencyPolicy": string(cronJob.Spec.ConcurrencyPolicy),
+			"ActiveJobs":        fmt.Sprintf("%d", len(cronJob.Status.Active)),
+		},
+	}
+	if cronJob.Spec.TimeZone != nil {
+		result.Metadata["TimeZone"] = *cronJob.Spec.TimeZone
+	}
+	if cronJob.Spec.StartingDeadlineSeconds != nil {
+		result.Metadata["StartingDeadlineSeconds"] = fmt.Sprintf("%d", *cronJob.Spec.StartingDeadlineSeconds)
+	}
+	if cronJob.Status.LastScheduleTime != nil {
+		result.Metadata["LastScheduleTime"] = cronJob.Status.LastScheduleTime.Format(time.RFC3339)
+	}
+	if cronJob.Status.LastSuccessfulTime != nil {
+		result.Metadata["LastSuccessfulTime"] = cronJob.Status.LastSuccessfulTime.Format(time.RFC3339)
+	}
+
+	// === 调度分析 ===
+	suspended := cronJob.Spec.Suspend != nil && *cronJob.Spec.Suspend
+	if suspended {
+		result.Warning = append(result.Warning, common.Warning{
+			Text: "CronJob is suspended, no jobs are scheduled",
+		})
+	}
+	if err := analyzeSchedules(cronJob, time.Now(), result); err != nil {
+		result.Error = append(result.Error, kcommon.Failure{
+			Text: fmt.Sprintf("Invalid schedule %q: %v", cronJob.Spec.Schedule, err),
+		})
+	}
+
+	appendWorkloadEvents(a, c.prometheus, kind, cronJob.Name, result)
+
+	// === Job 分析 ===
+	if err := c.analyzeCronJobJobs(a, cronJob, result); err != nil {
+		klog.Warningf("analyze jobs for %s/%s failed: %v", a.Namespace, cronJob.Name, err)
+	}
+
+	if len(result.Error) > 0 {
+		analyzer.AnalyzerErrorsMetric.WithLabelValues(kind, cronJob.Name, cronJob.Namespace).Set(float64(len(result.Error)))
+	}
+
+	return result, nil
+}
+
+// analyzeSchedules reports the schedules due before now but not started,
+// and why the cronjob controller skipped them
+func analyzeSchedules(cronJob *batchv1.CronJob, now time.Time, result *common.Result) error {
+	spec := cronJob.Spec.Schedule
+	if cronJob.Spec.TimeZone != nil && !strings.Contains(spec, "TZ") {
+		spec = fmt.Sprintf("CRON_TZ=%s %s", *cronJob.Spec.TimeZone, spec)
+	}
+	sched, err := cron.ParseStandard(spec)
+	if err != nil {
+		return err
+	}
+
+	earliest := cronJob.CreationTimestamp.Time
+	if cronJob.Status.LastScheduleTime != nil {
+		earliest = cronJob.Status.LastScheduleTime.Time
+	}
+	if next := sched.Next(now); !next.IsZero() {
+		result.Metadata["NextScheduleTime"] = next.Format(time.RFC3339)
+	}
+
+	missed := missedSchedules(sched, earliest, now.Add(-scheduleTolerance))
+	if len(missed) == 0 || (cronJob.Spec.Suspend != nil && *cronJob.Spec.Suspend) {
+		return nil
+	}
+
+	count := fmt.Sprintf("%d", len(missed))
+	if len(missed) > maxMissedSchedules {
+		count = fmt.Sprintf("more than %d", maxMissedSchedules)
+	}
+	result.Metadata["MissedSchedules"] = count
+	text := fmt.Sprintf("%s schedule(s) since %s are not started, the first at %s", count, earliest.Format(time.RFC3339), missed[0].Format(time.RFC3339))
+
+	last := missed[len(missed)-1]
+	switch {
+	case cronJob.Spec.ConcurrencyPolicy == batchv1.ForbidConcurrent && len(cronJob.Status.Active) > 0:
+		text += fmt.Sprintf(", skipped as of the Forbid concurrency policy while job %s is still active", cronJob.Status.Active[0].Name)
+	case cronJob.Spec.StartingDeadlineSeconds != nil && now.Sub(last) > time.Duration(*cronJob.Spec.StartingDeadlineSeconds)*time.Second:
+		text += fmt.Sprintf(", missing the startingDeadlineSeconds %d", *cronJob.Spec.StartingDeadlineSeconds)
+	case len(missed) > maxMissedSchedules:
+		text += ", the cronjob controller does not start jobs beyond 100 missed schedules, set startingDeadlineSeconds to recover"
+	}
+	result.Error = append(result.Error, kcommon.Failure{Text: text})
+	return nil
+}
+
+// missedSchedules returns the schedule times after earliest up to until,
+// at most maxMissedSchedules+1 of them
+func missedSchedules(sched cron.Schedule, earliest, until time.Time) []time.Time {
+	missed := make([]time.Time, 0)
+	for t := sched.Next(earliest); !t.IsZero() && !t.After(until); t = sched.Next(t) {
+		missed = append(missed, t)
+		if len(missed) > maxMissedSchedules {
+			break
+		}
+	}
+	return missed
+}
+
+// analyzeCronJobJobs lists the recent jobs of the cronjob, and analyzes the
+// last failed one
+func (c CronJobAnalyzer) analyzeCronJobJobs(a common.Analyzer, cronJob *batchv1.CronJob, result *common.Result) error {
+	list, err := a.Client.GetClient().BatchV1().Jobs(a.Namespace).List(a.Context, metav1.ListOptions{})
+	if err != nil {
+		return fmt.Errorf("list jobs failed: %w", err)
+	}
+
+	jobs := make([]*batchv1.Job, 0)
+	for i := range list.Items {
+		job := &list.Items[i]
+		if ref := metav1.GetControllerOf(job); ref != nil && ref.UID == cronJob.UID {
+			jobs = append(jobs, job)
+		}
+	}
+	sort.SliceStable(jobs, func(i, k int) bool {
+		return jobs[i].CreationTimestamp.After(jobs[k].CreationTimestamp.Time)
+	})
+
+	failedCount := 0
+	var lastFailed *batchv1.Job
+	lines := make([]string, 0, min(len(jobs), maxListedJobs))
+	for i, job := range jobs {
+		status := jobStatus(job)
+		if status == "Failed" {
+			failedCount++
+			if lastFailed == nil {
+				lastFailed = job
+			}
+		}
+		if i < maxListedJobs {
+			lines = append(lines, fmt.Sprintf("%s created at %s: %s", job.Name, job.CreationTimestamp.Format(time.RFC3339), status))
+		}
+	}
+	result.Metadata["JobCount"] = fmt.Sprintf("%d", len(jobs))
+	result.Metadata["FailedJobCount"] = fmt.Sprintf("%d", failedCount)
+	if len(lines) > 0 {
+		result.Metadata["RecentJobs"] = strings.Join(lines, "\n")
+	}
+	if lastFailed == nil {
+		return nil
+	}
+
+	// the job history is kept by failedJobsHistoryLimit, so the last failed
+	// job is still worth explaining after a later success
+	jobResult := NewJobAnalyzer(c.prometheus).analyzeJob(a, lastFailed)
+	details := []string{fmt.Sprintf("Job %s (%s):", lastFailed.Name, jobResult.Metadata["JobStatus"])}
+	for _, e := range jobResult.Error {
+		details = append(details, e.Text)
+	}
+	if failedPods, ok := jobResult.Metadata["FailedPods"]; ok {
+		details = append(details, "Failed pods:", failedPods)
+	}
+	if diagnosis, ok := jobResult.Metadata["PodDiagnosis"]; ok {
+		details = append(details, "Pod diagnosis:", diagnosis)
+	}
+	result.Metadata["LastFailedJob"] = strings.Join(details, "\n")
+
+	if jobs[0] == lastFailed {
+		for _, e := range jobResult.Error {
+			result.Error = append(result.Error, kcommon.Failure{
+				Text: fmt.Sprintf("Last job %s: %s", lastFailed.Name, e.Text),
+			})
+		}
+	} else {
+		result.Warning = append(result.Warning, common.Warning{
+			Text: fmt.Sprintf("%d of the %d kept jobs failed, the last failed one is %s", failedCount, len(jobs), lastFailed.Name),
+		})
+	}
+	return nil
+}
+
+func (c CronJobAnalyzer) Prompt(result *common.Result) string {
+	return workloadPrompt("CronJob", result)
+}
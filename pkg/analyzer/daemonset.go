@@ -0,0 +1,188 @@
+package analyzer
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/k8sgpt-ai/k8sgpt/pkg/analyzer"
+	kcommon "github.com/k8sgpt-ai/k8sgpt/pkg/common"
+	"github.com/scitix/aegis/pkg/analyzer/common"
+	"github.com/scitix/aegis/pkg/prom"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	corev1helpers "k8s.io/component-helpers/scheduling/corev1"
+	"k8s.io/component-helpers/scheduling/corev1/nodeaffinity"
+	"k8s.io/klog/v2"
+)
+
+type DaemonSetAnalyzer struct {
+	prometheus *prom.PromAPI
+}
+
+func NewDaemonSetAnalyzer(prometheus *prom.PromAPI) DaemonSetAnalyzer {
+	return DaemonSetAnalyzer{
+		prometheus: prometheus,
+	}
+}
+
+func (d DaemonSetAnalyzer) Analyze(a common.Analyzer) (*common.Result, error) {
+	kind := "DaemonSet"
+
+	analyzer.AnalyzerErrorsMetric.DeletePartialMatch(map[string]string{
+		"analyzer_name": kind,
+	})
+
+	ds, err := a.Client.GetClient().AppsV1().DaemonSets(a.Namespace).Get(a.Context, a.Name, metav1.GetOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("error getting DaemonSet %s/%s: %w", a.Namespace, a.Name, err)
+	}
+
+	status := ds.Status
+	result := &common.Result{
+		Result: kcommon.Result{
+			Kind: kind,
+			Name: ds.Name,
+		},
+		Metadata: map[string]string{
+			"Kind":           kind,
+			"Name":           ds.Name,
+			"UpdateStrategy": string(ds.Spec.UpdateStrategy.Type),
+			"Desired":        fmt.Sprintf("%d", status.DesiredNumberScheduled),
+			"Current":        fmt.Sprintf("%d", status.CurrentNumberScheduled),
+			"Updated":        fmt.Sprintf("%d", status.UpdatedNumberScheduled),
+			"Ready":          fmt.Sprintf("%d", status.NumberReady),
+			"Available":      fmt.Sprintf("%d", status.NumberAvailable),
+			"Unavailable":    fmt.Sprintf("%d", status.NumberUnavailable),
+			"Misscheduled":   fmt.Sprintf("%d", status.NumberMisscheduled),
+		},
+	}
+
+	// === Rollout 状态分析 ===
+	if status.ObservedGeneration < ds.Generation {
+		result.Warning = append(result.Warning, common.Warning{
+			Text: fmt.Sprintf("DaemonSet generation %d is not observed by the controller yet (observed %d)", ds.Generation, status.ObservedGeneration),
+		})
+	}
+	if status.CurrentNumberScheduled < status.DesiredNumberScheduled {
+		result.Error = append(result.Error, kcommon.Failure{
+			Text: fmt.Sprintf("%d of %d nodes have no daemon pod scheduled", status.DesiredNumberScheduled-status.CurrentNumberScheduled, status.DesiredNumberScheduled),
+		})
+	}
+	if status.NumberUnavailable > 0 {
+		result.Error = append(result.Error, kcommon.Failure{
+			Text: fmt.Sprintf("%d of %d daemon pods are unavailable", status.NumberUnavailable, status.DesiredNumberScheduled),
+		})
+	}
+	if status.NumberMisscheduled > 0 {
+		result.Warning = append(result.Warning, common.Warning{
+			Text: fmt.Sprintf("%d daemon pods run on nodes they should not run on", status.NumberMisscheduled),
+		})
+	}
+	if status.UpdatedNumberScheduled < status.DesiredNumberScheduled {
+		result.Warning = append(result.Warning, common.Warning{
+			Text: fmt.Sprintf("Only %d of %d daemon pods are updated", status.UpdatedNumberScheduled, status.DesiredNumberScheduled),
+		})
+	}
+
+	appendWorkloadEvents(a, d.prometheus, kind, ds.Name, result)
+
+	// === Pod 与节点分析 ===
+	pods, err := listControlledPods(a, ds.Spec.Selector, ds.UID)
+	if err != nil {
+		klog.Warningf("analyze pods for %s/%s failed: %v", a.Namespace, ds.Name, err)
+		return result, nil
+	}
+
+	if err := analyzeDaemonNodes(a, ds.Spec.Template.Spec, pods, result); err != nil {
+		klog.Warningf("analyze nodes for %s/%s failed: %v", a.Namespace, ds.Name, err)
+	}
+	analyzeWorkloadPods(a, d.prometheus, pods, result)
+
+	if len(result.Error) > 0 {
+		analyzer.AnalyzerErrorsMetric.WithLabelValues(kind, ds.Name, ds.Namespace).Set(float64(len(result.Error)))
+	}
+
+	return result, nil
+}
+
+// analyzeDaemonNodes lists the nodes eligible for the daemon pod but
+// missing it, and the nodes where the daemon pod is unhealthy
+func analyzeDaemonNodes(a common.Analyzer, spec v1.PodSpec, pods []*v1.Pod, result *common.Result) error {
+	nodes, err := a.Client.GetClient().CoreV1().Nodes().List(a.Context, metav1.ListOptions{})
+	if err != nil {
+		return fmt.Errorf("list nodes failed: %w", err)
+	}
+
+	nodePods := make(map[string]*v1.Pod, len(pods))
+	for _, pod := range pods {
+		if len(pod.Spec.NodeName) > 0 {
+			nodePods[pod.Spec.NodeName] = pod
+		}
+	}
+
+	missing := make([]string, 0)
+	failing := make([]string, 0)
+	for i := range nodes.Items {
+		node := &nodes.Items[i]
+		pod, ok := nodePods[node.Name]
+		if !ok {
+			if shouldRunDaemonPod(spec, node) {
+				missing = append(missing, node.Name)
+			}
+			continue
+		}
+		if podSeverity(pod) > podHealthy {
+			failing = append(failing, fmt.Sprintf("%s(%s)", node.Name, podFailureReason(pod)))
+		}
+	}
+	sort.Strings(missing)
+	sort.Strings(failing)
+
+	if len(missing) > 0 {
+		result.Metadata["MissingNodes"] = listNames(missing)
+		result.Error = append(result.Error, kcommon.Failure{
+			Text: fmt.Sprintf("Daemon pod is missing on %d eligible node(s): %s", len(missing), listNames(missing)),
+		})
+	}
+	if len(failing) > 0 {
+		result.Metadata["FailingNodes"] = listNames(failing)
+	}
+	return nil
+}
+
+// shouldRunDaemonPod tells whether the daemon pod fits the node selector,
+// the required node affinity and the taints of the node, as the daemonset
+// controller does
+func shouldRunDaemonPod(spec v1.PodSpec, node *v1.Node) bool {
+	pod := &v1.Pod{Spec: *spec.DeepCopy()}
+	pod.Spec.Tolerations = append(pod.Spec.Tolerations, daemonTolerations(spec)...)
+
+	if fits, _ := nodeaffinity.GetRequiredNodeAffinity(pod).Match(node); !fits {
+		return false
+	}
+	_, untolerated := corev1helpers.FindMatchingUntoleratedTaint(node.Spec.Taints, pod.Spec.Tolerations, func(t *v1.Taint) bool {
+		return t.Effect == v1.TaintEffectNoExecute || t.Effect == v1.TaintEffectNoSchedule
+	})
+	return !untolerated
+}
+
+// daemonTolerations are the tolerations the daemonset controller adds to
+// its pods
+func daemonTolerations(spec v1.PodSpec) []v1.Toleration {
+	tolerations := []v1.Toleration{
+		{Key: v1.TaintNodeNotReady, Operator: v1.TolerationOpExists, Effect: v1.TaintEffectNoExecute},
+		{Key: v1.TaintNodeUnreachable, Operator: v1.TolerationOpExists, Effect: v1.TaintEffectNoExecute},
+		{Key: v1.TaintNodeDiskPressure, Operator: v1.TolerationOpExists, Effect: v1.TaintEffectNoSchedule},
+		{Key: v1.TaintNodeMemoryPressure, Operator: v1.TolerationOpExists, Effect: v1.TaintEffectNoSchedule},
+		{Key: v1.TaintNodePIDPressure, Operator: v1.TolerationOpExists, Effect: v1.TaintEffectNoSchedule},
+		{Key: v1.TaintNodeUnschedulable, Operator: v1.TolerationOpExists, Effect: v1.TaintEffectNoSchedule},
+	}
+	if spec.HostNetwork {
+		tolerations = append(tolerations, v1.Toleration{Key: v1.TaintNodeNetworkUnavailable, Operator: v1.TolerationOpExists, Effect: v1.TaintEffectNoSchedule})
+	}
+	return tolerations
+}
+
+func (d DaemonSetAnalyzer) Prompt(result *common.Result) string {
+	return workloadPrompt("DaemonSet", result)
+}
@@ -0,0 +1,148 @@
+package analyzer
+
+import (
+	"fmt"
+
+	"github.com/k8sgpt-ai/k8sgpt/pkg/analyzer"
+	kcommon "github.com/k8sgpt-ai/k8sgpt/pkg/common"
+	"github.com/scitix/aegis/pkg/analyzer/common"
+	"github.com/scitix/aegis/pkg/prom"
+	appsv1 "k8s.io/api/apps/v1"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/klog/v2"
+)
+
+// deploymentRevisionAnnotation is set by the deployment controller on the
+// deployment and its replicasets
+const deploymentRevisionAnnotation = "deployment.kubernetes.io/revision"
+
+type DeploymentAnalyzer struct {
+	prometheus *prom.PromAPI
+}
+
+func NewDeploymentAnalyzer(prometheus *prom.PromAPI) DeploymentAnalyzer {
+	return DeploymentAnalyzer{
+		prometheus: prometheus,
+	}
+}
+
+func (d DeploymentAnalyzer) Analyze(a common.Analyzer) (*common.Result, error) {
+	kind := "Deployment"
+
+	analyzer.AnalyzerErrorsMetric.DeletePartialMatch(map[string]string{
+		"analyzer_name": kind,
+	})
+
+	deployment, err := a.Client.GetClient().AppsV1().Deployments(a.Namespace).Get(a.Context, a.Name, metav1.GetOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("error getting Deployment %s/%s: %w", a.Namespace, a.Name, err)
+	}
+
+	desired := int32(1)
+	if deployment.Spec.Replicas != nil {
+		desired = *deployment.Spec.Replicas
+	}
+	revision := deployment.Annotations[deploymentRevisionAnnotation]
+	result := &common.Result{
+		Result: kcommon.Result{
+			Kind: kind,
+			Name: deployment.Name,
+		},
+		Metadata: map[string]string{
+			"Kind":        kind,
+			"Name":        deployment.Name,
+			"Revision":    revision,
+			"Desired":     fmt.Sprintf("%d", desired),
+			"Updated":     fmt.Sprintf("%d", deployment.Status.UpdatedReplicas),
+			"Ready":       fmt.Sprintf("%d", deployment.Status.ReadyReplicas),
+			"Available":   fmt.Sprintf("%d", deployment.Status.AvailableReplicas),
+			"Unavailable": fmt.Sprintf("%d", deployment.Status.UnavailableReplicas),
+		},
+	}
+
+	// === Rollout 状态分析 ===
+	if deployment.Spec.Paused {
+		result.Warning = append(result.Warning, common.Warning{
+			Text: "Deployment rollout is paused",
+		})
+	}
+	if deployment.Status.ObservedGeneration < deployment.Generation {
+		result.Warning = append(result.Warning, common.Warning{
+			Text: fmt.Sprintf("Deployment generation %d is not observed by the controller yet (observed %d)", deployment.Generation, deployment.Status.ObservedGeneration),
+		})
+	}
+	for _, cond := range deployment.Status.Conditions {
+		switch {
+		case cond.Type == appsv1.DeploymentProgressing && cond.Reason == "ProgressDeadlineExceeded":
+			result.Error = append(result.Error, kcommon.Failure{
+				Text: fmt.Sprintf("Deployment rollout of revision %s is stuck: %s", revision, cond.Message),
+			})
+		case cond.Type == appsv1.DeploymentAvailable && cond.Status != v1.ConditionTrue:
+			result.Error = append(result.Error, kcommon.Failure{
+				Text: fmt.Sprintf("Deployment is unavailable: %s - %s", cond.Reason, cond.Message),
+			})
+		case cond.Type == appsv1.DeploymentReplicaFailure && cond.Status == v1.ConditionTrue:
+			result.Error = append(result.Error, kcommon.Failure{
+				Text: fmt.Sprintf("Deployment fails to create replicas: %s - %s", cond.Reason, cond.Message),
+			})
+		}
+	}
+	if deployment.Status.UnavailableReplicas > 0 {
+		result.Error = append(result.Error, kcommon.Failure{
+			Text: fmt.Sprintf("%d of %d replicas are unavailable", deployment.Status.UnavailableReplicas, desired),
+		})
+	}
+	if deployment.Status.UpdatedReplicas < desired {
+		result.Warning = append(result.Warning, common.Warning{
+			Text: fmt.Sprintf("Only %d of %d replicas are updated to revision %s", deployment.Status.UpdatedReplicas, desired, revision),
+		})
+	}
+
+	appendWorkloadEvents(a, d.prometheus, kind, deployment.Name, result)
+
+	// === ReplicaSet 与 Pod 分析 ===
+	replicaSets, err := a.Client.GetClient().AppsV1().ReplicaSets(a.Namespace).List(a.Context, metav1.ListOptions{
+		LabelSelector: metav1.FormatLabelSelector(deployment.Spec.Selector),
+	})
+	if err != nil {
+		klog.Warningf("list replicasets for %s/%s failed: %v", a.Namespace, deployment.Name, err)
+		return result, nil
+	}
+
+	owners := make([]types.UID, 0, len(replicaSets.Items))
+	for i := range replicaSets.Items {
+		rs := &replicaSets.Items[i]
+		if ref := metav1.GetControllerOf(rs); ref == nil || ref.UID != deployment.UID {
+			continue
+		}
+		owners = append(owners, rs.UID)
+		if rs.Annotations[deploymentRevisionAnnotation] == revision {
+			result.Metadata["NewReplicaSet"] = rs.Name
+			// e.g. FailedCreate of a quota or an admission webhook
+			appendWorkloadEvents(a, d.prometheus, "ReplicaSet", rs.Name, result)
+		} else if rs.Status.Replicas > 0 {
+			result.Warning = append(result.Warning, common.Warning{
+				Text: fmt.Sprintf("Old ReplicaSet %s of revision %s still has %d replicas", rs.Name, rs.Annotations[deploymentRevisionAnnotation], rs.Status.Replicas),
+			})
+		}
+	}
+
+	pods, err := listControlledPods(a, deployment.Spec.Selector, owners...)
+	if err != nil {
+		klog.Warningf("analyze pods for %s/%s failed: %v", a.Namespace, deployment.Name, err)
+		return result, nil
+	}
+	analyzeWorkloadPods(a, d.prometheus, pods, result)
+
+	if len(result.Error) > 0 {
+		analyzer.AnalyzerErrorsMetric.WithLabelValues(kind, deployment.Name, deployment.Namespace).Set(float64(len(result.Error)))
+	}
+
+	return result, nil
+}
+
+func (d DeploymentAnalyzer) Prompt(result *common.Result) string {
+	return workloadPrompt("Deployment", result)
+}
@@ -0,0 +1,346 @@
+package analyzer
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/k8sgpt-ai/k8sgpt/pkg/analyzer"
+	kcommon "github.com/k8sgpt-ai/k8sgpt/pkg/common"
+	ai "github.com/scitix/aegis/pkg/ai"
+	"github.com/scitix/aegis/pkg/analyzer/common"
+	"github.com/scitix/aegis/pkg/prom"
+	"gopkg.in/yaml.v3"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/util/validation"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/util/jsonpath"
+	"k8s.io/klog/v2"
+)
+
+// genericConfigPath is where the aegis-analyzer ConfigMap is mounted, with a
+// <kind>.yaml key per diagnosis kind analyzed by the GenericAnalyzer
+const genericConfigPath = "/aegis/analyzer"
+
+// GenericConfig tells the GenericAnalyzer how to read a kind of object
+type GenericConfig struct {
+	// name is the diagnosis kind, which may differ from the object kind when
+	// it clashes with a builtin analyzer, e.g. VolcanoJob for a volcano Job
+	name string
+
+	APIVersion string `yaml:"apiVersion"`
+	Kind       string `yaml:"kind"`
+
+	// Resource is the plural resource name, guessed from the kind if empty
+	Resource string `yaml:"resource,omitempty"`
+
+	// Status is the JSONPath of the object status, e.g. {.status.phase}
+	Status string `yaml:"status,omitempty"`
+
+	HealthChecks []GenericHealthCheck `yaml:"healthChecks,omitempty"`
+
+	// PodSelector is the label selector of the child pods, with JSONPath
+	// templates of the object, e.g. ray.io/cluster={.metadata.name}
+	PodSelector string `yaml:"podSelector,omitempty"`
+
+	// Events are the objects whose warning events are fetched, the object
+	// itself if empty
+	Events []GenericEventSource `yaml:"events,omitempty"`
+
+	// Metadata are the JSONPaths of the fields added to the result metadata
+	Metadata map[string]string `yaml:"metadata,omitempty"`
+}
+
+// GenericHealthCheck fails when the value of its JSONPath is not healthy.
+// Checks whose path is missing are skipped.
+type GenericHealthCheck struct {
+	Name string `yaml:"name"`
+	Path string `yaml:"path"`
+
+	// Healthy values pass the check, any other value fails it
+	Healthy []string `yaml:"healthy,omitempty"`
+
+	// Unhealthy values fail the check, any other value passes it
+	Unhealthy []string `yaml:"unhealthy,omitempty"`
+
+	// Message is the JSONPath of the failure message, e.g. the condition message
+	Message string `yaml:"message,omitempty"`
+
+	// Severity is Error (default) or Warning
+	Severity string `yaml:"severity,omitempty"`
+}
+
+// GenericEventSource is an object whose events are fetched
+type GenericEventSource struct {
+	Kind string `yaml:"kind"`
+
+	// Name is the JSONPath of the object name, the analyzed object if empty
+	Name string `yaml:"name,omitempty"`
+}
+
+// LoadGenericConfig loads the config of the diagnosis kind from the mounted
+// ConfigMap. The kind names the file, it must be a DNS-1035 label once
+// lowercased, so that it can't point out of the ConfigMap.
+func LoadGenericConfig(kind string) (*GenericConfig, error) {
+	name := strings.ToLower(kind)
+	if errs := validation.IsDNS1035Label(name); len(errs) > 0 {
+		return nil, fmt.Errorf("invalid diagnosis kind %q: %s", kind, strings.Join(errs, ", "))
+	}
+
+	content, err := os.ReadFile(filepath.Join(genericConfigPath, name+".yaml"))
+	if err != nil {
+		return nil, err
+	}
+	config, err := ParseGenericConfig(content)
+	if err != nil {
+		return nil, err
+	}
+	config.name = kind
+	return config, nil
+}
+
+// ParseGenericConfig parses and validates a GenericConfig
+func ParseGenericConfig(content []byte) (*GenericConfig, error) {
+	config := &GenericConfig{}
+	if err := yaml.Unmarshal(content, config); err != nil {
+		return nil, fmt.Errorf("invalid generic analyzer config: %w", err)
+	}
+	if err := config.validate(); err != nil {
+		return nil, fmt.Errorf("invalid generic analyzer config of %s: %w", config.Kind, err)
+	}
+	config.name = config.Kind
+	return config, nil
+}
+
+func (c *GenericConfig) validate() error {
+	if c.APIVersion == "" || c.Kind == "" {
+		return fmt.Errorf("apiVersion and kind are required")
+	}
+	if _, err := schema.ParseGroupVersion(c.APIVersion); err != nil {
+		return err
+	}
+
+	paths := []string{c.Status, c.PodSelector}
+	for _, check := range c.HealthChecks {
+		if check.Name == "" || check.Path == "" {
+			return fmt.Errorf("health check name and path are required")
+		}
+		if len(check.Healthy) > 0 && len(check.Unhealthy) > 0 {
+			return fmt.Errorf("health check %s has both healthy and unhealthy values", check.Name)
+		}
+		if check.Severity != "" && check.Severity != "Error" && check.Severity != "Warning" {
+			return fmt.Errorf("health check %s has unknown severity %s", check.Name, check.Severity)
+		}
+		paths = append(paths, check.Path, check.Message)
+	}
+	for _, source := range c.Events {
+		if source.Kind == "" {
+			return fmt.Errorf("event kind is required")
+		}
+		paths = append(paths, source.Name)
+	}
+	for _, path := range c.Metadata {
+		paths = append(paths, path)
+	}
+	for _, path := range paths {
+		if path == "" {
+			continue
+		}
+		if err := jsonpath.New("generic").Parse(path); err != nil {
+			return fmt.Errorf("invalid JSONPath %q: %w", path, err)
+		}
+	}
+	return nil
+}
+
+func (c *GenericConfig) resource() (schema.GroupVersionResource, error) {
+	gv, err := schema.ParseGroupVersion(c.APIVersion)
+	if err != nil {
+		return schema.GroupVersionResource{}, err
+	}
+	if c.Resource != "" {
+		return gv.WithResource(c.Resource), nil
+	}
+	plural, _ := meta.UnsafeGuessKindToResource(gv.WithKind(c.Kind))
+	return plural, nil
+}
+
+// GenericAnalyzer analyzes the objects of any kind by a GenericConfig
+type GenericAnalyzer struct {
+	prometheus *prom.PromAPI
+	client     dynamic.Interface
+	config     *GenericConfig
+}
+
+func NewGenericAnalyzer(prometheus *prom.PromAPI, client dynamic.Interface, config *GenericConfig) GenericAnalyzer {
+	return GenericAnalyzer{
+		prometheus: prometheus,
+		client:     client,
+		config:     config,
+	}
+}
+
+func (g GenericAnalyzer) Analyze(a common.Analyzer) (*common.Result, error) {
+	kind := g.config.name
+
+	analyzer.AnalyzerErrorsMetric.DeletePartialMatch(map[string]string{
+		"analyzer_name": kind,
+	})
+
+	gvr, err := g.config.resource()
+	if err != nil {
+		return nil, err
+	}
+	var resource dynamic.ResourceInterface = g.client.Resource(gvr)
+	if a.Namespace != "" {
+		resource = g.client.Resource(gvr).Namespace(a.Namespace)
+	}
+	obj, err := resource.Get(a.Context, a.Name, metav1.GetOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("error getting %s %s/%s: %w", g.config.Kind, a.Namespace, a.Name, err)
+	}
+	content := obj.UnstructuredContent()
+
+	result := &common.Result{
+		Result: kcommon.Result{
+			Kind: kind,
+			Name: obj.GetName(),
+		},
+		Metadata: map[string]string{
+			"Kind":       g.config.Kind,
+			"Name":       obj.GetName(),
+			"APIVersion": g.config.APIVersion,
+		},
+	}
+	if g.config.Status != "" {
+		result.Metadata["Status"] = evalJSONPath(content, g.config.Status)
+	}
+	for key, path := range g.config.Metadata {
+		if value := evalJSONPath(content, path); value != "" {
+			result.Metadata[key] = value
+		}
+	}
+
+	// === 健康检查 ===
+	for _, check := range g.config.HealthChecks {
+		value := evalJSONPath(content, check.Path)
+		if value == "" || check.passes(value) {
+			continue
+		}
+		text := fmt.Sprintf("%s is %s", check.Name, value)
+		if message := evalJSONPath(content, check.Message); message != "" {
+			text += ": " + message
+		}
+		if check.Severity == "Warning" {
+			result.Warning = append(result.Warning, common.Warning{Text: text})
+		} else {
+			result.Error = append(result.Error, kcommon.Failure{Text: text})
+		}
+	}
+
+	// === Events 分析 ===
+	sources := g.config.Events
+	if len(sources) == 0 {
+		sources = []GenericEventSource{{Kind: g.config.Kind}}
+	}
+	for _, source := range sources {
+		name := obj.GetName()
+		if source.Name != "" {
+			name = evalJSONPath(content, source.Name)
+		}
+		if name != "" {
+			appendWorkloadEvents(a, g.prometheus, source.Kind, name, result)
+		}
+	}
+
+	// === Pod 分析 ===
+	if g.config.PodSelector != "" {
+		if err := g.analyzeGenericPods(a, content, result); err != nil {
+			klog.Warningf("analyze pods for %s %s/%s failed: %v", g.config.Kind, a.Namespace, obj.GetName(), err)
+		}
+	}
+
+	if len(result.Error) > 0 {
+		analyzer.AnalyzerErrorsMetric.WithLabelValues(kind, obj.GetName(), a.Namespace).Set(float64(len(result.Error)))
+	}
+
+	return result, nil
+}
+
+func (c GenericHealthCheck) passes(value string) bool {
+	if len(c.Healthy) > 0 {
+		return slices.Contains(c.Healthy, value)
+	}
+	return !slices.Contains(c.Unhealthy, value)
+}
+
+// analyzeGenericPods analyzes the pods of the rendered pod selector
+func (g GenericAnalyzer) analyzeGenericPods(a common.Analyzer, content map[string]interface{}, result *common.Result) error {
+	selector := evalJSONPath(content, g.config.PodSelector)
+	if selector == "" {
+		return fmt.Errorf("pod selector %q is empty", g.config.PodSelector)
+	}
+	result.Metadata["PodSelector"] = selector
+
+	list, err := a.Client.GetClient().CoreV1().Pods(a.Namespace).List(a.Context, metav1.ListOptions{
+		LabelSelector: selector,
+	})
+	if err != nil {
+		return fmt.Errorf("list pods failed: %w", err)
+	}
+	pods := make([]*v1.Pod, 0, len(list.Items))
+	for i := range list.Items {
+		pods = append(pods, &list.Items[i])
+	}
+	analyzeWorkloadPods(a, g.prometheus, pods, result)
+	return nil
+}
+
+// evalJSONPath returns the value of a JSONPath template of the object, empty
+// if it is missing
+func evalJSONPath(content map[string]interface{}, path string) string {
+	if path == "" {
+		return ""
+	}
+	jp := jsonpath.New("generic").AllowMissingKeys(true)
+	if err := jp.Parse(path); err != nil {
+		klog.Warningf("invalid JSONPath %q: %v", path, err)
+		return ""
+	}
+	buf := &bytes.Buffer{}
+	if err := jp.Execute(buf, content); err != nil {
+		klog.V(4).Infof("evaluate JSONPath %q failed: %v", path, err)
+		return ""
+	}
+	return strings.TrimSpace(buf.String())
+}
+
+// Prompt renders the <kind>.tmpl prompt override of the diagnosis kind if
+// present, otherwise the Generic prompt
+func (g GenericAnalyzer) Prompt(result *common.Result) string {
+	if result == nil || (len(result.Error) == 0 && len(result.Warning) == 0) {
+		return ""
+	}
+
+	data := workloadPromptData(result)
+	if override, err := ai.LoadPromptOverride(g.config.name); err == nil {
+		klog.Infof("using override prompt for kind %s's AI diagnosis", g.config.name)
+		prompt, err := ai.RenderTextTemplate(override, data)
+		if err != nil {
+			return fmt.Sprintf("Prompt rendering error: %v", err)
+		}
+		return prompt
+	}
+
+	prompt, err := ai.GetRenderedPrompt("Generic", data)
+	if err != nil {
+		return fmt.Sprintf("Prompt rendering error: %v", err)
+	}
+	return prompt
+}
@@ -0,0 +1,127 @@
+package analyzer
+
+import (
+	"os"
+	"strings"
+	"testing"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	dynamicfake "k8s.io/client-go/dynamic/fake"
+)
+
+const rayJobConfig = `
+apiVersion: ray.io/v1
+kind: RayJob
+status: "{.status.jobStatus}"
+healthChecks:
+  - name: JobStatus
+    path: "{.status.jobStatus}"
+    unhealthy: ["FAILED", "STOPPED"]
+    message: "{.status.message}"
+  - name: Ready
+    path: '{.status.conditions[?(@.type=="Ready")].status}'
+    healthy: ["True"]
+    severity: Warning
+podSelector: "ray.io/cluster={.status.rayClusterName}"
+events:
+  - kind: RayJob
+  - kind: RayCluster
+    name: "{.status.rayClusterName}"
+metadata:
+  RayClusterName: "{.status.rayClusterName}"
+  Entrypoint: "{.spec.entrypoint}"
+  Missing: "{.status.missing}"
+`
+
+func TestParseGenericConfig(t *testing.T) {
+	config, err := ParseGenericConfig([]byte(rayJobConfig))
+	if err != nil {
+		t.Fatal(err)
+	}
+	gvr, err := config.resource()
+	if err != nil || gvr.Group != "ray.io" || gvr.Version != "v1" || gvr.Resource != "rayjobs" {
+		t.Errorf("unexpected resource %v %v", gvr, err)
+	}
+
+	for _, invalid := range []string{
+		"kind: RayJob",
+		"apiVersion: ray.io/v1\nkind: RayJob\nstatus: '{.status.jobStatus'",
+		"apiVersion: ray.io/v1\nkind: RayJob\nhealthChecks: [{name: A, path: '{.a}', healthy: [x], unhealthy: [y]}]",
+		"apiVersion: ray.io/v1\nkind: RayJob\nhealthChecks: [{name: A, path: '{.a}', severity: Fatal}]",
+	} {
+		if _, err := ParseGenericConfig([]byte(invalid)); err == nil {
+			t.Errorf("expected config %q invalid", invalid)
+		}
+	}
+}
+
+func TestLoadGenericConfigKind(t *testing.T) {
+	for _, kind := range []string{"../../etc/passwd", "..", "a/b", "Ray.Job", "", "1job", "job_"} {
+		if _, err := LoadGenericConfig(kind); err == nil || os.IsNotExist(err) || !strings.Contains(err.Error(), "invalid diagnosis kind") {
+			t.Errorf("expected kind %q invalid, got %v", kind, err)
+		}
+	}
+	// a valid kind is looked up in the ConfigMap
+	if _, err := LoadGenericConfig("RayJob"); !os.IsNotExist(err) {
+		t.Errorf("expected the config of RayJob looked up, got %v", err)
+	}
+}
+
+func TestGenericAnalyzer(t *testing.T) {
+	config, err := ParseGenericConfig([]byte(rayJobConfig))
+	if err != nil {
+		t.Fatal(err)
+	}
+	job := &unstructured.Unstructured{Object: map[string]interface{}{
+		"apiVersion": "ray.io/v1",
+		"kind":       "RayJob",
+		"metadata":   map[string]interface{}{"name": "train", "namespace": "default"},
+		"spec":       map[string]interface{}{"entrypoint": "python train.py"},
+		"status": map[string]interface{}{
+			"jobStatus":      "FAILED",
+			"message":        "Job entrypoint command failed with exit code 1",
+			"rayClusterName": "train-raycluster",
+			"conditions": []interface{}{
+				map[string]interface{}{"type": "Ready", "status": "False"},
+			},
+		},
+	}}
+
+	owner := &metav1.ObjectMeta{Name: "train-raycluster", UID: "raycluster-uid"}
+	head := crashing(newTestPod("train-raycluster-head", "node1", owner, "RayCluster"), 4)
+	head.Labels = map[string]string{"ray.io/cluster": "train-raycluster"}
+	worker := newTestPod("train-raycluster-worker", "node1", owner, "RayCluster")
+	worker.Labels = map[string]string{"ray.io/cluster": "train-raycluster"}
+	other := crashing(newTestPod("other", "node1", owner, "RayCluster"), 4)
+
+	analyzer := NewGenericAnalyzer(nil, dynamicfake.NewSimpleDynamicClient(runtime.NewScheme(), job), config)
+	result := analyzeTestWorkload(t, analyzer, "train", head, worker, other)
+
+	if result.Metadata["Status"] != "FAILED" || result.Metadata["RayClusterName"] != "train-raycluster" || result.Metadata["Entrypoint"] != "python train.py" {
+		t.Errorf("unexpected metadata %v", result.Metadata)
+	}
+	if _, ok := result.Metadata["Missing"]; ok {
+		t.Errorf("expected missing fields skipped, got %v", result.Metadata)
+	}
+	if len(result.Error) == 0 || result.Error[0].Text != "JobStatus is FAILED: Job entrypoint command failed with exit code 1" {
+		t.Errorf("unexpected errors %+v", result.Error)
+	}
+	if len(result.Warning) != 1 || result.Warning[0].Text != "Ready is False" {
+		t.Errorf("unexpected warnings %+v", result.Warning)
+	}
+	if result.Metadata["PodCount"] != "2" || result.Metadata["UnhealthyPodCount"] != "1" {
+		t.Errorf("expected the pods of the selector analyzed, got %v", result.Metadata)
+	}
+	if !strings.Contains(result.Metadata["PodDiagnosis"], "Pod train-raycluster-head") {
+		t.Errorf("expected the head pod detailed, got %s", result.Metadata["PodDiagnosis"])
+	}
+
+	prompt := analyzer.Prompt(result)
+	for _, expected := range []string{"类型: RayJob", "状态: FAILED", "Entrypoint: python train.py", "选择器 ray.io/cluster=train-raycluster"} {
+		if !strings.Contains(prompt, expected) {
+			t.Errorf("expected %q in the prompt:\n%s", expected, prompt)
+		}
+	}
+}
@@ -0,0 +1,338 @@
+package analyzer
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/k8sgpt-ai/k8sgpt/pkg/analyzer"
+	kcommon "github.com/k8sgpt-ai/k8sgpt/pkg/common"
+	"github.com/scitix/aegis/pkg/analyzer/common"
+	"github.com/scitix/aegis/pkg/prom"
+	batchv1 "k8s.io/api/batch/v1"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/klog/v2"
+)
+
+// maxDetailedFailedPods bounds the last failed pods of a job detailed with
+// their exit codes
+const maxDetailedFailedPods = 3
+
+// signalNames of the exit codes above 128, i.e. killed by a signal
+var signalNames = map[int32]string{
+	1:  "SIGHUP",
+	2:  "SIGINT",
+	6:  "SIGABRT",
+	7:  "SIGBUS",
+	8:  "SIGFPE",
+	9:  "SIGKILL",
+	11: "SIGSEGV",
+	13: "SIGPIPE",
+	15: "SIGTERM",
+}
+
+type JobAnalyzer struct {
+	prometheus *prom.PromAPI
+}
+
+func NewJobAnalyzer(prometheus *prom.PromAPI) JobAnalyzer {
+	return JobAnalyzer{
+		prometheus: prometheus,
+	}
+}
+
+func (j JobAnalyzer) Analyze(a common.Analyzer) (*common.Result, error) {
+	kind := "Job"
+
+	analyzer.AnalyzerErrorsMetric.DeletePartialMatch(map[string]string{
+		"analyzer_name": kind,
+	})
+
+	job, err := a.Client.GetClient().BatchV1().Jobs(a.Namespace).Get(a.Context, a.Name, metav1.GetOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("error getting Job %s/%s: %w", a.Namespace, a.Name, err)
+	}
+
+	result := j.analyzeJob(a, job)
+
+	if len(result.Error) > 0 {
+		analyzer.AnalyzerErrorsMetric.WithLabelValues(kind, job.Name, job.Namespace).Set(float64(len(result.Error)))
+	}
+
+	return result, nil
+}
+
+func (j JobAnalyzer) analyzeJob(a common.Analyzer, job *batchv1.Job) *common.Result {
+	kind := "Job"
+
+	completions := int32(1)
+	if job.Spec.Completions != nil {
+		completions = *job.Spec.Completions
+	}
+	parallelism := int32(1)
+	if job.Spec.Parallelism != nil {
+		parallelism = *job.Spec.Parallelism
+	}
+	backoffLimit := int32(6)
+	if job.Spec.BackoffLimit != nil {
+		backoffLimit = *job.Spec.BackoffLimit
+	}
+
+	result := &common.Result{
+		Result: kcommon.Result{
+			Kind: kind,
+			Name: job.Name,
+		},
+		Metadata: map[string]string{
+			"Kind":         kind,
+			"Name":         job.Name,
+			"JobStatus":    jobStatus(job),
+			"Completions":  fmt.Sprintf("%d", completions),
+			"Parallelism":  fmt.Sprintf("%d", parallelism),
+			"BackoffLimit": fmt.Sprintf("%d", backoffLimit),
+			"Active":       fmt.Sprintf("%d", job.Status.Active),
+			"Succeeded":    fmt.Sprintf("%d", job.Status.Succeeded),
+			"Failed":       fmt.Sprintf("%d", job.Status.Failed),
+		},
+	}
+	if job.Spec.ActiveDeadlineSeconds != nil {
+		result.Metadata["ActiveDeadlineSeconds"] = fmt.Sprintf("%d", *job.Spec.ActiveDeadlineSeconds)
+	}
+	if job.Status.StartTime != nil {
+		result.Metadata["StartTime"] = job.Status.StartTime.Format(time.RFC3339)
+	}
+	if job.Status.CompletionTime != nil {
+		result.Metadata["CompletionTime"] = job.Status.CompletionTime.Format(time.RFC3339)
+	}
+	if policy := describePodFailurePolicy(job.Spec.PodFailurePolicy); len(policy) > 0 {
+		result.Metadata["PodFailurePolicy"] = policy
+	}
+
+	// === Job 状态分析 ===
+	for _, cond := range job.Status.Conditions {
+		if cond.Status != v1.ConditionTrue {
+			continue
+		}
+		switch cond.Type {
+		case batchv1.JobComplete, batchv1.JobSuccessCriteriaMet:
+			result.Info = append(result.Info, common.Info{
+				Text: "Job completed successfully.",
+			})
+			return result
+		case batchv1.JobFailed:
+			result.Error = append(result.Error, kcommon.Failure{
+				Text: jobFailureText(job, cond, backoffLimit),
+			})
+		case batchv1.JobFailureTarget:
+			result.Warning = append(result.Warning, common.Warning{
+				Text: fmt.Sprintf("Job is terminating its pods to fail: %s - %s", cond.Reason, cond.Message),
+			})
+		case batchv1.JobSuspended:
+			result.Warning = append(result.Warning, common.Warning{
+				Text: "Job is suspended, no pods are created",
+			})
+		}
+	}
+
+	// === Indexed Job 分析 ===
+	if job.Spec.CompletionMode != nil && *job.Spec.CompletionMode == batchv1.IndexedCompletion {
+		result.Metadata["CompletionMode"] = string(batchv1.IndexedCompletion)
+		result.Metadata["CompletedIndexes"] = job.Status.CompletedIndexes
+		if job.Spec.BackoffLimitPerIndex != nil {
+			result.Metadata["BackoffLimitPerIndex"] = fmt.Sprintf("%d", *job.Spec.BackoffLimitPerIndex)
+		}
+		if job.Status.FailedIndexes != nil && len(*job.Status.FailedIndexes) > 0 {
+			result.Metadata["FailedIndexes"] = *job.Status.FailedIndexes
+			result.Error = append(result.Error, kcommon.Failure{
+				Text: fmt.Sprintf("Indexes %s failed after exhausting their backoff limit", *job.Status.FailedIndexes),
+			})
+		}
+	}
+
+	if jobStatus(job) == "Running" && job.Status.Failed > 0 {
+		result.Warning = append(result.Warning, common.Warning{
+			Text: fmt.Sprintf("%d pod(s) failed so far, the backoff limit is %d", job.Status.Failed, backoffLimit),
+		})
+	}
+
+	appendWorkloadEvents(a, j.prometheus, kind, job.Name, result)
+
+	// === Pod 分析 ===
+	pods, err := listControlledPods(a, job.Spec.Selector, job.UID)
+	if err != nil {
+		klog.Warningf("analyze pods for %s/%s failed: %v", a.Namespace, job.Name, err)
+		return result
+	}
+	j.analyzeJobPods(a, job, pods, result)
+
+	return result
+}
+
+// analyzeJobPods details the exit codes of the last failed pods, and
+// delegates them, or the worst unhealthy active pods, to the PodAnalyzer
+func (j JobAnalyzer) analyzeJobPods(a common.Analyzer, job *batchv1.Job, pods []*v1.Pod, result *common.Result) {
+	unhealthy := unhealthyPods(pods)
+	result.Metadata["PodCount"] = fmt.Sprintf("%d", len(pods))
+	result.Metadata["UnhealthyPodCount"] = fmt.Sprintf("%d", len(unhealthy))
+	if len(unhealthy) > 0 {
+		result.Metadata["FailureReasons"] = strings.Join(summarizeFailureReasons(unhealthy), "\n")
+	}
+
+	failed := lastFailedPods(pods)
+	if len(failed) == 0 {
+		if job.Status.Failed > 0 {
+			result.Warning = append(result.Warning, common.Warning{
+				Text: fmt.Sprintf("%d failed pod(s) are not found, they may have been garbage collected", job.Status.Failed),
+			})
+		}
+		if len(unhealthy) > 0 {
+			detailPods(a, j.prometheus, unhealthy[:min(len(unhealthy), maxDetailedUnhealthyPods)], len(unhealthy), result)
+		}
+		return
+	}
+
+	detailed := failed[:min(len(failed), maxDetailedFailedPods)]
+	lines := make([]string, 0, len(detailed))
+	for _, pod := range detailed {
+		lines = append(lines, describeFailedPod(pod))
+	}
+	result.Metadata["FailedPods"] = strings.Join(lines, "\n")
+	result.Error = append(result.Error, kcommon.Failure{
+		Text: fmt.Sprintf("Last failed pod %s", lines[0]),
+	})
+	detailPods(a, j.prometheus, detailed, len(failed), result)
+}
+
+// jobStatus is Complete, Failed, Suspended or Running
+func jobStatus(job *batchv1.Job) string {
+	for _, cond := range job.Status.Conditions {
+		if cond.Status != v1.ConditionTrue {
+			continue
+		}
+		switch cond.Type {
+		case batchv1.JobComplete, batchv1.JobSuccessCriteriaMet:
+			return "Complete"
+		case batchv1.JobFailed:
+			return "Failed"
+		case batchv1.JobSuspended:
+			return "Suspended"
+		}
+	}
+	return "Running"
+}
+
+func jobFailureText(job *batchv1.Job, cond batchv1.JobCondition, backoffLimit int32) string {
+	switch cond.Reason {
+	case batchv1.JobReasonBackoffLimitExceeded:
+		return fmt.Sprintf("Job failed as %d pod(s) failed, reaching the backoff limit %d", job.Status.Failed, backoffLimit)
+	case batchv1.JobReasonDeadlineExceeded:
+		deadline := int64(0)
+		if job.Spec.ActiveDeadlineSeconds != nil {
+			deadline = *job.Spec.ActiveDeadlineSeconds
+		}
+		return fmt.Sprintf("Job failed as it was active longer than activeDeadlineSeconds %d", deadline)
+	case batchv1.JobReasonPodFailurePolicy:
+		return fmt.Sprintf("Job failed by its pod failure policy: %s", cond.Message)
+	case batchv1.JobReasonMaxFailedIndexesExceeded:
+		return fmt.Sprintf("Job failed as more indexes failed than maxFailedIndexes: %s", cond.Message)
+	default:
+		return fmt.Sprintf("Job failed: %s - %s", cond.Reason, cond.Message)
+	}
+}
+
+// describePodFailurePolicy summarizes the rules of the pod failure policy
+func describePodFailurePolicy(policy *batchv1.PodFailurePolicy) string {
+	if policy == nil {
+		return ""
+	}
+	rules := make([]string, 0, len(policy.Rules))
+	for i, rule := range policy.Rules {
+		if rule.OnExitCodes != nil {
+			codes := make([]string, 0, len(rule.OnExitCodes.Values))
+			for _, code := range rule.OnExitCodes.Values {
+				codes = append(codes, fmt.Sprintf("%d", code))
+			}
+			container := "any container"
+			if rule.OnExitCodes.ContainerName != nil {
+				container = "container " + *rule.OnExitCodes.ContainerName
+			}
+			rules = append(rules, fmt.Sprintf("rule %d: %s on exit codes %s [%s] of %s", i, rule.Action, rule.OnExitCodes.Operator, strings.Join(codes, ","), container))
+			continue
+		}
+		conditions := make([]string, 0, len(rule.OnPodConditions))
+		for _, cond := range rule.OnPodConditions {
+			conditions = append(conditions, fmt.Sprintf("%s=%s", cond.Type, cond.Status))
+		}
+		rules = append(rules, fmt.Sprintf("rule %d: %s on pod conditions %s", i, rule.Action, strings.Join(conditions, ",")))
+	}
+	return strings.Join(rules, "\n")
+}
+
+// lastFailedPods returns the failed pods, the last finished first
+func lastFailedPods(pods []*v1.Pod) []*v1.Pod {
+	failed := make([]*v1.Pod, 0)
+	for _, pod := range pods {
+		if pod.Status.Phase == v1.PodFailed {
+			failed = append(failed, pod)
+		}
+	}
+	sort.SliceStable(failed, func(i, k int) bool {
+		return podFinishedAt(failed[i]).After(podFinishedAt(failed[k]))
+	})
+	return failed
+}
+
+// podFinishedAt is the last termination of the pod containers
+func podFinishedAt(pod *v1.Pod) time.Time {
+	finished := pod.CreationTimestamp.Time
+	for _, status := range pod.Status.ContainerStatuses {
+		if t := status.State.Terminated; t != nil && t.FinishedAt.After(finished) {
+			finished = t.FinishedAt.Time
+		}
+	}
+	return finished
+}
+
+// describeFailedPod describes the exit codes, signals and OOM kills of the
+// failed containers of a pod
+func describeFailedPod(pod *v1.Pod) string {
+	text := pod.Name
+	if index, ok := pod.Annotations[batchv1.JobCompletionIndexAnnotation]; ok {
+		text += fmt.Sprintf(" (index %s)", index)
+	}
+	text += fmt.Sprintf(" on node %s", pod.Spec.NodeName)
+	if len(pod.Status.Reason) > 0 {
+		text += fmt.Sprintf(": %s - %s", pod.Status.Reason, pod.Status.Message)
+	}
+
+	for _, status := range append(pod.Status.InitContainerStatuses, pod.Status.ContainerStatuses...) {
+		terminated := status.State.Terminated
+		if terminated == nil || terminated.ExitCode == 0 {
+			// e.g. restarted in place as of restartPolicy OnFailure
+			terminated = status.LastTerminationState.Terminated
+		}
+		if terminated == nil || terminated.ExitCode == 0 {
+			continue
+		}
+		text += fmt.Sprintf("; container %s exited with code %d", status.Name, terminated.ExitCode)
+		if signal, ok := signalNames[terminated.ExitCode-128]; ok {
+			text += fmt.Sprintf(" (%s)", signal)
+		}
+		if len(terminated.Reason) > 0 {
+			text += fmt.Sprintf(" reason %s", terminated.Reason)
+		}
+		if !terminated.FinishedAt.IsZero() {
+			text += fmt.Sprintf(" at %s", terminated.FinishedAt.Format(time.RFC3339))
+		}
+		if status.RestartCount > 0 {
+			text += fmt.Sprintf(" after %d restarts", status.RestartCount)
+		}
+	}
+	return text
+}
+
+func (j JobAnalyzer) Prompt(result *common.Result) string {
+	return workloadPrompt("Job", result)
+}
@@ -0,0 +1,219 @@
+package analyzer
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/scitix/aegis/pkg/analyzer/common"
+	batchv1 "k8s.io/api/batch/v1"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+func newTestJob(name string, owner metav1.Object) *batchv1.Job {
+	backoffLimit := int32(2)
+	job := &batchv1.Job{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      name,
+			Namespace: "default",
+			UID:       types.UID(name + "-uid"),
+		},
+		Spec: batchv1.JobSpec{
+			BackoffLimit: &backoffLimit,
+			Selector:     &metav1.LabelSelector{MatchLabels: testLabels},
+		},
+	}
+	if owner != nil {
+		job.OwnerReferences = []metav1.OwnerReference{*metav1.NewControllerRef(owner, batchv1.SchemeGroupVersion.WithKind("CronJob"))}
+	}
+	return job
+}
+
+func failedJobPod(name string, job *batchv1.Job, exitCode int32, reason string, finishedAt time.Time) *v1.Pod {
+	pod := newTestPod(name, "node1", job, "Job")
+	pod.OwnerReferences[0].APIVersion = batchv1.SchemeGroupVersion.String()
+	pod.Status = v1.PodStatus{
+		Phase: v1.PodFailed,
+		ContainerStatuses: []v1.ContainerStatus{{
+			Name: "main",
+			State: v1.ContainerState{Terminated: &v1.ContainerStateTerminated{
+				ExitCode:   exitCode,
+				Reason:     reason,
+				FinishedAt: metav1.NewTime(finishedAt),
+			}},
+		}},
+	}
+	return pod
+}
+
+func TestJobAnalyzer(t *testing.T) {
+	job := newTestJob("etl", nil)
+	job.Status = batchv1.JobStatus{
+		Failed: 3,
+		Conditions: []batchv1.JobCondition{{
+			Type:   batchv1.JobFailed,
+			Status: v1.ConditionTrue,
+			Reason: batchv1.JobReasonBackoffLimitExceeded,
+		}},
+	}
+	now := time.Now()
+
+	result := analyzeTestWorkload(t, NewJobAnalyzer(nil), "etl", job,
+		failedJobPod("etl-a", job, 1, "Error", now.Add(-2*time.Minute)),
+		failedJobPod("etl-b", job, 137, "OOMKilled", now.Add(-time.Minute)),
+		failedJobPod("etl-c", job, 1, "Error", now.Add(-3*time.Minute)),
+	)
+
+	if result.Metadata["JobStatus"] != "Failed" {
+		t.Errorf("expected a failed job, got %s", result.Metadata["JobStatus"])
+	}
+	if len(result.Error) != 2 || result.Error[0].Text != "Job failed as 3 pod(s) failed, reaching the backoff limit 2" {
+		t.Fatalf("unexpected errors %+v", result.Error)
+	}
+	if !strings.Contains(result.Error[1].Text, "etl-b on node node1; container main exited with code 137 (SIGKILL) reason OOMKilled") {
+		t.Errorf("expected the last failed pod OOM killed, got %s", result.Error[1].Text)
+	}
+	failed := strings.Split(result.Metadata["FailedPods"], "\n")
+	if len(failed) != 3 || !strings.HasPrefix(failed[1], "etl-a") || !strings.HasPrefix(failed[2], "etl-c") {
+		t.Errorf("expected the failed pods the last first, got %v", failed)
+	}
+
+	prompt := NewJobAnalyzer(nil).Prompt(result)
+	if !strings.Contains(prompt, "Job 名称: etl") || !strings.Contains(prompt, "Job 状态: Failed") {
+		t.Errorf("unexpected prompt:\n%s", prompt)
+	}
+
+	// completed jobs need no explain
+	job.Status = batchv1.JobStatus{
+		Succeeded:  1,
+		Conditions: []batchv1.JobCondition{{Type: batchv1.JobComplete, Status: v1.ConditionTrue}},
+	}
+	result = analyzeTestWorkload(t, NewJobAnalyzer(nil), "etl", job)
+	if prompt := NewJobAnalyzer(nil).Prompt(result); prompt != "" {
+		t.Errorf("expected no prompt of a completed job, got %s", prompt)
+	}
+}
+
+func TestIndexedJobFailedIndexes(t *testing.T) {
+	job := newTestJob("shards", nil)
+	mode := batchv1.IndexedCompletion
+	perIndex := int32(1)
+	failedIndexes := "1,3-4"
+	job.Spec.CompletionMode = &mode
+	job.Spec.BackoffLimitPerIndex = &perIndex
+	job.Status = batchv1.JobStatus{
+		Failed:           6,
+		CompletedIndexes: "0,2",
+		FailedIndexes:    &failedIndexes,
+		Conditions: []batchv1.JobCondition{{
+			Type:   batchv1.JobFailed,
+			Status: v1.ConditionTrue,
+			Reason: batchv1.JobReasonFailedIndexes,
+		}},
+	}
+	pod := failedJobPod("shards-3", job, 143, "Error", time.Now())
+	pod.Annotations = map[string]string{batchv1.JobCompletionIndexAnnotation: "3"}
+
+	result := analyzeTestWorkload(t, NewJobAnalyzer(nil), "shards", job, pod)
+	if result.Metadata["FailedIndexes"] != "1,3-4" {
+		t.Errorf("unexpected failed indexes %q", result.Metadata["FailedIndexes"])
+	}
+	if !strings.Contains(result.Metadata["FailedPods"], "shards-3 (index 3) on node node1; container main exited with code 143 (SIGTERM)") {
+		t.Errorf("unexpected failed pods %s", result.Metadata["FailedPods"])
+	}
+}
+
+func TestAnalyzeSchedules(t *testing.T) {
+	now := time.Date(2026, 1, 1, 12, 30, 0, 0, time.UTC)
+	lastSchedule := metav1.NewTime(time.Date(2026, 1, 1, 9, 0, 0, 0, time.UTC))
+	cronJob := &batchv1.CronJob{
+		ObjectMeta: metav1.ObjectMeta{Name: "report", CreationTimestamp: metav1.NewTime(now.Add(-24 * time.Hour))},
+		Spec: batchv1.CronJobSpec{
+			Schedule:          "0 * * * *",
+			ConcurrencyPolicy: batchv1.ForbidConcurrent,
+		},
+		Status: batchv1.CronJobStatus{
+			LastScheduleTime: &lastSchedule,
+			Active:           []v1.ObjectReference{{Name: "report-29000000"}},
+		},
+	}
+
+	result := &common.Result{Metadata: map[string]string{}}
+	if err := analyzeSchedules(cronJob, now, result); err != nil {
+		t.Fatal(err)
+	}
+	if result.Metadata["MissedSchedules"] != "3" || result.Metadata["NextScheduleTime"] != "2026-01-01T13:00:00Z" {
+		t.Errorf("unexpected metadata %v", result.Metadata)
+	}
+	if len(result.Error) != 1 || !strings.Contains(result.Error[0].Text, "skipped as of the Forbid concurrency policy while job report-29000000 is still active") {
+		t.Errorf("expected the schedules skipped by the concurrency policy, got %+v", result.Error)
+	}
+
+	// missing the starting deadline
+	deadline := int64(60)
+	cronJob.Spec.ConcurrencyPolicy = batchv1.AllowConcurrent
+	cronJob.Spec.StartingDeadlineSeconds = &deadline
+	result = &common.Result{Metadata: map[string]string{}}
+	if err := analyzeSchedules(cronJob, now, result); err != nil {
+		t.Fatal(err)
+	}
+	if len(result.Error) != 1 || !strings.Contains(result.Error[0].Text, "missing the startingDeadlineSeconds 60") {
+		t.Errorf("expected the starting deadline missed, got %+v", result.Error)
+	}
+
+	// on time
+	cronJob.Status.LastScheduleTime = &metav1.Time{Time: time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)}
+	result = &common.Result{Metadata: map[string]string{}}
+	if err := analyzeSchedules(cronJob, now, result); err != nil || len(result.Error) != 0 {
+		t.Errorf("expected no missed schedules, got %v %+v", err, result.Error)
+	}
+}
+
+func TestCronJobAnalyzer(t *testing.T) {
+	cronJob := &batchv1.CronJob{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:              "report",
+			Namespace:         "default",
+			UID:               "cronjob-uid",
+			CreationTimestamp: metav1.Now(),
+		},
+		Spec: batchv1.CronJobSpec{Schedule: "@yearly"},
+	}
+	older := newTestJob("report-1", cronJob)
+	older.CreationTimestamp = metav1.NewTime(time.Now().Add(-2 * time.Hour))
+	older.Status.Conditions = []batchv1.JobCondition{{Type: batchv1.JobComplete, Status: v1.ConditionTrue}}
+	last := newTestJob("report-2", cronJob)
+	last.CreationTimestamp = metav1.NewTime(time.Now().Add(-time.Hour))
+	last.Status = batchv1.JobStatus{
+		Failed: 1,
+		Conditions: []batchv1.JobCondition{{
+			Type:    batchv1.JobFailed,
+			Status:  v1.ConditionTrue,
+			Reason:  batchv1.JobReasonPodFailurePolicy,
+			Message: "Container main for pod default/report-2-x failed with exit code 42 matching FailJob rule at index 0",
+		}},
+	}
+	pod := failedJobPod("report-2-x", last, 42, "Error", time.Now())
+
+	result := analyzeTestWorkload(t, NewCronJobAnalyzer(nil), "report", cronJob, older, last, pod)
+	if result.Metadata["JobCount"] != "2" || result.Metadata["FailedJobCount"] != "1" {
+		t.Errorf("unexpected metadata %v", result.Metadata)
+	}
+	if !strings.HasPrefix(result.Metadata["RecentJobs"], "report-2 created at") {
+		t.Errorf("expected the recent jobs the last first, got %s", result.Metadata["RecentJobs"])
+	}
+	if !strings.Contains(result.Metadata["LastFailedJob"], "report-2-x on node node1; container main exited with code 42") {
+		t.Errorf("expected the failed pod of the last job, got %s", result.Metadata["LastFailedJob"])
+	}
+	found := false
+	for _, e := range result.Error {
+		if strings.Contains(e.Text, "Last job report-2: Job failed by its pod failure policy") {
+			found = true
+		}
+	}
+	if !found {
+		t.Errorf("expected the pod failure policy outcome, got %+v", result.Error)
+	}
+}
@@ -0,0 +1,38 @@
+package analyzer
+
+import (
+	"context"
+
+	kubeflowv1 "github.com/kubeflow/training-operator/pkg/apis/kubeflow.org/v1"
+	kfclientset "github.com/kubeflow/training-operator/pkg/client/clientset/versioned"
+	"github.com/scitix/aegis/pkg/prom"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// paddleJobKind analyzes the master pods, the parameter servers in PS mode, and samples the abnormal workers
+var paddleJobKind = trainingJobKind{
+	Kind:        "PaddleJob",
+	EventKind:   "PaddleJob",
+	LeaderTypes: []kubeflowv1.ReplicaType{kubeflowv1.PaddleJobReplicaTypeMaster},
+	MemberTypes: []kubeflowv1.ReplicaType{kubeflowv1.PaddleJobReplicaTypeWorker},
+	Get: func(ctx context.Context, client kfclientset.Interface, namespace, name string) (*trainingJob, error) {
+		job, err := client.KubeflowV1().PaddleJobs(namespace).Get(ctx, name, metav1.GetOptions{})
+		if err != nil {
+			return nil, err
+		}
+		return &trainingJob{
+			Name:         job.Name,
+			ReplicaSpecs: job.Spec.PaddleReplicaSpecs,
+			RunPolicy:    job.Spec.RunPolicy,
+			Status:       job.Status,
+		}, nil
+	},
+}
+
+func NewPaddleJobAnalyzer(prometheus *prom.PromAPI, client kfclientset.Interface) TrainingJobAnalyzer {
+	return TrainingJobAnalyzer{
+		client:     client,
+		prometheus: prometheus,
+		kind:       paddleJobKind,
+	}
+}
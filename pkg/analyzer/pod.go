@@ -53,7 +53,7 @@ func (p PodAnalyzer) Analyze(a common.Analyzer) (*common.Result, error) {
 				if containerStatus.Message != "" {
 					failures = append(failures, kcommon.Failure{
 						Text:      containerStatus.Message,
-						Sensitive: []kcommon.Sensitive{},
+						Sensitive: sensitive(name),
 					})
 				}
 			}
@@ -154,7 +154,8 @@ func fetchContainerLogs(ctx context.Context, client *kubernetes.Client, pod *v1.
 		}
 		if logs, err := getContainerLogs(ctx, client, pod, &c, status, cfg); err == nil && len(logs) > 0 {
 			infos = append(infos, common.Info{
-				Text: fmt.Sprintf("pod %s container %s logs:\n%s", pod.Name, c.Name, strings.Join(logs, "\n")),
+				Text:      fmt.Sprintf("pod %s container %s logs:\n%s", pod.Name, c.Name, strings.Join(logs, "\n")),
+				Sensitive: sensitive(pod.Name, pod.Spec.NodeName),
 			})
 		}
 	}
@@ -178,19 +179,19 @@ func analyzeContainerStatusFailures(a common.Analyzer, statuses []v1.ContainerSt
 				if isEvtErrorReason(evt.Reason) && evt.Message != "" {
 					failures = append(failures, kcommon.Failure{
 						Text:      evt.Message,
-						Sensitive: []kcommon.Sensitive{},
+						Sensitive: sensitive(name),
 					})
 				}
 			} else if containerStatus.State.Waiting.Reason == "CrashLoopBackOff" && containerStatus.LastTerminationState.Terminated != nil {
 				// This represents container that is in CrashLoopBackOff state due to conditions such as OOMKilled
 				failures = append(failures, kcommon.Failure{
 					Text:      fmt.Sprintf("the last termination reason is %s container=%s pod=%s", containerStatus.LastTerminationState.Terminated.Reason, containerStatus.Name, name),
-					Sensitive: []kcommon.Sensitive{},
+					Sensitive: sensitive(name),
 				})
 			} else if isErrorReason(containerStatus.State.Waiting.Reason) && containerStatus.State.Waiting.Message != "" {
 				failures = append(failures, kcommon.Failure{
 					Text:      containerStatus.State.Waiting.Message,
-					Sensitive: []kcommon.Sensitive{},
+					Sensitive: sensitive(name),
 				})
 			}
 		} else if containerStatus.State.Terminated != nil && containerStatus.State.Terminated.ExitCode != 0 {
@@ -198,7 +199,7 @@ func analyzeContainerStatusFailures(a common.Analyzer, statuses []v1.ContainerSt
 			if !containerStatus.Ready && statusPhase == "Failed" {
 				failures = append(failures, kcommon.Failure{
 					Text:      fmt.Sprintf("termination reason is %s exitcode=%d container=%s pod=%s", containerStatus.State.Terminated.Reason, containerStatus.State.Terminated.ExitCode, containerStatus.Name, name),
-					Sensitive: []kcommon.Sensitive{},
+					Sensitive: sensitive(name),
 				})
 			}
 		} else {
@@ -212,7 +213,7 @@ func analyzeContainerStatusFailures(a common.Analyzer, statuses []v1.ContainerSt
 				if evt.Reason == "Unhealthy" && evt.Message != "" {
 					failures = append(failures, kcommon.Failure{
 						Text:      evt.Message,
-						Sensitive: []kcommon.Sensitive{},
+						Sensitive: sensitive(name),
 					})
 				}
 			}
@@ -0,0 +1,139 @@
+package analyzer
+
+import (
+	"fmt"
+
+	"github.com/k8sgpt-ai/k8sgpt/pkg/analyzer"
+	kcommon "github.com/k8sgpt-ai/k8sgpt/pkg/common"
+	"github.com/scitix/aegis/pkg/analyzer/common"
+	"github.com/scitix/aegis/pkg/prom"
+	appsv1 "k8s.io/api/apps/v1"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/klog/v2"
+)
+
+type StatefulSetAnalyzer struct {
+	prometheus *prom.PromAPI
+}
+
+func NewStatefulSetAnalyzer(prometheus *prom.PromAPI) StatefulSetAnalyzer {
+	return StatefulSetAnalyzer{
+		prometheus: prometheus,
+	}
+}
+
+func (s StatefulSetAnalyzer) Analyze(a common.Analyzer) (*common.Result, error) {
+	kind := "StatefulSet"
+
+	analyzer.AnalyzerErrorsMetric.DeletePartialMatch(map[string]string{
+		"analyzer_name": kind,
+	})
+
+	sts, err := a.Client.GetClient().AppsV1().StatefulSets(a.Namespace).Get(a.Context, a.Name, metav1.GetOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("error getting StatefulSet %s/%s: %w", a.Namespace, a.Name, err)
+	}
+
+	desired := int32(1)
+	if sts.Spec.Replicas != nil {
+		desired = *sts.Spec.Replicas
+	}
+	result := &common.Result{
+		Result: kcommon.Result{
+			Kind: kind,
+			Name: sts.Name,
+		},
+		Metadata: map[string]string{
+			"Kind":            kind,
+			"Name":            sts.Name,
+			"UpdateStrategy":  string(sts.Spec.UpdateStrategy.Type),
+			"CurrentRevision": sts.Status.CurrentRevision,
+			"UpdateRevision":  sts.Status.UpdateRevision,
+			"Desired":         fmt.Sprintf("%d", desired),
+			"Updated":         fmt.Sprintf("%d", sts.Status.UpdatedReplicas),
+			"Ready":           fmt.Sprintf("%d", sts.Status.ReadyReplicas),
+			"Available":       fmt.Sprintf("%d", sts.Status.AvailableReplicas),
+			"Unavailable":     fmt.Sprintf("%d", max(desired-sts.Status.AvailableReplicas, 0)),
+		},
+	}
+
+	// === Rollout 状态分析 ===
+	if sts.Status.ObservedGeneration < sts.Generation {
+		result.Warning = append(result.Warning, common.Warning{
+			Text: fmt.Sprintf("StatefulSet generation %d is not observed by the controller yet (observed %d)", sts.Generation, sts.Status.ObservedGeneration),
+		})
+	}
+	if sts.Status.ReadyReplicas < desired {
+		result.Error = append(result.Error, kcommon.Failure{
+			Text: fmt.Sprintf("%d of %d replicas are not ready", desired-sts.Status.ReadyReplicas, desired),
+		})
+	}
+	if len(sts.Status.UpdateRevision) > 0 && sts.Status.CurrentRevision != sts.Status.UpdateRevision {
+		text := fmt.Sprintf("StatefulSet rollout from revision %s to %s is in progress, %d of %d replicas are updated", sts.Status.CurrentRevision, sts.Status.UpdateRevision, sts.Status.UpdatedReplicas, desired)
+		if sts.Spec.UpdateStrategy.Type == appsv1.OnDeleteStatefulSetStrategyType {
+			text += ", pods are only updated once deleted as of the OnDelete update strategy"
+		}
+		result.Warning = append(result.Warning, common.Warning{Text: text})
+	}
+
+	appendWorkloadEvents(a, s.prometheus, kind, sts.Name, result)
+
+	// === Pod 分析 ===
+	pods, err := listControlledPods(a, sts.Spec.Selector, sts.UID)
+	if err != nil {
+		klog.Warningf("analyze pods for %s/%s failed: %v", a.Namespace, sts.Name, err)
+		return result, nil
+	}
+
+	if len(sts.Status.UpdateRevision) > 0 {
+		outdated := make([]string, 0)
+		for _, pod := range pods {
+			if pod.Labels[appsv1.ControllerRevisionHashLabelKey] != sts.Status.UpdateRevision {
+				outdated = append(outdated, pod.Name)
+			}
+		}
+		if len(outdated) > 0 {
+			result.Metadata["OutdatedPods"] = listNames(outdated)
+		}
+	}
+
+	analyzeWorkloadPods(a, s.prometheus, pods, result)
+	appendPendingClaims(a, sts, pods, result)
+
+	if len(result.Error) > 0 {
+		analyzer.AnalyzerErrorsMetric.WithLabelValues(kind, sts.Name, sts.Namespace).Set(float64(len(result.Error)))
+	}
+
+	return result, nil
+}
+
+// appendPendingClaims reports the unbound volume claims of the statefulset
+// pods, a common cause of pending pods
+func appendPendingClaims(a common.Analyzer, sts *appsv1.StatefulSet, pods []*v1.Pod, result *common.Result) {
+	if len(sts.Spec.VolumeClaimTemplates) == 0 {
+		return
+	}
+	for _, pod := range pods {
+		if pod.Status.Phase != v1.PodPending {
+			continue
+		}
+		for _, template := range sts.Spec.VolumeClaimTemplates {
+			name := fmt.Sprintf("%s-%s", template.Name, pod.Name)
+			pvc, err := a.Client.GetClient().CoreV1().PersistentVolumeClaims(a.Namespace).Get(a.Context, name, metav1.GetOptions{})
+			if err != nil {
+				klog.V(4).Infof("get pvc %s/%s failed: %v", a.Namespace, name, err)
+				continue
+			}
+			if pvc.Status.Phase != v1.ClaimBound {
+				result.Error = append(result.Error, kcommon.Failure{
+					Text: fmt.Sprintf("PersistentVolumeClaim %s of pod %s is %s", name, pod.Name, pvc.Status.Phase),
+				})
+			}
+		}
+	}
+}
+
+func (s StatefulSetAnalyzer) Prompt(result *common.Result) string {
+	return workloadPrompt("StatefulSet", result)
+}
@@ -0,0 +1,38 @@
+package analyzer
+
+import (
+	"context"
+
+	kubeflowv1 "github.com/kubeflow/training-operator/pkg/apis/kubeflow.org/v1"
+	kfclientset "github.com/kubeflow/training-operator/pkg/client/clientset/versioned"
+	"github.com/scitix/aegis/pkg/prom"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// tfJobKind analyzes the chief (or master) pod, and samples the abnormal parameter servers, workers and evaluators
+var tfJobKind = trainingJobKind{
+	Kind:        "TFJob",
+	EventKind:   "TFJob",
+	LeaderTypes: []kubeflowv1.ReplicaType{kubeflowv1.TFJobReplicaTypeChief, kubeflowv1.TFJobReplicaTypeMaster},
+	MemberTypes: []kubeflowv1.ReplicaType{kubeflowv1.TFJobReplicaTypePS, kubeflowv1.TFJobReplicaTypeWorker, kubeflowv1.TFJobReplicaTypeEval},
+	Get: func(ctx context.Context, client kfclientset.Interface, namespace, name string) (*trainingJob, error) {
+		job, err := client.KubeflowV1().TFJobs(namespace).Get(ctx, name, metav1.GetOptions{})
+		if err != nil {
+			return nil, err
+		}
+		return &trainingJob{
+			Name:         job.Name,
+			ReplicaSpecs: job.Spec.TFReplicaSpecs,
+			RunPolicy:    job.Spec.RunPolicy,
+			Status:       job.Status,
+		}, nil
+	},
+}
+
+func NewTFJobAnalyzer(prometheus *prom.PromAPI, client kfclientset.Interface) TrainingJobAnalyzer {
+	return TrainingJobAnalyzer{
+		client:     client,
+		prometheus: prometheus,
+		kind:       tfJobKind,
+	}
+}
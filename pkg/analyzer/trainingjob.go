@@ -0,0 +1,438 @@
+package analyzer
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/k8sgpt-ai/k8sgpt/pkg/analyzer"
+	kcommon "github.com/k8sgpt-ai/k8sgpt/pkg/common"
+	"github.com/k8sgpt-ai/k8sgpt/pkg/util"
+	kubeflowv1 "github.com/kubeflow/training-operator/pkg/apis/kubeflow.org/v1"
+	kfclientset "github.com/kubeflow/training-operator/pkg/client/clientset/versioned"
+	ai "github.com/scitix/aegis/pkg/ai"
+	"github.com/scitix/aegis/pkg/analyzer/common"
+	"github.com/scitix/aegis/pkg/prom"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/klog/v2"
+)
+
+// maxDetailedAbnormalWorkers bounds the abnormal pods analyzed per member
+// replica type of a training job
+const maxDetailedAbnormalWorkers = 5
+
+// condPriority defines the order in which job conditions are evaluated.
+// The first condition with Status=True in this list wins.
+var condPriority = []kubeflowv1.JobConditionType{
+	kubeflowv1.JobFailed,
+	kubeflowv1.JobRestarting,
+	kubeflowv1.JobSucceeded,
+	kubeflowv1.JobSuspended,
+	kubeflowv1.JobRunning,
+	kubeflowv1.JobCreated,
+}
+
+// findActiveCondition returns the highest-priority condition whose Status is True,
+// or nil if none is active.
+func findActiveCondition(conds []kubeflowv1.JobCondition) *kubeflowv1.JobCondition {
+	for _, pt := range condPriority {
+		for i := range conds {
+			if conds[i].Type == pt && conds[i].Status == v1.ConditionTrue {
+				return &conds[i]
+			}
+		}
+	}
+	return nil
+}
+
+// trainingJob is the shape shared by the kubeflow training operator jobs
+type trainingJob struct {
+	Name         string
+	ReplicaSpecs map[kubeflowv1.ReplicaType]*kubeflowv1.ReplicaSpec
+	RunPolicy    kubeflowv1.RunPolicy
+	Status       kubeflowv1.JobStatus
+}
+
+// trainingJobKind tells how a kind of training job is fetched, and how its
+// replica types are analyzed
+type trainingJobKind struct {
+	// Kind is the diagnosis kind, also the name of its prompt
+	Kind string
+
+	// EventKind is the involved object kind of the job events
+	EventKind string
+
+	// LeaderTypes coordinate the job, e.g. the master or the MPI launcher,
+	// their pods are all analyzed
+	LeaderTypes []kubeflowv1.ReplicaType
+
+	// MemberTypes are sampled, only their abnormal pods are analyzed
+	MemberTypes []kubeflowv1.ReplicaType
+
+	Get func(ctx context.Context, client kfclientset.Interface, namespace, name string) (*trainingJob, error)
+}
+
+// TrainingJobAnalyzer analyzes the kubeflow training jobs by their replica types
+type TrainingJobAnalyzer struct {
+	prometheus *prom.PromAPI
+	client     kfclientset.Interface
+	kind       trainingJobKind
+}
+
+func (t TrainingJobAnalyzer) Analyze(a common.Analyzer) (*common.Result, error) {
+	kind := t.kind.Kind
+
+	analyzer.AnalyzerErrorsMetric.DeletePartialMatch(map[string]string{
+		"analyzer_name": kind,
+	})
+
+	job, err := t.kind.Get(a.Context, t.client, a.Namespace, a.Name)
+	if err != nil {
+		return nil, fmt.Errorf("error getting %s %s/%s: %w", t.kind.EventKind, a.Namespace, a.Name, err)
+	}
+
+	result := &common.Result{
+		Result: kcommon.Result{
+			Kind: kind,
+			Name: job.Name,
+		},
+		Metadata: map[string]string{
+			"JobName": job.Name,
+		},
+	}
+
+	// === Job Condition 分析 ===
+	skipPodAnalysis := false
+	active := findActiveCondition(job.Status.Conditions)
+	if active == nil {
+		result.Metadata["JobStatus"] = "Unknown"
+		result.Warning = append(result.Warning, common.Warning{
+			Text: "Job has no active condition",
+		})
+	} else {
+		result.Metadata["JobStatus"] = string(active.Type)
+		switch active.Type {
+		case kubeflowv1.JobSucceeded:
+			result.Info = append(result.Info, common.Info{
+				Text: "Job completed successfully.",
+			})
+			skipPodAnalysis = true
+		case kubeflowv1.JobFailed:
+			result.Error = append(result.Error, kcommon.Failure{
+				Text: fmt.Sprintf("Job failed: %s - %s", active.Reason, active.Message),
+			})
+		case kubeflowv1.JobRestarting:
+			result.Warning = append(result.Warning, common.Warning{
+				Text: fmt.Sprintf("Job is restarting: %s - %s", active.Reason, active.Message),
+			})
+		case kubeflowv1.JobSuspended:
+			result.Info = append(result.Info, common.Info{
+				Text: fmt.Sprintf("Job is suspended: %s", active.Reason),
+			})
+			skipPodAnalysis = true
+		case kubeflowv1.JobRunning, kubeflowv1.JobCreated:
+			result.Info = append(result.Info, common.Info{
+				Text: fmt.Sprintf("Job is %s.", active.Type),
+			})
+		}
+	}
+
+	// === Job Events 分析 ===
+	rawEvents, err := FetchEvents(a.Context, a.EnableProm, t.prometheus, a.Client, t.kind.EventKind, a.Namespace, job.Name, "Warning", "")
+	if err != nil {
+		klog.Warningf("fetch %s events failed: %v", strings.ToLower(t.kind.EventKind), err)
+	} else {
+		if a.EnableProm {
+			for _, event := range rawEvents.([]prom.Event) {
+				result.Warning = append(result.Warning, jobEventWarning(job.Name, event))
+			}
+		} else {
+			for _, event := range rawEvents.([]v1.Event) {
+				result.Warning = append(result.Warning, jobEventWarningLegacy(job.Name, event))
+			}
+		}
+	}
+
+	// === Replica spec 与 status 采集 ===
+	var statuses []string
+	for _, rtype := range t.replicaTypes() {
+		result.Metadata[string(rtype)+"Expected"] = "0"
+		if spec, ok := job.ReplicaSpecs[rtype]; ok && spec != nil && spec.Replicas != nil {
+			result.Metadata[string(rtype)+"Expected"] = fmt.Sprintf("%d", *spec.Replicas)
+		}
+		if status, ok := job.Status.ReplicaStatuses[rtype]; ok && status != nil {
+			statuses = append(statuses, fmt.Sprintf("%s: active %d, succeeded %d, failed %d", rtype, status.Active, status.Succeeded, status.Failed))
+		}
+	}
+	if len(statuses) > 0 {
+		result.Metadata["ReplicaStatuses"] = strings.Join(statuses, "\n")
+	}
+	if policy := job.RunPolicy.CleanPodPolicy; policy != nil {
+		result.Metadata["CleanPodPolicy"] = string(*policy)
+	}
+
+	// === 只有非 Succeeded/Suspended 才下沉 Pod 分析 ===
+	if !skipPodAnalysis {
+		if err := t.analyzeReplicaPods(a, job, result); err != nil {
+			klog.Warningf("analyze pods for %s/%s failed: %v", a.Namespace, job.Name, err)
+		}
+	}
+
+	return result, nil
+}
+
+func (t TrainingJobAnalyzer) replicaTypes() []kubeflowv1.ReplicaType {
+	return append(append([]kubeflowv1.ReplicaType{}, t.kind.LeaderTypes...), t.kind.MemberTypes...)
+}
+
+// categorizeWorkers splits worker pods into abnormal and normal buckets.
+// Abnormal: Failed, Pending, Unknown, or Running but not Ready.
+func categorizeWorkers(workerPods []*v1.Pod) (abnormal, normal []*v1.Pod) {
+	for _, wp := range workerPods {
+		isAbnormal := false
+		switch wp.Status.Phase {
+		case v1.PodFailed, v1.PodPending, v1.PodUnknown:
+			isAbnormal = true
+		case v1.PodRunning:
+			ready := false
+			for _, cond := range wp.Status.Conditions {
+				if cond.Type == v1.PodReady && cond.Status == v1.ConditionTrue {
+					ready = true
+					break
+				}
+			}
+			if !ready {
+				isAbnormal = true
+			}
+		}
+		if isAbnormal {
+			abnormal = append(abnormal, wp)
+		} else {
+			normal = append(normal, wp)
+		}
+	}
+	return
+}
+
+// analyzePodWithExplain diagnoses a single Pod.
+//
+//   - Explain mode (a.AIClient != nil): calls Pod-level LLM and returns a
+//     structured conclusion (~4 lines).
+//   - Non-explain mode: falls back to Summarize() raw text.
+//
+// All relevant fields from the parent Analyzer are forwarded to the sub-analyzer.
+func analyzePodWithExplain(
+	a common.Analyzer,
+	pod *v1.Pod,
+	podAnalyzer PodAnalyzer,
+) string {
+	sub := common.Analyzer{
+		Analyzer: kcommon.Analyzer{
+			Client:    a.Client,
+			Context:   a.Context,
+			Namespace: a.Namespace,
+			AIClient:  a.AIClient,
+		},
+		Name:           pod.Name,
+		CollectorImage: a.CollectorImage,
+		EnableProm:     a.EnableProm,
+		EnablePodLog:   a.EnablePodLog,
+		PodLogConfig:   a.PodLogConfig,
+	}
+
+	r, err := podAnalyzer.Analyze(sub)
+	if err != nil || r == nil {
+		return fmt.Sprintf("Pod %s: analysis failed: %v", pod.Name, err)
+	}
+
+	if a.AIClient != nil {
+		prompt := podAnalyzer.Prompt(r)
+		if prompt != "" {
+			if explain, err := a.AIClient.GetCompletion(a.Context, prompt); err == nil {
+				return explain
+			} else {
+				klog.Warningf("pod LLM for %s failed: %v", pod.Name, err)
+			}
+		}
+	}
+
+	return podAnalyzer.Summarize(r)
+}
+
+// analyzeReplicaPods analyzes all the pods of the leader replica types, and
+// up to maxDetailedAbnormalWorkers abnormal pods of each member replica type
+func (t TrainingJobAnalyzer) analyzeReplicaPods(a common.Analyzer, job *trainingJob, result *common.Result) error {
+	pods, err := a.Client.GetClient().CoreV1().Pods(a.Namespace).List(a.Context, metav1.ListOptions{
+		LabelSelector: fmt.Sprintf("%s=%s", kubeflowv1.JobNameLabel, job.Name),
+	})
+	if err != nil {
+		return fmt.Errorf("list pods failed: %w", err)
+	}
+
+	replicaPods := make(map[kubeflowv1.ReplicaType][]*v1.Pod)
+	for i := range pods.Items {
+		pod := &pods.Items[i]
+		for _, rtype := range t.replicaTypes() {
+			if pod.Labels[kubeflowv1.ReplicaTypeLabel] == strings.ToLower(string(rtype)) {
+				replicaPods[rtype] = append(replicaPods[rtype], pod)
+			}
+		}
+	}
+	for _, rtype := range t.replicaTypes() {
+		sort.Slice(replicaPods[rtype], func(i, j int) bool {
+			return replicaPods[rtype][i].Name < replicaPods[rtype][j].Name
+		})
+		result.Metadata[string(rtype)+"CreatedCount"] = fmt.Sprintf("%d", len(replicaPods[rtype]))
+	}
+
+	if len(pods.Items) == 0 && job.RunPolicy.CleanPodPolicy != nil && *job.RunPolicy.CleanPodPolicy != kubeflowv1.CleanPodPolicyNone {
+		result.Warning = append(result.Warning, common.Warning{
+			Text: fmt.Sprintf("No pods are found, they may have been cleaned up by the %s cleanPodPolicy", *job.RunPolicy.CleanPodPolicy),
+		})
+	}
+
+	// Build the list of pods that need full analysis (leaders + up to N
+	// abnormal pods of each member type).
+	type podTask struct {
+		pod   *v1.Pod
+		rtype kubeflowv1.ReplicaType
+	}
+	var tasks []podTask
+	for _, rtype := range t.kind.LeaderTypes {
+		for _, pod := range replicaPods[rtype] {
+			tasks = append(tasks, podTask{pod, rtype})
+		}
+	}
+	skipped := make(map[kubeflowv1.ReplicaType]int)
+	normals := make(map[kubeflowv1.ReplicaType]int)
+	for _, rtype := range t.kind.MemberTypes {
+		abnormal, normal := categorizeWorkers(replicaPods[rtype])
+		limit := min(len(abnormal), maxDetailedAbnormalWorkers)
+		for _, pod := range abnormal[:limit] {
+			tasks = append(tasks, podTask{pod, rtype})
+		}
+		skipped[rtype] = len(abnormal) - limit
+		normals[rtype] = len(normal)
+	}
+
+	// Concurrent analysis.
+	podAnalyzer := NewPodAnalyzer(t.prometheus)
+	explains := make([]string, len(tasks))
+	var wg sync.WaitGroup
+	for i, task := range tasks {
+		wg.Add(1)
+		go func(i int, task podTask) {
+			defer wg.Done()
+			explains[i] = analyzePodWithExplain(a, task.pod, podAnalyzer)
+		}(i, task)
+	}
+	wg.Wait()
+
+	// Distribute results.
+	lines := make(map[kubeflowv1.ReplicaType][]string)
+	for i, task := range tasks {
+		if t.isLeader(task.rtype) && len(replicaPods[task.rtype]) == 1 {
+			lines[task.rtype] = append(lines[task.rtype], explains[i])
+			continue
+		}
+		status := "Abnormal"
+		if t.isLeader(task.rtype) {
+			status = string(task.pod.Status.Phase)
+		}
+		lines[task.rtype] = append(lines[task.rtype], fmt.Sprintf("%s Pod %s (%s):\n%s", task.rtype, task.pod.Name, status, explains[i]))
+	}
+
+	// Normal members: single summary line.
+	for _, rtype := range t.kind.MemberTypes {
+		if skipped[rtype] > 0 {
+			lines[rtype] = append(lines[rtype], fmt.Sprintf("Other %d abnormal %s pod(s) are not detailed.", skipped[rtype], strings.ToLower(string(rtype))))
+		}
+		if normals[rtype] > 0 {
+			lines[rtype] = append(lines[rtype], fmt.Sprintf("Other %d %s pod(s) are Running and Ready.", normals[rtype], strings.ToLower(string(rtype))))
+		}
+	}
+
+	for rtype, l := range lines {
+		result.Metadata[string(rtype)+"Diagnosis"] = strings.Join(l, "\n---\n")
+	}
+
+	return nil
+}
+
+func (t TrainingJobAnalyzer) isLeader(rtype kubeflowv1.ReplicaType) bool {
+	for _, leader := range t.kind.LeaderTypes {
+		if leader == rtype {
+			return true
+		}
+	}
+	return false
+}
+
+func jobEventWarning(jobName string, event prom.Event) common.Warning {
+	return common.Warning{
+		Text: fmt.Sprintf("Job %s has %s event at %s %s(%s) count %d", jobName, event.Type, event.TimeStamps, event.Reason, event.Message, event.Count),
+		Sensitive: []kcommon.Sensitive{
+			{
+				Unmasked: jobName,
+				Masked:   util.MaskString(jobName),
+			},
+		},
+	}
+}
+
+func jobEventWarningLegacy(jobName string, event v1.Event) common.Warning {
+	timestamp := event.LastTimestamp.Time
+	if timestamp.IsZero() {
+		timestamp = event.EventTime.Time
+	}
+	return common.Warning{
+		Text: fmt.Sprintf("Job %s has %s event at %s %s(%s) count %d", jobName, event.Type, timestamp.Format(time.RFC3339), event.Reason, event.Message, event.Count),
+		Sensitive: []kcommon.Sensitive{
+			{
+				Unmasked: jobName,
+				Masked:   util.MaskString(jobName),
+			},
+		},
+	}
+}
+
+func (t TrainingJobAnalyzer) Prompt(result *common.Result) string {
+	if result == nil {
+		return ""
+	}
+
+	metadata := make(map[string]string, len(result.Metadata))
+	for key, value := range result.Metadata {
+		metadata[key] = value
+	}
+
+	errorInfo := ""
+	for _, e := range result.Error {
+		errorInfo += e.Text + "\n"
+	}
+	eventInfo := ""
+	for _, w := range result.Warning {
+		eventInfo += w.Text + "\n"
+	}
+	logInfo := ""
+	for _, i := range result.Info {
+		logInfo += i.Text + "\n"
+	}
+
+	data := ai.PromptData{
+		ErrorInfo: strings.TrimSpace(errorInfo),
+		EventInfo: strings.TrimSpace(eventInfo),
+		LogInfo:   strings.TrimSpace(logInfo),
+		Metadata:  metadata,
+	}
+
+	prompt, err := ai.GetRenderedPrompt(t.kind.Kind, data)
+	if err != nil {
+		return fmt.Sprintf("Prompt rendering error: %v", err)
+	}
+	return prompt
+}
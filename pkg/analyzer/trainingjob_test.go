@@ -0,0 +1,125 @@
+package analyzer
+
+import (
+	"strings"
+	"testing"
+
+	kubeflowv1 "github.com/kubeflow/training-operator/pkg/apis/kubeflow.org/v1"
+	kffake "github.com/kubeflow/training-operator/pkg/client/clientset/versioned/fake"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+func newTestReplicaPod(job *metav1.ObjectMeta, kind string, rtype kubeflowv1.ReplicaType, name string) *v1.Pod {
+	pod := newTestPod(name, "node1", job, kind)
+	pod.Labels = map[string]string{
+		kubeflowv1.JobNameLabel:     job.Name,
+		kubeflowv1.ReplicaTypeLabel: strings.ToLower(string(rtype)),
+	}
+	return pod
+}
+
+func replicaSpecs(replicas map[kubeflowv1.ReplicaType]int32) map[kubeflowv1.ReplicaType]*kubeflowv1.ReplicaSpec {
+	specs := make(map[kubeflowv1.ReplicaType]*kubeflowv1.ReplicaSpec, len(replicas))
+	for rtype, n := range replicas {
+		specs[rtype] = &kubeflowv1.ReplicaSpec{Replicas: &n}
+	}
+	return specs
+}
+
+func TestMPIJobAnalyzer(t *testing.T) {
+	job := &kubeflowv1.MPIJob{
+		ObjectMeta: metav1.ObjectMeta{Name: "allreduce", Namespace: "default", UID: "mpijob-uid"},
+		Spec: kubeflowv1.MPIJobSpec{
+			MPIReplicaSpecs: replicaSpecs(map[kubeflowv1.ReplicaType]int32{
+				kubeflowv1.MPIJobReplicaTypeLauncher: 1,
+				kubeflowv1.MPIJobReplicaTypeWorker:   8,
+			}),
+		},
+		Status: kubeflowv1.JobStatus{
+			Conditions: []kubeflowv1.JobCondition{{
+				Type:    kubeflowv1.JobFailed,
+				Status:  v1.ConditionTrue,
+				Reason:  "MPIJobFailed",
+				Message: "launcher failed",
+			}},
+			ReplicaStatuses: map[kubeflowv1.ReplicaType]*kubeflowv1.ReplicaStatus{
+				kubeflowv1.MPIJobReplicaTypeLauncher: {Failed: 1},
+				kubeflowv1.MPIJobReplicaTypeWorker:   {Active: 8},
+			},
+		},
+	}
+
+	launcher := newTestReplicaPod(&job.ObjectMeta, "MPIJob", kubeflowv1.MPIJobReplicaTypeLauncher, "allreduce-launcher")
+	launcher.Status.Phase = v1.PodFailed
+	objs := []runtime.Object{launcher}
+	for _, name := range []string{"w0", "w1", "w2", "w3", "w4", "w5"} {
+		objs = append(objs, crashing(newTestReplicaPod(&job.ObjectMeta, "MPIJob", kubeflowv1.MPIJobReplicaTypeWorker, "allreduce-"+name), 3))
+	}
+	for _, name := range []string{"w6", "w7"} {
+		objs = append(objs, newTestReplicaPod(&job.ObjectMeta, "MPIJob", kubeflowv1.MPIJobReplicaTypeWorker, "allreduce-"+name))
+	}
+
+	analyzer := NewMPIJobAnalyzer(nil, kffake.NewSimpleClientset(job))
+	result := analyzeTestWorkload(t, analyzer, "allreduce", objs...)
+
+	if result.Metadata["JobStatus"] != "Failed" || result.Metadata["LauncherExpected"] != "1" || result.Metadata["WorkerCreatedCount"] != "8" {
+		t.Errorf("unexpected metadata %v", result.Metadata)
+	}
+	if !strings.Contains(result.Metadata["ReplicaStatuses"], "Launcher: active 0, succeeded 0, failed 1") {
+		t.Errorf("unexpected replica statuses %s", result.Metadata["ReplicaStatuses"])
+	}
+	if strings.HasPrefix(result.Metadata["LauncherDiagnosis"], "Launcher Pod") {
+		t.Errorf("expected the single launcher diagnosed without a header, got %s", result.Metadata["LauncherDiagnosis"])
+	}
+
+	workers := result.Metadata["WorkerDiagnosis"]
+	if strings.Count(workers, "(Abnormal):") != maxDetailedAbnormalWorkers {
+		t.Errorf("expected %d abnormal workers detailed, got %s", maxDetailedAbnormalWorkers, workers)
+	}
+	if !strings.Contains(workers, "Worker Pod allreduce-w0 (Abnormal):") || strings.Contains(workers, "allreduce-w5 (Abnormal)") {
+		t.Errorf("expected the first abnormal workers by name detailed, got %s", workers)
+	}
+	if !strings.Contains(workers, "Other 1 abnormal worker pod(s) are not detailed.") || !strings.Contains(workers, "Other 2 worker pod(s) are Running and Ready.") {
+		t.Errorf("expected the other workers summarized, got %s", workers)
+	}
+
+	prompt := analyzer.Prompt(result)
+	if !strings.Contains(prompt, "Launcher Pod 分析摘要（期望 1 个，已创建 1 个）") || !strings.Contains(prompt, "Job 名称: allreduce") {
+		t.Errorf("unexpected prompt:\n%s", prompt)
+	}
+}
+
+func TestTFJobAnalyzer(t *testing.T) {
+	job := &kubeflowv1.TFJob{
+		ObjectMeta: metav1.ObjectMeta{Name: "mnist", Namespace: "default", UID: "tfjob-uid"},
+		Spec: kubeflowv1.TFJobSpec{
+			TFReplicaSpecs: replicaSpecs(map[kubeflowv1.ReplicaType]int32{
+				kubeflowv1.TFJobReplicaTypePS:     2,
+				kubeflowv1.TFJobReplicaTypeWorker: 2,
+			}),
+		},
+		Status: kubeflowv1.JobStatus{
+			Conditions: []kubeflowv1.JobCondition{{Type: kubeflowv1.JobRunning, Status: v1.ConditionTrue}},
+		},
+	}
+
+	analyzer := NewTFJobAnalyzer(nil, kffake.NewSimpleClientset(job))
+	result := analyzeTestWorkload(t, analyzer, "mnist",
+		pullFailing(newTestReplicaPod(&job.ObjectMeta, "TFJob", kubeflowv1.TFJobReplicaTypePS, "mnist-ps-0")),
+		newTestReplicaPod(&job.ObjectMeta, "TFJob", kubeflowv1.TFJobReplicaTypePS, "mnist-ps-1"),
+		newTestReplicaPod(&job.ObjectMeta, "TFJob", kubeflowv1.TFJobReplicaTypeWorker, "mnist-worker-0"),
+		newTestReplicaPod(&job.ObjectMeta, "TFJob", kubeflowv1.TFJobReplicaTypeWorker, "mnist-worker-1"),
+	)
+
+	if result.Metadata["ChiefExpected"] != "0" || result.Metadata["PSExpected"] != "2" || result.Metadata["PSCreatedCount"] != "2" {
+		t.Errorf("unexpected metadata %v", result.Metadata)
+	}
+	if ps := result.Metadata["PSDiagnosis"]; !strings.Contains(ps, "PS Pod mnist-ps-0 (Abnormal):") || !strings.Contains(ps, "Other 1 ps pod(s) are Running and Ready.") {
+		t.Errorf("expected the pending ps detailed, got %s", ps)
+	}
+	if workers := result.Metadata["WorkerDiagnosis"]; workers != "Other 2 worker pod(s) are Running and Ready." {
+		t.Errorf("expected the healthy workers summarized, got %s", workers)
+	}
+}
@@ -9,6 +9,7 @@ import (
 
 	kcommon "github.com/k8sgpt-ai/k8sgpt/pkg/common"
 	kkubernetes "github.com/k8sgpt-ai/k8sgpt/pkg/kubernetes"
+	kutil "github.com/k8sgpt-ai/k8sgpt/pkg/util"
 	aprom "github.com/scitix/aegis/pkg/prom"
 	corev1 "k8s.io/api/core/v1"
 	apierrors "k8s.io/apimachinery/pkg/api/errors"
@@ -17,6 +18,17 @@ import (
 	"k8s.io/klog/v2"
 )
 
+// sensitive marks the values redacted before the prompts leave the cluster
+func sensitive(values ...string) []kcommon.Sensitive {
+	sensitive := make([]kcommon.Sensitive, 0, len(values))
+	for _, value := range values {
+		if value != "" {
+			sensitive = append(sensitive, kcommon.Sensitive{Unmasked: value, Masked: kutil.MaskString(value)})
+		}
+	}
+	return sensitive
+}
+
 func FetchEvents(
 	ctx context.Context,
 	enableProm bool,
@@ -34,10 +46,17 @@ func FetchEvents(
 			return prom.GetEventWithRange(ctx, "Pod", namespace, name, eventType, "7d")
 		case "PyTorchJob":
 			return prom.GetEventWithRange(ctx, "PyTorchJob", namespace, name, eventType, "7d")
+		case "TFJob", "MPIJob", "XGBoostJob", "PaddleJob":
+			return prom.GetEventWithRange(ctx, objectKind, namespace, name, eventType, "7d")
 		case "Node":
 			return prom.GetEventWithRange(ctx, "Node", namespace, name, eventType, "2d")
+		case "Workflow":
+			return prom.GetEventWithRange(ctx, "Workflow", namespace, name, eventType, "7d")
+		case "Deployment", "ReplicaSet", "StatefulSet", "DaemonSet", "Job", "CronJob":
+			return prom.GetEventWithRange(ctx, objectKind, namespace, name, eventType, "7d")
 		default:
-			return nil, fmt.Errorf("unsupported objectKind for Prometheus: %s", objectKind)
+			// kinds configured for the GenericAnalyzer
+			return prom.GetEventWithRange(ctx, objectKind, namespace, name, eventType, "7d")
 		}
 	}
 
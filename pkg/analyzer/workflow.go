@@ -0,0 +1,315 @@
+package analyzer
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	wfv1alpha1 "github.com/argoproj/argo-workflows/v3/pkg/apis/workflow/v1alpha1"
+	wfclientset "github.com/argoproj/argo-workflows/v3/pkg/client/clientset/versioned"
+	"github.com/k8sgpt-ai/k8sgpt/pkg/analyzer"
+	kcommon "github.com/k8sgpt-ai/k8sgpt/pkg/common"
+	"github.com/k8sgpt-ai/k8sgpt/pkg/util"
+	ai "github.com/scitix/aegis/pkg/ai"
+	"github.com/scitix/aegis/pkg/analyzer/common"
+	"github.com/scitix/aegis/pkg/prom"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/klog/v2"
+)
+
+const (
+	// workflowLabelKey and workflowNodeIDAnnotation are set by argo on the step pods
+	workflowLabelKey         = "workflows.argoproj.io/workflow"
+	workflowNodeIDAnnotation = "workflows.argoproj.io/node-id"
+
+	// maxDetailedFailedSteps bounds the failed steps whose pods are analyzed
+	maxDetailedFailedSteps = 5
+)
+
+type WorkflowAnalyzer struct {
+	prometheus *prom.PromAPI
+	client     wfclientset.Interface
+}
+
+func NewWorkflowAnalyzer(prometheus *prom.PromAPI, client wfclientset.Interface) WorkflowAnalyzer {
+	return WorkflowAnalyzer{
+		client:     client,
+		prometheus: prometheus,
+	}
+}
+
+func (w WorkflowAnalyzer) Analyze(a common.Analyzer) (*common.Result, error) {
+	kind := "Workflow"
+
+	analyzer.AnalyzerErrorsMetric.DeletePartialMatch(map[string]string{
+		"analyzer_name": kind,
+	})
+
+	workflow, err := w.client.ArgoprojV1alpha1().Workflows(a.Namespace).Get(a.Context, a.Name, metav1.GetOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("error getting Workflow %s/%s: %w", a.Namespace, a.Name, err)
+	}
+
+	result := &common.Result{
+		Result: kcommon.Result{
+			Kind: kind,
+			Name: workflow.Name,
+		},
+		Metadata: map[string]string{
+			"WorkflowName":    workflow.Name,
+			"WorkflowPhase":   string(workflow.Status.Phase),
+			"WorkflowMessage": workflow.Status.Message,
+		},
+	}
+	if !workflow.Status.StartedAt.IsZero() {
+		result.Metadata["StartedAt"] = workflow.Status.StartedAt.Format(time.RFC3339)
+	}
+	if !workflow.Status.FinishedAt.IsZero() {
+		result.Metadata["FinishedAt"] = workflow.Status.FinishedAt.Format(time.RFC3339)
+	}
+
+	// === Workflow phase 分析 ===
+	switch workflow.Status.Phase {
+	case wfv1alpha1.WorkflowSucceeded:
+		result.Info = append(result.Info, common.Info{
+			Text: "Workflow completed successfully.",
+		})
+		return result, nil
+	case wfv1alpha1.WorkflowFailed, wfv1alpha1.WorkflowError:
+		result.Error = append(result.Error, kcommon.Failure{
+			Text: fmt.Sprintf("Workflow %s: %s", workflow.Status.Phase, workflow.Status.Message),
+		})
+	case "":
+		result.Metadata["WorkflowPhase"] = "Unknown"
+		result.Warning = append(result.Warning, common.Warning{
+			Text: "Workflow has no phase, it is not reconciled by the workflow controller yet",
+		})
+	default:
+		result.Info = append(result.Info, common.Info{
+			Text: fmt.Sprintf("Workflow is %s.", workflow.Status.Phase),
+		})
+	}
+
+	// === Workflow Events 分析 ===
+	rawEvents, err := FetchEvents(a.Context, a.EnableProm, w.prometheus, a.Client, kind, a.Namespace, workflow.Name, "Warning", "")
+	if err != nil {
+		klog.Warningf("fetch workflow events failed: %v", err)
+	} else {
+		if a.EnableProm {
+			for _, event := range rawEvents.([]prom.Event) {
+				result.Warning = append(result.Warning, workflowEventWarning(workflow.Name, event))
+			}
+		} else {
+			for _, event := range rawEvents.([]v1.Event) {
+				result.Warning = append(result.Warning, workflowEventWarningLegacy(workflow.Name, event))
+			}
+		}
+	}
+
+	// === 失败步骤分析 ===
+	if workflow.Status.IsOffloadNodeStatus() {
+		result.Warning = append(result.Warning, common.Warning{
+			Text: "Workflow node status is offloaded, failed steps are not analyzed",
+		})
+		return result, nil
+	}
+
+	steps := failedSteps(workflow)
+	result.Metadata["FailedStepCount"] = fmt.Sprintf("%d", len(steps))
+	if len(steps) > 0 {
+		if err := w.analyzeFailedSteps(a, workflow, steps, result); err != nil {
+			klog.Warningf("analyze failed steps for %s/%s failed: %v", a.Namespace, workflow.Name, err)
+		}
+	}
+
+	if len(result.Error) > 0 {
+		analyzer.AnalyzerErrorsMetric.WithLabelValues(kind, workflow.Name, workflow.Namespace).Set(float64(len(result.Error)))
+	}
+
+	return result, nil
+}
+
+// failedSteps walks the node tree from the workflow root and its exit
+// handler, and returns the failed or errored nodes whose children all
+// succeeded, i.e. where the failure originates. Only the last attempt of a
+// retried step is walked.
+func failedSteps(workflow *wfv1alpha1.Workflow) []wfv1alpha1.NodeStatus {
+	nodes := workflow.Status.Nodes
+	roots := []string{workflow.Name}
+	for id, node := range nodes {
+		if node.IsExitNode() && node.BoundaryID == "" {
+			roots = append(roots, id)
+		}
+	}
+	// deterministic order of the exit handlers
+	sort.Strings(roots[1:])
+
+	var steps []wfv1alpha1.NodeStatus
+	visited := make(map[string]bool, len(nodes))
+	var walk func(id string)
+	walk = func(id string) {
+		node, ok := nodes[id]
+		if !ok || visited[id] {
+			return
+		}
+		visited[id] = true
+
+		children := node.Children
+		if node.Type == wfv1alpha1.NodeTypeRetry && len(children) > 0 {
+			children = children[len(children)-1:]
+		}
+
+		failedChild := false
+		for _, child := range children {
+			if n, ok := nodes[child]; ok && n.FailedOrError() {
+				failedChild = true
+			}
+			walk(child)
+		}
+		if node.FailedOrError() && !failedChild {
+			steps = append(steps, node)
+		}
+	}
+	for _, root := range roots {
+		walk(root)
+	}
+	return steps
+}
+
+func (w WorkflowAnalyzer) analyzeFailedSteps(a common.Analyzer, workflow *wfv1alpha1.Workflow, steps []wfv1alpha1.NodeStatus, result *common.Result) error {
+	pods, err := a.Client.GetClient().CoreV1().Pods(a.Namespace).List(a.Context, metav1.ListOptions{
+		LabelSelector: fmt.Sprintf("%s=%s", workflowLabelKey, workflow.Name),
+	})
+	if err != nil {
+		return fmt.Errorf("list pods failed: %w", err)
+	}
+
+	stepPods := make(map[string]*v1.Pod, len(pods.Items))
+	for i := range pods.Items {
+		pod := &pods.Items[i]
+		if id := pod.Annotations[workflowNodeIDAnnotation]; len(id) > 0 {
+			stepPods[id] = pod
+		}
+	}
+
+	enablePodLog := true
+	if a.EnablePodLog != nil {
+		enablePodLog = *a.EnablePodLog
+	}
+
+	var stepLines []string
+	for i, step := range steps {
+		if i >= maxDetailedFailedSteps {
+			stepLines = append(stepLines, fmt.Sprintf("Other %d failed step(s) are not detailed.", len(steps)-maxDetailedFailedSteps))
+			break
+		}
+
+		result.Error = append(result.Error, kcommon.Failure{
+			Text: fmt.Sprintf("Step %s (template %s) %s: %s", step.DisplayName, step.TemplateName, step.Phase, step.Message),
+		})
+
+		lines := []string{fmt.Sprintf("Step %s (template %s, type %s) %s: %s", step.DisplayName, step.TemplateName, step.Type, step.Phase, step.Message)}
+		pod, ok := stepPods[step.ID]
+		if step.Type != wfv1alpha1.NodeTypePod {
+			stepLines = append(stepLines, strings.Join(lines, "\n"))
+			continue
+		}
+		if !ok {
+			lines = append(lines, "Step pod not found, it may have been garbage collected.")
+			stepLines = append(stepLines, strings.Join(lines, "\n"))
+			continue
+		}
+
+		lines = append(lines, fmt.Sprintf("Pod %s on node %s is %s", pod.Name, pod.Spec.NodeName, pod.Status.Phase))
+		for _, failure := range analyzeContainerStatusFailures(a, pod.Status.InitContainerStatuses, pod.Name, pod.Namespace, string(pod.Status.Phase)) {
+			lines = append(lines, failure.Text)
+		}
+		for _, failure := range analyzeContainerStatusFailures(a, pod.Status.ContainerStatuses, pod.Name, pod.Namespace, string(pod.Status.Phase)) {
+			lines = append(lines, failure.Text)
+		}
+
+		if enablePodLog && shouldFetchLog(pod) {
+			result.Info = append(result.Info, fetchContainerLogs(a.Context, a.Client, pod, a.PodLogConfig)...)
+		}
+		stepLines = append(stepLines, strings.Join(lines, "\n"))
+	}
+
+	result.Metadata["FailedSteps"] = strings.Join(stepLines, "\n---\n")
+	if a.PodLogConfig != nil && len(a.PodLogConfig.Keywords) > 0 {
+		result.Metadata["pod_log_keywords"] = strings.Join(a.PodLogConfig.Keywords, ",")
+	}
+	return nil
+}
+
+func workflowEventWarning(workflowName string, event prom.Event) common.Warning {
+	return common.Warning{
+		Text: fmt.Sprintf("Workflow %s has %s event at %s %s(%s) count %d", workflowName, event.Type, event.TimeStamps, event.Reason, event.Message, event.Count),
+		Sensitive: []kcommon.Sensitive{
+			{
+				Unmasked: workflowName,
+				Masked:   util.MaskString(workflowName),
+			},
+		},
+	}
+}
+
+func workflowEventWarningLegacy(workflowName string, event v1.Event) common.Warning {
+	timestamp := event.LastTimestamp.Time
+	if timestamp.IsZero() {
+		timestamp = event.EventTime.Time
+	}
+	return common.Warning{
+		Text: fmt.Sprintf("Workflow %s has %s event at %s %s(%s) count %d", workflowName, event.Type, timestamp.Format(time.RFC3339), event.Reason, event.Message, event.Count),
+		Sensitive: []kcommon.Sensitive{
+			{
+				Unmasked: workflowName,
+				Masked:   util.MaskString(workflowName),
+			},
+		},
+	}
+}
+
+func (w WorkflowAnalyzer) Prompt(result *common.Result) string {
+	if result == nil || result.Metadata["WorkflowPhase"] == string(wfv1alpha1.WorkflowSucceeded) {
+		return ""
+	}
+
+	metadata := map[string]string{
+		"WorkflowName":    result.Metadata["WorkflowName"],
+		"WorkflowPhase":   result.Metadata["WorkflowPhase"],
+		"WorkflowMessage": result.Metadata["WorkflowMessage"],
+		"StartedAt":       result.Metadata["StartedAt"],
+		"FinishedAt":      result.Metadata["FinishedAt"],
+		"FailedStepCount": result.Metadata["FailedStepCount"],
+		"FailedSteps":     result.Metadata["FailedSteps"],
+	}
+
+	errorInfo := ""
+	for _, e := range result.Error {
+		errorInfo += e.Text + "\n"
+	}
+	eventInfo := ""
+	for _, w := range result.Warning {
+		eventInfo += w.Text + "\n"
+	}
+	logInfo := ""
+	for _, i := range result.Info {
+		logInfo += i.Text + "\n"
+	}
+
+	data := ai.PromptData{
+		ErrorInfo: strings.TrimSpace(errorInfo),
+		EventInfo: strings.TrimSpace(eventInfo),
+		LogInfo:   strings.TrimSpace(logInfo),
+		Keywords:  result.Metadata["pod_log_keywords"],
+		Metadata:  metadata,
+	}
+
+	prompt, err := ai.GetRenderedPrompt("Workflow", data)
+	if err != nil {
+		return fmt.Sprintf("Prompt rendering error: %v", err)
+	}
+	return prompt
+}
@@ -0,0 +1,123 @@
+package analyzer
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+
+	wfv1alpha1 "github.com/argoproj/argo-workflows/v3/pkg/apis/workflow/v1alpha1"
+	wffake "github.com/argoproj/argo-workflows/v3/pkg/client/clientset/versioned/fake"
+	kcommon "github.com/k8sgpt-ai/k8sgpt/pkg/common"
+	"github.com/k8sgpt-ai/k8sgpt/pkg/kubernetes"
+	"github.com/scitix/aegis/pkg/analyzer/common"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+// newTestWorkflow returns a failed steps workflow: the drain step failed
+// twice as retried, and its exit handler errored
+func newTestWorkflow() *wfv1alpha1.Workflow {
+	return &wfv1alpha1.Workflow{
+		ObjectMeta: metav1.ObjectMeta{Name: "ops", Namespace: "monitoring"},
+		Status: wfv1alpha1.WorkflowStatus{
+			Phase:   wfv1alpha1.WorkflowFailed,
+			Message: "child 'ops-2' failed",
+			Nodes: wfv1alpha1.Nodes{
+				"ops":         {ID: "ops", DisplayName: "ops", Type: wfv1alpha1.NodeTypeSteps, Phase: wfv1alpha1.NodeFailed, Children: []string{"ops-1"}},
+				"ops-1":       {ID: "ops-1", DisplayName: "[0]", Type: wfv1alpha1.NodeTypeStepGroup, Phase: wfv1alpha1.NodeFailed, Children: []string{"ops-cordon", "ops-drain"}},
+				"ops-cordon":  {ID: "ops-cordon", DisplayName: "cordon", TemplateName: "cordon", Type: wfv1alpha1.NodeTypePod, Phase: wfv1alpha1.NodeSucceeded},
+				"ops-drain":   {ID: "ops-drain", DisplayName: "drain", TemplateName: "drain", Type: wfv1alpha1.NodeTypeRetry, Phase: wfv1alpha1.NodeFailed, Children: []string{"ops-drain-0", "ops-drain-1"}},
+				"ops-drain-0": {ID: "ops-drain-0", DisplayName: "drain(0)", TemplateName: "drain", Type: wfv1alpha1.NodeTypePod, Phase: wfv1alpha1.NodeFailed, Message: "Error (exit code 1)"},
+				"ops-drain-1": {ID: "ops-drain-1", DisplayName: "drain(1)", TemplateName: "drain", Type: wfv1alpha1.NodeTypePod, Phase: wfv1alpha1.NodeFailed, Message: "Error (exit code 1)"},
+				"ops-exit":    {ID: "ops-exit", DisplayName: "ops.onExit", TemplateName: "notify", Type: wfv1alpha1.NodeTypePod, Phase: wfv1alpha1.NodeError, Message: "pod deleted"},
+			},
+		},
+	}
+}
+
+func TestFailedSteps(t *testing.T) {
+	steps := failedSteps(newTestWorkflow())
+	names := make([]string, 0, len(steps))
+	for _, step := range steps {
+		names = append(names, step.DisplayName)
+	}
+	if fmt.Sprint(names) != "[drain(1) ops.onExit]" {
+		t.Errorf("expected the last drain attempt and the exit handler, got %v", names)
+	}
+}
+
+func TestWorkflowAnalyzer(t *testing.T) {
+	pod := &v1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        "ops-drain-1-pod",
+			Namespace:   "monitoring",
+			Labels:      map[string]string{workflowLabelKey: "ops"},
+			Annotations: map[string]string{workflowNodeIDAnnotation: "ops-drain-1"},
+		},
+		Spec: v1.PodSpec{
+			NodeName:   "node1",
+			Containers: []v1.Container{{Name: "main"}},
+		},
+		Status: v1.PodStatus{
+			Phase: v1.PodFailed,
+			ContainerStatuses: []v1.ContainerStatus{{
+				Name:        "main",
+				ContainerID: "containerd://abc",
+				State: v1.ContainerState{Terminated: &v1.ContainerStateTerminated{
+					ExitCode: 1,
+					Reason:   "Error",
+				}},
+			}},
+		},
+	}
+	client := &kubernetes.Client{Client: fake.NewSimpleClientset(pod)}
+	wfAnalyzer := NewWorkflowAnalyzer(nil, wffake.NewSimpleClientset(newTestWorkflow()))
+
+	result, err := wfAnalyzer.Analyze(common.Analyzer{
+		Analyzer: kcommon.Analyzer{
+			Client:    client,
+			Context:   context.Background(),
+			Namespace: "monitoring",
+		},
+		Name: "ops",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if result.Metadata["FailedStepCount"] != "2" {
+		t.Errorf("expected 2 failed steps, got %s", result.Metadata["FailedStepCount"])
+	}
+	steps := result.Metadata["FailedSteps"]
+	if !strings.Contains(steps, "Pod ops-drain-1-pod on node node1") || !strings.Contains(steps, "exitcode=1 container=main") {
+		t.Errorf("expected the drain pod container status, got %s", steps)
+	}
+	if !strings.Contains(steps, "Step pod not found") {
+		t.Errorf("expected the missing exit handler pod reported, got %s", steps)
+	}
+	if len(result.Info) != 1 || !strings.Contains(result.Info[0].Text, "container main logs") {
+		t.Errorf("expected the drain pod logs, got %+v", result.Info)
+	}
+
+	prompt := wfAnalyzer.Prompt(result)
+	if !strings.Contains(prompt, "Workflow 名称: ops") || !strings.Contains(prompt, "Step drain(1)") {
+		t.Errorf("unexpected prompt:\n%s", prompt)
+	}
+
+	// succeeded workflows need no explain
+	succeeded := newTestWorkflow()
+	succeeded.Status.Phase = wfv1alpha1.WorkflowSucceeded
+	wfAnalyzer = NewWorkflowAnalyzer(nil, wffake.NewSimpleClientset(succeeded))
+	result, err = wfAnalyzer.Analyze(common.Analyzer{
+		Analyzer: kcommon.Analyzer{Client: client, Context: context.Background(), Namespace: "monitoring"},
+		Name:     "ops",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if prompt := wfAnalyzer.Prompt(result); prompt != "" {
+		t.Errorf("expected no prompt of a succeeded workflow, got %s", prompt)
+	}
+}
@@ -0,0 +1,338 @@
+package analyzer
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	kcommon "github.com/k8sgpt-ai/k8sgpt/pkg/common"
+	"github.com/k8sgpt-ai/k8sgpt/pkg/util"
+	ai "github.com/scitix/aegis/pkg/ai"
+	"github.com/scitix/aegis/pkg/analyzer/common"
+	"github.com/scitix/aegis/pkg/prom"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/klog/v2"
+)
+
+const (
+	// maxDetailedUnhealthyPods bounds the unhealthy pods of a workload
+	// delegated to the PodAnalyzer, the worst first
+	maxDetailedUnhealthyPods = 3
+
+	// maxListedPods bounds the pod and node names listed per failure reason
+	maxListedPods = 3
+)
+
+// Pod severities, the higher the worse
+const (
+	podHealthy = iota
+	podNotReady
+	podPending
+	podWaitingError
+	podCrashing
+)
+
+// podSeverity ranks how broken a pod is
+func podSeverity(pod *v1.Pod) int {
+	switch pod.Status.Phase {
+	case v1.PodSucceeded:
+		return podHealthy
+	case v1.PodFailed:
+		return podCrashing
+	case v1.PodPending, v1.PodUnknown, "":
+		severity := podPending
+		for _, status := range append(pod.Status.InitContainerStatuses, pod.Status.ContainerStatuses...) {
+			if status.State.Waiting != nil && isErrorReason(status.State.Waiting.Reason) {
+				severity = max(severity, podWaitingError)
+			}
+		}
+		return severity
+	}
+
+	severity := podHealthy
+	for _, status := range append(pod.Status.InitContainerStatuses, pod.Status.ContainerStatuses...) {
+		switch {
+		case status.State.Waiting != nil && status.State.Waiting.Reason == "CrashLoopBackOff":
+			severity = max(severity, podCrashing)
+		case status.State.Terminated != nil && status.State.Terminated.ExitCode != 0:
+			severity = max(severity, podCrashing)
+		case status.State.Waiting != nil && isErrorReason(status.State.Waiting.Reason):
+			severity = max(severity, podWaitingError)
+		}
+	}
+	if severity == podHealthy && !isPodReady(pod) {
+		severity = podNotReady
+	}
+	return severity
+}
+
+func isPodReady(pod *v1.Pod) bool {
+	for _, cond := range pod.Status.Conditions {
+		if cond.Type == v1.PodReady {
+			return cond.Status == v1.ConditionTrue
+		}
+	}
+	return false
+}
+
+func podRestarts(pod *v1.Pod) int32 {
+	var restarts int32
+	for _, status := range pod.Status.ContainerStatuses {
+		restarts += status.RestartCount
+	}
+	return restarts
+}
+
+// podFailureReason returns the main reason a pod is unhealthy, used to
+// group the pods of a workload failing for the same reason
+func podFailureReason(pod *v1.Pod) string {
+	if len(pod.Status.Reason) > 0 {
+		// e.g. Evicted, NodeLost
+		return pod.Status.Reason
+	}
+	for _, cond := range pod.Status.Conditions {
+		if cond.Type == v1.PodScheduled && cond.Status == v1.ConditionFalse && len(cond.Reason) > 0 {
+			return cond.Reason
+		}
+	}
+	for _, status := range append(pod.Status.InitContainerStatuses, pod.Status.ContainerStatuses...) {
+		if status.State.Waiting != nil && status.State.Waiting.Reason == "CrashLoopBackOff" && status.LastTerminationState.Terminated != nil {
+			return fmt.Sprintf("CrashLoopBackOff(%s)", status.LastTerminationState.Terminated.Reason)
+		}
+		if status.State.Waiting != nil && len(status.State.Waiting.Reason) > 0 && status.State.Waiting.Reason != "ContainerCreating" && status.State.Waiting.Reason != "PodInitializing" {
+			return status.State.Waiting.Reason
+		}
+		if status.State.Terminated != nil && status.State.Terminated.ExitCode != 0 {
+			return fmt.Sprintf("Terminated(%s)", status.State.Terminated.Reason)
+		}
+	}
+	if pod.Status.Phase == v1.PodPending {
+		return "Pending"
+	}
+	return "NotReady"
+}
+
+// unhealthyPods returns the unhealthy pods, the worst first: by severity,
+// then restarts, then name
+func unhealthyPods(pods []*v1.Pod) []*v1.Pod {
+	unhealthy := make([]*v1.Pod, 0)
+	for _, pod := range pods {
+		if podSeverity(pod) > podHealthy {
+			unhealthy = append(unhealthy, pod)
+		}
+	}
+	sort.SliceStable(unhealthy, func(i, j int) bool {
+		si, sj := podSeverity(unhealthy[i]), podSeverity(unhealthy[j])
+		if si != sj {
+			return si > sj
+		}
+		ri, rj := podRestarts(unhealthy[i]), podRestarts(unhealthy[j])
+		if ri != rj {
+			return ri > rj
+		}
+		return unhealthy[i].Name < unhealthy[j].Name
+	})
+	return unhealthy
+}
+
+// summarizeFailureReasons groups the unhealthy pods by failure reason, the
+// most recurring first
+func summarizeFailureReasons(pods []*v1.Pod) []string {
+	reasons := make(map[string][]string)
+	for _, pod := range pods {
+		reason := podFailureReason(pod)
+		reasons[reason] = append(reasons[reason], pod.Name)
+	}
+
+	keys := make([]string, 0, len(reasons))
+	for reason := range reasons {
+		keys = append(keys, reason)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if len(reasons[keys[i]]) != len(reasons[keys[j]]) {
+			return len(reasons[keys[i]]) > len(reasons[keys[j]])
+		}
+		return keys[i] < keys[j]
+	})
+
+	lines := make([]string, 0, len(keys))
+	for _, reason := range keys {
+		lines = append(lines, fmt.Sprintf("%d pod(s) %s: %s", len(reasons[reason]), reason, listNames(reasons[reason])))
+	}
+	return lines
+}
+
+// listNames lists a few names, and how many more there are
+func listNames(names []string) string {
+	if len(names) <= maxListedPods {
+		return strings.Join(names, ", ")
+	}
+	return fmt.Sprintf("%s and %d more", strings.Join(names[:maxListedPods], ", "), len(names)-maxListedPods)
+}
+
+// listControlledPods lists the pods of the selector controlled by one of owners
+func listControlledPods(a common.Analyzer, selector *metav1.LabelSelector, owners ...types.UID) ([]*v1.Pod, error) {
+	labelSelector, err := metav1.LabelSelectorAsSelector(selector)
+	if err != nil {
+		return nil, fmt.Errorf("invalid selector: %w", err)
+	}
+	list, err := a.Client.GetClient().CoreV1().Pods(a.Namespace).List(a.Context, metav1.ListOptions{
+		LabelSelector: labelSelector.String(),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("list pods failed: %w", err)
+	}
+
+	pods := make([]*v1.Pod, 0, len(list.Items))
+	for i := range list.Items {
+		pod := &list.Items[i]
+		ref := metav1.GetControllerOf(pod)
+		if ref == nil {
+			continue
+		}
+		for _, owner := range owners {
+			if ref.UID == owner {
+				pods = append(pods, pod)
+				break
+			}
+		}
+	}
+	return pods, nil
+}
+
+// analyzeWorkloadPods summarizes the failure reasons of the unhealthy pods
+// and delegates the worst ones to the PodAnalyzer
+func analyzeWorkloadPods(a common.Analyzer, prometheus *prom.PromAPI, pods []*v1.Pod, result *common.Result) {
+	unhealthy := unhealthyPods(pods)
+	result.Metadata["PodCount"] = fmt.Sprintf("%d", len(pods))
+	result.Metadata["UnhealthyPodCount"] = fmt.Sprintf("%d", len(unhealthy))
+	if len(unhealthy) == 0 {
+		return
+	}
+
+	reasons := summarizeFailureReasons(unhealthy)
+	for _, reason := range reasons {
+		result.Error = append(result.Error, kcommon.Failure{Text: reason})
+	}
+	result.Metadata["FailureReasons"] = strings.Join(reasons, "\n")
+
+	detailPods(a, prometheus, unhealthy[:min(len(unhealthy), maxDetailedUnhealthyPods)], len(unhealthy), result)
+}
+
+// detailPods delegates the pods to the PodAnalyzer concurrently, out of
+// total unhealthy pods
+func detailPods(a common.Analyzer, prometheus *prom.PromAPI, detailed []*v1.Pod, total int, result *common.Result) {
+	podAnalyzer := NewPodAnalyzer(prometheus)
+	explains := make([]string, len(detailed))
+	var wg sync.WaitGroup
+	for i, pod := range detailed {
+		wg.Add(1)
+		go func(i int, pod *v1.Pod) {
+			defer wg.Done()
+			explains[i] = analyzePodWithExplain(a, pod, podAnalyzer)
+		}(i, pod)
+	}
+	wg.Wait()
+
+	lines := make([]string, 0, len(detailed)+1)
+	for i, pod := range detailed {
+		lines = append(lines, fmt.Sprintf("Pod %s on node %s (%s, %d restarts):\n%s", pod.Name, pod.Spec.NodeName, podFailureReason(pod), podRestarts(pod), explains[i]))
+	}
+	if total > len(detailed) {
+		lines = append(lines, fmt.Sprintf("Other %d unhealthy pod(s) are not detailed.", total-len(detailed)))
+	}
+	result.Metadata["PodDiagnosis"] = strings.Join(lines, "\n---\n")
+}
+
+// appendWorkloadEvents appends the warning events of a workload object
+func appendWorkloadEvents(a common.Analyzer, prometheus *prom.PromAPI, kind, name string, result *common.Result) {
+	rawEvents, err := FetchEvents(a.Context, a.EnableProm, prometheus, a.Client, kind, a.Namespace, name, "Warning", "")
+	if err != nil {
+		klog.Warningf("fetch %s events failed: %v", strings.ToLower(kind), err)
+		return
+	}
+	if a.EnableProm {
+		for _, event := range rawEvents.([]prom.Event) {
+			result.Warning = append(result.Warning, workloadEventWarning(kind, name, event))
+		}
+	} else {
+		for _, event := range rawEvents.([]v1.Event) {
+			result.Warning = append(result.Warning, workloadEventWarningLegacy(kind, name, event))
+		}
+	}
+}
+
+func workloadEventWarning(kind, name string, event prom.Event) common.Warning {
+	return common.Warning{
+		Text: fmt.Sprintf("%s %s has %s event at %s %s(%s) count %d", kind, name, event.Type, event.TimeStamps, event.Reason, event.Message, event.Count),
+		Sensitive: []kcommon.Sensitive{
+			{
+				Unmasked: name,
+				Masked:   util.MaskString(name),
+			},
+		},
+	}
+}
+
+func workloadEventWarningLegacy(kind, name string, event v1.Event) common.Warning {
+	timestamp := event.LastTimestamp.Time
+	if timestamp.IsZero() {
+		timestamp = event.EventTime.Time
+	}
+	return common.Warning{
+		Text: fmt.Sprintf("%s %s has %s event at %s %s(%s) count %d", kind, name, event.Type, timestamp.Format(time.RFC3339), event.Reason, event.Message, event.Count),
+		Sensitive: []kcommon.Sensitive{
+			{
+				Unmasked: name,
+				Masked:   util.MaskString(name),
+			},
+		},
+	}
+}
+
+// workloadPrompt renders the prompt of a workload kind, nothing to explain
+// if its rollout and pods are healthy
+func workloadPrompt(kind string, result *common.Result) string {
+	if result == nil || (len(result.Error) == 0 && len(result.Warning) == 0) {
+		return ""
+	}
+
+	prompt, err := ai.GetRenderedPrompt(kind, workloadPromptData(result))
+	if err != nil {
+		return fmt.Sprintf("Prompt rendering error: %v", err)
+	}
+	return prompt
+}
+
+// workloadPromptData collects the errors, warnings, infos and a copy of the
+// metadata of the result
+func workloadPromptData(result *common.Result) ai.PromptData {
+	metadata := make(map[string]string, len(result.Metadata))
+	for key, value := range result.Metadata {
+		metadata[key] = value
+	}
+
+	errorInfo := ""
+	for _, e := range result.Error {
+		errorInfo += e.Text + "\n"
+	}
+	eventInfo := ""
+	for _, w := range result.Warning {
+		eventInfo += w.Text + "\n"
+	}
+	logInfo := ""
+	for _, i := range result.Info {
+		logInfo += i.Text + "\n"
+	}
+
+	return ai.PromptData{
+		ErrorInfo: strings.TrimSpace(errorInfo),
+		EventInfo: strings.TrimSpace(eventInfo),
+		LogInfo:   strings.TrimSpace(logInfo),
+		Metadata:  metadata,
+	}
+}
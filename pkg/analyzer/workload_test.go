@@ -0,0 +1,260 @@
+package analyzer
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+
+	kcommon "github.com/k8sgpt-ai/k8sgpt/pkg/common"
+	"github.com/k8sgpt-ai/k8sgpt/pkg/kubernetes"
+	"github.com/scitix/aegis/pkg/analyzer/common"
+	appsv1 "k8s.io/api/apps/v1"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+var testLabels = map[string]string{"app": "web"}
+
+func newTestPod(name, node string, owner metav1.Object, kind string) *v1.Pod {
+	return &v1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      name,
+			Namespace: "default",
+			Labels:    testLabels,
+			OwnerReferences: []metav1.OwnerReference{
+				*metav1.NewControllerRef(owner, appsv1.SchemeGroupVersion.WithKind(kind)),
+			},
+		},
+		Spec: v1.PodSpec{
+			NodeName:   node,
+			Containers: []v1.Container{{Name: "main"}},
+		},
+		Status: v1.PodStatus{
+			Phase:      v1.PodRunning,
+			Conditions: []v1.PodCondition{{Type: v1.PodReady, Status: v1.ConditionTrue}},
+		},
+	}
+}
+
+func crashing(pod *v1.Pod, restarts int32) *v1.Pod {
+	pod.Status.Conditions = []v1.PodCondition{{Type: v1.PodReady, Status: v1.ConditionFalse}}
+	pod.Status.ContainerStatuses = []v1.ContainerStatus{{
+		Name:         "main",
+		RestartCount: restarts,
+		State:        v1.ContainerState{Waiting: &v1.ContainerStateWaiting{Reason: "CrashLoopBackOff"}},
+		LastTerminationState: v1.ContainerState{Terminated: &v1.ContainerStateTerminated{
+			ExitCode: 137,
+			Reason:   "OOMKilled",
+		}},
+	}}
+	return pod
+}
+
+func pullFailing(pod *v1.Pod) *v1.Pod {
+	pod.Status.Phase = v1.PodPending
+	pod.Status.Conditions = nil
+	pod.Status.ContainerStatuses = []v1.ContainerStatus{{
+		Name:  "main",
+		State: v1.ContainerState{Waiting: &v1.ContainerStateWaiting{Reason: "ImagePullBackOff"}},
+	}}
+	return pod
+}
+
+func analyzeTestWorkload(t *testing.T, a common.IAnalyzer, name string, objs ...runtime.Object) *common.Result {
+	t.Helper()
+	result, err := a.Analyze(common.Analyzer{
+		Analyzer: kcommon.Analyzer{
+			Client:    &kubernetes.Client{Client: fake.NewSimpleClientset(objs...)},
+			Context:   context.Background(),
+			Namespace: "default",
+		},
+		Name: name,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return result
+}
+
+func TestUnhealthyPods(t *testing.T) {
+	owner := &metav1.ObjectMeta{Name: "web", UID: "uid"}
+	pods := []*v1.Pod{
+		newTestPod("healthy", "node1", owner, "ReplicaSet"),
+		pullFailing(newTestPod("pull", "node1", owner, "ReplicaSet")),
+		crashing(newTestPod("crash-few", "node1", owner, "ReplicaSet"), 2),
+		crashing(newTestPod("crash-many", "node1", owner, "ReplicaSet"), 9),
+	}
+
+	names := make([]string, 0)
+	for _, pod := range unhealthyPods(pods) {
+		names = append(names, pod.Name)
+	}
+	if fmt.Sprint(names) != "[crash-many crash-few pull]" {
+		t.Errorf("expected the crashing pods first by restarts, got %v", names)
+	}
+
+	reasons := summarizeFailureReasons(unhealthyPods(pods))
+	if fmt.Sprint(reasons) != "[2 pod(s) CrashLoopBackOff(OOMKilled): crash-many, crash-few 1 pod(s) ImagePullBackOff: pull]" {
+		t.Errorf("unexpected failure reasons %v", reasons)
+	}
+}
+
+func TestDeploymentAnalyzer(t *testing.T) {
+	replicas := int32(3)
+	deployment := &appsv1.Deployment{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        "web",
+			Namespace:   "default",
+			UID:         "deploy-uid",
+			Generation:  2,
+			Annotations: map[string]string{deploymentRevisionAnnotation: "2"},
+		},
+		Spec: appsv1.DeploymentSpec{
+			Replicas: &replicas,
+			Selector: &metav1.LabelSelector{MatchLabels: testLabels},
+		},
+		Status: appsv1.DeploymentStatus{
+			ObservedGeneration:  2,
+			UpdatedReplicas:     1,
+			ReadyReplicas:       2,
+			AvailableReplicas:   2,
+			UnavailableReplicas: 1,
+			Conditions: []appsv1.DeploymentCondition{{
+				Type:    appsv1.DeploymentProgressing,
+				Status:  v1.ConditionFalse,
+				Reason:  "ProgressDeadlineExceeded",
+				Message: `ReplicaSet "web-new" has timed out progressing.`,
+			}},
+		},
+	}
+	rs := func(name, revision string, uid types.UID) *appsv1.ReplicaSet {
+		return &appsv1.ReplicaSet{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:            name,
+				Namespace:       "default",
+				UID:             uid,
+				Labels:          testLabels,
+				Annotations:     map[string]string{deploymentRevisionAnnotation: revision},
+				OwnerReferences: []metav1.OwnerReference{*metav1.NewControllerRef(deployment, appsv1.SchemeGroupVersion.WithKind("Deployment"))},
+			},
+			Status: appsv1.ReplicaSetStatus{Replicas: 2},
+		}
+	}
+	oldRS, newRS := rs("web-old", "1", "old-uid"), rs("web-new", "2", "new-uid")
+	// a pod of the same labels not controlled by the deployment
+	stray := crashing(newTestPod("stray", "node1", &metav1.ObjectMeta{Name: "other", UID: "other-uid"}, "ReplicaSet"), 1)
+
+	result := analyzeTestWorkload(t, NewDeploymentAnalyzer(nil), "web",
+		deployment, oldRS, newRS, stray,
+		newTestPod("web-old-a", "node1", oldRS, "ReplicaSet"),
+		newTestPod("web-old-b", "node2", oldRS, "ReplicaSet"),
+		crashing(newTestPod("web-new-a", "node1", newRS, "ReplicaSet"), 5),
+	)
+
+	if result.Metadata["NewReplicaSet"] != "web-new" || result.Metadata["PodCount"] != "3" || result.Metadata["UnhealthyPodCount"] != "1" {
+		t.Errorf("unexpected metadata %v", result.Metadata)
+	}
+	errors := make([]string, 0)
+	for _, e := range result.Error {
+		errors = append(errors, e.Text)
+	}
+	text := strings.Join(errors, "\n")
+	if !strings.Contains(text, "rollout of revision 2 is stuck") || !strings.Contains(text, "1 pod(s) CrashLoopBackOff(OOMKilled): web-new-a") {
+		t.Errorf("unexpected errors:\n%s", text)
+	}
+	if !strings.Contains(result.Metadata["PodDiagnosis"], "Pod web-new-a on node node1") {
+		t.Errorf("expected the crashing pod delegated to the pod analyzer, got %s", result.Metadata["PodDiagnosis"])
+	}
+
+	prompt := NewDeploymentAnalyzer(nil).Prompt(result)
+	if !strings.Contains(prompt, "名称: web") || !strings.Contains(prompt, "新 ReplicaSet: web-new") {
+		t.Errorf("unexpected prompt:\n%s", prompt)
+	}
+}
+
+func TestStatefulSetAnalyzer(t *testing.T) {
+	replicas := int32(2)
+	sts := &appsv1.StatefulSet{
+		ObjectMeta: metav1.ObjectMeta{Name: "db", Namespace: "default", UID: "sts-uid"},
+		Spec: appsv1.StatefulSetSpec{
+			Replicas:       &replicas,
+			Selector:       &metav1.LabelSelector{MatchLabels: testLabels},
+			UpdateStrategy: appsv1.StatefulSetUpdateStrategy{Type: appsv1.OnDeleteStatefulSetStrategyType},
+		},
+		Status: appsv1.StatefulSetStatus{
+			CurrentRevision: "db-1",
+			UpdateRevision:  "db-2",
+			UpdatedReplicas: 0,
+			ReadyReplicas:   2,
+		},
+	}
+	pod0 := newTestPod("db-0", "node1", sts, "StatefulSet")
+	pod0.Labels = map[string]string{"app": "web", appsv1.ControllerRevisionHashLabelKey: "db-1"}
+
+	result := analyzeTestWorkload(t, NewStatefulSetAnalyzer(nil), "db", sts, pod0)
+	if len(result.Error) != 0 {
+		t.Errorf("expected no errors, got %+v", result.Error)
+	}
+	if result.Metadata["OutdatedPods"] != "db-0" {
+		t.Errorf("expected db-0 outdated, got %q", result.Metadata["OutdatedPods"])
+	}
+	if len(result.Warning) != 1 || !strings.Contains(result.Warning[0].Text, "OnDelete") {
+		t.Errorf("expected the OnDelete rollout warning, got %+v", result.Warning)
+	}
+}
+
+func TestDaemonSetAnalyzer(t *testing.T) {
+	ds := &appsv1.DaemonSet{
+		ObjectMeta: metav1.ObjectMeta{Name: "agent", Namespace: "default", UID: "ds-uid"},
+		Spec: appsv1.DaemonSetSpec{
+			Selector: &metav1.LabelSelector{MatchLabels: testLabels},
+			Template: v1.PodTemplateSpec{
+				Spec: v1.PodSpec{NodeSelector: map[string]string{"gpu": "true"}},
+			},
+		},
+		Status: appsv1.DaemonSetStatus{
+			DesiredNumberScheduled: 4,
+			CurrentNumberScheduled: 3,
+			UpdatedNumberScheduled: 4,
+			NumberReady:            2,
+			NumberAvailable:        2,
+			NumberUnavailable:      2,
+		},
+	}
+	node := func(name string, gpu bool, taints ...v1.Taint) *v1.Node {
+		n := &v1.Node{ObjectMeta: metav1.ObjectMeta{Name: name}, Spec: v1.NodeSpec{Taints: taints}}
+		if gpu {
+			n.Labels = map[string]string{"gpu": "true"}
+		}
+		return n
+	}
+
+	result := analyzeTestWorkload(t, NewDaemonSetAnalyzer(nil), "agent", ds,
+		node("gpu1", true),
+		node("gpu2", true),
+		node("gpu3", true),
+		// missing the pod but not eligible
+		node("cpu1", false),
+		node("gpu-tainted", true, v1.Taint{Key: "dedicated", Value: "infra", Effect: v1.TaintEffectNoSchedule}),
+		// the not ready taint is tolerated by daemon pods
+		node("gpu-notready", true, v1.Taint{Key: v1.TaintNodeNotReady, Effect: v1.TaintEffectNoExecute}),
+		newTestPod("agent-a", "gpu1", ds, "DaemonSet"),
+		crashing(newTestPod("agent-b", "gpu2", ds, "DaemonSet"), 3),
+	)
+
+	if result.Metadata["MissingNodes"] != "gpu-notready, gpu3" {
+		t.Errorf("unexpected missing nodes %q", result.Metadata["MissingNodes"])
+	}
+	if result.Metadata["FailingNodes"] != "gpu2(CrashLoopBackOff(OOMKilled))" {
+		t.Errorf("unexpected failing nodes %q", result.Metadata["FailingNodes"])
+	}
+
+	prompt := NewDaemonSetAnalyzer(nil).Prompt(result)
+	if !strings.Contains(prompt, "符合条件但缺少 Pod 的节点: gpu-notready, gpu3") {
+		t.Errorf("unexpected prompt:\n%s", prompt)
+	}
+}
@@ -25,6 +25,19 @@ const (
 	AlertTrackingFinalizer = "aegis.io/alert-tracking"
 )
 
+const (
+	// OpsResultOutputName is the well-known output parameter name an ops
+	// workflow step can emit to report its result back to the alert.
+	OpsResultOutputName = "aegis-result"
+	// OpsMessageOutputName is the outputs key holding the final workflow node message.
+	OpsMessageOutputName = "message"
+
+	// MaxOpsOutputs bounds the number of entries kept in opsStatus.outputs.
+	MaxOpsOutputs = 32
+	// MaxOpsOutputValueLength bounds the length of each opsStatus.outputs value.
+	MaxOpsOutputValueLength = 1024
+)
+
 // +genclient
 // +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
 
@@ -92,6 +105,7 @@ const (
 	OpsTriggerStatusTemplateInvalid  AlertOpsTriggerStatusType = "TemplateInvalid"
 	OpsTriggerStatusTriggerFailed    AlertOpsTriggerStatusType = "TriggerFailed"
 	OpsTriggerStatusTriggered        AlertOpsTriggerStatusType = "Triggered"
+	OpsTriggerStatusEscalated        AlertOpsTriggerStatusType = "Escalated"
 )
 
 const (
@@ -99,6 +113,7 @@ const (
 	OpsStatusRunning   AlertOpsStatusType = "Running"
 	OpsStatusFailed    AlertOpsStatusType = "Failed"
 	OpsStatusSucceeded AlertOpsStatusType = "Succeeded"
+	OpsStatusRetrying  AlertOpsStatusType = "Retrying"
 )
 
 // TTLStrategy is the strategy for the time to live depending on if the workflow succeeded or failed
@@ -115,7 +130,8 @@ type TTLStrategy struct {
 
 // AegisAlertStatus defines the alert/ops status.
 type AegisAlertStatus struct {
-	// The latest available observations of an object's current state
+	// The latest available observations of an object's current state, one
+	// condition per type.
 	// +optional
 	// +patchMergeKey=type
 	// +patchStrategy=type
@@ -134,6 +150,11 @@ type AegisAlertStatus struct {
 
 	// +optional
 	StartTime *metav1.Time `json:"startTime,omitempty" protobuf:"bytes,5,rep,name=startTime"`
+
+	// History is the chronological record of condition changes, oldest first,
+	// capped to the configured history size.
+	// +optional
+	History []AlertOpsHistory `json:"history,omitempty" protobuf:"bytes,6,rep,name=history"`
 }
 
 // AegisAlertOpsStatus defines the corresponding ops status
@@ -162,6 +183,32 @@ type AegisAlertOpsStatus struct {
 
 	// +optional
 	Failed int32 `json:"failed,omitempty" protobuf:"bytes,8,rep,name=failed"`
+
+	// Outputs holds the output parameters and final message reported by the
+	// ops workflows, bounded by MaxOpsOutputs and MaxOpsOutputValueLength.
+	// +optional
+	Outputs map[string]string `json:"outputs,omitempty" protobuf:"bytes,9,rep,name=outputs"`
+
+	// Retries is the number of times the ops workflow has been recreated after failure.
+	// +optional
+	Retries int32 `json:"retries,omitempty" protobuf:"varint,10,opt,name=retries"`
+
+	// RetriedWorkflows are the failed workflows already handled by a retry,
+	// they are no longer counted in the ops status.
+	// +optional
+	RetriedWorkflows []string `json:"retriedWorkflows,omitempty" protobuf:"bytes,11,rep,name=retriedWorkflows"`
+
+	// NextRetryTime is when the ops workflow will be recreated.
+	// +optional
+	NextRetryTime *metav1.Time `json:"nextRetryTime,omitempty" protobuf:"bytes,12,opt,name=nextRetryTime"`
+
+	// Rule is the namespace/name of the ops rule the workflow was created by.
+	// +optional
+	Rule string `json:"rule,omitempty" protobuf:"bytes,13,opt,name=rule"`
+
+	// Template is the namespace/name of the ops template the workflow was rendered from.
+	// +optional
+	Template string `json:"template,omitempty" protobuf:"bytes,14,opt,name=template"`
 }
 
 type AlertOpsConditionType string
@@ -171,6 +218,10 @@ const (
 	AlertFailedCreateOpsWorkflow    AlertOpsConditionType = "FailedCreateWorkflow"
 	AlertCompleteOpsWrofklow        AlertOpsConditionType = "Complete"
 	AlertFailedOpsWrofklow          AlertOpsConditionType = "Failed"
+	AlertRetryOpsWorkflow           AlertOpsConditionType = "Retry"
+	AlertVerified                   AlertOpsConditionType = "Verified"
+	AlertVerificationFailed         AlertOpsConditionType = "VerificationFailed"
+	AlertEscalated                  AlertOpsConditionType = "Escalated"
 )
 
 type AlertOpsCondition struct {
@@ -182,6 +233,19 @@ type AlertOpsCondition struct {
 	Message            string                 `json:"message,omitempty" protobuf:"bytes,6,rep,name=message"`
 }
 
+// DefaultAlertHistorySize is the default number of status.history entries kept
+const DefaultAlertHistorySize = 20
+
+// AlertOpsHistory is an entry of the alert status history
+type AlertOpsHistory struct {
+	Time    metav1.Time           `json:"time,omitempty" protobuf:"bytes,1,opt,name=time"`
+	Type    AlertOpsConditionType `json:"type,omitempty" protobuf:"bytes,2,opt,name=type"`
+	Reason  string                `json:"reason,omitempty" protobuf:"bytes,3,opt,name=reason"`
+	Message string                `json:"message,omitempty" protobuf:"bytes,4,opt,name=message"`
+	// Workflow is the related ops workflow, if any
+	Workflow string `json:"workflow,omitempty" protobuf:"bytes,5,opt,name=workflow"`
+}
+
 // +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
 
 // AegisAlertList is a list of AegisAlert items.
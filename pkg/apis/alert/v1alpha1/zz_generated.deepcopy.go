@@ -119,6 +119,22 @@ func (in *AegisAlertOpsStatus) DeepCopyInto(out *AegisAlertOpsStatus) {
 		*out = new(int32)
 		**out = **in
 	}
+	if in.Outputs != nil {
+		in, out := &in.Outputs, &out.Outputs
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+	if in.RetriedWorkflows != nil {
+		in, out := &in.RetriedWorkflows, &out.RetriedWorkflows
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.NextRetryTime != nil {
+		in, out := &in.NextRetryTime, &out.NextRetryTime
+		*out = (*in).DeepCopy()
+	}
 	return
 }
 
@@ -181,6 +197,13 @@ func (in *AegisAlertStatus) DeepCopyInto(out *AegisAlertStatus) {
 		in, out := &in.StartTime, &out.StartTime
 		*out = (*in).DeepCopy()
 	}
+	if in.History != nil {
+		in, out := &in.History, &out.History
+		*out = make([]AlertOpsHistory, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
 	return
 }
 
@@ -212,6 +235,23 @@ func (in *AlertOpsCondition) DeepCopy() *AlertOpsCondition {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *AlertOpsHistory) DeepCopyInto(out *AlertOpsHistory) {
+	*out = *in
+	in.Time.DeepCopyInto(&out.Time)
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new AlertOpsHistory.
+func (in *AlertOpsHistory) DeepCopy() *AlertOpsHistory {
+	if in == nil {
+		return nil
+	}
+	out := new(AlertOpsHistory)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *TTLStrategy) DeepCopyInto(out *TTLStrategy) {
 	*out = *in
@@ -60,14 +60,54 @@ type AegisDiagnosisSpec struct {
 
 	// TTLStrategy limits the lifetime of a alert
 	TTLStrategy *TTLStrategy `json:"ttlStrategy,omitempty" protobuf:"bytes,8,opt,name=ttlStrategy"`
+
+	// Remediation raises an alert for the recognised category of a completed
+	// diagnosis, none by default
+	// +optional
+	Remediation DiagnosisRemediation `json:"remediation,omitempty" protobuf:"bytes,3,opt,name=remediation"`
+
+	// Mode is how the explain is got, single by default
+	// +optional
+	Mode DiagnosisMode `json:"mode,omitempty" protobuf:"bytes,4,opt,name=mode"`
 }
 
+type DiagnosisMode string
+
+const (
+	// ModeSingle explains the analyzer result with one completion
+	ModeSingle DiagnosisMode = "single"
+	// ModeAgent lets the LLM call read-only tools for more data before
+	// explaining, within the budget of the controller
+	ModeAgent DiagnosisMode = "agent"
+)
+
+type DiagnosisRemediation string
+
+const (
+	// RemediationNone does nothing
+	RemediationNone DiagnosisRemediation = "none"
+	// RemediationSuggest records the alert in the status only
+	RemediationSuggest DiagnosisRemediation = "suggest"
+	// RemediationAuto creates the alert, for the rules and templates to act
+	RemediationAuto DiagnosisRemediation = "auto"
+)
+
 type DiagnosisObjectKind string
 
 const (
-	NodeKind       DiagnosisObjectKind = "Node"
-	PodKind        DiagnosisObjectKind = "Pod"
-	PytorchJobKind DiagnosisObjectKind = "PytorchJob"
+	NodeKind        DiagnosisObjectKind = "Node"
+	PodKind         DiagnosisObjectKind = "Pod"
+	PytorchJobKind  DiagnosisObjectKind = "PytorchJob"
+	WorkflowKind    DiagnosisObjectKind = "Workflow"
+	DeploymentKind  DiagnosisObjectKind = "Deployment"
+	StatefulSetKind DiagnosisObjectKind = "StatefulSet"
+	DaemonSetKind   DiagnosisObjectKind = "DaemonSet"
+	JobKind         DiagnosisObjectKind = "Job"
+	CronJobKind     DiagnosisObjectKind = "CronJob"
+	TFJobKind       DiagnosisObjectKind = "TFJob"
+	MPIJobKind      DiagnosisObjectKind = "MPIJob"
+	XGBoostJobKind  DiagnosisObjectKind = "XGBoostJob"
+	PaddleJobKind   DiagnosisObjectKind = "PaddleJob"
 )
 
 type AegisDiagnosisObject struct {
@@ -97,6 +137,18 @@ type AegisDiagnosisStatus struct {
 
 	// +optional
 	ErrorResult *string `json:"errorResult,omitempty" protobuf:"bytes,6,rep,name=errorResult"`
+
+	// Analysis is the structured explain, Explain keeps its rendered text.
+	// +optional
+	Analysis *DiagnosisAnalysis `json:"analysis,omitempty" protobuf:"bytes,7,rep,name=analysis"`
+
+	// Remediation is the alert suggested or created for the diagnosis.
+	// +optional
+	Remediation *DiagnosisRemediationStatus `json:"remediation,omitempty" protobuf:"bytes,8,rep,name=remediation"`
+
+	// Agent is the audit trail of the agent mode.
+	// +optional
+	Agent *DiagnosisAgentStatus `json:"agent,omitempty" protobuf:"bytes,9,rep,name=agent"`
 }
 
 type DiagnosisPhase string
@@ -137,3 +189,140 @@ type DiagnosisResult struct {
 	Warnings []string `json:"warnings,omitempty" protobuf:"bytes,3,rep,name=warnings"`
 	Infos    []string `json:"infos,omitempty" protobuf:"bytes,1,rep,name=infos"`
 }
+
+type DiagnosisCategory string
+
+const (
+	CategoryHardware      DiagnosisCategory = "Hardware"
+	CategoryNetwork       DiagnosisCategory = "Network"
+	CategoryStorage       DiagnosisCategory = "Storage"
+	CategoryScheduling    DiagnosisCategory = "Scheduling"
+	CategoryResource      DiagnosisCategory = "Resource"
+	CategoryImage         DiagnosisCategory = "Image"
+	CategoryConfiguration DiagnosisCategory = "Configuration"
+	CategoryApplication   DiagnosisCategory = "Application"
+	CategoryPlatform      DiagnosisCategory = "Platform"
+	CategoryNone          DiagnosisCategory = "None"
+	CategoryUnknown       DiagnosisCategory = "Unknown"
+)
+
+// DiagnosisCategories are the categories the explain may choose from
+var DiagnosisCategories = []DiagnosisCategory{
+	CategoryHardware,
+	CategoryNetwork,
+	CategoryStorage,
+	CategoryScheduling,
+	CategoryResource,
+	CategoryImage,
+	CategoryConfiguration,
+	CategoryApplication,
+	CategoryPlatform,
+	CategoryNone,
+	CategoryUnknown,
+}
+
+// DiagnosisAnalysis is the structured explain of a diagnosis
+type DiagnosisAnalysis struct {
+	Healthy   bool              `json:"healthy" protobuf:"varint,1,opt,name=healthy"`
+	RootCause string            `json:"rootCause,omitempty" protobuf:"bytes,2,opt,name=rootCause"`
+	Category  DiagnosisCategory `json:"category,omitempty" protobuf:"bytes,3,opt,name=category"`
+
+	// Confidence in the root cause, from 0 to 100
+	Confidence int32 `json:"confidence,omitempty" protobuf:"varint,4,opt,name=confidence"`
+
+	// +optional
+	Evidence []DiagnosisEvidence `json:"evidence,omitempty" protobuf:"bytes,5,rep,name=evidence"`
+
+	// +optional
+	RecommendedActions []string `json:"recommendedActions,omitempty" protobuf:"bytes,6,rep,name=recommendedActions"`
+}
+
+type EvidenceSource string
+
+const (
+	EvidenceFailure EvidenceSource = "failure"
+	EvidenceWarning EvidenceSource = "warning"
+	EvidenceInfo    EvidenceSource = "info"
+)
+
+// DiagnosisEvidence references a failure, warning or info of the result
+type DiagnosisEvidence struct {
+	Source EvidenceSource `json:"source" protobuf:"bytes,1,opt,name=source"`
+
+	// Index of the failure, warning or info in the result
+	Index int32 `json:"index" protobuf:"varint,2,opt,name=index"`
+
+	// Reason tells how it supports the root cause
+	// +optional
+	Reason string `json:"reason,omitempty" protobuf:"bytes,3,opt,name=reason"`
+}
+
+type CategorySource string
+
+const (
+	CategorySourceAnalysis  CategorySource = "analysis"
+	CategorySourceSignature CategorySource = "signature"
+)
+
+// DiagnosisRemediationStatus is the alert mapped from the category of a diagnosis
+type DiagnosisRemediationStatus struct {
+	Category DiagnosisCategory `json:"category" protobuf:"bytes,1,opt,name=category"`
+
+	// Source is where the category comes from, the analysis or the failure signatures
+	Source CategorySource `json:"source" protobuf:"bytes,2,opt,name=source"`
+
+	AlertType string `json:"alertType" protobuf:"bytes,3,opt,name=alertType"`
+
+	// Fingerprint of the alert, repeated diagnoses of the object update the same alert
+	Fingerprint string `json:"fingerprint" protobuf:"bytes,4,opt,name=fingerprint"`
+
+	// AlertRaised is set once the alert is created in auto remediation
+	// +optional
+	AlertRaised bool `json:"alertRaised,omitempty" protobuf:"varint,5,opt,name=alertRaised"`
+
+	// Message tells the alert raised in auto remediation, or why it was not
+	// +optional
+	Message string `json:"message,omitempty" protobuf:"bytes,6,opt,name=message"`
+}
+
+type AgentStopReason string
+
+const (
+	AgentStopAnswered  AgentStopReason = "Answered"
+	AgentStopMaxSteps  AgentStopReason = "MaxSteps"
+	AgentStopMaxTokens AgentStopReason = "MaxTokens"
+	AgentStopTimeout   AgentStopReason = "Timeout"
+)
+
+// DiagnosisAgentStatus records the tool calls of the agent mode
+type DiagnosisAgentStatus struct {
+	// +optional
+	Steps []DiagnosisAgentStep `json:"steps,omitempty" protobuf:"bytes,1,rep,name=steps"`
+
+	// Tokens used by all the completions
+	Tokens int32 `json:"tokens" protobuf:"varint,2,opt,name=tokens"`
+
+	// StopReason is Answered, or the budget which stopped the tool calls
+	StopReason AgentStopReason `json:"stopReason" protobuf:"bytes,3,opt,name=stopReason"`
+}
+
+// DiagnosisAgentStep is a tool call of the agent mode
+type DiagnosisAgentStep struct {
+	Tool string `json:"tool" protobuf:"bytes,1,opt,name=tool"`
+
+	// Arguments are the JSON arguments of the call
+	// +optional
+	Arguments string `json:"arguments,omitempty" protobuf:"bytes,2,opt,name=arguments"`
+
+	// Output is the result of the call, truncated
+	// +optional
+	Output string `json:"output,omitempty" protobuf:"bytes,3,opt,name=output"`
+
+	// +optional
+	Error string `json:"error,omitempty" protobuf:"bytes,4,opt,name=error"`
+
+	StartTime metav1.Time `json:"startTime" protobuf:"bytes,5,opt,name=startTime"`
+
+	// DurationMillis is the time the call took
+	DurationMillis int64 `json:"durationMillis" protobuf:"varint,6,opt,name=durationMillis"`
+}
@@ -150,6 +150,21 @@ func (in *AegisDiagnosisStatus) DeepCopyInto(out *AegisDiagnosisStatus) {
 		*out = new(string)
 		**out = **in
 	}
+	if in.Analysis != nil {
+		in, out := &in.Analysis, &out.Analysis
+		*out = new(DiagnosisAnalysis)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Remediation != nil {
+		in, out := &in.Remediation, &out.Remediation
+		*out = new(DiagnosisRemediationStatus)
+		**out = **in
+	}
+	if in.Agent != nil {
+		in, out := &in.Agent, &out.Agent
+		*out = new(DiagnosisAgentStatus)
+		(*in).DeepCopyInto(*out)
+	}
 	return
 }
 
@@ -163,6 +178,104 @@ func (in *AegisDiagnosisStatus) DeepCopy() *AegisDiagnosisStatus {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *DiagnosisAgentStatus) DeepCopyInto(out *DiagnosisAgentStatus) {
+	*out = *in
+	if in.Steps != nil {
+		in, out := &in.Steps, &out.Steps
+		*out = make([]DiagnosisAgentStep, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new DiagnosisAgentStatus.
+func (in *DiagnosisAgentStatus) DeepCopy() *DiagnosisAgentStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(DiagnosisAgentStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *DiagnosisAgentStep) DeepCopyInto(out *DiagnosisAgentStep) {
+	*out = *in
+	in.StartTime.DeepCopyInto(&out.StartTime)
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new DiagnosisAgentStep.
+func (in *DiagnosisAgentStep) DeepCopy() *DiagnosisAgentStep {
+	if in == nil {
+		return nil
+	}
+	out := new(DiagnosisAgentStep)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *DiagnosisAnalysis) DeepCopyInto(out *DiagnosisAnalysis) {
+	*out = *in
+	if in.Evidence != nil {
+		in, out := &in.Evidence, &out.Evidence
+		*out = make([]DiagnosisEvidence, len(*in))
+		copy(*out, *in)
+	}
+	if in.RecommendedActions != nil {
+		in, out := &in.RecommendedActions, &out.RecommendedActions
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new DiagnosisAnalysis.
+func (in *DiagnosisAnalysis) DeepCopy() *DiagnosisAnalysis {
+	if in == nil {
+		return nil
+	}
+	out := new(DiagnosisAnalysis)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *DiagnosisEvidence) DeepCopyInto(out *DiagnosisEvidence) {
+	*out = *in
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new DiagnosisEvidence.
+func (in *DiagnosisEvidence) DeepCopy() *DiagnosisEvidence {
+	if in == nil {
+		return nil
+	}
+	out := new(DiagnosisEvidence)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *DiagnosisRemediationStatus) DeepCopyInto(out *DiagnosisRemediationStatus) {
+	*out = *in
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new DiagnosisRemediationStatus.
+func (in *DiagnosisRemediationStatus) DeepCopy() *DiagnosisRemediationStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(DiagnosisRemediationStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *DiagnosisResult) DeepCopyInto(out *DiagnosisResult) {
 	*out = *in
@@ -46,6 +46,88 @@ type AegisAlertOpsRuleSpec struct {
 	Selector        *metav1.LabelSelector   `json:"selector,omitempty" protobuf:"bytes,1,rep,name=selector"`
 	AlertConditions []AegisAlertCondition   `json:"alertConditions,omitempty" protobuf:"bytes,4,opt,name=alertConditions"`
 	OpsTemplate     *corev1.ObjectReference `json:"opsTemplate,omitempty" protobuf:"bytes,1,rep,name=opsTemplate"`
+
+	// RetryPolicy controls how failed ops workflows are retried, it takes
+	// precedence over the retry policy of the ops template.
+	// +optional
+	RetryPolicy *RetryPolicy `json:"retryPolicy,omitempty" protobuf:"bytes,5,opt,name=retryPolicy"`
+
+	// Verify checks against prometheus that the problem is resolved after the ops workflow succeeded.
+	// +optional
+	Verify *Verification `json:"verify,omitempty" protobuf:"bytes,6,opt,name=verify"`
+
+	// Escalation stops auto-remediating an object whose ops keep failing and
+	// takes the escalation actions instead.
+	// +optional
+	Escalation *EscalationPolicy `json:"escalation,omitempty" protobuf:"bytes,7,opt,name=escalation"`
+
+	// ActiveDeadlineSeconds bounds the ops duration of an alert, retries
+	// included. The workflows are terminated and the alert fails once it's
+	// exceeded. It takes precedence over the deadline of the ops template.
+	// +optional
+	ActiveDeadlineSeconds *int64 `json:"activeDeadlineSeconds,omitempty" protobuf:"varint,8,opt,name=activeDeadlineSeconds"`
+}
+
+type EscalationGroupBy string
+
+const (
+	EscalationGroupByFingerprint    EscalationGroupBy = "Fingerprint"
+	EscalationGroupByInvolvedObject EscalationGroupBy = "InvolvedObject"
+)
+
+type EscalationActionType string
+
+const (
+	// EscalationActionRaiseSeverity sets the alert severity to EscalationPolicy.Severity
+	EscalationActionRaiseSeverity EscalationActionType = "RaiseSeverity"
+	// EscalationActionDisableNode labels the node aegis.io/disable=true
+	EscalationActionDisableNode EscalationActionType = "DisableNode"
+	// EscalationActionNotify fires the OnOpsEscalated notifiers
+	EscalationActionNotify EscalationActionType = "Notify"
+	// EscalationActionCreateTicket creates a ticket for the node
+	EscalationActionCreateTicket EscalationActionType = "CreateTicket"
+)
+
+// EscalationPolicy defines when repeatedly failing ops are escalated
+type EscalationPolicy struct {
+	// MaxFailures is the number of failed ops of the same object within the
+	// window after which the alerts are escalated instead of remediated
+	MaxFailures int32 `json:"maxFailures" protobuf:"varint,1,opt,name=maxFailures"`
+
+	// Window is the period failed ops are counted in, e.g. "24h". Defaults to "24h"
+	// +optional
+	Window string `json:"window,omitempty" protobuf:"bytes,2,opt,name=window"`
+
+	// GroupBy is Fingerprint or InvolvedObject. Defaults to Fingerprint
+	// +optional
+	GroupBy EscalationGroupBy `json:"groupBy,omitempty" protobuf:"bytes,3,opt,name=groupBy,casttype=EscalationGroupBy"`
+
+	// Actions taken on escalation: RaiseSeverity, DisableNode, Notify and CreateTicket
+	Actions []EscalationActionType `json:"actions,omitempty" protobuf:"bytes,4,rep,name=actions,casttype=EscalationActionType"`
+
+	// Severity set by RaiseSeverity. Defaults to "critical"
+	// +optional
+	Severity string `json:"severity,omitempty" protobuf:"bytes,5,opt,name=severity"`
+}
+
+// Verification defines how to check the problem is resolved after remediation
+type Verification struct {
+	// Query is a PromQL expression templated with the alert parameters, e.g.
+	// node_filesystem_avail_bytes{instance="{{.node}}",mountpoint="/"} > 10e9.
+	// Verification passes once it returns samples which are all non-zero.
+	Query string `json:"query" protobuf:"bytes,1,opt,name=query"`
+
+	// Wait is the delay after the ops workflow succeeded before the first evaluation, e.g. "1m"
+	// +optional
+	Wait string `json:"wait,omitempty" protobuf:"bytes,2,opt,name=wait"`
+
+	// Timeout is how long the query keeps being evaluated before verification fails. Defaults to "5m"
+	// +optional
+	Timeout string `json:"timeout,omitempty" protobuf:"bytes,3,opt,name=timeout"`
+
+	// Interval is the delay between evaluations. Defaults to "30s"
+	// +optional
+	Interval string `json:"interval,omitempty" protobuf:"bytes,4,opt,name=interval"`
 }
 
 type AegisAlertCondition struct {
@@ -53,6 +135,36 @@ type AegisAlertCondition struct {
 	Status string `json:"status,omitempty" protobuf:"bytes,1,rep,name=status"`
 }
 
+// RetryPolicy defines how failed ops workflows of an alert are retried
+type RetryPolicy struct {
+	// MaxRetries is the maximum number of times the ops workflow is recreated after failure
+	MaxRetries int32 `json:"maxRetries,omitempty" protobuf:"varint,1,opt,name=maxRetries"`
+
+	// Backoff is the backoff strategy between retries
+	// +optional
+	Backoff *Backoff `json:"backoff,omitempty" protobuf:"bytes,2,opt,name=backoff"`
+
+	// RetryOn lists the workflow phases (Failed, Error), the trigger status (TriggerFailed)
+	// or failure message fragments (e.g. ImagePullBackOff) worth a retry. Empty means any failure.
+	// +optional
+	RetryOn []string `json:"retryOn,omitempty" protobuf:"bytes,3,rep,name=retryOn"`
+}
+
+// Backoff is a backoff strategy between retries
+type Backoff struct {
+	// Duration is the delay before the first retry, e.g. "30s". Defaults to "10s"
+	// +optional
+	Duration string `json:"duration,omitempty" protobuf:"bytes,1,opt,name=duration"`
+
+	// Factor multiplies the delay after each retry. Defaults to 2
+	// +optional
+	Factor *int32 `json:"factor,omitempty" protobuf:"varint,2,opt,name=factor"`
+
+	// MaxDuration caps the delay between retries, e.g. "10m"
+	// +optional
+	MaxDuration string `json:"maxDuration,omitempty" protobuf:"bytes,3,opt,name=maxDuration"`
+}
+
 // type AegisAlertOpsRuleStatus struct defines the rule status.
 type AegisAlertOpsRuleStatus struct {
 	// Status is the rule status.
@@ -50,6 +50,44 @@ type AegisOpsTemplateSpec struct {
 
 	// +optional
 	Manifest string `json:"manifest,omitempty" protobuf:"bytes,3,opt,name=manifest"`
+
+	// RetryPolicy controls how failed workflows rendered from this template are retried.
+	// +optional
+	RetryPolicy *RetryPolicy `json:"retryPolicy,omitempty" protobuf:"bytes,4,opt,name=retryPolicy"`
+
+	// ActiveDeadlineSeconds bounds the ops duration of the alerts using this template.
+	// +optional
+	ActiveDeadlineSeconds *int64 `json:"activeDeadlineSeconds,omitempty" protobuf:"varint,5,opt,name=activeDeadlineSeconds"`
+}
+
+// RetryPolicy defines how failed ops workflows of an alert are retried
+type RetryPolicy struct {
+	// MaxRetries is the maximum number of times the ops workflow is recreated after failure
+	MaxRetries int32 `json:"maxRetries,omitempty" protobuf:"varint,1,opt,name=maxRetries"`
+
+	// Backoff is the backoff strategy between retries
+	// +optional
+	Backoff *Backoff `json:"backoff,omitempty" protobuf:"bytes,2,opt,name=backoff"`
+
+	// RetryOn lists the workflow phases (Failed, Error), the trigger status (TriggerFailed)
+	// or failure message fragments (e.g. ImagePullBackOff) worth a retry. Empty means any failure.
+	// +optional
+	RetryOn []string `json:"retryOn,omitempty" protobuf:"bytes,3,rep,name=retryOn"`
+}
+
+// Backoff is a backoff strategy between retries
+type Backoff struct {
+	// Duration is the delay before the first retry, e.g. "30s". Defaults to "10s"
+	// +optional
+	Duration string `json:"duration,omitempty" protobuf:"bytes,1,opt,name=duration"`
+
+	// Factor multiplies the delay after each retry. Defaults to 2
+	// +optional
+	Factor *int32 `json:"factor,omitempty" protobuf:"varint,2,opt,name=factor"`
+
+	// MaxDuration caps the delay between retries, e.g. "10m"
+	// +optional
+	MaxDuration string `json:"maxDuration,omitempty" protobuf:"bytes,3,opt,name=maxDuration"`
 }
 
 // AegisOpsTemplateStatus defines the alert/ops status.
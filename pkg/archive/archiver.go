@@ -0,0 +1,104 @@
+package archive
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/klog/v2"
+)
+
+const pruneInterval = time.Hour
+
+// Config of the archive, loaded from the archive section of the config file
+type Config struct {
+	Enable bool `mapstructure:"enable"`
+	// Path of the bbolt database, on a persistent volume
+	Path string `mapstructure:"path"`
+	// Retention of the records after they finished, forever if zero
+	Retention time.Duration `mapstructure:"retention"`
+}
+
+// Archiver snapshots the finished aegis objects of the informers, and the
+// deleted ones, before their TTL cleanup loses them
+type Archiver struct {
+	store     *Store
+	retention time.Duration
+}
+
+func NewArchiver(store *Store, retention time.Duration, informers ...cache.SharedIndexInformer) *Archiver {
+	a := &Archiver{
+		store:     store,
+		retention: retention,
+	}
+
+	for _, informer := range informers {
+		informer.AddEventHandler(cache.ResourceEventHandlerFuncs{
+			AddFunc:    a.addObject,
+			UpdateFunc: a.updateObject,
+			DeleteFunc: a.deleteObject,
+		})
+	}
+	return a
+}
+
+// Run prunes the records older than the retention until the context is done
+func (a *Archiver) Run(ctx context.Context) {
+	if a.retention <= 0 {
+		return
+	}
+
+	klog.Infof("Starting archive pruning, retention %v", a.retention)
+	wait.UntilWithContext(ctx, a.prune, pruneInterval)
+}
+
+func (a *Archiver) prune(ctx context.Context) {
+	pruned, err := a.store.Prune(time.Now().Add(-a.retention))
+	if err != nil {
+		utilruntime.HandleError(fmt.Errorf("pruning archive: %v", err))
+		return
+	}
+	klog.V(4).Infof("Pruned %d archive records", pruned)
+}
+
+func (a *Archiver) addObject(obj interface{}) {
+	a.archive(obj, false)
+}
+
+func (a *Archiver) updateObject(old, cur interface{}) {
+	oldObj, oldOk := old.(metav1.Object)
+	curObj, curOk := cur.(metav1.Object)
+	if oldOk && curOk && oldObj.GetResourceVersion() == curObj.GetResourceVersion() {
+		// periodic resync
+		return
+	}
+	a.archive(cur, false)
+}
+
+func (a *Archiver) deleteObject(obj interface{}) {
+	if tombstone, ok := obj.(cache.DeletedFinalStateUnknown); ok {
+		obj = tombstone.Obj
+	}
+	a.archive(obj, true)
+}
+
+func (a *Archiver) archive(obj interface{}, deleted bool) {
+	record, err := NewRecord(obj, deleted)
+	if err != nil {
+		utilruntime.HandleError(fmt.Errorf("snapshot object for archive: %v", err))
+		return
+	}
+	if record == nil {
+		return
+	}
+
+	if err := a.store.Put(record); err != nil {
+		utilruntime.HandleError(fmt.Errorf("archive %s %s/%s: %v", record.Kind, record.Namespace, record.Name, err))
+		return
+	}
+	klog.V(6).Infof("Archived %s %s/%s", record.Kind, record.Namespace, record.Name)
+}
@@ -0,0 +1,182 @@
+package archive
+
+import (
+	"encoding/json"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	alertv1alpha1 "github.com/scitix/aegis/pkg/apis/alert/v1alpha1"
+	clustercheckv1alpha1 "github.com/scitix/aegis/pkg/apis/clustercheck/v1alpha1"
+	diagnosisv1alpha1 "github.com/scitix/aegis/pkg/apis/diagnosis/v1alpha1"
+	nodecheckv1alpha1 "github.com/scitix/aegis/pkg/apis/nodecheck/v1alpha1"
+)
+
+const (
+	KindAlert        = "AegisAlert"
+	KindDiagnosis    = "AegisDiagnosis"
+	KindNodeCheck    = "AegisNodeHealthCheck"
+	KindClusterCheck = "AegisClusterHealthCheck"
+)
+
+// Record is the snapshot of a finished aegis object
+type Record struct {
+	Kind      string `json:"kind"`
+	Namespace string `json:"namespace"`
+	Name      string `json:"name"`
+	UID       string `json:"uid"`
+	// Type is the alert type or the diagnosis object kind
+	Type string `json:"type,omitempty"`
+	// Node is the node the object is about, if any
+	Node string `json:"node,omitempty"`
+	// Outcome is the final ops status, diagnosis or check phase
+	Outcome    string    `json:"outcome,omitempty"`
+	StartTime  time.Time `json:"startTime,omitzero"`
+	FinishTime time.Time `json:"finishTime"`
+	// Object is the object itself, without managed fields
+	Object json.RawMessage `json:"object,omitempty"`
+}
+
+// Matches reports whether the record passes the filters of the query, the
+// time range aside
+func (r *Record) Matches(q Query) bool {
+	return (len(q.Kind) == 0 || q.Kind == r.Kind) &&
+		(len(q.Type) == 0 || q.Type == r.Type) &&
+		(len(q.Node) == 0 || q.Node == r.Node) &&
+		(len(q.Outcome) == 0 || q.Outcome == r.Outcome)
+}
+
+// NewRecord snapshots the object, it returns nil if the object isn't an aegis
+// object or isn't finished yet. Deleted objects are snapshotted anyway.
+func NewRecord(obj interface{}, deleted bool) (*Record, error) {
+	var record *Record
+	var object metav1.Object
+
+	switch o := obj.(type) {
+	case *alertv1alpha1.AegisAlert:
+		if !deleted && !alertFinished(o) {
+			return nil, nil
+		}
+		record = newAlertRecord(o)
+		object = o.DeepCopy()
+	case *diagnosisv1alpha1.AegisDiagnosis:
+		if !deleted && o.Status.Phase != diagnosisv1alpha1.DiagnosisPhaseCompleted && o.Status.Phase != diagnosisv1alpha1.DiagnosisPhaseFailed {
+			return nil, nil
+		}
+		record = newDiagnosisRecord(o)
+		object = o.DeepCopy()
+	case *nodecheckv1alpha1.AegisNodeHealthCheck:
+		if !deleted && o.Status.Status != nodecheckv1alpha1.CheckStatusSucceeded && o.Status.Status != nodecheckv1alpha1.CheckStatusFailed {
+			return nil, nil
+		}
+		record = newNodeCheckRecord(o)
+		object = o.DeepCopy()
+	case *clustercheckv1alpha1.AegisClusterHealthCheck:
+		if !deleted && o.Status.Phase != clustercheckv1alpha1.CheckPhaseCompleted && o.Status.Phase != clustercheckv1alpha1.CheckPhaseFailed {
+			return nil, nil
+		}
+		record = newClusterCheckRecord(o)
+		object = o.DeepCopy()
+	default:
+		return nil, nil
+	}
+
+	object.SetManagedFields(nil)
+	data, err := json.Marshal(object)
+	if err != nil {
+		return nil, err
+	}
+
+	record.Namespace = object.GetNamespace()
+	record.Name = object.GetName()
+	record.UID = string(object.GetUID())
+	record.Object = data
+	if record.FinishTime.IsZero() {
+		record.FinishTime = object.GetCreationTimestamp().Time
+	}
+	return record, nil
+}
+
+// alertFinished reports whether the alert ops completed, or will never run
+func alertFinished(alert *alertv1alpha1.AegisAlert) bool {
+	if alert.Status.OpsStatus.CompletionTime != nil {
+		return true
+	}
+
+	trigger := alert.Status.OpsStatus.TriggerStatus
+	return len(trigger) > 0 && trigger != alertv1alpha1.OpsTriggerStatusTriggered && trigger != alertv1alpha1.OpsTriggerStatusTriggerFailed
+}
+
+func newAlertRecord(alert *alertv1alpha1.AegisAlert) *Record {
+	record := &Record{
+		Kind:    KindAlert,
+		Type:    alert.Spec.Type,
+		Node:    alert.Spec.InvolvedObject.Node,
+		Outcome: string(alert.Status.OpsStatus.Status),
+	}
+	if alert.Spec.InvolvedObject.Kind == alertv1alpha1.NodeKind {
+		record.Node = alert.Spec.InvolvedObject.Name
+	}
+	if len(record.Outcome) == 0 {
+		record.Outcome = string(alert.Status.OpsStatus.TriggerStatus)
+	}
+
+	if alert.Status.StartTime != nil {
+		record.StartTime = alert.Status.StartTime.Time
+	}
+	if alert.Status.OpsStatus.CompletionTime != nil {
+		record.FinishTime = alert.Status.OpsStatus.CompletionTime.Time
+	} else {
+		// alerts without ops finish at their last condition
+		for _, condition := range alert.Status.Conditions {
+			if condition.LastTransitionTime.Time.After(record.FinishTime) {
+				record.FinishTime = condition.LastTransitionTime.Time
+			}
+		}
+	}
+	return record
+}
+
+func newDiagnosisRecord(diagnosis *diagnosisv1alpha1.AegisDiagnosis) *Record {
+	record := &Record{
+		Kind:    KindDiagnosis,
+		Type:    string(diagnosis.Spec.Object.Kind),
+		Node:    diagnosis.Spec.Object.Node,
+		Outcome: string(diagnosis.Status.Phase),
+	}
+	if diagnosis.Spec.Object.Kind == diagnosisv1alpha1.NodeKind {
+		record.Node = diagnosis.Spec.Object.Name
+	}
+	record.StartTime, record.FinishTime = timeRange(diagnosis.Status.StartTime, diagnosis.Status.CompletionTime)
+	return record
+}
+
+func newNodeCheckRecord(check *nodecheckv1alpha1.AegisNodeHealthCheck) *Record {
+	record := &Record{
+		Kind:    KindNodeCheck,
+		Node:    check.Spec.Node,
+		Outcome: string(check.Status.Status),
+	}
+	record.StartTime, record.FinishTime = timeRange(check.Status.StartTime, check.Status.CompletionTime)
+	return record
+}
+
+func newClusterCheckRecord(check *clustercheckv1alpha1.AegisClusterHealthCheck) *Record {
+	record := &Record{
+		Kind:    KindClusterCheck,
+		Outcome: string(check.Status.Phase),
+	}
+	record.StartTime, record.FinishTime = timeRange(check.Status.StartTime, check.Status.CompletionTime)
+	return record
+}
+
+func timeRange(start, completion *metav1.Time) (time.Time, time.Time) {
+	var startTime, completionTime time.Time
+	if start != nil {
+		startTime = start.Time
+	}
+	if completion != nil {
+		completionTime = completion.Time
+	}
+	return startTime, completionTime
+}
@@ -0,0 +1,77 @@
+package archive
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	alertv1alpha1 "github.com/scitix/aegis/pkg/apis/alert/v1alpha1"
+	diagnosisv1alpha1 "github.com/scitix/aegis/pkg/apis/diagnosis/v1alpha1"
+)
+
+func TestNewRecord(t *testing.T) {
+	completion := metav1.NewTime(time.Date(2024, 5, 20, 8, 0, 0, 0, time.UTC))
+	alert := &alertv1alpha1.AegisAlert{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:          "alert",
+			Namespace:     "monitoring",
+			UID:           "uid-1",
+			ManagedFields: []metav1.ManagedFieldsEntry{{Manager: "aegis"}},
+		},
+		Spec: alertv1alpha1.AegisAlertSpec{
+			Type:           "NodeNotReady",
+			InvolvedObject: alertv1alpha1.AegisAlertObject{Kind: alertv1alpha1.NodeKind, Name: "node-1"},
+		},
+	}
+
+	// running alerts are archived on deletion only
+	if record, err := NewRecord(alert, false); err != nil || record != nil {
+		t.Fatalf("expected no record of running alert, got %+v, %v", record, err)
+	}
+	if record, err := NewRecord(alert, true); err != nil || record == nil {
+		t.Fatalf("expected record of deleted alert, got %v", err)
+	}
+
+	alert.Status.OpsStatus.Status = alertv1alpha1.OpsStatusFailed
+	alert.Status.OpsStatus.CompletionTime = &completion
+	record, err := NewRecord(alert, false)
+	if err != nil || record == nil {
+		t.Fatalf("expected record of finished alert, got %v", err)
+	}
+	if record.Kind != KindAlert || record.UID != "uid-1" || record.Node != "node-1" || record.Type != "NodeNotReady" ||
+		record.Outcome != "Failed" || !record.FinishTime.Equal(completion.Time) {
+		t.Errorf("unexpected record %+v", record)
+	}
+
+	object := &alertv1alpha1.AegisAlert{}
+	if err := json.Unmarshal(record.Object, object); err != nil || object.Name != "alert" || len(object.ManagedFields) != 0 {
+		t.Errorf("unexpected snapshot %s, %v", record.Object, err)
+	}
+	if len(alert.ManagedFields) == 0 {
+		t.Error("the informer object must not be modified")
+	}
+
+	// alerts without ops rule are finished too
+	noops := &alertv1alpha1.AegisAlert{ObjectMeta: metav1.ObjectMeta{Name: "noops", UID: "uid-2"}}
+	noops.Status.OpsStatus.TriggerStatus = alertv1alpha1.OpsTriggerStatusRuleNotFound
+	if record, _ := NewRecord(noops, false); record == nil || record.Outcome != "RuleNotFound" {
+		t.Errorf("expected record of alert without ops, got %+v", record)
+	}
+
+	diagnosis := &diagnosisv1alpha1.AegisDiagnosis{
+		ObjectMeta: metav1.ObjectMeta{Name: "diagnosis", UID: "uid-3"},
+		Spec: diagnosisv1alpha1.AegisDiagnosisSpec{
+			Object: diagnosisv1alpha1.AegisDiagnosisObject{Kind: diagnosisv1alpha1.PodKind, Name: "pod", Node: "node-2"},
+		},
+		Status: diagnosisv1alpha1.AegisDiagnosisStatus{Phase: diagnosisv1alpha1.DiagnosisPhaseCompleted, CompletionTime: &completion},
+	}
+	if record, _ := NewRecord(diagnosis, false); record == nil || record.Kind != KindDiagnosis || record.Node != "node-2" || record.Type != "Pod" {
+		t.Errorf("unexpected diagnosis record %+v", record)
+	}
+
+	if record, err := NewRecord("not an object", true); err != nil || record != nil {
+		t.Errorf("expected no record, got %+v, %v", record, err)
+	}
+}
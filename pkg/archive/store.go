@@ -0,0 +1,267 @@
+package archive
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+const (
+	DefaultPath  = "/aegis/archive/archive.db"
+	DefaultLimit = 100
+	MaxLimit     = 1000
+
+	openTimeout = time.Minute
+)
+
+var (
+	// records keyed by finish time and uid, so that scans are ordered by time
+	recordsBucket = []byte("records")
+	// uid to records key, so that an object archived twice is replaced
+	uidBucket = []byte("uids")
+)
+
+// Query filters the archived records. Empty fields match everything.
+type Query struct {
+	Kind    string
+	Type    string
+	Node    string
+	Outcome string
+	// Since and Until bound the finish time of the records
+	Since time.Time
+	Until time.Time
+	// Limit is the page size, DefaultLimit if zero
+	Limit int
+	// Continue is the token returned by the previous page
+	Continue string
+}
+
+// Store is a bbolt database of archived records
+type Store struct {
+	db *bolt.DB
+}
+
+// Open opens or creates the archive database at path
+func Open(path string) (*Store, error) {
+	if len(path) == 0 {
+		path = DefaultPath
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return nil, fmt.Errorf("archive: create directory of %s: %v", path, err)
+	}
+
+	db, err := bolt.Open(path, 0600, &bolt.Options{Timeout: openTimeout})
+	if err != nil {
+		return nil, fmt.Errorf("archive: open %s: %v", path, err)
+	}
+
+	err = db.Update(func(tx *bolt.Tx) error {
+		for _, name := range [][]byte{recordsBucket, uidBucket} {
+			if _, err := tx.CreateBucketIfNotExists(name); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		db.Close()
+		return nil, fmt.Errorf("archive: init %s: %v", path, err)
+	}
+
+	return &Store{db: db}, nil
+}
+
+func (s *Store) Close() error {
+	return s.db.Close()
+}
+
+// recordKey sorts by finish time, the uid makes it unique
+func recordKey(r *Record) []byte {
+	key := make([]byte, 8, 8+len(r.UID))
+	binary.BigEndian.PutUint64(key, uint64(r.FinishTime.UnixNano()))
+	return append(key, r.UID...)
+}
+
+func keyTime(key []byte) time.Time {
+	return time.Unix(0, int64(binary.BigEndian.Uint64(key[:8])))
+}
+
+func timeKey(t time.Time) []byte {
+	key := make([]byte, 8)
+	binary.BigEndian.PutUint64(key, uint64(t.UnixNano()))
+	return key
+}
+
+// Put archives the record, replacing the previous snapshot of the same object
+func (s *Store) Put(r *Record) error {
+	if len(r.UID) == 0 {
+		return fmt.Errorf("archive: record %s/%s has no uid", r.Namespace, r.Name)
+	}
+
+	data, err := json.Marshal(r)
+	if err != nil {
+		return err
+	}
+
+	key := recordKey(r)
+
+	// informer resyncs deliver the same finished objects again and again
+	unchanged := false
+	s.db.View(func(tx *bolt.Tx) error {
+		unchanged = bytes.Equal(tx.Bucket(recordsBucket).Get(key), data)
+		return nil
+	})
+	if unchanged {
+		return nil
+	}
+
+	return s.db.Update(func(tx *bolt.Tx) error {
+		records, uids := tx.Bucket(recordsBucket), tx.Bucket(uidBucket)
+		if old := uids.Get([]byte(r.UID)); old != nil && !bytes.Equal(old, key) {
+			if err := records.Delete(old); err != nil {
+				return err
+			}
+		}
+
+		if err := records.Put(key, data); err != nil {
+			return err
+		}
+		return uids.Put([]byte(r.UID), key)
+	})
+}
+
+// Get returns the record of the object uid, nil if not archived
+func (s *Store) Get(uid string) (*Record, error) {
+	var record *Record
+	err := s.db.View(func(tx *bolt.Tx) error {
+		key := tx.Bucket(uidBucket).Get([]byte(uid))
+		if key == nil {
+			return nil
+		}
+
+		data := tx.Bucket(recordsBucket).Get(key)
+		if data == nil {
+			return nil
+		}
+
+		record = &Record{}
+		return json.Unmarshal(data, record)
+	})
+	return record, err
+}
+
+// List returns the records matching the query, most recent first, and the
+// token of the next page, empty if it's the last one.
+func (s *Store) List(q Query) ([]*Record, string, error) {
+	limit := q.Limit
+	if limit <= 0 {
+		limit = DefaultLimit
+	}
+	if limit > MaxLimit {
+		limit = MaxLimit
+	}
+
+	var after []byte
+	if len(q.Continue) > 0 {
+		var err error
+		if after, err = base64.RawURLEncoding.DecodeString(q.Continue); err != nil || len(after) < 8 {
+			return nil, "", fmt.Errorf("archive: invalid continue token %q", q.Continue)
+		}
+	}
+
+	records := make([]*Record, 0)
+	next := ""
+	err := s.db.View(func(tx *bolt.Tx) error {
+		cursor := tx.Bucket(recordsBucket).Cursor()
+
+		var k, v, last []byte
+		switch {
+		case after != nil:
+			// the token is the last key returned, resume right before it
+			k, v = cursor.Seek(after)
+			if k == nil {
+				k, v = cursor.Last()
+			}
+			for k != nil && bytes.Compare(k, after) >= 0 {
+				k, v = cursor.Prev()
+			}
+		case !q.Until.IsZero():
+			k, v = cursor.Seek(timeKey(q.Until.Add(time.Nanosecond)))
+			if k == nil {
+				k, v = cursor.Last()
+			} else {
+				k, v = cursor.Prev()
+			}
+		default:
+			k, v = cursor.Last()
+		}
+
+		for ; k != nil; k, v = cursor.Prev() {
+			finished := keyTime(k)
+			if !q.Since.IsZero() && finished.Before(q.Since) {
+				break
+			}
+			if !q.Until.IsZero() && finished.After(q.Until) {
+				continue
+			}
+
+			record := &Record{}
+			if err := json.Unmarshal(v, record); err != nil {
+				return err
+			}
+			if !record.Matches(q) {
+				continue
+			}
+
+			if len(records) == limit {
+				next = base64.RawURLEncoding.EncodeToString(last)
+				break
+			}
+			records = append(records, record)
+			last = append(last[:0], k...)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, "", err
+	}
+
+	return records, next, nil
+}
+
+// Prune deletes the records finished before the time and returns their number
+func (s *Store) Prune(before time.Time) (int, error) {
+	pruned := 0
+	err := s.db.Update(func(tx *bolt.Tx) error {
+		records, uids := tx.Bucket(recordsBucket), tx.Bucket(uidBucket)
+
+		// deleting while iterating makes the cursor skip keys
+		expired := make([][]byte, 0)
+		end := timeKey(before)
+		cursor := records.Cursor()
+		for k, _ := cursor.First(); k != nil && bytes.Compare(k[:8], end) < 0; k, _ = cursor.Next() {
+			expired = append(expired, append([]byte{}, k...))
+		}
+
+		for _, key := range expired {
+			// the uid follows the finish time in the key
+			if err := uids.Delete(key[8:]); err != nil {
+				return err
+			}
+			if err := records.Delete(key); err != nil {
+				return err
+			}
+			pruned++
+		}
+		return nil
+	})
+	return pruned, err
+}
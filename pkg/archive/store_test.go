@@ -0,0 +1,152 @@
+package archive
+
+import (
+	"fmt"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func newTestStore(t *testing.T) *Store {
+	store, err := Open(filepath.Join(t.TempDir(), "archive.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { store.Close() })
+	return store
+}
+
+func newTestRecord(i int, base time.Time) *Record {
+	record := &Record{
+		Kind:       KindAlert,
+		Namespace:  "monitoring",
+		Name:       fmt.Sprintf("alert-%d", i),
+		UID:        fmt.Sprintf("uid-%d", i),
+		Type:       "NodeNotReady",
+		Node:       fmt.Sprintf("node-%d", i%2),
+		Outcome:    "Succeeded",
+		FinishTime: base.Add(time.Duration(i) * time.Minute),
+	}
+	if i%3 == 0 {
+		record.Outcome = "Failed"
+	}
+	return record
+}
+
+func TestStorePutGet(t *testing.T) {
+	store := newTestStore(t)
+	base := time.Date(2024, 5, 20, 8, 0, 0, 0, time.UTC)
+
+	record := newTestRecord(1, base)
+	if err := store.Put(record); err != nil {
+		t.Fatal(err)
+	}
+
+	// archived again once finished, the snapshot is replaced
+	record.Outcome = "Failed"
+	record.FinishTime = base.Add(time.Hour)
+	if err := store.Put(record); err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := store.Get("uid-1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got == nil || got.Outcome != "Failed" || !got.FinishTime.Equal(record.FinishTime) {
+		t.Errorf("unexpected record %+v", got)
+	}
+
+	records, _, err := store.List(Query{})
+	if err != nil || len(records) != 1 {
+		t.Errorf("expected a single record, got %d, %v", len(records), err)
+	}
+
+	if got, err := store.Get("missing"); err != nil || got != nil {
+		t.Errorf("expected no record, got %+v, %v", got, err)
+	}
+}
+
+func TestStoreList(t *testing.T) {
+	store := newTestStore(t)
+	base := time.Date(2024, 5, 20, 8, 0, 0, 0, time.UTC)
+	for i := 0; i < 10; i++ {
+		if err := store.Put(newTestRecord(i, base)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	names := func(records []*Record) string {
+		result := make([]string, 0, len(records))
+		for _, r := range records {
+			result = append(result, r.Name)
+		}
+		return fmt.Sprint(result)
+	}
+
+	cases := []struct {
+		name     string
+		query    Query
+		expected string
+	}{
+		{"all", Query{}, "[alert-9 alert-8 alert-7 alert-6 alert-5 alert-4 alert-3 alert-2 alert-1 alert-0]"},
+		{"node", Query{Node: "node-1"}, "[alert-9 alert-7 alert-5 alert-3 alert-1]"},
+		{"outcome", Query{Outcome: "Failed"}, "[alert-9 alert-6 alert-3 alert-0]"},
+		{"kind", Query{Kind: KindDiagnosis}, "[]"},
+		{"range", Query{Since: base.Add(3 * time.Minute), Until: base.Add(5 * time.Minute)}, "[alert-5 alert-4 alert-3]"},
+	}
+	for _, c := range cases {
+		records, next, err := store.List(c.query)
+		if err != nil {
+			t.Fatalf("%s: %v", c.name, err)
+		}
+		if got := names(records); got != c.expected || len(next) > 0 {
+			t.Errorf("%s: expected %s, got %s (continue %q)", c.name, c.expected, got, next)
+		}
+	}
+
+	// paginate the node-0 records by 2
+	pages := []string{}
+	query := Query{Node: "node-0", Limit: 2}
+	for {
+		records, next, err := store.List(query)
+		if err != nil {
+			t.Fatal(err)
+		}
+		pages = append(pages, names(records))
+		if len(next) == 0 {
+			break
+		}
+		query.Continue = next
+	}
+	if fmt.Sprint(pages) != "[[alert-8 alert-6] [alert-4 alert-2] [alert-0]]" {
+		t.Errorf("unexpected pages %v", pages)
+	}
+
+	if _, _, err := store.List(Query{Continue: "invalid"}); err == nil {
+		t.Error("expected invalid continue token error")
+	}
+}
+
+func TestStorePrune(t *testing.T) {
+	store := newTestStore(t)
+	base := time.Date(2024, 5, 20, 8, 0, 0, 0, time.UTC)
+	for i := 0; i < 5; i++ {
+		if err := store.Put(newTestRecord(i, base)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	pruned, err := store.Prune(base.Add(3 * time.Minute))
+	if err != nil || pruned != 3 {
+		t.Fatalf("expected 3 records pruned, got %d, %v", pruned, err)
+	}
+
+	records, _, _ := store.List(Query{})
+	if len(records) != 2 {
+		t.Errorf("expected 2 records left, got %d", len(records))
+	}
+	if got, _ := store.Get("uid-0"); got != nil {
+		t.Errorf("expected pruned record gone, got %+v", got)
+	}
+}
@@ -0,0 +1,270 @@
+package cloudevents
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	cache "github.com/patrickmn/go-cache"
+	"k8s.io/apimachinery/pkg/util/errors"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/klog/v2"
+
+	alertv1alpha1 "github.com/scitix/aegis/pkg/apis/alert/v1alpha1"
+	diagnosisv1alpha1 "github.com/scitix/aegis/pkg/apis/diagnosis/v1alpha1"
+	nodecheckv1alpha1 "github.com/scitix/aegis/pkg/apis/nodecheck/v1alpha1"
+)
+
+const (
+	// sendTimeout bounds an emit, retries included
+	sendTimeout = 30 * time.Second
+	// lifecycle callbacks fire on every sync of a finished object, remember
+	// what has been emitted for a while so each transition is emitted once.
+	sentExpiration = 24 * time.Hour
+)
+
+// sendBackoff retries the failed sends of an emit
+var sendBackoff = wait.Backoff{
+	Duration: 500 * time.Millisecond,
+	Factor:   2,
+	Jitter:   0.1,
+	Steps:    3,
+}
+
+// Config of the cloudevents emitter, loaded from the cloudevents section of the config file
+type Config struct {
+	Enable bool `mapstructure:"enable"`
+	// Source is the ce-source attribute, e.g. /aegis/<cluster>
+	Source string `mapstructure:"source"`
+	// Sink is the http endpoint events are posted to in binary mode
+	Sink    string            `mapstructure:"sink"`
+	Headers map[string]string `mapstructure:"headers"`
+	// File appends events as json lines if set
+	File string `mapstructure:"file"`
+}
+
+// Emitter emits aegis state transitions as cloudevents to its sinks
+type Emitter struct {
+	source  string
+	sinks   []Sink
+	sent    *cache.Cache
+	backoff wait.Backoff
+}
+
+func NewEmitter(cfg Config) (*Emitter, error) {
+	source := cfg.Source
+	if len(source) == 0 {
+		source = DefaultSource
+	}
+
+	sinks := make([]Sink, 0)
+	if len(cfg.Sink) > 0 {
+		sinks = append(sinks, NewHTTPSink(cfg.Sink, cfg.Headers))
+	}
+	if len(cfg.File) > 0 {
+		sinks = append(sinks, NewFileSink(cfg.File))
+	}
+	if len(sinks) == 0 {
+		return nil, fmt.Errorf("cloudevents: neither sink nor file is configured")
+	}
+
+	return NewEmitterWithSinks(source, sinks...), nil
+}
+
+func NewEmitterWithSinks(source string, sinks ...Sink) *Emitter {
+	return &Emitter{
+		source:  source,
+		sinks:   sinks,
+		sent:    cache.New(sentExpiration, time.Hour),
+		backoff: sendBackoff,
+	}
+}
+
+// Emit sends the event to all sinks once per type and key. The sends are
+// retried with backoff, a sink still failing is sent the event again on the
+// next emit.
+func (e *Emitter) Emit(ctx context.Context, eventType, subject, key string, data interface{}) error {
+	// the sinks are reserved before sending, so that concurrent syncs of
+	// the same transition don't send it twice
+	sinks := make(map[int]Sink, len(e.sinks))
+	for i, sink := range e.sinks {
+		if err := e.sent.Add(sentKey(eventType, key, i), struct{}{}, cache.DefaultExpiration); err == nil {
+			sinks[i] = sink
+		}
+	}
+	if len(sinks) == 0 {
+		return nil
+	}
+
+	event, err := NewEvent(e.source, eventType, subject, key, data)
+	if err != nil {
+		for i := range sinks {
+			e.sent.Delete(sentKey(eventType, key, i))
+		}
+		return fmt.Errorf("cloudevents: build %s event: %v", eventType, err)
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, sendTimeout)
+	defer cancel()
+
+	errs := make([]error, 0)
+	for i, sink := range sinks {
+		if err := e.send(ctx, sink, event); err != nil {
+			e.sent.Delete(sentKey(eventType, key, i))
+			errs = append(errs, err)
+		}
+	}
+
+	if len(errs) > 0 {
+		return fmt.Errorf("cloudevents: send %s event of %s: %v", eventType, subject, errors.NewAggregate(errs))
+	}
+	klog.V(4).Infof("Emitted %s event of %s", eventType, subject)
+	return nil
+}
+
+// send retries the send to the sink until it succeeds, the backoff steps
+// are exhausted or the context is done
+func (e *Emitter) send(ctx context.Context, sink Sink, event *Event) error {
+	backoff := e.backoff
+	for {
+		err := sink.Send(ctx, event)
+		if err == nil || backoff.Steps <= 0 {
+			return err
+		}
+
+		delay := backoff.Step()
+		klog.V(4).Infof("Retry %s event %s in %v: %v", event.Type, event.ID, delay, err)
+		select {
+		case <-ctx.Done():
+			return err
+		case <-time.After(delay):
+		}
+	}
+}
+
+func sentKey(eventType, key string, sink int) string {
+	return fmt.Sprintf("%s/%s/%d", eventType, key, sink)
+}
+
+func alertSubject(alert *alertv1alpha1.AegisAlert) string {
+	return fmt.Sprintf("aegisalerts/%s/%s", alert.Namespace, alert.Name)
+}
+
+// emitAlert emits an alert event, keyed by the retry so that retried ops are reported again
+func (e *Emitter) emitAlert(eventType string, alert *alertv1alpha1.AegisAlert) error {
+	data := alert.DeepCopy()
+	data.SetGroupVersionKind(alertv1alpha1.SchemeGroupVersion.WithKind("AegisAlert"))
+
+	key := fmt.Sprintf("%s/%d", alert.UID, alert.Status.OpsStatus.Retries)
+	return e.Emit(context.Background(), eventType, alertSubject(alert), key, data)
+}
+
+func (e *Emitter) OnCreate(alert *alertv1alpha1.AegisAlert) error {
+	return e.emitAlert(TypeAlertCreated, alert)
+}
+
+func (e *Emitter) OnUpdate(alert *alertv1alpha1.AegisAlert) error {
+	return nil
+}
+
+func (e *Emitter) OnDelete(alert *alertv1alpha1.AegisAlert) error {
+	return e.emitAlert(TypeAlertDeleted, alert)
+}
+
+func (e *Emitter) OnNoOpsRule(alert *alertv1alpha1.AegisAlert) error {
+	return e.emitAlert(TypeAlertNoOpsRule, alert)
+}
+
+// OnNoOpsTemplate means a rule matched but its template is missing
+func (e *Emitter) OnNoOpsTemplate(alert *alertv1alpha1.AegisAlert) error {
+	return errors.NewAggregate([]error{
+		e.emitAlert(TypeAlertRuleMatched, alert),
+		e.emitAlert(TypeAlertNoOpsTemplate, alert),
+	})
+}
+
+// OnFailedCreateOpsWorkflow means a rule matched but its workflow couldn't be created
+func (e *Emitter) OnFailedCreateOpsWorkflow(alert *alertv1alpha1.AegisAlert) error {
+	return errors.NewAggregate([]error{
+		e.emitAlert(TypeAlertRuleMatched, alert),
+		e.emitAlert(TypeAlertOpsCreateFailed, alert),
+	})
+}
+
+func (e *Emitter) OnSucceedCreateOpsWorkflow(alert *alertv1alpha1.AegisAlert) error {
+	return errors.NewAggregate([]error{
+		e.emitAlert(TypeAlertRuleMatched, alert),
+		e.emitAlert(TypeAlertOpsCreated, alert),
+	})
+}
+
+func (e *Emitter) OnOpsWorkflowSucceed(alert *alertv1alpha1.AegisAlert) error {
+	return e.emitAlert(TypeAlertOpsSucceeded, alert)
+}
+
+func (e *Emitter) OnOpsWorkflowFailed(alert *alertv1alpha1.AegisAlert) error {
+	return e.emitAlert(TypeAlertOpsFailed, alert)
+}
+
+func (e *Emitter) OnOpsEscalated(alert *alertv1alpha1.AegisAlert) error {
+	return e.emitAlert(TypeAlertOpsEscalated, alert)
+}
+
+// OnNodeCheckUpdate emits finished node checks
+func (e *Emitter) OnNodeCheckUpdate(nodecheck *nodecheckv1alpha1.AegisNodeHealthCheck) error {
+	var eventType string
+	switch nodecheck.Status.Status {
+	case nodecheckv1alpha1.CheckStatusSucceeded:
+		eventType = TypeNodeCheckCompleted
+	case nodecheckv1alpha1.CheckStatusFailed:
+		eventType = TypeNodeCheckFailed
+	default:
+		return nil
+	}
+
+	data := nodecheck.DeepCopy()
+	data.SetGroupVersionKind(nodecheckv1alpha1.SchemeGroupVersion.WithKind("AegisNodeHealthCheck"))
+
+	subject := fmt.Sprintf("aegisnodehealthchecks/%s/%s", nodecheck.Namespace, nodecheck.Name)
+	return e.Emit(context.Background(), eventType, subject, string(nodecheck.UID), data)
+}
+
+// OnDiagnosisFinished emits completed and failed diagnoses
+func (e *Emitter) OnDiagnosisFinished(diagnosis *diagnosisv1alpha1.AegisDiagnosis) error {
+	var eventType string
+	switch diagnosis.Status.Phase {
+	case diagnosisv1alpha1.DiagnosisPhaseCompleted:
+		eventType = TypeDiagnosisCompleted
+	case diagnosisv1alpha1.DiagnosisPhaseFailed:
+		eventType = TypeDiagnosisFailed
+	default:
+		return nil
+	}
+
+	data := diagnosis.DeepCopy()
+	data.SetGroupVersionKind(diagnosisv1alpha1.SchemeGroupVersion.WithKind("AegisDiagnosis"))
+
+	subject := fmt.Sprintf("aegisdiagnosises/%s/%s", diagnosis.Namespace, diagnosis.Name)
+	return e.Emit(context.Background(), eventType, subject, string(diagnosis.UID), data)
+}
+
+// SOPResult is the data of self-healing SOP events
+type SOPResult struct {
+	Node      string `json:"node"`
+	Condition string `json:"condition"`
+	Type      string `json:"type,omitempty"`
+	Alert     string `json:"alert,omitempty"`
+	Message   string `json:"message,omitempty"`
+}
+
+// EmitSOP emits the result of a self-healing SOP execution
+func (e *Emitter) EmitSOP(ctx context.Context, result *SOPResult, err error) error {
+	eventType := TypeSOPSucceeded
+	if err != nil {
+		eventType = TypeSOPFailed
+		result.Message = err.Error()
+	}
+
+	key := fmt.Sprintf("%s/%s/%s/%d", result.Node, result.Condition, result.Alert, time.Now().UnixNano())
+	return e.Emit(ctx, eventType, "nodes/"+result.Node, key, result)
+}
@@ -0,0 +1,197 @@
+package cloudevents
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	alertv1alpha1 "github.com/scitix/aegis/pkg/apis/alert/v1alpha1"
+	nodecheckv1alpha1 "github.com/scitix/aegis/pkg/apis/nodecheck/v1alpha1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/wait"
+)
+
+// fakeSink fails the first sends, as many as failures
+type fakeSink struct {
+	failures int
+	types    []string
+}
+
+func (s *fakeSink) Send(ctx context.Context, event *Event) error {
+	if s.failures > 0 {
+		s.failures--
+		return fmt.Errorf("sink unavailable")
+	}
+	s.types = append(s.types, event.Type)
+	return nil
+}
+
+func TestHTTPSinkBinaryMode(t *testing.T) {
+	var header http.Header
+	var body []byte
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		header = r.Header
+		body, _ = io.ReadAll(r.Body)
+		w.WriteHeader(http.StatusAccepted)
+	}))
+	defer server.Close()
+
+	emitter := NewEmitterWithSinks("/aegis/test", NewHTTPSink(server.URL, map[string]string{"Authorization": "Bearer token"}))
+	alert := &alertv1alpha1.AegisAlert{
+		ObjectMeta: metav1.ObjectMeta{Name: "alert", Namespace: "monitoring", UID: "uid-1"},
+	}
+	if err := emitter.OnOpsWorkflowFailed(alert); err != nil {
+		t.Fatal(err)
+	}
+
+	expected := map[string]string{
+		"Content-Type":   DataContentType,
+		"Ce-Specversion": SpecVersion,
+		"Ce-Source":      "/aegis/test",
+		"Ce-Type":        TypeAlertOpsFailed,
+		"Ce-Subject":     "aegisalerts/monitoring/alert",
+		"Authorization":  "Bearer token",
+	}
+	for key, value := range expected {
+		if header.Get(key) != value {
+			t.Errorf("header %s: expected %q, got %q", key, value, header.Get(key))
+		}
+	}
+	if len(header.Get("Ce-Id")) == 0 {
+		t.Error("missing ce-id")
+	}
+
+	data := &alertv1alpha1.AegisAlert{}
+	if err := json.Unmarshal(body, data); err != nil {
+		t.Fatal(err)
+	}
+	if data.Kind != "AegisAlert" || data.Name != "alert" {
+		t.Errorf("unexpected data %s", body)
+	}
+}
+
+func TestEmitOnce(t *testing.T) {
+	var calls int
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+	}))
+	defer server.Close()
+
+	emitter := NewEmitterWithSinks(DefaultSource, NewHTTPSink(server.URL, nil))
+	alert := &alertv1alpha1.AegisAlert{
+		ObjectMeta: metav1.ObjectMeta{Name: "alert", Namespace: "monitoring", UID: "uid-1"},
+	}
+	emitter.OnOpsWorkflowSucceed(alert)
+	emitter.OnOpsWorkflowSucceed(alert)
+	if calls != 1 {
+		t.Errorf("expected 1 call, got %d", calls)
+	}
+
+	// a retried ops is a new transition
+	alert.Status.OpsStatus.Retries = 1
+	emitter.OnOpsWorkflowSucceed(alert)
+	if calls != 2 {
+		t.Errorf("expected 2 calls, got %d", calls)
+	}
+}
+
+func TestEmitRetry(t *testing.T) {
+	alert := &alertv1alpha1.AegisAlert{
+		ObjectMeta: metav1.ObjectMeta{Name: "alert", Namespace: "monitoring", UID: "uid-1"},
+	}
+
+	flaky := &fakeSink{failures: 1}
+	emitter := NewEmitterWithSinks(DefaultSource, flaky)
+	emitter.backoff = wait.Backoff{Duration: time.Millisecond, Factor: 2, Steps: 2}
+	if err := emitter.OnOpsWorkflowSucceed(alert); err != nil {
+		t.Fatalf("expected the send retried, got %v", err)
+	}
+	if len(flaky.types) != 1 || flaky.types[0] != TypeAlertOpsSucceeded {
+		t.Errorf("expected the event sent once, got %v", flaky.types)
+	}
+
+	// a send failing all the attempts is retried on the next emit, the
+	// sinks that got the event are not sent it again
+	down, up := &fakeSink{failures: 3}, &fakeSink{}
+	emitter = NewEmitterWithSinks(DefaultSource, down, up)
+	emitter.backoff = wait.Backoff{Duration: time.Millisecond, Factor: 2, Steps: 2}
+	if err := emitter.OnOpsWorkflowFailed(alert); err == nil {
+		t.Fatal("expected the send failed")
+	}
+	if err := emitter.OnOpsWorkflowFailed(alert); err != nil {
+		t.Fatalf("expected the send retried, got %v", err)
+	}
+	if len(down.types) != 1 || len(up.types) != 1 {
+		t.Errorf("expected the event sent once to each sink, got %v and %v", down.types, up.types)
+	}
+}
+
+func TestEmitRuleMatched(t *testing.T) {
+	sink := &fakeSink{}
+	emitter := NewEmitterWithSinks(DefaultSource, sink)
+	alert := &alertv1alpha1.AegisAlert{
+		ObjectMeta: metav1.ObjectMeta{Name: "alert", Namespace: "monitoring", UID: "uid-1"},
+	}
+	if err := emitter.OnFailedCreateOpsWorkflow(alert); err != nil {
+		t.Fatal(err)
+	}
+	if len(sink.types) != 2 || sink.types[0] != TypeAlertRuleMatched || sink.types[1] != TypeAlertOpsCreateFailed {
+		t.Errorf("expected rule matched and create failed events, got %v", sink.types)
+	}
+}
+
+func TestFileSink(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "events.jsonl")
+	emitter, err := NewEmitter(Config{File: path})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	nodecheck := &nodecheckv1alpha1.AegisNodeHealthCheck{
+		ObjectMeta: metav1.ObjectMeta{Name: "check", UID: "uid-2"},
+	}
+	if err := emitter.OnNodeCheckUpdate(nodecheck); err != nil {
+		t.Fatal(err)
+	}
+	nodecheck.Status.Status = nodecheckv1alpha1.CheckStatusSucceeded
+	if err := emitter.OnNodeCheckUpdate(nodecheck); err != nil {
+		t.Fatal(err)
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	var events []Event
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		event := Event{}
+		if err := json.Unmarshal(scanner.Bytes(), &event); err != nil {
+			t.Fatal(err)
+		}
+		events = append(events, event)
+	}
+
+	if len(events) != 1 {
+		t.Fatalf("expected 1 event, got %d", len(events))
+	}
+	if events[0].Type != TypeNodeCheckCompleted || events[0].SpecVersion != SpecVersion || events[0].Source != DefaultSource {
+		t.Errorf("unexpected event %+v", events[0])
+	}
+}
+
+func TestNewEmitterWithoutSink(t *testing.T) {
+	if _, err := NewEmitter(Config{Enable: true}); err == nil {
+		t.Error("expected error without sinks")
+	}
+}
@@ -0,0 +1,72 @@
+package cloudevents
+
+import (
+	"crypto/sha1"
+	"encoding/hex"
+	"encoding/json"
+	"time"
+)
+
+const (
+	SpecVersion     = "1.0"
+	DataContentType = "application/json"
+
+	DefaultSource = "/aegis"
+)
+
+// Event types emitted by aegis, io.aegis.<resource>.<transition>
+const (
+	TypeAlertCreated         = "io.aegis.alert.created"
+	TypeAlertDeleted         = "io.aegis.alert.deleted"
+	TypeAlertRuleMatched     = "io.aegis.alert.ruleMatched"
+	TypeAlertNoOpsRule       = "io.aegis.alert.noOpsRule"
+	TypeAlertNoOpsTemplate   = "io.aegis.alert.noOpsTemplate"
+	TypeAlertOpsCreated      = "io.aegis.alert.opsCreated"
+	TypeAlertOpsCreateFailed = "io.aegis.alert.opsCreateFailed"
+	TypeAlertOpsSucceeded    = "io.aegis.alert.opsSucceeded"
+	TypeAlertOpsFailed       = "io.aegis.alert.opsFailed"
+	TypeAlertOpsEscalated    = "io.aegis.alert.opsEscalated"
+
+	TypeDiagnosisCompleted = "io.aegis.diagnosis.completed"
+	TypeDiagnosisFailed    = "io.aegis.diagnosis.failed"
+
+	TypeNodeCheckCompleted = "io.aegis.nodecheck.completed"
+	TypeNodeCheckFailed    = "io.aegis.nodecheck.failed"
+
+	TypeSOPSucceeded = "io.aegis.selfhealing.sopSucceeded"
+	TypeSOPFailed    = "io.aegis.selfhealing.sopFailed"
+)
+
+// Event is a CloudEvents v1.0 event in structured json format
+type Event struct {
+	SpecVersion     string          `json:"specversion"`
+	ID              string          `json:"id"`
+	Source          string          `json:"source"`
+	Type            string          `json:"type"`
+	Subject         string          `json:"subject,omitempty"`
+	Time            time.Time       `json:"time"`
+	DataContentType string          `json:"datacontenttype"`
+	Data            json.RawMessage `json:"data,omitempty"`
+}
+
+// NewEvent marshals data into a new event. The id is derived from the source,
+// type and key, so that a transition reported twice has the same id and
+// subscribers can drop the duplicate.
+func NewEvent(source, eventType, subject, key string, data interface{}) (*Event, error) {
+	raw, err := json.Marshal(data)
+	if err != nil {
+		return nil, err
+	}
+
+	sum := sha1.Sum([]byte(source + "/" + eventType + "/" + key))
+	return &Event{
+		SpecVersion:     SpecVersion,
+		ID:              hex.EncodeToString(sum[:]),
+		Source:          source,
+		Type:            eventType,
+		Subject:         subject,
+		Time:            time.Now().UTC(),
+		DataContentType: DataContentType,
+		Data:            raw,
+	}, nil
+}
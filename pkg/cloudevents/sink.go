@@ -0,0 +1,96 @@
+package cloudevents
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Sink delivers events to subscribers
+type Sink interface {
+	Send(ctx context.Context, event *Event) error
+}
+
+// httpSink posts events in binary content mode: attributes go to ce- headers
+// and the data is the request body.
+type httpSink struct {
+	url     string
+	headers map[string]string
+	client  *http.Client
+}
+
+func NewHTTPSink(url string, headers map[string]string) Sink {
+	return &httpSink{
+		url:     url,
+		headers: headers,
+		client:  &http.Client{Timeout: 10 * time.Second},
+	}
+}
+
+func (s *httpSink) Send(ctx context.Context, event *Event) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.url, bytes.NewReader(event.Data))
+	if err != nil {
+		return err
+	}
+
+	for k, v := range s.headers {
+		req.Header.Set(k, v)
+	}
+	req.Header.Set("Content-Type", event.DataContentType)
+	req.Header.Set("ce-specversion", event.SpecVersion)
+	req.Header.Set("ce-id", event.ID)
+	req.Header.Set("ce-source", event.Source)
+	req.Header.Set("ce-type", event.Type)
+	req.Header.Set("ce-time", event.Time.Format(time.RFC3339Nano))
+	if len(event.Subject) > 0 {
+		req.Header.Set("ce-subject", event.Subject)
+	}
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return fmt.Errorf("unexpected status %d: %s", resp.StatusCode, strings.TrimSpace(string(body)))
+	}
+	return nil
+}
+
+// fileSink appends events in structured json format, one per line
+type fileSink struct {
+	mu   sync.Mutex
+	path string
+}
+
+func NewFileSink(path string) Sink {
+	return &fileSink{path: path}
+}
+
+func (s *fileSink) Send(ctx context.Context, event *Event) error {
+	line, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	f, err := os.OpenFile(s.path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	_, err = f.Write(append(line, '\n'))
+	return err
+}
@@ -11,6 +11,7 @@ import (
 	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/types"
 	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
 	"k8s.io/apimachinery/pkg/util/wait"
 	"k8s.io/client-go/kubernetes"
@@ -26,7 +27,11 @@ import (
 	alertclientset "github.com/scitix/aegis/pkg/generated/alert/clientset/versioned"
 	alertInformer "github.com/scitix/aegis/pkg/generated/alert/informers/externalversions/alert/v1alpha1"
 	alertLister "github.com/scitix/aegis/pkg/generated/alert/listers/alert/v1alpha1"
+	"github.com/scitix/aegis/pkg/prom"
+	"github.com/scitix/aegis/pkg/tracing"
 	"github.com/scitix/aegis/tools"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
 
 	wfv1alpha1 "github.com/argoproj/argo-workflows/v3/pkg/apis/workflow/v1alpha1"
 	wfclientset "github.com/argoproj/argo-workflows/v3/pkg/client/clientset/versioned"
@@ -74,6 +79,9 @@ type AlertController struct {
 	// RuleEngineInterface: get ops refs according to alert condition
 	ruleEngineController controller.RuleEngineInterface
 
+	// prometheus client to verify the problem is resolved after ops
+	prometheus *prom.PromAPI
+
 	alertclientset alertclientset.Interface
 
 	// A TTLCache of workflow create/delete each expect to use
@@ -88,8 +96,8 @@ type AlertController struct {
 	// alerts that need to be updated
 	workqueue workqueue.RateLimitingInterface
 
-	// Orphan deleted workflows that still have a tracking finalizer to be removed
-	// orphanqueue workqueue.RateLimitingInterface
+	// Orphan workflows whose alert is gone, to be cleaned
+	orphanqueue workqueue.RateLimitingInterface
 
 	broadcaster record.EventBroadcaster
 	recorder    record.EventRecorder
@@ -99,6 +107,19 @@ type AlertController struct {
 
 	workflowUpdatePeriod time.Duration
 
+	// number of status.history entries kept per alert
+	historySize int
+
+	orphanGC      OrphanGCConfig
+	orphanMetrics OrphanMetricsInterface
+
+	// creates the ticket manager of a node for escalated alerts
+	newTicketManager TicketManagerFunc
+
+	// ops running longer than the SLO are reported, zero disables it
+	opsSLO     time.Duration
+	opsMetrics OpsMetricsInterface
+
 	logger klog.Logger
 }
 
@@ -109,13 +130,27 @@ type AlertController struct {
 // ruleEngineController: rule engine controller, for list correspending ops template
 // wfinformer: argo workflow informer
 // alertinformer: alert informer
+// prometheus: prometheus client for post-remediation verification
+// historySize: number of status.history entries kept per alert
+// orphanGC: garbage collection of workflows whose alert is gone
+// orphanMetrics: metrics of the orphan workflow garbage collection
+// newTicketManager: ticket manager of escalated alert nodes, nil disables tickets
+// opsSLO: ops duration after which alerts are reported as running over SLO
+// opsMetrics: metrics of alerts running past their SLO or deadline
 func NewController(kubeclient kubernetes.Interface,
 	alertclient alertclientset.Interface,
 	workflowclient wfclientset.Interface,
 	ruleEngineController controller.RuleEngineInterface,
 	wfinformer wfInformer.WorkflowInformer,
 	alertinformer alertInformer.AegisAlertInformer,
-	lifecycleControl controller.AegisCallbackInterface) *AlertController {
+	lifecycleControl controller.AegisCallbackInterface,
+	prometheus *prom.PromAPI,
+	historySize int,
+	orphanGC OrphanGCConfig,
+	orphanMetrics OrphanMetricsInterface,
+	newTicketManager TicketManagerFunc,
+	opsSLO time.Duration,
+	opsMetrics OpsMetricsInterface) *AlertController {
 
 	eventBroadcaster := record.NewBroadcaster()
 	eventBroadcaster.StartStructuredLogging(0)
@@ -132,15 +167,22 @@ func NewController(kubeclient kubernetes.Interface,
 		},
 		expectations:         nativecontroller.NewControllerExpectations(),
 		ruleEngineController: ruleEngineController,
+		prometheus:           prometheus,
 		alertLister:          alertinformer.Lister(),
 		workflowLister:       wfinformer.Lister(),
 		workqueue:            workqueue.NewNamedRateLimitingQueue(workqueue.DefaultControllerRateLimiter(), "alerts"),
-		// orphanqueue:          workqueue.NewNamedRateLimitingQueue(workqueue.DefaultControllerRateLimiter(), "alert_orphan_workflows"),
+		orphanqueue:          workqueue.NewNamedRateLimitingQueue(workqueue.DefaultControllerRateLimiter(), "alert_orphan_workflows"),
 		broadcaster:          eventBroadcaster,
 		recorder:             recorder,
 		alertSynced:          alertinformer.Informer().HasSynced,
 		workflowSynced:       wfinformer.Informer().HasSynced,
 		workflowUpdatePeriod: workflowDefaultUpdatePeriod,
+		historySize:          historySize,
+		orphanGC:             orphanGC,
+		orphanMetrics:        orphanMetrics,
+		newTicketManager:     newTicketManager,
+		opsSLO:               opsSLO,
+		opsMetrics:           opsMetrics,
 		logger:               klog.NewKlogr(),
 	}
 
@@ -168,6 +210,7 @@ func (c *AlertController) Run(ctx context.Context, workers int) error {
 	defer utilruntime.HandleCrash()
 	defer c.broadcaster.Shutdown()
 	defer c.workqueue.ShutDown()
+	defer c.orphanqueue.ShutDown()
 
 	klog.Info("Starting Alert controller")
 	defer klog.Info("Shutting down Alert controller.")
@@ -181,7 +224,10 @@ func (c *AlertController) Run(ctx context.Context, workers int) error {
 	for i := 0; i < workers; i++ {
 		go wait.UntilWithContext(ctx, c.runWorker, time.Second)
 	}
-	// go wait.UntilWithContext(ctx, c.orphanWorkflow, time.Second)
+	if c.orphanGC.Enable {
+		go wait.UntilWithContext(ctx, c.orphanWorkflow, time.Second)
+		go wait.UntilWithContext(ctx, c.gcOrphanWorkflows, c.orphanGC.period())
+	}
 	klog.Info("Started workers")
 
 	<-ctx.Done()
@@ -412,18 +458,22 @@ func (c *AlertController) deleteAlert(obj interface{}) {
 	klog.Infof("enqueueing alert %s for deleted", alertKey)
 	c.enqueueController(obj, true)
 
+	if !c.orphanGC.Enable {
+		return
+	}
+
 	// Listing workflows shouldn't really fail, as we are just querying the informer cache.
-	// selector, err := metav1.LabelSelectorAsSelector(alert.Spec.Selector)
-	// if err != nil {
-	// 	utilruntime.HandleError(fmt.Errorf("parsing deleted alert selector: %v", err))
-	// 	return
-	// }
-	// workflows, _ := c.workflowLister.Workflows(alert.Namespace).List(selector)
-	// for _, workflow := range workflows {
-	// 	if metav1.IsControlledBy(workflow, alert) {
-	// 		c.enqueueOrphanWorkflow(workflow)
-	// 	}
-	// }
+	selector, err := metav1.LabelSelectorAsSelector(alert.Spec.Selector)
+	if err != nil {
+		utilruntime.HandleError(fmt.Errorf("parsing deleted alert selector: %v", err))
+		return
+	}
+	workflows, _ := c.workflowLister.Workflows(alert.Namespace).List(selector)
+	for _, workflow := range workflows {
+		if metav1.IsControlledBy(workflow, alert) {
+			c.enqueueOrphanWorkflow(workflow)
+		}
+	}
 }
 
 func (c *AlertController) enqueueController(obj interface{}, immediate bool) {
@@ -443,69 +493,6 @@ func (c *AlertController) enqueueControllerDelayed(obj interface{}, immediate bo
 	c.workqueue.AddAfter(key, delay)
 }
 
-// func (c *AlertController) enqueueOrphanWorkflow(obj interface{}) {
-// 	key, err := controller.KeyFunc(obj)
-// 	if err != nil {
-// 		utilruntime.HandleError(fmt.Errorf("Couldn't get key for object %+v: %v", obj, err))
-// 		return
-// 	}
-
-// 	c.orphanqueue.Add(key)
-// }
-
-// func (c *AlertController) orphanWorkflow(ctx context.Context) {
-// 	for c.processNexOrphanWorkflow(ctx) {
-
-// 	}
-// }
-
-// func (c *AlertController) processNexOrphanWorkflow(ctx context.Context) bool {
-// 	key, quit := c.orphanqueue.Get()
-// 	if quit {
-// 		return false
-// 	}
-// 	defer c.orphanqueue.Done(key)
-// 	err := c.syncOrphanWorkflow(ctx, key.(string))
-// 	if err != nil {
-// 		utilruntime.HandleError(fmt.Errorf("Error syncing orphan workflow: %v", err))
-// 		c.orphanqueue.AddRateLimited(key)
-// 	} else {
-// 		c.orphanqueue.Forget(key)
-// 	}
-
-// 	return true
-// }
-
-// syncOrphanWorkflow removes the tracking finalizer from an orphan workflow if found.
-// func (c *AlertController) syncOrphanWorkflow(ctx context.Context, key string) error {
-// 	startTime := time.Now()
-// 	defer func() {
-// 		klog.V(4).Infof("Finished syncing orphan workflow %q (%v)", key, time.Since(startTime))
-// 	}()
-
-// 	ns, name, err := cache.SplitMetaNamespaceKey(key)
-// 	if err != nil {
-// 		return err
-// 	}
-
-// 	sharedWorfklow, err := c.workflowLister.Workflows(ns).Get(name)
-// 	if err != nil {
-// 		if apierrors.IsNotFound(err) {
-// 			klog.V(4).Infof("Orphan workflow has been deleted: %v", key)
-// 			return nil
-// 		}
-// 	}
-
-// 	if controllerRef := metav1.GetControllerOf(sharedWorfklow); controllerRef != nil {
-// 		alert := c.resolveControllerRef(sharedWorfklow.Namespace, controllerRef)
-// 		if alert != nil && !IsAlertOpsFinished(alert) {
-// 			return nil
-// 		}
-// 	}
-
-// 	return nil
-// }
-
 // getWorkflowsForAlert return the workflow set that belong to the alert
 func (c *AlertController) getWorkflowsForAlert(ctx context.Context, alert *alertv1alpha1.AegisAlert, withFinalizer bool) ([]*wfv1alpha1.Workflow, error) {
 	labelMap, err := metav1.LabelSelectorAsMap(alert.Spec.Selector)
@@ -567,7 +554,20 @@ func (c *AlertController) syncAlert(ctx context.Context, key string) (forget boo
 	// If alert finished previously. we don't want to redo the termination
 	if IsAlertOpsFinished(&alert) {
 		if IsAlertOpsSucceed(&alert) {
-			go callback(c.lifecycleControl.OnOpsWorkflowSucceed, &alert, key)
+			result, err := c.syncAlertVerification(ctx, &alert)
+			if err != nil {
+				return false, err
+			}
+			switch result {
+			case verificationPending:
+				// hold callbacks and ttl until the problem is verified
+				return true, nil
+			case verificationFailed:
+				// the workflow succeeded but the problem persists
+				go callback(c.lifecycleControl.OnOpsWorkflowFailed, &alert, key)
+			default:
+				go callback(c.lifecycleControl.OnOpsWorkflowSucceed, &alert, key)
+			}
 		}
 
 		if IsAlertOpsFailed(&alert) {
@@ -595,6 +595,7 @@ func (c *AlertController) syncAlert(ctx context.Context, key string) (forget boo
 	if err != nil {
 		return false, nil
 	}
+	workflows = excludeRetriedWorkflows(&alert, workflows)
 
 	activeWorkflow, succeededWorkflow, failedWorkflow := controller.FilterActiveWorkflow(workflows), controller.FilterSucceededWorkflow(workflows), controller.FilterFailedWorkflow(workflows)
 	active, succeeded, failed := int32(len(activeWorkflow)), int32(len(succeededWorkflow)), int32(len(failedWorkflow))
@@ -613,53 +614,102 @@ func (c *AlertController) syncAlert(ctx context.Context, key string) (forget boo
 		now := metav1.Now()
 		alert.Status.OpsStatus.StartTime = &now
 		alert.Status.OpsStatus.Status = alertv1alpha1.OpsStatusRunning
+	} else if total > 0 && alert.Status.OpsStatus.Status == alertv1alpha1.OpsStatusPending {
+		// workflow recreated by retry
+		alert.Status.OpsStatus.Status = alertv1alpha1.OpsStatusRunning
 	}
 
 	var createWorkflowErr error
 	alertOpsFailed := false
 	var failureReason string
 	var failureMessage string
+	var failureWorkflow string
+
+	deadlineExceeded := false
+	if failed == 0 && !(total > 0 && total <= succeeded) {
+		var message string
+		if deadlineExceeded, message, err = c.syncAlertDeadline(ctx, &alert, activeWorkflow); err != nil {
+			return false, err
+		}
+		if deadlineExceeded {
+			alertOpsFailed = true
+			failureReason = reasonDeadlineExceeded
+			failureMessage = message
+			failureWorkflow = workflowNames(activeWorkflow)
+		}
+	}
 
 	// any failed lead to fail status
 	if failed > 0 {
 		alertOpsFailed = true
 		failureReason = "WorkflowFailed"
 		failureMessage = "Workflow for alert has failed"
+		if message := finalNodeMessage(failedWorkflow[0]); len(message) > 0 {
+			failureMessage = fmt.Sprintf("%s: %s", failureMessage, message)
+		}
+		failureWorkflow = failedWorkflow[0].Name
 	}
 
 	alertConditionChanged := false
-	if alertOpsFailed {
-		alert.Status.Conditions = append(alert.Status.Conditions, *newCondition(alertv1alpha1.AlertFailedOpsWrofklow, v1.ConditionTrue, failureReason, failureMessage))
+	if alertOpsFailed && !deadlineExceeded && c.retryAlertOps(&alert, failedWorkflow, failureReason, failureMessage) {
+		alertConditionChanged = true
+	} else if alertOpsFailed {
+		c.recordCondition(&alert, alertv1alpha1.AlertFailedOpsWrofklow, v1.ConditionTrue, failureReason, failureMessage, failureWorkflow)
 		alertConditionChanged = true
 		now := metav1.Now()
 		alert.Status.OpsStatus.CompletionTime = &now
 		alert.Status.OpsStatus.Status = alertv1alpha1.OpsStatusFailed
+		alert.Status.OpsStatus.Outputs = collectWorkflowOutputs(append(succeededWorkflow, failedWorkflow...))
 		c.recorder.Event(&alert, v1.EventTypeWarning, failureReason, failureMessage)
 	} else {
+		backoff := alertRetryBackoff(&alert)
+		if backoff > 0 && total == 0 {
+			klog.V(4).Infof("Alert %v waits %v before retrying ops workflow", key, backoff)
+			c.enqueueControllerDelayed(&alert, false, backoff)
+			return true, nil
+		}
+
+		escalated := false
 		if alertNeedSync && total == 0 {
-			total, alert.Status.OpsStatus.TriggerStatus, createWorkflowErr = c.createWorkflowForAlert(ctx, &alert)
-			if createWorkflowErr == nil {
+			if policy, message := c.escalateAlert(ctx, &alert); policy != nil {
+				escalated = true
+				alert.Status.OpsStatus.TriggerStatus = alertv1alpha1.OpsTriggerStatusEscalated
+				c.recordCondition(&alert, alertv1alpha1.AlertEscalated, v1.ConditionTrue, "TooManyFailures", message, "")
+				alertConditionChanged = true
+				c.recorder.Event(&alert, v1.EventTypeWarning, "Escalated", message)
+				if policy.HasAction(controller.EscalationActionNotify) {
+					go callback(c.onOpsEscalated, &alert, key)
+				}
+			} else {
+				total, alert.Status.OpsStatus.TriggerStatus, createWorkflowErr = c.createWorkflowForAlert(ctx, &alert)
+			}
+			if !escalated && createWorkflowErr == nil {
 				// alert.Status.Conditions = append(alert.Status.Conditions, *newCondition(alertv1alpha1.AlertSucceededCreateOpsWorkflow, v1.ConditionTrue, "", ""))
 				alert.Status.OpsStatus.Status = alertv1alpha1.OpsStatusPending
 				alert.Status.OpsStatus.Total = &total
+				alert.Status.OpsStatus.NextRetryTime = nil
 				alertConditionChanged = true
 				c.recorder.Event(&alert, v1.EventTypeNormal, "SucceededCreateOpsWorkflow", "Alert succeeded create ops workflow")
 			}
 		}
 
-		if createWorkflowErr != nil {
-			alert.Status.Conditions = append(alert.Status.Conditions, *newCondition(alertv1alpha1.AlertFailedCreateOpsWorkflow, v1.ConditionTrue, "", ""))
+		if createWorkflowErr != nil && alert.Status.OpsStatus.TriggerStatus == alertv1alpha1.OpsTriggerStatusTriggerFailed &&
+			c.retryAlertOps(&alert, nil, string(alertv1alpha1.OpsTriggerStatusTriggerFailed), createWorkflowErr.Error()) {
+			alertConditionChanged = true
+		} else if createWorkflowErr != nil {
+			c.recordCondition(&alert, alertv1alpha1.AlertFailedCreateOpsWorkflow, v1.ConditionTrue, string(alert.Status.OpsStatus.TriggerStatus), createWorkflowErr.Error(), "")
 			alertConditionChanged = true
 			c.recorder.Event(&alert, v1.EventTypeWarning, "FailedCreateOpsWorkflow", fmt.Sprintf("Alert failed create ops workflow: %v", createWorkflowErr))
 		}
 
-		complete := (total <= succeeded && createWorkflowErr == nil)
+		complete := (total <= succeeded && createWorkflowErr == nil && !escalated)
 		if complete {
-			alert.Status.Conditions = append(alert.Status.Conditions, *newCondition(alertv1alpha1.AlertCompleteOpsWrofklow, v1.ConditionTrue, "", ""))
+			c.recordCondition(&alert, alertv1alpha1.AlertCompleteOpsWrofklow, v1.ConditionTrue, "WorkflowSucceeded", "Alert ops completed", workflowNames(succeededWorkflow))
 			alertConditionChanged = true
 			now := metav1.Now()
 			alert.Status.OpsStatus.CompletionTime = &now
 			alert.Status.OpsStatus.Status = alertv1alpha1.OpsStatusSucceeded
+			alert.Status.OpsStatus.Outputs = collectWorkflowOutputs(succeededWorkflow)
 			c.recorder.Event(&alert, v1.EventTypeNormal, "Completed", "Alert Ops completed")
 		}
 	}
@@ -726,17 +776,25 @@ func (c *AlertController) createWorkflowForAlert(ctx context.Context, alert *ale
 	}
 
 	if alertUntriggerWorkflow(alert) {
+		// continue the trace of the alert creation
+		var span trace.Span
+		ctx, span = tracing.Tracer().Start(tracing.ExtractAnnotations(ctx, alert.Annotations), "AlertController.syncAlert", trace.WithAttributes(
+			attribute.String("aegis.alert", alertKey),
+			attribute.String("aegis.alert.type", alert.Spec.Type),
+		))
+		defer func() {
+			span.SetAttributes(attribute.String("aegis.alert.trigger_status", string(triggerStatus)))
+			tracing.End(span, err)
+		}()
+
 		// query rule engine to get template refs
-		rule := &controller.MatchRule{
-			Labels: alert.Labels,
-			Condition: &controller.Condition{
-				Type:   alert.Spec.Type,
-				Status: string(alert.Spec.Status),
-			},
-		}
+		rule := newMatchRule(alert)
 		var templateRefs []*v1.ObjectReference
 
+		_, matchSpan := tracing.Tracer().Start(ctx, "RuleEngine.GetTemplateRefs")
 		templateRefs, err = c.ruleEngineController.GetTemplateRefs(rule)
+		matchSpan.SetAttributes(attribute.Int("aegis.rule.matched", len(templateRefs)))
+		tracing.End(matchSpan, err)
 		if err != nil {
 			utilruntime.HandleError(fmt.Errorf("Get workflow template refs for alert %v: %v", alert, err))
 			triggerStatus = alertv1alpha1.OpsTriggerStatusRuleError
@@ -758,9 +816,13 @@ func (c *AlertController) createWorkflowForAlert(ctx context.Context, alert *ale
 			return
 		}
 
+		_, renderSpan := tracing.Tracer().Start(ctx, "RenderWorkflowTemplate", trace.WithAttributes(
+			attribute.String("aegis.template", templateRefs[0].Namespace+"/"+templateRefs[0].Name),
+		))
 		var tpl string
 		tpl, err = c.ruleEngineController.GetTemplateContentByRefs(templateRefs[0])
 		if err != nil {
+			tracing.End(renderSpan, err)
 			utilruntime.HandleError(fmt.Errorf("No workflow template(%v) found for alert: %v", templateRefs[0], alert))
 			triggerStatus = alertv1alpha1.OpsTriggerStatusTemplateNotFound
 			go callback(c.lifecycleControl.OnNoOpsTemplate, alert, alertKey)
@@ -768,8 +830,10 @@ func (c *AlertController) createWorkflowForAlert(ctx context.Context, alert *ale
 		}
 
 		parameters := prepareWorkflowParameters(alert)
+		parameters["TraceParent"] = tracing.TraceParent(ctx)
 		var yamlContent string
 		yamlContent, err = tools.RenderWorkflowTemplate(tpl, parameters)
+		tracing.End(renderSpan, err)
 		if err != nil {
 			utilruntime.HandleError(fmt.Errorf("Invalid workflow template for alert %v: %v", alert, err))
 			triggerStatus = alertv1alpha1.OpsTriggerStatusTemplateInvalid
@@ -779,7 +843,9 @@ func (c *AlertController) createWorkflowForAlert(ctx context.Context, alert *ale
 		}
 
 		c.expectations.ExpectCreations(c.logger, alertKey, int(total))
-		err = c.workflowControl.CreateWorkflowWithPlainContent(ctx, alert.Namespace, yamlContent, alert, metav1.NewControllerRef(alert, controllerKind))
+		submitCtx, submitSpan := tracing.Tracer().Start(ctx, "WorkflowControl.CreateWorkflow")
+		err = c.workflowControl.CreateWorkflowWithPlainContent(submitCtx, alert.Namespace, yamlContent, alert, metav1.NewControllerRef(alert, controllerKind))
+		tracing.End(submitSpan, err)
 		if err != nil {
 			utilruntime.HandleError(err)
 			klog.V(2).Infof("Failed creation, decrementing expectation for alert %s", alertKey)
@@ -791,6 +857,11 @@ func (c *AlertController) createWorkflowForAlert(ctx context.Context, alert *ale
 			return
 		}
 
+		alert.Status.OpsStatus.Template = templateRefs[0].Namespace + "/" + templateRefs[0].Name
+		if policy := c.getOpsPolicy(alert); policy != nil {
+			alert.Status.OpsStatus.Rule = policy.Rule
+		}
+
 		go c.ruleEngineController.SucceedExecuteTemplateCallback(templateRefs[0])
 		go callback(c.lifecycleControl.OnSucceedCreateOpsWorkflow, alert, alertKey)
 		triggerStatus = alertv1alpha1.OpsTriggerStatusTriggered
@@ -798,27 +869,20 @@ func (c *AlertController) createWorkflowForAlert(ctx context.Context, alert *ale
 	return
 }
 
-// func removeTrackingFinalizerPatch(workflow *wfv1alpha1.Workflow) []byte {
-// 	if !hasAlertTrackingFinalizer(workflow) {
-// 		return nil
-// 	}
-// 	patch := map[string]interface{}{
-// 		"metadata": map[string]interface{}{
-// 			"$deleteFromPrimitiveList/finalizers": []string{alertv1alpha1.AlertTrackingFinalizer},
-// 		},
-// 	}
-// 	patchBytes, _ := json.Marshal(patch)
-// 	return patchBytes
-// }
-
+// updateAlertStatus merge-patches the status subresource with the fields
+// changed since the cached alert, so concurrent count/status patches are kept.
 func (c *AlertController) updateAlertStatus(ctx context.Context, alert *alertv1alpha1.AegisAlert) error {
-	newAlert, err := c.alertclientset.AegisV1alpha1().AegisAlerts(alert.Namespace).Get(ctx, alert.Name, metav1.GetOptions{})
+	old, err := c.alertLister.AegisAlerts(alert.Namespace).Get(alert.Name)
 	if err != nil {
 		return err
 	}
 
-	newAlert.Status = alert.Status
-	_, err = c.alertclientset.AegisV1alpha1().AegisAlerts(alert.Namespace).Update(ctx, newAlert, metav1.UpdateOptions{})
+	patch, err := statusMergePatch(old, alert)
+	if err != nil || patch == nil {
+		return err
+	}
+
+	_, err = c.alertclientset.AegisV1alpha1().AegisAlerts(alert.Namespace).Patch(ctx, alert.Name, types.MergePatchType, patch, metav1.PatchOptions{}, "status")
 	return err
 }
 
@@ -0,0 +1,93 @@
+package alert
+
+import (
+	"encoding/json"
+
+	jsonpatch "github.com/evanphx/json-patch"
+	alertv1alpha1 "github.com/scitix/aegis/pkg/apis/alert/v1alpha1"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// getCondition returns the condition of the given type, nil if not present
+func getCondition(status *alertv1alpha1.AegisAlertStatus, conditionType alertv1alpha1.AlertOpsConditionType) *alertv1alpha1.AlertOpsCondition {
+	for i := range status.Conditions {
+		if status.Conditions[i].Type == conditionType {
+			return &status.Conditions[i]
+		}
+	}
+	return nil
+}
+
+// setCondition keeps one condition per type. LastTransitionTime only moves when
+// the status changes. It returns false if nothing but the probe time changed.
+func setCondition(status *alertv1alpha1.AegisAlertStatus, condition *alertv1alpha1.AlertOpsCondition) bool {
+	existing := getCondition(status, condition.Type)
+	if existing == nil {
+		status.Conditions = append(status.Conditions, *condition)
+		return true
+	}
+
+	changed := existing.Reason != condition.Reason || existing.Message != condition.Message
+	if existing.Status != condition.Status {
+		existing.Status = condition.Status
+		existing.LastTransitionTime = condition.LastTransitionTime
+		changed = true
+	}
+	existing.Reason = condition.Reason
+	existing.Message = condition.Message
+	existing.LastProbeTime = condition.LastProbeTime
+	return changed
+}
+
+// appendHistory records the entry, dropping the oldest ones beyond size
+func appendHistory(status *alertv1alpha1.AegisAlertStatus, entry alertv1alpha1.AlertOpsHistory, size int) {
+	if size <= 0 {
+		size = alertv1alpha1.DefaultAlertHistorySize
+	}
+
+	status.History = append(status.History, entry)
+	if over := len(status.History) - size; over > 0 {
+		status.History = append([]alertv1alpha1.AlertOpsHistory(nil), status.History[over:]...)
+	}
+}
+
+// recordCondition sets the condition and records the change in the alert history
+func (c *AlertController) recordCondition(alert *alertv1alpha1.AegisAlert, conditionType alertv1alpha1.AlertOpsConditionType, status v1.ConditionStatus, reason, message, workflow string) bool {
+	if !setCondition(&alert.Status, newCondition(conditionType, status, reason, message)) {
+		return false
+	}
+
+	appendHistory(&alert.Status, alertv1alpha1.AlertOpsHistory{
+		Time:     metav1.Now(),
+		Type:     conditionType,
+		Reason:   reason,
+		Message:  message,
+		Workflow: workflow,
+	}, c.historySize)
+	return true
+}
+
+// statusMergePatch returns the json merge patch from the old to the new alert
+// status, nil if the status is unchanged.
+func statusMergePatch(old, new *alertv1alpha1.AegisAlert) ([]byte, error) {
+	oldData, err := json.Marshal(map[string]interface{}{"status": old.Status})
+	if err != nil {
+		return nil, err
+	}
+
+	newData, err := json.Marshal(map[string]interface{}{"status": new.Status})
+	if err != nil {
+		return nil, err
+	}
+
+	patch, err := jsonpatch.CreateMergePatch(oldData, newData)
+	if err != nil {
+		return nil, err
+	}
+
+	if string(patch) == "{}" {
+		return nil, nil
+	}
+	return patch, nil
+}
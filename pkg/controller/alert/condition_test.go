@@ -0,0 +1,90 @@
+package alert
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	v1alpha1 "github.com/scitix/aegis/pkg/apis/alert/v1alpha1"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestSetCondition(t *testing.T) {
+	status := &v1alpha1.AegisAlertStatus{}
+	first := newCondition(v1alpha1.AlertRetryOpsWorkflow, v1.ConditionTrue, "WorkflowFailed", "retry 1")
+	first.LastTransitionTime = metav1.NewTime(time.Now().Add(-time.Hour))
+	if !setCondition(status, first) {
+		t.Fatal("expected new condition to change status")
+	}
+
+	// same condition is only probed
+	if setCondition(status, newCondition(v1alpha1.AlertRetryOpsWorkflow, v1.ConditionTrue, "WorkflowFailed", "retry 1")) {
+		t.Error("expected unchanged condition")
+	}
+
+	// message changed, status not
+	if !setCondition(status, newCondition(v1alpha1.AlertRetryOpsWorkflow, v1.ConditionTrue, "WorkflowFailed", "retry 2")) {
+		t.Error("expected changed message")
+	}
+	if len(status.Conditions) != 1 {
+		t.Fatalf("expected 1 condition, got %d", len(status.Conditions))
+	}
+	cond := status.Conditions[0]
+	if cond.Message != "retry 2" || !cond.LastTransitionTime.Equal(&first.LastTransitionTime) {
+		t.Errorf("unexpected condition %+v", cond)
+	}
+
+	// status changed
+	setCondition(status, newCondition(v1alpha1.AlertRetryOpsWorkflow, v1.ConditionFalse, "", ""))
+	cond = status.Conditions[0]
+	if cond.Status != v1.ConditionFalse || cond.LastTransitionTime.Equal(&first.LastTransitionTime) {
+		t.Errorf("expected transition, got %+v", cond)
+	}
+
+	setCondition(status, newCondition(v1alpha1.AlertFailedOpsWrofklow, v1.ConditionTrue, "WorkflowFailed", ""))
+	if len(status.Conditions) != 2 {
+		t.Errorf("expected 2 conditions, got %d", len(status.Conditions))
+	}
+}
+
+func TestRecordConditionHistory(t *testing.T) {
+	c := &AlertController{historySize: 3}
+	alert := &v1alpha1.AegisAlert{}
+	for i := 0; i < 5; i++ {
+		c.recordCondition(alert, v1alpha1.AlertRetryOpsWorkflow, v1.ConditionTrue, "WorkflowFailed", fmt.Sprintf("retry %d", i), fmt.Sprintf("wf-%d", i))
+	}
+	// unchanged condition is not recorded
+	c.recordCondition(alert, v1alpha1.AlertRetryOpsWorkflow, v1.ConditionTrue, "WorkflowFailed", "retry 4", "wf-4")
+
+	if len(alert.Status.Conditions) != 1 {
+		t.Errorf("expected 1 condition, got %d", len(alert.Status.Conditions))
+	}
+	if len(alert.Status.History) != 3 {
+		t.Fatalf("expected 3 history entries, got %d", len(alert.Status.History))
+	}
+	for i, entry := range alert.Status.History {
+		if entry.Workflow != fmt.Sprintf("wf-%d", i+2) || entry.Message != fmt.Sprintf("retry %d", i+2) {
+			t.Errorf("unexpected history entry %d: %+v", i, entry)
+		}
+	}
+}
+
+func TestStatusMergePatch(t *testing.T) {
+	old := &v1alpha1.AegisAlert{
+		Status: v1alpha1.AegisAlertStatus{Count: 3, Status: "Firing"},
+	}
+	alert := old.DeepCopy()
+	if patch, err := statusMergePatch(old, alert); err != nil || patch != nil {
+		t.Fatalf("expected no patch, got %s, %v", patch, err)
+	}
+
+	alert.Status.OpsStatus.Status = v1alpha1.OpsStatusRunning
+	patch, err := statusMergePatch(old, alert)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(patch) != `{"status":{"opsStatus":{"alertOpsStatus":"Running"}}}` {
+		t.Errorf("unexpected patch %s", patch)
+	}
+}
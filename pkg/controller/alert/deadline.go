@@ -0,0 +1,73 @@
+package alert
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	wfv1alpha1 "github.com/argoproj/argo-workflows/v3/pkg/apis/workflow/v1alpha1"
+	alertv1alpha1 "github.com/scitix/aegis/pkg/apis/alert/v1alpha1"
+	"k8s.io/klog/v2"
+)
+
+const reasonDeadlineExceeded = "DeadlineExceeded"
+
+// OpsMetricsInterface records the alerts whose ops run for too long
+type OpsMetricsInterface interface {
+	RecordOpsDeadlineExceeded(alert *alertv1alpha1.AegisAlert)
+	RecordOpsSLOOverrun(alert *alertv1alpha1.AegisAlert)
+}
+
+// syncAlertDeadline checks how long the ops of the alert have been running.
+// Alerts running longer than the SLO are reported, and the active workflows
+// are terminated once the deadline of the rule is exceeded, in which case it
+// returns true with the failure message. Otherwise the alert is requeued for
+// the next check.
+func (c *AlertController) syncAlertDeadline(ctx context.Context, alert *alertv1alpha1.AegisAlert, activeWorkflows []*wfv1alpha1.Workflow) (bool, string, error) {
+	if alert.Status.OpsStatus.StartTime == nil {
+		return false, "", nil
+	}
+
+	elapsed := time.Since(alert.Status.OpsStatus.StartTime.Time)
+	var next time.Duration
+
+	if c.opsSLO > 0 {
+		if elapsed >= c.opsSLO {
+			if c.opsMetrics != nil {
+				c.opsMetrics.RecordOpsSLOOverrun(alert)
+			}
+		} else {
+			next = c.opsSLO - elapsed
+		}
+	}
+
+	var deadline time.Duration
+	if policy := c.getOpsPolicy(alert); policy != nil {
+		deadline = policy.ActiveDeadline
+	}
+
+	if deadline > 0 && elapsed >= deadline {
+		for _, wf := range activeWorkflows {
+			if wf.Spec.Shutdown.Enabled() {
+				continue
+			}
+			if err := c.workflowControl.MergePatchWorkflow(ctx, wf.Namespace, wf.Name, terminateWorkflowPatch()); err != nil {
+				return false, "", fmt.Errorf("terminate workflow %s/%s after deadline: %v", wf.Namespace, wf.Name, err)
+			}
+			klog.Infof("Terminated workflow %s/%s of alert %s/%s after deadline %v", wf.Namespace, wf.Name, alert.Namespace, alert.Name, deadline)
+		}
+
+		if c.opsMetrics != nil {
+			c.opsMetrics.RecordOpsDeadlineExceeded(alert)
+		}
+		return true, fmt.Sprintf("Alert ops exceeded the active deadline %v", deadline), nil
+	}
+
+	if deadline > 0 && (next == 0 || deadline-elapsed < next) {
+		next = deadline - elapsed
+	}
+	if next > 0 {
+		c.enqueueControllerDelayed(alert, false, next)
+	}
+	return false, "", nil
+}
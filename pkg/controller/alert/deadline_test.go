@@ -0,0 +1,135 @@
+package alert
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	wfv1alpha1 "github.com/argoproj/argo-workflows/v3/pkg/apis/workflow/v1alpha1"
+	wfLister "github.com/argoproj/argo-workflows/v3/pkg/client/listers/workflow/v1alpha1"
+	alertv1alpha1 "github.com/scitix/aegis/pkg/apis/alert/v1alpha1"
+	"github.com/scitix/aegis/pkg/controller"
+	alertLister "github.com/scitix/aegis/pkg/generated/alert/listers/alert/v1alpha1"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/client-go/tools/record"
+	"k8s.io/client-go/util/workqueue"
+	nativecontroller "k8s.io/kubernetes/pkg/controller"
+)
+
+type fakeOpsMetrics struct {
+	overrun  int
+	exceeded int
+}
+
+func (f *fakeOpsMetrics) RecordOpsSLOOverrun(alert *alertv1alpha1.AegisAlert) {
+	f.overrun++
+}
+
+func (f *fakeOpsMetrics) RecordOpsDeadlineExceeded(alert *alertv1alpha1.AegisAlert) {
+	f.exceeded++
+}
+
+func newDeadlineTestController(alert *alertv1alpha1.AegisAlert, workflow *wfv1alpha1.Workflow, deadline, slo time.Duration) (*AlertController, *fakeWorkflowControl, *fakeOpsMetrics, *alertv1alpha1.AegisAlert) {
+	alertIndexer := cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{cache.NamespaceIndex: cache.MetaNamespaceIndexFunc})
+	alertIndexer.Add(alert)
+	wfIndexer := cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{cache.NamespaceIndex: cache.MetaNamespaceIndexFunc})
+	wfIndexer.Add(workflow)
+
+	control := &fakeWorkflowControl{patches: map[string]string{}}
+	metrics := &fakeOpsMetrics{}
+	updated := &alertv1alpha1.AegisAlert{}
+	c := &AlertController{
+		workflowControl:      control,
+		ruleEngineController: &fakeRuleEngine{policy: &controller.OpsPolicy{ActiveDeadline: deadline}},
+		expectations:         nativecontroller.NewControllerExpectations(),
+		alertLister:          alertLister.NewAegisAlertLister(alertIndexer),
+		workflowLister:       wfLister.NewWorkflowLister(wfIndexer),
+		workqueue:            workqueue.NewNamedRateLimitingQueue(workqueue.DefaultControllerRateLimiter(), "test_alerts"),
+		recorder:             record.NewFakeRecorder(10),
+		opsSLO:               slo,
+		opsMetrics:           metrics,
+	}
+	c.updateStatusHandler = func(ctx context.Context, alert *alertv1alpha1.AegisAlert) error {
+		alert.DeepCopyInto(updated)
+		return nil
+	}
+	return c, control, metrics, updated
+}
+
+func newDeadlineTestAlert(startedAt time.Time) (*alertv1alpha1.AegisAlert, *wfv1alpha1.Workflow) {
+	total := int32(1)
+	start := metav1.NewTime(startedAt)
+	alert := &alertv1alpha1.AegisAlert{
+		ObjectMeta: metav1.ObjectMeta{Name: "drain", Namespace: "monitoring", UID: "uid-1"},
+		Spec: alertv1alpha1.AegisAlertSpec{
+			Type:     "NodeNotReady",
+			Selector: &metav1.LabelSelector{MatchLabels: map[string]string{"uuid": "uid-1"}},
+		},
+		Status: alertv1alpha1.AegisAlertStatus{
+			StartTime: &start,
+			OpsStatus: alertv1alpha1.AegisAlertOpsStatus{
+				Total:     &total,
+				StartTime: &start,
+				Status:    alertv1alpha1.OpsStatusRunning,
+			},
+		},
+	}
+
+	workflow := &wfv1alpha1.Workflow{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:            "drain-x7k2p",
+			Namespace:       "monitoring",
+			Labels:          map[string]string{"uuid": "uid-1"},
+			OwnerReferences: []metav1.OwnerReference{*metav1.NewControllerRef(alert, controllerKind)},
+		},
+		Status: wfv1alpha1.WorkflowStatus{Phase: wfv1alpha1.WorkflowRunning},
+	}
+	return alert, workflow
+}
+
+func TestSyncAlertDeadlineExceeded(t *testing.T) {
+	alert, workflow := newDeadlineTestAlert(time.Now().Add(-2 * time.Hour))
+	c, control, metrics, updated := newDeadlineTestController(alert, workflow, time.Hour, 30*time.Minute)
+
+	if _, err := c.syncAlert(context.Background(), "monitoring/drain"); err != nil {
+		t.Fatal(err)
+	}
+
+	if control.patches["drain-x7k2p"] != `{"spec":{"shutdown":"Terminate"}}` {
+		t.Errorf("expected workflow terminated, got patches %v", control.patches)
+	}
+	if updated.Status.OpsStatus.Status != alertv1alpha1.OpsStatusFailed || updated.Status.OpsStatus.CompletionTime == nil {
+		t.Errorf("expected alert ops failed, got %+v", updated.Status.OpsStatus)
+	}
+	condition := getCondition(&updated.Status, alertv1alpha1.AlertFailedOpsWrofklow)
+	if condition == nil || condition.Status != v1.ConditionTrue || condition.Reason != reasonDeadlineExceeded {
+		t.Errorf("expected failed condition with reason %s, got %+v", reasonDeadlineExceeded, condition)
+	}
+	if !IsAlertOpsFailed(updated) {
+		t.Error("expected alert to be failed")
+	}
+	if metrics.exceeded != 1 || metrics.overrun != 1 {
+		t.Errorf("expected deadline exceeded and slo overrun recorded, got %+v", metrics)
+	}
+}
+
+func TestSyncAlertWithinDeadline(t *testing.T) {
+	alert, workflow := newDeadlineTestAlert(time.Now().Add(-10 * time.Minute))
+	c, control, metrics, updated := newDeadlineTestController(alert, workflow, time.Hour, 30*time.Minute)
+
+	if _, err := c.syncAlert(context.Background(), "monitoring/drain"); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(control.patches) != 0 {
+		t.Errorf("expected no workflow terminated, got patches %v", control.patches)
+	}
+	if IsAlertOpsFailed(updated) {
+		t.Error("expected alert not failed")
+	}
+	if metrics.exceeded != 0 || metrics.overrun != 0 {
+		t.Errorf("expected nothing recorded, got %+v", metrics)
+	}
+}
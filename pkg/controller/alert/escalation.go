@@ -0,0 +1,203 @@
+package alert
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	alertv1alpha1 "github.com/scitix/aegis/pkg/apis/alert/v1alpha1"
+	"github.com/scitix/aegis/pkg/controller"
+	"github.com/scitix/aegis/pkg/prom"
+	"github.com/scitix/aegis/pkg/ticketmodel"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/types"
+	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
+	"k8s.io/klog/v2"
+)
+
+const (
+	nodeDisableLabel = "aegis.io/disable"
+
+	escalationHardwareType = "unknown"
+)
+
+// TicketManagerFunc creates the ticket manager of a node
+type TicketManagerFunc func(ctx context.Context, node *v1.Node) (ticketmodel.TicketManagerInterface, error)
+
+// escalateAlert checks the escalation policy of the rule matching the alert.
+// If the ops of the same object failed too many times within the window, it
+// takes the escalation actions instead of remediating again and returns the
+// policy with the reason, nil if the alert should be remediated.
+func (c *AlertController) escalateAlert(ctx context.Context, alert *alertv1alpha1.AegisAlert) (*controller.EscalationPolicy, string) {
+	policy := c.getOpsPolicy(alert)
+	if policy == nil || policy.Escalation == nil {
+		return nil, ""
+	}
+	escalation := policy.Escalation
+
+	alerts, err := c.alertLister.AegisAlerts(alert.Namespace).List(labels.Everything())
+	if err != nil {
+		utilruntime.HandleError(fmt.Errorf("List alerts for escalation of %s/%s: %v", alert.Namespace, alert.Name, err))
+		return nil, ""
+	}
+
+	failures := countFailedOps(alerts, alert, escalation, time.Now())
+	if failures < escalation.MaxFailures {
+		return nil, ""
+	}
+
+	message := fmt.Sprintf("Ops of %s failed %d times within %v, escalated instead of remediated", escalationSubject(alert, escalation.GroupBy), failures, escalation.Window)
+	klog.Infof("Alert %s/%s: %s", alert.Namespace, alert.Name, message)
+
+	if escalation.HasAction(controller.EscalationActionRaiseSeverity) {
+		c.escalationAction(alert, controller.EscalationActionRaiseSeverity, c.raiseAlertSeverity(ctx, alert, escalation.Severity))
+	}
+	if escalation.HasAction(controller.EscalationActionDisableNode) {
+		c.escalationAction(alert, controller.EscalationActionDisableNode, c.disableAlertNode(ctx, alert))
+	}
+	if escalation.HasAction(controller.EscalationActionCreateTicket) {
+		c.escalationAction(alert, controller.EscalationActionCreateTicket, c.createAlertTicket(ctx, alert, message))
+	}
+
+	return escalation, message
+}
+
+// onOpsEscalated fires the lifecycle callbacks interested in escalated alerts
+func (c *AlertController) onOpsEscalated(alert *alertv1alpha1.AegisAlert) error {
+	if callback, ok := c.lifecycleControl.(controller.EscalationCallbackInterface); ok {
+		return callback.OnOpsEscalated(alert)
+	}
+	return nil
+}
+
+func (c *AlertController) escalationAction(alert *alertv1alpha1.AegisAlert, action string, err error) {
+	if err != nil {
+		utilruntime.HandleError(fmt.Errorf("Escalation %s for alert %s/%s: %v", action, alert.Namespace, alert.Name, err))
+		c.recorder.Eventf(alert, v1.EventTypeWarning, "FailedEscalation", "Escalation %s failed: %v", action, err)
+		return
+	}
+	c.recorder.Eventf(alert, v1.EventTypeNormal, "Escalation", "Escalation %s done", action)
+}
+
+// countFailedOps counts the failed ops of the same group within the window.
+// Every failed attempt counts: a failed alert counts once plus its retries,
+// and the retries of the alert itself count while it is being retried.
+func countFailedOps(alerts []*alertv1alpha1.AegisAlert, alert *alertv1alpha1.AegisAlert, policy *controller.EscalationPolicy, now time.Time) int32 {
+	since := now.Add(-policy.Window)
+	failures := alert.Status.OpsStatus.Retries
+	for _, other := range alerts {
+		if other.UID == alert.UID || !IsAlertOpsFailed(other) || !sameEscalationGroup(alert, other, policy.GroupBy) {
+			continue
+		}
+
+		completion := other.Status.OpsStatus.CompletionTime
+		if completion == nil || completion.Time.Before(since) {
+			continue
+		}
+		failures += 1 + other.Status.OpsStatus.Retries
+	}
+	return failures
+}
+
+// sameEscalationGroup compares the fingerprint of the alerts, or their involved
+// object if grouped by object or the alert has no fingerprint.
+func sameEscalationGroup(alert, other *alertv1alpha1.AegisAlert, groupBy string) bool {
+	if fingerprint := alert.Labels["fingerprint"]; groupBy == controller.EscalationGroupByFingerprint && len(fingerprint) > 0 {
+		return other.Labels["fingerprint"] == fingerprint
+	}
+
+	return alert.Spec.InvolvedObject.Kind == other.Spec.InvolvedObject.Kind &&
+		alert.Spec.InvolvedObject.Namespace == other.Spec.InvolvedObject.Namespace &&
+		alert.Spec.InvolvedObject.Name == other.Spec.InvolvedObject.Name
+}
+
+func escalationSubject(alert *alertv1alpha1.AegisAlert, groupBy string) string {
+	if fingerprint := alert.Labels["fingerprint"]; groupBy == controller.EscalationGroupByFingerprint && len(fingerprint) > 0 {
+		return fmt.Sprintf("fingerprint %s", fingerprint)
+	}
+
+	object := alert.Spec.InvolvedObject
+	if len(object.Namespace) > 0 {
+		return fmt.Sprintf("%s %s/%s", object.Kind, object.Namespace, object.Name)
+	}
+	return fmt.Sprintf("%s %s", object.Kind, object.Name)
+}
+
+// escalationNode returns the node of the alert, empty if unknown
+func escalationNode(alert *alertv1alpha1.AegisAlert) string {
+	if alert.Spec.InvolvedObject.Kind == alertv1alpha1.NodeKind {
+		return alert.Spec.InvolvedObject.Name
+	}
+	return alert.Spec.InvolvedObject.Node
+}
+
+func (c *AlertController) raiseAlertSeverity(ctx context.Context, alert *alertv1alpha1.AegisAlert, severity string) error {
+	if alert.Spec.Severity == severity {
+		return nil
+	}
+
+	patch, _ := json.Marshal(map[string]interface{}{
+		"spec": map[string]interface{}{
+			"severity": severity,
+		},
+	})
+	if _, err := c.alertclientset.AegisV1alpha1().AegisAlerts(alert.Namespace).Patch(ctx, alert.Name, types.MergePatchType, patch, metav1.PatchOptions{}); err != nil {
+		return err
+	}
+
+	alert.Spec.Severity = severity
+	return nil
+}
+
+func (c *AlertController) disableAlertNode(ctx context.Context, alert *alertv1alpha1.AegisAlert) error {
+	node := escalationNode(alert)
+	if len(node) == 0 {
+		return fmt.Errorf("no node found for %s", escalationSubject(alert, controller.EscalationGroupByInvolvedObject))
+	}
+
+	patch, _ := json.Marshal(map[string]interface{}{
+		"metadata": map[string]interface{}{
+			"labels": map[string]string{
+				nodeDisableLabel: "true",
+			},
+		},
+	})
+	_, err := c.kubeClient.CoreV1().Nodes().Patch(ctx, node, types.MergePatchType, patch, metav1.PatchOptions{})
+	return err
+}
+
+func (c *AlertController) createAlertTicket(ctx context.Context, alert *alertv1alpha1.AegisAlert, message string) error {
+	if c.newTicketManager == nil {
+		return fmt.Errorf("no ticket system configured")
+	}
+
+	name := escalationNode(alert)
+	if len(name) == 0 {
+		return fmt.Errorf("no node found for %s", escalationSubject(alert, controller.EscalationGroupByInvolvedObject))
+	}
+
+	node, err := c.kubeClient.CoreV1().Nodes().Get(ctx, name, metav1.GetOptions{})
+	if err != nil {
+		return err
+	}
+
+	manager, err := c.newTicketManager(ctx, node)
+	if err != nil {
+		return err
+	}
+
+	status := &prom.AegisNodeStatus{
+		Name:      name,
+		Type:      alert.Spec.Type,
+		Condition: alert.Spec.Type,
+		Msg:       message,
+	}
+	title := fmt.Sprintf("aegis escalated alert %s on node %s: %s", alert.Spec.Type, name, message)
+	if err := manager.CreateTicket(ctx, status, escalationHardwareType, title); err != nil && err != ticketmodel.TicketAlreadyExistErr {
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,153 @@
+package alert
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	alertv1alpha1 "github.com/scitix/aegis/pkg/apis/alert/v1alpha1"
+	"github.com/scitix/aegis/pkg/controller"
+	alertfake "github.com/scitix/aegis/pkg/generated/alert/clientset/versioned/fake"
+	alertLister "github.com/scitix/aegis/pkg/generated/alert/listers/alert/v1alpha1"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	kubefake "k8s.io/client-go/kubernetes/fake"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/client-go/tools/record"
+)
+
+type fakeRuleEngine struct {
+	controller.RuleEngineInterface
+	policy *controller.OpsPolicy
+}
+
+func (f *fakeRuleEngine) GetOpsPolicy(r *controller.MatchRule) (*controller.OpsPolicy, error) {
+	return f.policy, nil
+}
+
+func newEscalationTestAlert(name string, fingerprint, node string, failedAt time.Time) *alertv1alpha1.AegisAlert {
+	alert := &alertv1alpha1.AegisAlert{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      name,
+			Namespace: "monitoring",
+			UID:       types.UID(name),
+			Labels:    map[string]string{"fingerprint": fingerprint},
+		},
+		Spec: alertv1alpha1.AegisAlertSpec{
+			Type:     "NodeNotReady",
+			Severity: "warning",
+			InvolvedObject: alertv1alpha1.AegisAlertObject{
+				Kind: alertv1alpha1.NodeKind,
+				Name: node,
+			},
+		},
+	}
+	if !failedAt.IsZero() {
+		completion := metav1.NewTime(failedAt)
+		alert.Status.OpsStatus.CompletionTime = &completion
+		alert.Status.OpsStatus.Status = alertv1alpha1.OpsStatusFailed
+		alert.Status.Conditions = []alertv1alpha1.AlertOpsCondition{*newCondition(alertv1alpha1.AlertFailedOpsWrofklow, v1.ConditionTrue, "WorkflowFailed", "")}
+	}
+	return alert
+}
+
+func TestCountFailedOps(t *testing.T) {
+	now := time.Now()
+	alert := newEscalationTestAlert("current", "fp-1", "node-1", time.Time{})
+	alerts := []*alertv1alpha1.AegisAlert{
+		alert,
+		newEscalationTestAlert("failed-1", "fp-1", "node-1", now.Add(-time.Hour)),
+		newEscalationTestAlert("failed-2", "fp-1", "node-1", now.Add(-2*time.Hour)),
+		newEscalationTestAlert("expired", "fp-1", "node-1", now.Add(-48*time.Hour)),
+		newEscalationTestAlert("running", "fp-1", "node-1", time.Time{}),
+		newEscalationTestAlert("other-fingerprint", "fp-2", "node-1", now.Add(-time.Hour)),
+		newEscalationTestAlert("other-node", "fp-3", "node-2", now.Add(-time.Hour)),
+	}
+
+	cases := map[string]int32{
+		controller.EscalationGroupByFingerprint:    2,
+		controller.EscalationGroupByInvolvedObject: 3,
+	}
+	for groupBy, expected := range cases {
+		policy := &controller.EscalationPolicy{MaxFailures: 1, Window: 24 * time.Hour, GroupBy: groupBy}
+		if got := countFailedOps(alerts, alert, policy, now); got != expected {
+			t.Errorf("group by %s: expected %d failures, got %d", groupBy, expected, got)
+		}
+	}
+}
+
+func TestCountFailedOpsRetries(t *testing.T) {
+	now := time.Now()
+	policy := &controller.EscalationPolicy{MaxFailures: 1, Window: 24 * time.Hour, GroupBy: controller.EscalationGroupByFingerprint}
+
+	// each retried workflow of the alert itself is a failed attempt
+	alert := newEscalationTestAlert("current", "fp-1", "node-1", time.Time{})
+	alert.Status.OpsStatus.Retries = 2
+	alert.Status.OpsStatus.RetriedWorkflows = []string{"current-1", "current-2"}
+	if got := countFailedOps([]*alertv1alpha1.AegisAlert{alert}, alert, policy, now); got != 2 {
+		t.Errorf("expected the retries of the alert counted, got %d", got)
+	}
+
+	// a failed alert counts its exhausted retries too
+	failed := newEscalationTestAlert("failed", "fp-1", "node-1", now.Add(-time.Hour))
+	failed.Status.OpsStatus.Retries = 3
+	expired := newEscalationTestAlert("expired", "fp-1", "node-1", now.Add(-48*time.Hour))
+	expired.Status.OpsStatus.Retries = 3
+	alerts := []*alertv1alpha1.AegisAlert{alert, failed, expired}
+	if got := countFailedOps(alerts, alert, policy, now); got != 6 {
+		t.Errorf("expected 6 failures, got %d", got)
+	}
+}
+
+func TestEscalateAlert(t *testing.T) {
+	now := time.Now()
+	alert := newEscalationTestAlert("current", "fp-1", "node-1", time.Time{})
+	failed := newEscalationTestAlert("failed-1", "fp-1", "node-1", now.Add(-time.Hour))
+
+	indexer := cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{cache.NamespaceIndex: cache.MetaNamespaceIndexFunc})
+	indexer.Add(alert)
+	indexer.Add(failed)
+
+	kubeclient := kubefake.NewSimpleClientset(&v1.Node{ObjectMeta: metav1.ObjectMeta{Name: "node-1"}})
+	alertclient := alertfake.NewSimpleClientset(alert)
+	policy, err := controller.NewEscalationPolicy(2, "", "", []string{controller.EscalationActionRaiseSeverity, controller.EscalationActionDisableNode}, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	c := &AlertController{
+		kubeClient:           kubeclient,
+		alertclientset:       alertclient,
+		alertLister:          alertLister.NewAegisAlertLister(indexer),
+		ruleEngineController: &fakeRuleEngine{policy: &controller.OpsPolicy{Escalation: policy}},
+		recorder:             record.NewFakeRecorder(10),
+	}
+
+	// a single failure is below the threshold
+	if escalation, _ := c.escalateAlert(context.Background(), alert); escalation != nil {
+		t.Fatal("expected no escalation")
+	}
+
+	indexer.Add(newEscalationTestAlert("failed-2", "fp-1", "node-1", now.Add(-2*time.Hour)))
+	escalation, message := c.escalateAlert(context.Background(), alert)
+	if escalation == nil || len(message) == 0 {
+		t.Fatal("expected escalation")
+	}
+
+	node, err := kubeclient.CoreV1().Nodes().Get(context.Background(), "node-1", metav1.GetOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if node.Labels[nodeDisableLabel] != "true" {
+		t.Errorf("expected node disabled, got labels %v", node.Labels)
+	}
+
+	updated, err := alertclient.AegisV1alpha1().AegisAlerts("monitoring").Get(context.Background(), "current", metav1.GetOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if updated.Spec.Severity != controller.DefaultEscalationSeverity || alert.Spec.Severity != controller.DefaultEscalationSeverity {
+		t.Errorf("expected severity raised, got %s", updated.Spec.Severity)
+	}
+}
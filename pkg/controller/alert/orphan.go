@@ -0,0 +1,277 @@
+package alert
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	wfv1alpha1 "github.com/argoproj/argo-workflows/v3/pkg/apis/workflow/v1alpha1"
+	alertv1alpha1 "github.com/scitix/aegis/pkg/apis/alert/v1alpha1"
+	"github.com/scitix/aegis/pkg/controller"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/selection"
+	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/klog/v2"
+)
+
+const (
+	orphanDefaultPeriod = 10 * time.Minute
+
+	OrphanActionRemoveFinalizer = "remove_finalizer"
+	OrphanActionTerminate       = "terminate"
+	OrphanActionDelete          = "delete"
+)
+
+// labels every ops workflow inherits from its alert
+var aegisWorkflowLabels = []string{"alert-type", "uuid"}
+
+// OrphanGCConfig controls the garbage collection of ops workflows whose alert
+// was deleted or replaced
+type OrphanGCConfig struct {
+	Enable bool
+	// Period between two scans of the workflow cache
+	Period time.Duration
+	// GracePeriod an orphan workflow is kept after it finished, or after
+	// creation if it's still running
+	GracePeriod time.Duration
+	// TerminateRunning shuts down orphan workflows that are still running
+	TerminateRunning bool
+	// DryRun only logs and counts the actions
+	DryRun bool
+}
+
+// OrphanMetricsInterface records the orphan workflow garbage collection
+type OrphanMetricsInterface interface {
+	RecordOrphanWorkflows(count int)
+	RecordOrphanWorkflowAction(action string, dryRun bool)
+}
+
+func (c OrphanGCConfig) period() time.Duration {
+	if c.Period <= 0 {
+		return orphanDefaultPeriod
+	}
+	return c.Period
+}
+
+// aegisWorkflowSelector selects the workflows labelled as created for an alert
+func aegisWorkflowSelector() labels.Selector {
+	selector := labels.NewSelector()
+	for _, key := range aegisWorkflowLabels {
+		requirement, _ := labels.NewRequirement(key, selection.Exists, nil)
+		selector = selector.Add(*requirement)
+	}
+	return selector
+}
+
+// isOrphanWorkflow returns true if the owner alert of the workflow no longer
+// exists or has a different uid, or if the workflow has no owner but still
+// carries the tracking finalizer. Workflows controlled by other kinds, or
+// only labelled like ours, are left alone.
+func (c *AlertController) isOrphanWorkflow(workflow *wfv1alpha1.Workflow) bool {
+	controllerRef := metav1.GetControllerOf(workflow)
+	if controllerRef == nil {
+		return hasAlertTrackingFinalizer(workflow)
+	}
+
+	if controllerRef.Kind != controllerKind.Kind {
+		return false
+	}
+
+	return c.resolveControllerRef(workflow.Namespace, controllerRef) == nil
+}
+
+// gcOrphanWorkflows enqueues all the orphan workflows found in the cache
+func (c *AlertController) gcOrphanWorkflows(ctx context.Context) {
+	workflows, err := c.workflowLister.List(aegisWorkflowSelector())
+	if err != nil {
+		utilruntime.HandleError(fmt.Errorf("listing workflows for orphan gc: %v", err))
+		return
+	}
+
+	count := 0
+	for _, workflow := range workflows {
+		if c.isOrphanWorkflow(workflow) {
+			count++
+			c.enqueueOrphanWorkflow(workflow)
+		}
+	}
+
+	if c.orphanMetrics != nil {
+		c.orphanMetrics.RecordOrphanWorkflows(count)
+	}
+	klog.V(4).Infof("Found %d orphan workflows", count)
+}
+
+func (c *AlertController) enqueueOrphanWorkflow(obj interface{}) {
+	key, err := controller.KeyFunc(obj)
+	if err != nil {
+		utilruntime.HandleError(fmt.Errorf("Couldn't get key for object %+v: %v", obj, err))
+		return
+	}
+
+	c.orphanqueue.Add(key)
+}
+
+func (c *AlertController) orphanWorkflow(ctx context.Context) {
+	for c.processNexOrphanWorkflow(ctx) {
+	}
+}
+
+func (c *AlertController) processNexOrphanWorkflow(ctx context.Context) bool {
+	key, quit := c.orphanqueue.Get()
+	if quit {
+		return false
+	}
+	defer c.orphanqueue.Done(key)
+
+	err := c.syncOrphanWorkflow(ctx, key.(string))
+	if err != nil {
+		utilruntime.HandleError(fmt.Errorf("Error syncing orphan workflow: %v", err))
+		c.orphanqueue.AddRateLimited(key)
+	} else {
+		c.orphanqueue.Forget(key)
+	}
+
+	return true
+}
+
+// syncOrphanWorkflow removes the tracking finalizer from an orphan workflow,
+// terminates it if still running and deletes it after the grace period. The
+// finalizer of an unowned workflow is removed when it's deleted.
+func (c *AlertController) syncOrphanWorkflow(ctx context.Context, key string) error {
+	startTime := time.Now()
+	defer func() {
+		klog.V(4).Infof("Finished syncing orphan workflow %q (%v)", key, time.Since(startTime))
+	}()
+
+	ns, name, err := cache.SplitMetaNamespaceKey(key)
+	if err != nil {
+		return err
+	}
+
+	workflow, err := c.workflowLister.Workflows(ns).Get(name)
+	if err != nil {
+		if apierrors.IsNotFound(err) {
+			klog.V(4).Infof("Orphan workflow has been deleted: %v", key)
+			return nil
+		}
+		return err
+	}
+
+	if !c.isOrphanWorkflow(workflow) {
+		return nil
+	}
+
+	// the tracking finalizer is all that marks an unowned workflow as ours,
+	// it's kept until the workflow is deleted
+	after := orphanDeleteAfter(workflow, c.orphanGC.GracePeriod, time.Now())
+	owned := metav1.GetControllerOf(workflow) != nil
+	if patch := removeTrackingFinalizerPatch(workflow); patch != nil && (owned || after <= 0 || workflow.DeletionTimestamp != nil) {
+		if err := c.orphanAction(workflow, OrphanActionRemoveFinalizer, func() error {
+			return c.workflowControl.MergePatchWorkflow(ctx, ns, name, patch)
+		}); err != nil {
+			return err
+		}
+	}
+
+	if workflow.DeletionTimestamp != nil {
+		return nil
+	}
+
+	if c.orphanGC.TerminateRunning && !workflow.Status.Fulfilled() && !workflow.Spec.Shutdown.Enabled() {
+		if err := c.orphanAction(workflow, OrphanActionTerminate, func() error {
+			return c.workflowControl.MergePatchWorkflow(ctx, ns, name, terminateWorkflowPatch())
+		}); err != nil {
+			return err
+		}
+	}
+
+	if after > 0 {
+		klog.V(4).Infof("Orphan workflow %s will be deleted in %v", key, after)
+		c.orphanqueue.AddAfter(key, after)
+		return nil
+	}
+
+	return c.orphanAction(workflow, OrphanActionDelete, func() error {
+		err := c.workflowControl.DeleteWorkflow(ctx, ns, name, workflow)
+		if apierrors.IsNotFound(err) {
+			return nil
+		}
+		return err
+	})
+}
+
+// orphanAction runs the action unless in dry-run mode and records it
+func (c *AlertController) orphanAction(workflow *wfv1alpha1.Workflow, action string, do func() error) error {
+	if c.orphanGC.DryRun {
+		klog.Infof("[dry-run] %s orphan workflow %s/%s", action, workflow.Namespace, workflow.Name)
+	} else {
+		if err := do(); err != nil {
+			return fmt.Errorf("%s orphan workflow %s/%s: %v", action, workflow.Namespace, workflow.Name, err)
+		}
+		klog.Infof("%s orphan workflow %s/%s", action, workflow.Namespace, workflow.Name)
+	}
+
+	if c.orphanMetrics != nil {
+		c.orphanMetrics.RecordOrphanWorkflowAction(action, c.orphanGC.DryRun)
+	}
+	return nil
+}
+
+// orphanDeleteAfter returns how long to wait before deleting the orphan
+// workflow, counted from its finish time, or its creation if unfinished.
+func orphanDeleteAfter(workflow *wfv1alpha1.Workflow, grace time.Duration, now time.Time) time.Duration {
+	since := workflow.CreationTimestamp.Time
+	if !workflow.Status.FinishedAt.IsZero() {
+		since = workflow.Status.FinishedAt.Time
+	}
+	return since.Add(grace).Sub(now)
+}
+
+func hasAlertTrackingFinalizer(workflow *wfv1alpha1.Workflow) bool {
+	for _, finalizer := range workflow.Finalizers {
+		if finalizer == alertv1alpha1.AlertTrackingFinalizer {
+			return true
+		}
+	}
+	return false
+}
+
+// removeTrackingFinalizerPatch returns the merge patch dropping the tracking
+// finalizer, nil if the workflow doesn't have it. The resourceVersion makes
+// the patch fail instead of dropping finalizers added concurrently.
+func removeTrackingFinalizerPatch(workflow *wfv1alpha1.Workflow) []byte {
+	if !hasAlertTrackingFinalizer(workflow) {
+		return nil
+	}
+
+	finalizers := []string{}
+	for _, finalizer := range workflow.Finalizers {
+		if finalizer != alertv1alpha1.AlertTrackingFinalizer {
+			finalizers = append(finalizers, finalizer)
+		}
+	}
+
+	patch := map[string]interface{}{
+		"metadata": map[string]interface{}{
+			"resourceVersion": workflow.ResourceVersion,
+			"finalizers":      finalizers,
+		},
+	}
+	patchBytes, _ := json.Marshal(patch)
+	return patchBytes
+}
+
+func terminateWorkflowPatch() []byte {
+	patch := map[string]interface{}{
+		"spec": map[string]interface{}{
+			"shutdown": wfv1alpha1.ShutdownStrategyTerminate,
+		},
+	}
+	patchBytes, _ := json.Marshal(patch)
+	return patchBytes
+}
@@ -0,0 +1,210 @@
+package alert
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	wfv1alpha1 "github.com/argoproj/argo-workflows/v3/pkg/apis/workflow/v1alpha1"
+	wfLister "github.com/argoproj/argo-workflows/v3/pkg/client/listers/workflow/v1alpha1"
+	alertv1alpha1 "github.com/scitix/aegis/pkg/apis/alert/v1alpha1"
+	alertLister "github.com/scitix/aegis/pkg/generated/alert/listers/alert/v1alpha1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/client-go/util/workqueue"
+)
+
+type fakeWorkflowControl struct {
+	patches map[string]string
+	deleted []string
+}
+
+func (f *fakeWorkflowControl) CreateWorkflowWithPlainContent(ctx context.Context, namespace string, template string, object runtime.Object, controllerRef *metav1.OwnerReference) error {
+	return nil
+}
+
+func (f *fakeWorkflowControl) CreateWorkflow(ctx context.Context, namespace string, template *wfv1alpha1.Workflow, object runtime.Object, controllerRef *metav1.OwnerReference) error {
+	return nil
+}
+
+func (f *fakeWorkflowControl) CreateWorkflowWithGenerateName(ctx context.Context, namespace string, template *wfv1alpha1.Workflow, object runtime.Object, controllerRef *metav1.OwnerReference, generateName string) error {
+	return nil
+}
+
+func (f *fakeWorkflowControl) PatchWorkflow(ctx context.Context, namespace string, name string, data []byte) error {
+	return nil
+}
+
+func (f *fakeWorkflowControl) MergePatchWorkflow(ctx context.Context, namespace string, name string, data []byte) error {
+	f.patches[name] += string(data)
+	return nil
+}
+
+func (f *fakeWorkflowControl) DeleteWorkflow(ctx context.Context, namespace string, workflowID string, object runtime.Object) error {
+	f.deleted = append(f.deleted, workflowID)
+	return nil
+}
+
+type fakeOrphanMetrics struct {
+	count   int
+	actions map[string]int
+}
+
+func (f *fakeOrphanMetrics) RecordOrphanWorkflows(count int) {
+	f.count = count
+}
+
+func (f *fakeOrphanMetrics) RecordOrphanWorkflowAction(action string, dryRun bool) {
+	f.actions[action]++
+}
+
+func newOrphanTestController(gc OrphanGCConfig, alerts []*alertv1alpha1.AegisAlert, workflows []*wfv1alpha1.Workflow) (*AlertController, *fakeWorkflowControl, *fakeOrphanMetrics) {
+	alertIndexer := cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{cache.NamespaceIndex: cache.MetaNamespaceIndexFunc})
+	for _, alert := range alerts {
+		alertIndexer.Add(alert)
+	}
+	wfIndexer := cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{cache.NamespaceIndex: cache.MetaNamespaceIndexFunc})
+	for _, workflow := range workflows {
+		wfIndexer.Add(workflow)
+	}
+
+	control := &fakeWorkflowControl{patches: map[string]string{}}
+	metrics := &fakeOrphanMetrics{actions: map[string]int{}}
+	c := &AlertController{
+		workflowControl: control,
+		alertLister:     alertLister.NewAegisAlertLister(alertIndexer),
+		workflowLister:  wfLister.NewWorkflowLister(wfIndexer),
+		orphanqueue:     workqueue.NewNamedRateLimitingQueue(workqueue.DefaultControllerRateLimiter(), "test_orphan_workflows"),
+		orphanGC:        gc,
+		orphanMetrics:   metrics,
+	}
+	return c, control, metrics
+}
+
+func newOrphanTestWorkflow(name string, owner *alertv1alpha1.AegisAlert, finished time.Time) *wfv1alpha1.Workflow {
+	workflow := &wfv1alpha1.Workflow{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:              name,
+			Namespace:         "monitoring",
+			ResourceVersion:   "10",
+			CreationTimestamp: metav1.NewTime(time.Now().Add(-2 * time.Hour)),
+			Labels:            map[string]string{"alert-type": "NodeNotReady", "uuid": name},
+			Finalizers:        []string{"other", alertv1alpha1.AlertTrackingFinalizer},
+		},
+	}
+	if owner != nil {
+		workflow.OwnerReferences = []metav1.OwnerReference{*metav1.NewControllerRef(owner, controllerKind)}
+	}
+	if !finished.IsZero() {
+		workflow.Status.Phase = wfv1alpha1.WorkflowFailed
+		workflow.Status.FinishedAt = metav1.NewTime(finished)
+	} else {
+		workflow.Status.Phase = wfv1alpha1.WorkflowRunning
+	}
+	return workflow
+}
+
+func TestGCOrphanWorkflows(t *testing.T) {
+	alive := &alertv1alpha1.AegisAlert{ObjectMeta: metav1.ObjectMeta{Name: "alive", Namespace: "monitoring", UID: "uid-1"}}
+	gone := &alertv1alpha1.AegisAlert{ObjectMeta: metav1.ObjectMeta{Name: "gone", Namespace: "monitoring", UID: "uid-2"}}
+	replaced := &alertv1alpha1.AegisAlert{ObjectMeta: metav1.ObjectMeta{Name: "alive", Namespace: "monitoring", UID: "uid-old"}}
+
+	owned := newOrphanTestWorkflow("owned", alive, time.Time{})
+	orphan := newOrphanTestWorkflow("orphan", gone, time.Time{})
+	mismatched := newOrphanTestWorkflow("mismatched", replaced, time.Time{})
+	unowned := newOrphanTestWorkflow("unowned", nil, time.Time{})
+	foreign := newOrphanTestWorkflow("foreign", nil, time.Time{})
+	foreign.OwnerReferences = []metav1.OwnerReference{{Kind: "CronWorkflow", Name: "cron", UID: "uid-3", Controller: &[]bool{true}[0]}}
+	unlabelled := newOrphanTestWorkflow("unlabelled", gone, time.Time{})
+	unlabelled.Labels = nil
+	// labelled like ours, but neither owned nor tracked
+	untracked := newOrphanTestWorkflow("untracked", nil, time.Time{})
+	untracked.Finalizers = nil
+
+	c, _, metrics := newOrphanTestController(OrphanGCConfig{Enable: true}, []*alertv1alpha1.AegisAlert{alive},
+		[]*wfv1alpha1.Workflow{owned, orphan, mismatched, unowned, foreign, unlabelled, untracked})
+	c.gcOrphanWorkflows(context.Background())
+
+	if metrics.count != 3 || c.orphanqueue.Len() != 3 {
+		t.Errorf("expected 3 orphan workflows, got %d (queue %d)", metrics.count, c.orphanqueue.Len())
+	}
+}
+
+func TestSyncOrphanWorkflow(t *testing.T) {
+	gone := &alertv1alpha1.AegisAlert{ObjectMeta: metav1.ObjectMeta{Name: "gone", Namespace: "monitoring", UID: "uid-2"}}
+	running := newOrphanTestWorkflow("running", gone, time.Time{})
+	recent := newOrphanTestWorkflow("recent", gone, time.Now().Add(-time.Minute))
+	expired := newOrphanTestWorkflow("expired", gone, time.Now().Add(-2*time.Hour))
+
+	c, control, metrics := newOrphanTestController(OrphanGCConfig{Enable: true, GracePeriod: time.Hour, TerminateRunning: true},
+		nil, []*wfv1alpha1.Workflow{running, recent, expired})
+
+	for _, key := range []string{"monitoring/running", "monitoring/recent", "monitoring/expired"} {
+		if err := c.syncOrphanWorkflow(context.Background(), key); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	finalizerPatch := `{"metadata":{"finalizers":["other"],"resourceVersion":"10"}}`
+	expectedPatches := map[string]string{
+		"running": finalizerPatch + `{"spec":{"shutdown":"Terminate"}}`,
+		"recent":  finalizerPatch,
+		"expired": finalizerPatch,
+	}
+	for name, patch := range expectedPatches {
+		if control.patches[name] != patch {
+			t.Errorf("%s: expected patch %s, got %s", name, patch, control.patches[name])
+		}
+	}
+
+	// running was created 2h ago, expired finished 2h ago
+	if len(control.deleted) != 2 || control.deleted[0] != "running" || control.deleted[1] != "expired" {
+		t.Errorf("unexpected deleted workflows %v", control.deleted)
+	}
+	if metrics.actions[OrphanActionRemoveFinalizer] != 3 || metrics.actions[OrphanActionTerminate] != 1 || metrics.actions[OrphanActionDelete] != 2 {
+		t.Errorf("unexpected actions %v", metrics.actions)
+	}
+}
+
+func TestSyncUnownedOrphanWorkflow(t *testing.T) {
+	recent := newOrphanTestWorkflow("recent", nil, time.Now().Add(-time.Minute))
+	expired := newOrphanTestWorkflow("expired", nil, time.Now().Add(-2*time.Hour))
+	untracked := newOrphanTestWorkflow("untracked", nil, time.Now().Add(-2*time.Hour))
+	untracked.Finalizers = nil
+
+	c, control, _ := newOrphanTestController(OrphanGCConfig{Enable: true, GracePeriod: time.Hour},
+		nil, []*wfv1alpha1.Workflow{recent, expired, untracked})
+	for _, key := range []string{"monitoring/recent", "monitoring/expired", "monitoring/untracked"} {
+		if err := c.syncOrphanWorkflow(context.Background(), key); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// the finalizer of the recent workflow is kept to find it again
+	finalizerPatch := `{"metadata":{"finalizers":["other"],"resourceVersion":"10"}}`
+	if len(control.patches) != 1 || control.patches["expired"] != finalizerPatch {
+		t.Errorf("expected only the expired workflow patched, got %v", control.patches)
+	}
+	if len(control.deleted) != 1 || control.deleted[0] != "expired" {
+		t.Errorf("expected only the expired workflow deleted, got %v", control.deleted)
+	}
+}
+
+func TestSyncOrphanWorkflowDryRun(t *testing.T) {
+	gone := &alertv1alpha1.AegisAlert{ObjectMeta: metav1.ObjectMeta{Name: "gone", Namespace: "monitoring", UID: "uid-2"}}
+	expired := newOrphanTestWorkflow("expired", gone, time.Now().Add(-2*time.Hour))
+
+	c, control, metrics := newOrphanTestController(OrphanGCConfig{Enable: true, GracePeriod: time.Hour, DryRun: true},
+		nil, []*wfv1alpha1.Workflow{expired})
+	if err := c.syncOrphanWorkflow(context.Background(), "monitoring/expired"); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(control.patches) != 0 || len(control.deleted) != 0 {
+		t.Errorf("expected no change in dry-run, got patches %v, deleted %v", control.patches, control.deleted)
+	}
+	if metrics.actions[OrphanActionRemoveFinalizer] != 1 || metrics.actions[OrphanActionDelete] != 1 {
+		t.Errorf("unexpected actions %v", metrics.actions)
+	}
+}
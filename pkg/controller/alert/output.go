@@ -0,0 +1,120 @@
+package alert
+
+import (
+	"sort"
+	"unicode/utf8"
+
+	wfv1alpha1 "github.com/argoproj/argo-workflows/v3/pkg/apis/workflow/v1alpha1"
+	v1alpha1 "github.com/scitix/aegis/pkg/apis/alert/v1alpha1"
+)
+
+// collectWorkflowOutputs gathers the workflow-level output parameters, the
+// well-known aegis-result output of any step and the final node message from
+// the given finished workflows. The result is bounded by MaxOpsOutputs entries
+// and MaxOpsOutputValueLength per value.
+func collectWorkflowOutputs(workflows []*wfv1alpha1.Workflow) map[string]string {
+	outputs := make(map[string]string)
+
+	put := func(key, value string) {
+		if len(key) == 0 || len(value) == 0 {
+			return
+		}
+		if _, ok := outputs[key]; !ok && len(outputs) >= v1alpha1.MaxOpsOutputs {
+			return
+		}
+		outputs[key] = truncateOutput(value, v1alpha1.MaxOpsOutputValueLength)
+	}
+
+	// keep a stable order so status doesn't flap between syncs
+	sorted := make([]*wfv1alpha1.Workflow, len(workflows))
+	copy(sorted, workflows)
+	sort.Slice(sorted, func(i, j int) bool {
+		return sorted[i].Name < sorted[j].Name
+	})
+
+	for _, wf := range sorted {
+		if wf.Status.Outputs != nil {
+			for _, p := range wf.Status.Outputs.Parameters {
+				if p.Value != nil {
+					put(p.Name, p.Value.String())
+				}
+			}
+		}
+
+		if result, ok := finalNodeOutput(wf, v1alpha1.OpsResultOutputName); ok {
+			put(v1alpha1.OpsResultOutputName, result)
+		}
+
+		if message := finalNodeMessage(wf); len(message) > 0 {
+			put(v1alpha1.OpsMessageOutputName, message)
+		}
+	}
+
+	if len(outputs) == 0 {
+		return nil
+	}
+	return outputs
+}
+
+// finalNodeOutput returns the named output parameter of the most recently finished node exporting it.
+func finalNodeOutput(wf *wfv1alpha1.Workflow, name string) (string, bool) {
+	var value string
+	var last *wfv1alpha1.NodeStatus
+	for id := range wf.Status.Nodes {
+		node := wf.Status.Nodes[id]
+		if node.Outputs == nil {
+			continue
+		}
+		for _, p := range node.Outputs.Parameters {
+			if p.Name != name || p.Value == nil {
+				continue
+			}
+			if last == nil || finishedLater(&node, last) {
+				value, last = p.Value.String(), &node
+			}
+		}
+	}
+	return value, last != nil
+}
+
+// finalNodeMessage returns the message of the most recently finished node,
+// falling back to the workflow message.
+func finalNodeMessage(wf *wfv1alpha1.Workflow) string {
+	var last *wfv1alpha1.NodeStatus
+	for id := range wf.Status.Nodes {
+		node := wf.Status.Nodes[id]
+		if len(node.Message) == 0 {
+			continue
+		}
+		if last == nil || finishedLater(&node, last) {
+			last = &node
+		}
+	}
+
+	if last == nil {
+		return wf.Status.Message
+	}
+	return last.Message
+}
+
+// finishedLater orders nodes by finish time, breaking ties by node ID so the
+// pick is deterministic across syncs.
+func finishedLater(a, b *wfv1alpha1.NodeStatus) bool {
+	if !a.FinishedAt.Equal(&b.FinishedAt) {
+		return b.FinishedAt.Before(&a.FinishedAt)
+	}
+	return a.ID > b.ID
+}
+
+// truncateOutput cuts the value to at most max bytes on a rune boundary, so
+// a multi-byte character is never split into invalid UTF-8.
+func truncateOutput(value string, max int) string {
+	if len(value) <= max {
+		return value
+	}
+	end := max
+	for end > 0 && !utf8.RuneStart(value[end]) {
+		end--
+	}
+	return value[:end]
+}
@@ -0,0 +1,104 @@
+package alert
+
+import (
+	"strings"
+	"testing"
+	"time"
+	"unicode/utf8"
+
+	wfv1alpha1 "github.com/argoproj/argo-workflows/v3/pkg/apis/workflow/v1alpha1"
+	v1alpha1 "github.com/scitix/aegis/pkg/apis/alert/v1alpha1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestCollectWorkflowOutputs(t *testing.T) {
+	now := time.Now()
+	wf := &wfv1alpha1.Workflow{
+		ObjectMeta: metav1.ObjectMeta{Name: "wf"},
+		Status: wfv1alpha1.WorkflowStatus{
+			Message: "child 'wf-2' failed",
+			Outputs: &wfv1alpha1.Outputs{
+				Parameters: []wfv1alpha1.Parameter{
+					{Name: "freed", Value: wfv1alpha1.AnyStringPtr("120GB")},
+					{Name: "empty"},
+				},
+			},
+			Nodes: wfv1alpha1.Nodes{
+				"wf-1": {
+					ID:         "wf-1",
+					FinishedAt: metav1.NewTime(now.Add(-time.Minute)),
+					Message:    "first step",
+					Outputs: &wfv1alpha1.Outputs{
+						Parameters: []wfv1alpha1.Parameter{{Name: v1alpha1.OpsResultOutputName, Value: wfv1alpha1.AnyStringPtr("old")}},
+					},
+				},
+				"wf-2": {
+					ID:         "wf-2",
+					FinishedAt: metav1.NewTime(now),
+					Message:    "GPU 3 reset failed",
+					Outputs: &wfv1alpha1.Outputs{
+						Parameters: []wfv1alpha1.Parameter{{Name: v1alpha1.OpsResultOutputName, Value: wfv1alpha1.AnyStringPtr("reset failed")}},
+					},
+				},
+			},
+		},
+	}
+
+	outputs := collectWorkflowOutputs([]*wfv1alpha1.Workflow{wf})
+	expected := map[string]string{
+		"freed":                       "120GB",
+		v1alpha1.OpsResultOutputName:  "reset failed",
+		v1alpha1.OpsMessageOutputName: "GPU 3 reset failed",
+	}
+	if len(outputs) != len(expected) {
+		t.Fatalf("expected %d outputs, got %d: %v", len(expected), len(outputs), outputs)
+	}
+	for key, value := range expected {
+		if outputs[key] != value {
+			t.Errorf("output %s: expected %q, got %q", key, value, outputs[key])
+		}
+	}
+}
+
+func TestCollectWorkflowOutputsBounded(t *testing.T) {
+	var params []wfv1alpha1.Parameter
+	for i := 0; i < v1alpha1.MaxOpsOutputs+10; i++ {
+		params = append(params, wfv1alpha1.Parameter{
+			Name:  strings.Repeat("p", i+1),
+			Value: wfv1alpha1.AnyStringPtr(strings.Repeat("x", v1alpha1.MaxOpsOutputValueLength+1)),
+		})
+	}
+	wf := &wfv1alpha1.Workflow{
+		Status: wfv1alpha1.WorkflowStatus{
+			Outputs: &wfv1alpha1.Outputs{Parameters: params},
+		},
+	}
+
+	outputs := collectWorkflowOutputs([]*wfv1alpha1.Workflow{wf})
+	if len(outputs) != v1alpha1.MaxOpsOutputs {
+		t.Fatalf("expected %d outputs, got %d", v1alpha1.MaxOpsOutputs, len(outputs))
+	}
+	for key, value := range outputs {
+		if len(value) != v1alpha1.MaxOpsOutputValueLength {
+			t.Errorf("output %s not truncated: %d", key, len(value))
+		}
+	}
+}
+
+func TestTruncateOutput(t *testing.T) {
+	// 3 bytes per character, the limit falls inside the last one
+	value := strings.Repeat("修", v1alpha1.MaxOpsOutputValueLength/3+1)
+	truncated := truncateOutput(value, v1alpha1.MaxOpsOutputValueLength)
+	if !utf8.ValidString(truncated) || len(truncated) != v1alpha1.MaxOpsOutputValueLength/3*3 {
+		t.Errorf("expected truncated on a rune boundary, got %d bytes", len(truncated))
+	}
+	if truncated := truncateOutput("ok", v1alpha1.MaxOpsOutputValueLength); truncated != "ok" {
+		t.Errorf("expected short value kept, got %q", truncated)
+	}
+}
+
+func TestCollectWorkflowOutputsEmpty(t *testing.T) {
+	if outputs := collectWorkflowOutputs([]*wfv1alpha1.Workflow{{}}); outputs != nil {
+		t.Errorf("expected nil outputs, got %v", outputs)
+	}
+}
@@ -0,0 +1,131 @@
+package alert
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	wfv1alpha1 "github.com/argoproj/argo-workflows/v3/pkg/apis/workflow/v1alpha1"
+	alertv1alpha1 "github.com/scitix/aegis/pkg/apis/alert/v1alpha1"
+	"github.com/scitix/aegis/pkg/controller"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
+	"k8s.io/apimachinery/pkg/util/sets"
+)
+
+// newMatchRule builds the rule engine query of the alert
+func newMatchRule(alert *alertv1alpha1.AegisAlert) *controller.MatchRule {
+	return &controller.MatchRule{
+		Labels: alert.Labels,
+		Condition: &controller.Condition{
+			Type:   alert.Spec.Type,
+			Status: string(alert.Spec.Status),
+		},
+	}
+}
+
+// getOpsPolicy returns the ops policy of the rule matching the alert, nil if none.
+func (c *AlertController) getOpsPolicy(alert *alertv1alpha1.AegisAlert) *controller.OpsPolicy {
+	policy, err := c.ruleEngineController.GetOpsPolicy(newMatchRule(alert))
+	if err != nil {
+		utilruntime.HandleError(fmt.Errorf("Get ops policy for alert %s/%s: %v", alert.Namespace, alert.Name, err))
+		return nil
+	}
+	return policy
+}
+
+// getRetryPolicy returns the retry policy of the rule matching the alert, nil if none.
+func (c *AlertController) getRetryPolicy(alert *alertv1alpha1.AegisAlert) *controller.RetryPolicy {
+	policy := c.getOpsPolicy(alert)
+	if policy == nil {
+		return nil
+	}
+	return policy.Retry
+}
+
+// retryAlertOps resets the ops status of the alert so that the workflow is
+// re-rendered and recreated after backoff. It returns false if the policy
+// doesn't allow another retry.
+func (c *AlertController) retryAlertOps(alert *alertv1alpha1.AegisAlert, failedWorkflows []*wfv1alpha1.Workflow, reason, message string) bool {
+	policy := c.getRetryPolicy(alert)
+	if policy == nil || alert.Status.OpsStatus.Retries >= policy.MaxRetries {
+		return false
+	}
+
+	names := make([]string, 0, len(failedWorkflows))
+	for _, wf := range failedWorkflows {
+		if !policy.ShouldRetryOn(string(wf.Status.Phase), workflowFailureMessages(wf)) {
+			return false
+		}
+		names = append(names, wf.Name)
+	}
+
+	if len(failedWorkflows) == 0 && !policy.ShouldRetryOn(string(alert.Status.OpsStatus.TriggerStatus), message) {
+		return false
+	}
+
+	retries := alert.Status.OpsStatus.Retries + 1
+	delay := policy.BackoffFor(retries)
+	next := metav1.NewTime(time.Now().Add(delay))
+
+	alert.Status.OpsStatus.Retries = retries
+	alert.Status.OpsStatus.RetriedWorkflows = append(alert.Status.OpsStatus.RetriedWorkflows, names...)
+	alert.Status.OpsStatus.NextRetryTime = &next
+	alert.Status.OpsStatus.Total = nil
+	alert.Status.OpsStatus.Status = alertv1alpha1.OpsStatusRetrying
+
+	retryMessage := fmt.Sprintf("Retry %d/%d in %v: %s", retries, policy.MaxRetries, delay, message)
+	if len(names) > 0 {
+		retryMessage = fmt.Sprintf("Retry %d/%d in %v after workflow %s failed: %s", retries, policy.MaxRetries, delay, strings.Join(names, ","), message)
+	}
+	c.recordCondition(alert, alertv1alpha1.AlertRetryOpsWorkflow, v1.ConditionTrue, reason, retryMessage, strings.Join(names, ","))
+	c.recorder.Event(alert, v1.EventTypeWarning, "RetryOpsWorkflow", retryMessage)
+
+	c.enqueueControllerDelayed(alert, false, delay)
+	return true
+}
+
+// alertRetryBackoff returns the remaining backoff before the ops workflow of the alert can be recreated
+func alertRetryBackoff(alert *alertv1alpha1.AegisAlert) time.Duration {
+	if alert.Status.OpsStatus.NextRetryTime == nil {
+		return 0
+	}
+	return time.Until(alert.Status.OpsStatus.NextRetryTime.Time)
+}
+
+// excludeRetriedWorkflows drops the workflows already handled by a retry
+func excludeRetriedWorkflows(alert *alertv1alpha1.AegisAlert, workflows []*wfv1alpha1.Workflow) []*wfv1alpha1.Workflow {
+	if len(alert.Status.OpsStatus.RetriedWorkflows) == 0 {
+		return workflows
+	}
+
+	retried := sets.NewString(alert.Status.OpsStatus.RetriedWorkflows...)
+	result := make([]*wfv1alpha1.Workflow, 0, len(workflows))
+	for _, wf := range workflows {
+		if !retried.Has(wf.Name) {
+			result = append(result, wf)
+		}
+	}
+	return result
+}
+
+// workflowNames joins the names of the workflows
+func workflowNames(workflows []*wfv1alpha1.Workflow) string {
+	names := make([]string, 0, len(workflows))
+	for _, wf := range workflows {
+		names = append(names, wf.Name)
+	}
+	return strings.Join(names, ",")
+}
+
+// workflowFailureMessages joins the workflow message and the messages of its failed nodes
+func workflowFailureMessages(wf *wfv1alpha1.Workflow) string {
+	messages := []string{wf.Status.Message}
+	for _, node := range wf.Status.Nodes {
+		if node.FailedOrError() && len(node.Message) > 0 {
+			messages = append(messages, node.Message)
+		}
+	}
+	return strings.Join(messages, "\n")
+}
@@ -9,7 +9,16 @@ import (
 // IsAlertOpsFinished checks whether the given alert's corresponding ops has finished execution.
 func IsAlertOpsFinished(alert *v1alpha1.AegisAlert) bool {
 	for _, c := range alert.Status.Conditions {
-		if (c.Type == v1alpha1.AlertCompleteOpsWrofklow || c.Type == v1alpha1.AlertFailedOpsWrofklow || c.Type == v1alpha1.AlertFailedCreateOpsWorkflow) && c.Status == v1.ConditionTrue {
+		if (c.Type == v1alpha1.AlertCompleteOpsWrofklow || c.Type == v1alpha1.AlertFailedOpsWrofklow || c.Type == v1alpha1.AlertFailedCreateOpsWorkflow || c.Type == v1alpha1.AlertEscalated) && c.Status == v1.ConditionTrue {
+			return true
+		}
+	}
+	return false
+}
+
+func IsAlertOpsEscalated(alert *v1alpha1.AegisAlert) bool {
+	for _, c := range alert.Status.Conditions {
+		if c.Type == v1alpha1.AlertEscalated && c.Status == v1.ConditionTrue {
 			return true
 		}
 	}
@@ -34,6 +43,25 @@ func IsAlertOpsFailed(alert *v1alpha1.AegisAlert) bool {
 	return false
 }
 
+// IsAlertOpsVerifyFinished checks whether the post-remediation verification of the alert has finished.
+func IsAlertOpsVerifyFinished(alert *v1alpha1.AegisAlert) bool {
+	for _, c := range alert.Status.Conditions {
+		if (c.Type == v1alpha1.AlertVerified || c.Type == v1alpha1.AlertVerificationFailed) && c.Status == v1.ConditionTrue {
+			return true
+		}
+	}
+	return false
+}
+
+func IsAlertOpsVerified(alert *v1alpha1.AegisAlert) bool {
+	for _, c := range alert.Status.Conditions {
+		if c.Type == v1alpha1.AlertVerified && c.Status == v1.ConditionTrue {
+			return true
+		}
+	}
+	return false
+}
+
 func CheckAlertExpireTTL(alert *v1alpha1.AegisAlert) (expired bool, ttl int32) {
 	if alert.Spec.TTLStrategy == nil || alert.Status.StartTime == nil {
 		return false, 0
@@ -52,7 +80,7 @@ func CheckAlertExpireTTL(alert *v1alpha1.AegisAlert) (expired bool, ttl int32) {
 			return true, ttl - int32(now.Sub(start).Seconds())
 		}
 
-		if c.Type == v1alpha1.AlertFailedCreateOpsWorkflow && c.Status == v1.ConditionTrue && alert.Spec.TTLStrategy.SecondsAfterNoOps != nil {
+		if (c.Type == v1alpha1.AlertFailedCreateOpsWorkflow || c.Type == v1alpha1.AlertEscalated) && c.Status == v1.ConditionTrue && alert.Spec.TTLStrategy.SecondsAfterNoOps != nil {
 			ttl := *alert.Spec.TTLStrategy.SecondsAfterNoOps
 			return true, ttl - int32(now.Sub(start).Seconds())
 		}
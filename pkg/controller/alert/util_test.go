@@ -48,6 +48,11 @@ func TestIsAlertFinished(t *testing.T) {
 			v1.ConditionTrue,
 			false,
 		},
+		"Alert is escalated and condition is true": {
+			v1alpha1.AlertEscalated,
+			v1.ConditionTrue,
+			false,
+		},
 	}
 
 	for name, tc := range testCases {
@@ -0,0 +1,132 @@
+package alert
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/prometheus/common/model"
+	alertv1alpha1 "github.com/scitix/aegis/pkg/apis/alert/v1alpha1"
+	"github.com/scitix/aegis/pkg/controller"
+	"github.com/scitix/aegis/tools"
+	v1 "k8s.io/api/core/v1"
+	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
+	"k8s.io/klog/v2"
+)
+
+const verifyQueryTimeout = 30 * time.Second
+
+// verificationResult is the outcome of the post-remediation verification
+type verificationResult int
+
+const (
+	// verificationPending holds the callbacks until the verification is done
+	verificationPending verificationResult = iota
+	// verificationPassed means the problem is resolved, or no verification applies
+	verificationPassed
+	// verificationFailed means the problem is not resolved before the timeout
+	verificationFailed
+)
+
+// getVerifyPolicy returns the verification of the rule matching the alert, nil if none.
+func (c *AlertController) getVerifyPolicy(alert *alertv1alpha1.AegisAlert) *controller.VerifyPolicy {
+	policy, err := c.ruleEngineController.GetOpsPolicy(newMatchRule(alert))
+	if err != nil {
+		utilruntime.HandleError(fmt.Errorf("Get ops policy for alert %s/%s: %v", alert.Namespace, alert.Name, err))
+		return nil
+	}
+
+	if policy == nil {
+		return nil
+	}
+	return policy.Verify
+}
+
+// syncAlertVerification checks against prometheus that the problem of a
+// succeeded alert is resolved. The alert is requeued while the verification
+// is pending.
+func (c *AlertController) syncAlertVerification(ctx context.Context, alert *alertv1alpha1.AegisAlert) (verificationResult, error) {
+	if IsAlertOpsVerifyFinished(alert) {
+		if IsAlertOpsVerified(alert) {
+			return verificationPassed, nil
+		}
+		return verificationFailed, nil
+	}
+
+	policy := c.getVerifyPolicy(alert)
+	if policy == nil || alert.Status.OpsStatus.CompletionTime == nil {
+		return verificationPassed, nil
+	}
+
+	if c.prometheus == nil {
+		klog.Warningf("Skip verification of alert %s/%s: no prometheus client", alert.Namespace, alert.Name)
+		return verificationPassed, nil
+	}
+
+	elapsed := time.Since(alert.Status.OpsStatus.CompletionTime.Time)
+	if elapsed < policy.Wait {
+		c.enqueueControllerDelayed(alert, false, policy.Wait-elapsed)
+		return verificationPending, nil
+	}
+
+	result := verificationPassed
+	resolved, message := c.evaluateVerification(ctx, alert, policy)
+	if resolved {
+		c.recordCondition(alert, alertv1alpha1.AlertVerified, v1.ConditionTrue, "ProblemResolved", message, "")
+		c.recorder.Event(alert, v1.EventTypeNormal, "Verified", message)
+	} else if elapsed >= policy.Wait+policy.Timeout {
+		c.recordCondition(alert, alertv1alpha1.AlertVerificationFailed, v1.ConditionTrue, "ProblemNotResolved", message, "")
+		c.recorder.Event(alert, v1.EventTypeWarning, "VerificationFailed", message)
+		result = verificationFailed
+	} else {
+		klog.V(4).Infof("Alert %s/%s not verified yet: %s", alert.Namespace, alert.Name, message)
+		c.enqueueControllerDelayed(alert, false, policy.Interval)
+		return verificationPending, nil
+	}
+
+	if err := c.updateStatusHandler(ctx, alert); err != nil {
+		return verificationPending, err
+	}
+	return result, nil
+}
+
+// evaluateVerification renders and evaluates the verify query of the alert
+func (c *AlertController) evaluateVerification(ctx context.Context, alert *alertv1alpha1.AegisAlert, policy *controller.VerifyPolicy) (bool, string) {
+	query, err := tools.RenderTemplate(policy.Query, prepareWorkflowParameters(alert))
+	if err != nil {
+		return false, fmt.Sprintf("Invalid verify query: %v", err)
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, verifyQueryTimeout)
+	defer cancel()
+
+	value, err := c.prometheus.Query(ctx, query)
+	if err != nil {
+		return false, fmt.Sprintf("Verify query %s failed: %v", query, err)
+	}
+
+	if !verificationResolved(value) {
+		return false, fmt.Sprintf("Verify query %s not satisfied", query)
+	}
+	return true, fmt.Sprintf("Verify query %s satisfied", query)
+}
+
+// verificationResolved reports whether the query result is non-empty and all non-zero
+func verificationResolved(value model.Value) bool {
+	switch v := value.(type) {
+	case model.Vector:
+		if len(v) == 0 {
+			return false
+		}
+		for _, sample := range v {
+			if sample.Value == 0 {
+				return false
+			}
+		}
+		return true
+	case *model.Scalar:
+		return v.Value != 0
+	default:
+		return false
+	}
+}
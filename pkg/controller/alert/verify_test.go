@@ -0,0 +1,172 @@
+package alert
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	promv1 "github.com/prometheus/client_golang/api/prometheus/v1"
+	"github.com/prometheus/common/model"
+	alertv1alpha1 "github.com/scitix/aegis/pkg/apis/alert/v1alpha1"
+	"github.com/scitix/aegis/pkg/controller"
+	alertLister "github.com/scitix/aegis/pkg/generated/alert/listers/alert/v1alpha1"
+	"github.com/scitix/aegis/pkg/prom"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/client-go/tools/record"
+	"k8s.io/client-go/util/workqueue"
+)
+
+func TestVerificationResolved(t *testing.T) {
+	cases := []struct {
+		name     string
+		value    model.Value
+		expected bool
+	}{
+		{"empty vector", model.Vector{}, false},
+		{"all non-zero", model.Vector{{Value: 1}, {Value: 2}}, true},
+		{"any zero", model.Vector{{Value: 1}, {Value: 0}}, false},
+		{"scalar", &model.Scalar{Value: 1}, true},
+		{"zero scalar", &model.Scalar{Value: 0}, false},
+		{"matrix", model.Matrix{}, false},
+	}
+
+	for _, c := range cases {
+		if got := verificationResolved(c.value); got != c.expected {
+			t.Errorf("%s: expected %v, got %v", c.name, c.expected, got)
+		}
+	}
+}
+
+type fakePromAPI struct {
+	promv1.API
+	value   model.Value
+	queries int
+}
+
+func (f *fakePromAPI) Query(ctx context.Context, query string, ts time.Time, opts ...promv1.Option) (model.Value, promv1.Warnings, error) {
+	f.queries++
+	return f.value, nil, nil
+}
+
+// fakeDelayingQueue records the delays of the requeued keys
+type fakeDelayingQueue struct {
+	workqueue.RateLimitingInterface
+	delays map[string]time.Duration
+}
+
+func (f *fakeDelayingQueue) AddAfter(item interface{}, duration time.Duration) {
+	f.delays[item.(string)] = duration
+}
+
+type fakeLifecycle struct {
+	controller.AegisCallbackInterface
+	events chan string
+}
+
+func (f *fakeLifecycle) OnOpsWorkflowSucceed(alert *alertv1alpha1.AegisAlert) error {
+	f.events <- "succeed"
+	return nil
+}
+
+func (f *fakeLifecycle) OnOpsWorkflowFailed(alert *alertv1alpha1.AegisAlert) error {
+	f.events <- "failed"
+	return nil
+}
+
+func newVerifyTestController(completedAgo time.Duration, value model.Value) (*AlertController, *fakePromAPI, *fakeDelayingQueue, *fakeLifecycle, *alertv1alpha1.AegisAlert) {
+	completion := metav1.NewTime(time.Now().Add(-completedAgo))
+	alert := &alertv1alpha1.AegisAlert{
+		ObjectMeta: metav1.ObjectMeta{Name: "drain", Namespace: "monitoring", UID: "uid-1"},
+		Spec:       alertv1alpha1.AegisAlertSpec{Type: "NodeNotReady"},
+		Status: alertv1alpha1.AegisAlertStatus{
+			StartTime: &completion,
+			Conditions: []alertv1alpha1.AlertOpsCondition{
+				{Type: alertv1alpha1.AlertCompleteOpsWrofklow, Status: v1.ConditionTrue},
+			},
+			OpsStatus: alertv1alpha1.AegisAlertOpsStatus{
+				Status:         alertv1alpha1.OpsStatusSucceeded,
+				CompletionTime: &completion,
+			},
+		},
+	}
+	alertIndexer := cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{cache.NamespaceIndex: cache.MetaNamespaceIndexFunc})
+	alertIndexer.Add(alert)
+
+	api := &fakePromAPI{value: value}
+	queue := &fakeDelayingQueue{delays: map[string]time.Duration{}}
+	lifecycle := &fakeLifecycle{events: make(chan string, 1)}
+	updated := &alertv1alpha1.AegisAlert{}
+	c := &AlertController{
+		lifecycleControl: lifecycle,
+		ruleEngineController: &fakeRuleEngine{policy: &controller.OpsPolicy{Verify: &controller.VerifyPolicy{
+			Query:    `up{job="node"} == 1`,
+			Wait:     5 * time.Minute,
+			Timeout:  30 * time.Minute,
+			Interval: time.Minute,
+		}}},
+		alertLister: alertLister.NewAegisAlertLister(alertIndexer),
+		prometheus:  &prom.PromAPI{API: api},
+		workqueue:   queue,
+		recorder:    record.NewFakeRecorder(10),
+	}
+	c.updateStatusHandler = func(ctx context.Context, alert *alertv1alpha1.AegisAlert) error {
+		alert.DeepCopyInto(updated)
+		return nil
+	}
+	return c, api, queue, lifecycle, updated
+}
+
+func TestSyncAlertVerification(t *testing.T) {
+	cases := []struct {
+		name         string
+		completedAgo time.Duration
+		value        model.Value
+		queries      int
+		delay        time.Duration
+		condition    alertv1alpha1.AlertOpsConditionType
+		callback     string
+	}{
+		{"wait", time.Minute, model.Vector{}, 0, 4 * time.Minute, "", ""},
+		{"interval", 10 * time.Minute, model.Vector{}, 1, time.Minute, "", ""},
+		{"resolved", 10 * time.Minute, model.Vector{{Value: 1}}, 1, 0, alertv1alpha1.AlertVerified, "succeed"},
+		{"timeout", 40 * time.Minute, model.Vector{}, 1, 0, alertv1alpha1.AlertVerificationFailed, "failed"},
+	}
+
+	for _, tc := range cases {
+		c, api, queue, lifecycle, updated := newVerifyTestController(tc.completedAgo, tc.value)
+		if _, err := c.syncAlert(context.Background(), "monitoring/drain"); err != nil {
+			t.Fatalf("%s: %v", tc.name, err)
+		}
+
+		if api.queries != tc.queries {
+			t.Errorf("%s: expected %d queries, got %d", tc.name, tc.queries, api.queries)
+		}
+		delay, requeued := queue.delays["monitoring/drain"]
+		if tc.delay == 0 && requeued {
+			t.Errorf("%s: expected no requeue, got %v", tc.name, delay)
+		} else if tc.delay > 0 && (!requeued || delay > tc.delay || delay < tc.delay-5*time.Second) {
+			t.Errorf("%s: expected requeue after %v, got %v %t", tc.name, tc.delay, delay, requeued)
+		}
+
+		if tc.condition == "" {
+			if IsAlertOpsVerifyFinished(updated) {
+				t.Errorf("%s: expected verification pending, got %+v", tc.name, updated.Status.Conditions)
+			}
+		} else if condition := getCondition(&updated.Status, tc.condition); condition == nil || condition.Status != v1.ConditionTrue {
+			t.Errorf("%s: expected condition %s, got %+v", tc.name, tc.condition, updated.Status.Conditions)
+		}
+
+		select {
+		case event := <-lifecycle.events:
+			if event != tc.callback {
+				t.Errorf("%s: expected callback %q, got %q", tc.name, tc.callback, event)
+			}
+		case <-time.After(100 * time.Millisecond):
+			if tc.callback != "" {
+				t.Errorf("%s: expected callback %q", tc.name, tc.callback)
+			}
+		}
+	}
+}
@@ -3,14 +3,17 @@ package controller
 import (
 	"context"
 	"fmt"
+	"reflect"
 	"sync"
 	"time"
 
+	diagnosisv1alpha1 "github.com/scitix/aegis/pkg/apis/diagnosis/v1alpha1"
 	nodecheckv1alpha1 "github.com/scitix/aegis/pkg/apis/nodecheck/v1alpha1"
 
 	alertv1alpha1 "github.com/scitix/aegis/pkg/apis/alert/v1alpha1"
 	alertclientset "github.com/scitix/aegis/pkg/generated/alert/clientset/versioned"
 	alertlister "github.com/scitix/aegis/pkg/generated/alert/listers/alert/v1alpha1"
+	"github.com/scitix/aegis/pkg/tracing"
 
 	wfv1alpha1 "github.com/argoproj/argo-workflows/v3/pkg/apis/workflow/v1alpha1"
 	wfclientset "github.com/argoproj/argo-workflows/v3/pkg/client/clientset/versioned"
@@ -54,18 +57,43 @@ const (
 	SucceessfulDeleteAlertReason = "SuccessfulDelete"
 )
 
+// AlertFingerprintIndex indexes the alerts by namespace and fingerprint label
+const AlertFingerprintIndex = "fingerprint"
+
+// AlertFingerprintIndexFunc returns the namespace/fingerprint key of an alert
+func AlertFingerprintIndexFunc(obj interface{}) ([]string, error) {
+	alert, ok := obj.(*alertv1alpha1.AegisAlert)
+	if !ok {
+		return nil, fmt.Errorf("object is not an alert: %T", obj)
+	}
+	fingerprint := alert.Labels["fingerprint"]
+	if len(fingerprint) == 0 {
+		return nil, nil
+	}
+	return []string{AlertFingerprintKey(alert.Namespace, fingerprint)}, nil
+}
+
+func AlertFingerprintKey(namespace, fingerprint string) string {
+	return namespace + "/" + fingerprint
+}
+
 type AlertControllerInterface interface {
 	CreateAlert(ctx context.Context, namespace string, template *alertv1alpha1.AegisAlert) error
 	CreateAlertWithGenerateName(ctx context.Context, namespace string, template *alertv1alpha1.AegisAlert, generateName string) error
+	GetAlert(ctx context.Context, namespace string, name string) (*alertv1alpha1.AegisAlert, error)
 	ListAlertWithLabelSelector(ctx context.Context, namespace string, labelSelector labels.Selector) ([]*alertv1alpha1.AegisAlert, error)
-	PatchAlert(ctx context.Context, namespace string, name string, data []byte) error
-	PatchAlertWithLabelSelector(ctx context.Context, namespace string, labelSelector labels.Selector, data []byte) error
+	ListAlertWithFingerprint(ctx context.Context, namespace string, fingerprint string) ([]*alertv1alpha1.AegisAlert, error)
+	UpdateAlertStatus(ctx context.Context, alert *alertv1alpha1.AegisAlert) error
+	PatchAlertStatus(ctx context.Context, namespace string, name string, data []byte) error
+	PatchAlertStatusWithLabelSelector(ctx context.Context, namespace string, labelSelector labels.Selector, data []byte) error
 	DeleteAlert(ctx context.Context, namespace string, name string) error
 }
 
 type RealAlertController struct {
 	AlertClient alertclientset.Interface
 	AlertLister alertlister.AegisAlertLister
+	// AlertIndexer of the alert informer with the fingerprint index
+	AlertIndexer cache.Indexer
 }
 
 func (r *RealAlertController) CreateAlert(ctx context.Context, namespace string, template *alertv1alpha1.AegisAlert) error {
@@ -76,28 +104,62 @@ func (r *RealAlertController) ListAlertWithLabelSelector(ctx context.Context, na
 	return r.AlertLister.AegisAlerts(namespace).List(selector)
 }
 
+// GetAlert gets the alert from the apiserver, not the cache
+func (r *RealAlertController) GetAlert(ctx context.Context, namespace string, name string) (*alertv1alpha1.AegisAlert, error) {
+	return r.AlertClient.AegisV1alpha1().AegisAlerts(namespace).Get(ctx, name, metav1.GetOptions{})
+}
+
+// ListAlertWithFingerprint lists the cached alerts of the fingerprint from the index
+func (r *RealAlertController) ListAlertWithFingerprint(ctx context.Context, namespace string, fingerprint string) ([]*alertv1alpha1.AegisAlert, error) {
+	objs, err := r.AlertIndexer.ByIndex(AlertFingerprintIndex, AlertFingerprintKey(namespace, fingerprint))
+	if err != nil {
+		return nil, err
+	}
+	alerts := make([]*alertv1alpha1.AegisAlert, 0, len(objs))
+	for _, obj := range objs {
+		alerts = append(alerts, obj.(*alertv1alpha1.AegisAlert))
+	}
+	return alerts, nil
+}
+
+// UpdateAlertStatus updates the status subresource, it fails with a conflict
+// if the alert changed since its resourceVersion
+func (r *RealAlertController) UpdateAlertStatus(ctx context.Context, alert *alertv1alpha1.AegisAlert) error {
+	_, err := r.AlertClient.AegisV1alpha1().AegisAlerts(alert.Namespace).UpdateStatus(ctx, alert, metav1.UpdateOptions{})
+	return err
+}
+
 func (r *RealAlertController) CreateAlertWithGenerateName(ctx context.Context, namespace string, template *alertv1alpha1.AegisAlert, generateName string) error {
 	if len(generateName) > 0 {
 		template.GenerateName = generateName
 	}
 
-	_, err := r.AlertClient.AegisV1alpha1().AegisAlerts(namespace).Create(ctx, template, metav1.CreateOptions{})
+	alert, err := r.AlertClient.AegisV1alpha1().AegisAlerts(namespace).Create(ctx, template, metav1.CreateOptions{})
 	if err != nil {
 		return err
 	}
 
+	// status is a subresource, it is dropped on create
+	if !reflect.DeepEqual(template.Status, alertv1alpha1.AegisAlertStatus{}) {
+		alert.Status = template.Status
+		if _, err := r.AlertClient.AegisV1alpha1().AegisAlerts(namespace).UpdateStatus(ctx, alert, metav1.UpdateOptions{}); err != nil {
+			return err
+		}
+	}
+
 	return nil
 }
 
-func (r *RealAlertController) PatchAlert(ctx context.Context, namespace string, name string, data []byte) error {
-	_, err := r.AlertClient.AegisV1alpha1().AegisAlerts(namespace).Patch(ctx, name, types.JSONPatchType, data, metav1.PatchOptions{})
+// PatchAlertStatus applies the json patch to the status subresource of the alert
+func (r *RealAlertController) PatchAlertStatus(ctx context.Context, namespace string, name string, data []byte) error {
+	_, err := r.AlertClient.AegisV1alpha1().AegisAlerts(namespace).Patch(ctx, name, types.JSONPatchType, data, metav1.PatchOptions{}, "status")
 	if err != nil {
 		return err
 	}
 	return nil
 }
 
-func (r *RealAlertController) PatchAlertWithLabelSelector(ctx context.Context, namespace string, selector labels.Selector, data []byte) error {
+func (r *RealAlertController) PatchAlertStatusWithLabelSelector(ctx context.Context, namespace string, selector labels.Selector, data []byte) error {
 	alerts, err := r.AlertLister.AegisAlerts(namespace).List(selector)
 	if err != nil {
 		return err
@@ -105,7 +167,7 @@ func (r *RealAlertController) PatchAlertWithLabelSelector(ctx context.Context, n
 
 	var errs []error
 	for _, alert := range alerts {
-		err := r.PatchAlert(ctx, namespace, alert.Name, data)
+		err := r.PatchAlertStatus(ctx, namespace, alert.Name, data)
 		if err != nil {
 			errs = append(errs, err)
 		}
@@ -134,6 +196,8 @@ type WorkflowControllerInterface interface {
 
 	PatchWorkflow(ctx context.Context, namespace string, name string, data []byte) error
 
+	MergePatchWorkflow(ctx context.Context, namespace string, name string, data []byte) error
+
 	DeleteWorkflow(ctx context.Context, namespace string, workflowID string, object runtime.Object) error
 }
 
@@ -229,6 +293,9 @@ func (r RealWorkflowControl) CreateWorkflowWithGenerateName(ctx context.Context,
 		wf.ObjectMeta.GenerateName = generateName
 	}
 
+	// sop scripts continue the trace from the workflow annotations
+	wf.ObjectMeta.Annotations = tracing.InjectAnnotations(ctx, wf.ObjectMeta.Annotations)
+
 	return r.createWorkflow(ctx, namespace, wf, object)
 }
 
@@ -269,6 +336,13 @@ func (r RealWorkflowControl) PatchWorkflow(ctx context.Context, namespace string
 	return err
 }
 
+// MergePatchWorkflow applies a json merge patch, workflow is a CRD and does not
+// support strategic merge patches on its spec.
+func (r RealWorkflowControl) MergePatchWorkflow(ctx context.Context, namespace string, name string, data []byte) error {
+	_, err := r.WfClient.ArgoprojV1alpha1().Workflows(namespace).Patch(ctx, name, types.MergePatchType, data, metav1.PatchOptions{})
+	return err
+}
+
 func (r RealWorkflowControl) DeleteWorkflow(ctx context.Context, namespace string, workflowID string, object runtime.Object) error {
 	accessor, err := meta.Accessor(object)
 	if err != nil {
@@ -409,3 +483,24 @@ type AegisCallbackInterface interface {
 	OnOpsWorkflowFailed(alert *alertv1alpha1.AegisAlert) error
 	OnNodeCheckUpdate(nodecheck *nodecheckv1alpha1.AegisNodeHealthCheck) error
 }
+
+// EscalationCallbackInterface is implemented by the alert lifecycle callbacks
+// interested in escalated alerts
+type EscalationCallbackInterface interface {
+	OnOpsEscalated(alert *alertv1alpha1.AegisAlert) error
+}
+
+// DiagnosisCallbackInterface define aegis diagnosis lifecycle callback
+type DiagnosisCallbackInterface interface {
+	OnDiagnosisFinished(diagnosis *diagnosisv1alpha1.AegisDiagnosis) error
+}
+
+// ErrAlertDisabled is returned when raising an alert with the alert disabled,
+// retrying doesn't help
+var ErrAlertDisabled = fmt.Errorf("alert is disabled")
+
+// DiagnosisRemediationInterface raises the alert of the diagnoses in auto
+// remediation
+type DiagnosisRemediationInterface interface {
+	RaiseAlert(ctx context.Context, diagnosis *diagnosisv1alpha1.AegisDiagnosis) error
+}
@@ -0,0 +1,207 @@
+package diagnosis
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	openai "github.com/sashabaranov/go-openai"
+	diagnosisv1alpha1 "github.com/scitix/aegis/pkg/apis/diagnosis/v1alpha1"
+	"github.com/scitix/aegis/pkg/redact"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/klog/v2"
+)
+
+const (
+	defaultAgentMaxSteps  = 8
+	defaultAgentMaxTokens = 32000
+	defaultAgentTimeout   = 2 * time.Minute
+
+	// maxStepOutput bounds the output of a step kept in the status
+	maxStepOutput = 1024
+)
+
+// AgentConfig is the budget of the agent mode
+type AgentConfig struct {
+	// MaxSteps is the maximum number of tool calls. Default: 8.
+	MaxSteps int
+	// MaxTokens is the maximum tokens of all the completions. Default: 32000.
+	MaxTokens int
+	// Timeout is the wall time of the tool calls, the final answer is asked
+	// for once exceeded. Default: 2m.
+	Timeout time.Duration
+	// Namespaces the tools may read besides the one of the diagnosed object
+	Namespaces []string
+}
+
+func (c *AgentConfig) withDefaults() AgentConfig {
+	config := AgentConfig{}
+	if c != nil {
+		config = *c
+	}
+	if config.MaxSteps <= 0 {
+		config.MaxSteps = defaultAgentMaxSteps
+	}
+	if config.MaxTokens <= 0 {
+		config.MaxTokens = defaultAgentMaxTokens
+	}
+	if config.Timeout <= 0 {
+		config.Timeout = defaultAgentTimeout
+	}
+	return config
+}
+
+const agentSystemPrompt = `你是 Kubernetes 与 AI 训练集群的故障诊断专家。用户消息中是分析器已采集的诊断信息。
+信息不足以确定根因时（如 NCCL 超时、IB/RoCE 网络、GPU Xid 等问题），可以调用提供的只读工具获取更多日志、事件、指标和节点设备状态，逐步追问直到确定根因。
+每次工具调用都会消耗预算，只查询与根因相关的信息，不要重复查询。确定根因后不再调用工具，按用户消息的要求输出 JSON。`
+
+const agentBudgetPrompt = "工具调用预算已用完，不能再调用工具，请根据已有信息按要求输出 JSON。"
+
+// agentBudget tracks the budget of an agent run
+type agentBudget struct {
+	config AgentConfig
+	start  time.Time
+	status *diagnosisv1alpha1.DiagnosisAgentStatus
+}
+
+// exceeded returns the budget exceeded, empty if none
+func (b *agentBudget) exceeded() diagnosisv1alpha1.AgentStopReason {
+	switch {
+	case len(b.status.Steps) >= b.config.MaxSteps:
+		return diagnosisv1alpha1.AgentStopMaxSteps
+	case int(b.status.Tokens) >= b.config.MaxTokens:
+		return diagnosisv1alpha1.AgentStopMaxTokens
+	case time.Since(b.start) >= b.config.Timeout:
+		return diagnosisv1alpha1.AgentStopTimeout
+	}
+	return ""
+}
+
+// getAgentAnalysis lets the LLM call the read-only tools, within the scope of
+// the object, until it answers with the structured explain or the budget is
+// exceeded, and records every tool call. Invalid answers are retried as in
+// getAnalysis. The tool outputs are redacted as the prompt, and the tool
+// arguments restored.
+func (d *Diagnosis) getAgentAnalysis(ctx context.Context, object diagnosisv1alpha1.AegisDiagnosisObject, prompt string, result *diagnosisv1alpha1.DiagnosisResult, redactor *redact.Redactor) (*Explanation, error) {
+	config := d.AgentConfig.withDefaults()
+	tools := d.agentTools(d.newAgentScope(ctx, object, config.Namespaces))
+	byName := make(map[string]agentTool, len(tools))
+	definitions := make([]openai.Tool, 0, len(tools))
+	for i := range tools {
+		byName[tools[i].definition.Name] = tools[i]
+		definitions = append(definitions, openai.Tool{Type: openai.ToolTypeFunction, Function: &tools[i].definition})
+	}
+
+	status := &diagnosisv1alpha1.DiagnosisAgentStatus{}
+	budget := &agentBudget{config: config, start: time.Now(), status: status}
+
+	// a hanging tool call does not outlive the wall time
+	toolCtx, cancel := context.WithDeadline(ctx, budget.start.Add(budget.config.Timeout))
+	defer cancel()
+
+	messages := []openai.ChatCompletionMessage{
+		{Role: openai.ChatMessageRoleSystem, Content: agentSystemPrompt},
+		{Role: openai.ChatMessageRoleUser, Content: redactPrompt(redactor, analysisPrompt(prompt, result))},
+	}
+
+	var completion string
+	for attempt := 0; ; {
+		offerTools := status.StopReason == ""
+		if offerTools {
+			if reason := budget.exceeded(); reason != "" {
+				klog.V(4).Infof("Agent budget %s exceeded after %d steps", reason, len(status.Steps))
+				status.StopReason = reason
+				offerTools = false
+				messages = append(messages, openai.ChatCompletionMessage{Role: openai.ChatMessageRoleUser, Content: agentBudgetPrompt})
+			}
+		}
+
+		request := openai.ChatCompletionRequest{
+			Model:       d.ChatClient.Model,
+			Messages:    messages,
+			Temperature: d.ChatClient.Temperature,
+		}
+		if offerTools {
+			request.Tools = definitions
+		}
+		chat, err := d.ChatClient.Client.CreateChatCompletion(ctx, request)
+		if err != nil {
+			return &Explanation{Agent: status}, err
+		}
+		status.Tokens += int32(chat.Usage.TotalTokens)
+		if len(chat.Choices) == 0 {
+			return &Explanation{Agent: status}, fmt.Errorf("no completion choices")
+		}
+
+		message := chat.Choices[0].Message
+		messages = append(messages, message)
+		if offerTools && len(message.ToolCalls) > 0 {
+			for _, call := range message.ToolCalls {
+				output := d.callAgentTool(toolCtx, byName, call, budget, redactor)
+				messages = append(messages, openai.ChatCompletionMessage{
+					Role:       openai.ChatMessageRoleTool,
+					Content:    output,
+					Name:       call.Function.Name,
+					ToolCallID: call.ID,
+				})
+			}
+			continue
+		}
+
+		completion = message.Content
+		response, err := parseAnalysis(completion, result)
+		if err == nil {
+			if status.StopReason == "" {
+				status.StopReason = diagnosisv1alpha1.AgentStopAnswered
+			}
+			return &Explanation{Text: renderAnalysis(response, result), Analysis: &response.DiagnosisAnalysis, Agent: status}, nil
+		}
+		klog.Warningf("Invalid structured explain (attempt %d): %v", attempt+1, err)
+		if attempt++; attempt > maxAnalysisRetries {
+			break
+		}
+		messages = append(messages, openai.ChatCompletionMessage{
+			Role:    openai.ChatMessageRoleUser,
+			Content: fmt.Sprintf("上次输出不符合要求：%v。请严格按照要求重新输出 JSON。", err),
+		})
+	}
+
+	if status.StopReason == "" {
+		status.StopReason = diagnosisv1alpha1.AgentStopAnswered
+	}
+	return &Explanation{Text: completion, Agent: status}, nil
+}
+
+// callAgentTool runs the tool call within the budget, records the step and
+// returns the redacted output for the LLM
+func (d *Diagnosis) callAgentTool(ctx context.Context, tools map[string]agentTool, call openai.ToolCall, budget *agentBudget, redactor *redact.Redactor) string {
+	step := diagnosisv1alpha1.DiagnosisAgentStep{
+		Tool:      call.Function.Name,
+		Arguments: redactor.Restore(call.Function.Arguments),
+		StartTime: metav1.Now(),
+	}
+
+	// every tool call is answered, the ones over the budget are not run
+	var output string
+	var err error
+	if reason := budget.exceeded(); reason != "" {
+		err = fmt.Errorf("budget %s exceeded, not called", reason)
+	} else if tool, ok := tools[call.Function.Name]; !ok {
+		err = fmt.Errorf("unknown tool %s", call.Function.Name)
+	} else {
+		output, err = tool.call(ctx, step.Arguments)
+	}
+	step.DurationMillis = time.Since(step.StartTime.Time).Milliseconds()
+
+	if err != nil {
+		klog.V(4).Infof("Agent tool %s(%s) failed: %v", call.Function.Name, call.Function.Arguments, err)
+		step.Error = err.Error()
+		output = fmt.Sprintf("error: %v", err)
+	} else {
+		step.Output = truncate(output, maxStepOutput)
+		output = truncate(output, maxToolOutput)
+	}
+	output = redactor.Redact(output)
+	budget.status.Steps = append(budget.status.Steps, step)
+	return output
+}
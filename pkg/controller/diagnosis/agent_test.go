@@ -0,0 +1,287 @@
+package diagnosis
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/k8sgpt-ai/k8sgpt/pkg/kubernetes"
+	openai "github.com/sashabaranov/go-openai"
+	"github.com/scitix/aegis/pkg/ai"
+	"github.com/scitix/aegis/pkg/analyzer/common"
+	diagnosisv1alpha1 "github.com/scitix/aegis/pkg/apis/diagnosis/v1alpha1"
+	"github.com/scitix/aegis/pkg/explaincache"
+	"github.com/scitix/aegis/pkg/redact"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+// stubLLM is an OpenAI compatible chat completions server replying the
+// messages in order, and recording the requests
+type stubLLM struct {
+	mu       sync.Mutex
+	replies  []openai.ChatCompletionMessage
+	requests []openai.ChatCompletionRequest
+}
+
+func (s *stubLLM) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Path != "/chat/completions" {
+		http.NotFound(w, r)
+		return
+	}
+	var request openai.ChatCompletionRequest
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	s.mu.Lock()
+	s.requests = append(s.requests, request)
+	reply := s.replies[0]
+	if len(s.replies) > 1 {
+		s.replies = s.replies[1:]
+	}
+	s.mu.Unlock()
+
+	reply.Role = openai.ChatMessageRoleAssistant
+	_ = json.NewEncoder(w).Encode(openai.ChatCompletionResponse{
+		Choices: []openai.ChatCompletionChoice{{Message: reply}},
+		Usage:   openai.Usage{TotalTokens: 100},
+	})
+}
+
+func toolCall(id, name, arguments string) openai.ToolCall {
+	return openai.ToolCall{ID: id, Type: openai.ToolTypeFunction, Function: openai.FunctionCall{Name: name, Arguments: arguments}}
+}
+
+func newAgentDiagnosis(t *testing.T, stub *stubLLM, config *AgentConfig) *Diagnosis {
+	server := httptest.NewServer(stub)
+	t.Cleanup(server.Close)
+
+	clientConfig := openai.DefaultConfig("token")
+	clientConfig.BaseURL = server.URL
+
+	pod := &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Name: "trainer-worker-0", Namespace: "default"},
+		Spec:       corev1.PodSpec{NodeName: "node-1"},
+		Status: corev1.PodStatus{
+			Phase:             corev1.PodFailed,
+			ContainerStatuses: []corev1.ContainerStatus{{Name: "pytorch", RestartCount: 2}},
+		},
+	}
+	node := &corev1.Node{
+		ObjectMeta: metav1.ObjectMeta{Name: "node-1"},
+		Status: corev1.NodeStatus{Conditions: []corev1.NodeCondition{
+			{Type: corev1.NodeReady, Status: corev1.ConditionTrue},
+			{Type: "IBLinkDown", Status: corev1.ConditionTrue, Reason: "mlx5_1 port down"},
+		}},
+	}
+
+	return &Diagnosis{
+		Client:      &kubernetes.Client{Client: fake.NewSimpleClientset(pod, node)},
+		AIClient:    &fakeAI{completions: []string{"unused"}},
+		ChatClient:  &ai.ChatClient{Client: openai.NewClientWithConfig(clientConfig), Model: "stub"},
+		AgentConfig: config,
+		Explain:     true,
+		NoCache:     true,
+		Cache:       explaincache.NewWithStore(explaincache.Config{}, nil),
+		AnalyzerFactory: map[string]func(*diagnosisv1alpha1.AegisDiagnosis) common.IAnalyzer{
+			"Pod": func(_ *diagnosisv1alpha1.AegisDiagnosis) common.IAnalyzer { return fakeAnalyzer{} },
+		},
+	}
+}
+
+func agentDiagnosis() *diagnosisv1alpha1.AegisDiagnosis {
+	return &diagnosisv1alpha1.AegisDiagnosis{
+		Spec: diagnosisv1alpha1.AegisDiagnosisSpec{
+			Object: diagnosisv1alpha1.AegisDiagnosisObject{Kind: "Pod", Name: "trainer-worker-0", Namespace: "default"},
+			Mode:   diagnosisv1alpha1.ModeAgent,
+		},
+	}
+}
+
+func TestAgentDiagnosis(t *testing.T) {
+	stub := &stubLLM{replies: []openai.ChatCompletionMessage{
+		{ToolCalls: []openai.ToolCall{
+			toolCall("call-1", "list_node_pods", `{"node": "node-1"}`),
+			toolCall("call-2", "get_node_device_errors", `{"node": "node-1"}`),
+		}},
+		{ToolCalls: []openai.ToolCall{
+			toolCall("call-3", "get_pod_logs", `{"namespace": "default", "name": "trainer-worker-0", "filter": "(?i)fake"}`),
+			toolCall("call-4", "restart_pod", `{}`),
+		}},
+		{Content: validAnalysis},
+	}}
+	d := newAgentDiagnosis(t, stub, nil)
+
+	_, explanation, err := d.RunDiagnosis(context.Background(), agentDiagnosis())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if explanation.Analysis == nil || explanation.Analysis.Category != diagnosisv1alpha1.CategoryImage {
+		t.Fatalf("unexpected analysis %+v", explanation.Analysis)
+	}
+
+	agent := explanation.Agent
+	if agent == nil || agent.StopReason != diagnosisv1alpha1.AgentStopAnswered || agent.Tokens != 300 || len(agent.Steps) != 4 {
+		t.Fatalf("unexpected audit trail %+v", agent)
+	}
+	for i, expected := range []string{"default/trainer-worker-0 Failed restarts=2", "IBLinkDown", "fake logs"} {
+		if step := agent.Steps[i]; step.Error != "" || !strings.Contains(step.Output, expected) {
+			t.Errorf("expected %q in step %d, got %+v", expected, i, step)
+		}
+	}
+	if agent.Steps[3].Error != "unknown tool restart_pod" {
+		t.Errorf("expected the unknown tool refused, got %+v", agent.Steps[3])
+	}
+
+	// the tool outputs are replied to the LLM, with the tools offered
+	if len(stub.requests) != 3 {
+		t.Fatalf("expected 3 completions, got %d", len(stub.requests))
+	}
+	second := stub.requests[1]
+	if len(second.Tools) != 4 {
+		t.Errorf("expected 4 tools without Prometheus, got %d", len(second.Tools))
+	}
+	replies := 0
+	for _, message := range second.Messages {
+		if message.Role == openai.ChatMessageRoleTool && (message.ToolCallID == "call-1" || message.ToolCallID == "call-2") {
+			replies++
+		}
+	}
+	if replies != 2 {
+		t.Errorf("expected the 2 tool calls replied, got %d", replies)
+	}
+}
+
+func TestAgentDiagnosisBudget(t *testing.T) {
+	stub := &stubLLM{replies: []openai.ChatCompletionMessage{
+		{ToolCalls: []openai.ToolCall{
+			toolCall("call-1", "list_node_pods", `{"node": "node-1"}`),
+			toolCall("call-2", "get_node_device_errors", `{"node": "node-1"}`),
+		}},
+		{Content: validAnalysis},
+	}}
+	d := newAgentDiagnosis(t, stub, &AgentConfig{MaxSteps: 1})
+
+	_, explanation, err := d.RunDiagnosis(context.Background(), agentDiagnosis())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	agent := explanation.Agent
+	if agent.StopReason != diagnosisv1alpha1.AgentStopMaxSteps || len(agent.Steps) != 2 {
+		t.Fatalf("unexpected audit trail %+v", agent)
+	}
+	if agent.Steps[0].Error != "" || !strings.Contains(agent.Steps[1].Error, "MaxSteps") {
+		t.Errorf("expected the call over the budget not run, got %+v", agent.Steps)
+	}
+
+	// the final answer is asked for without tools
+	last := stub.requests[len(stub.requests)-1]
+	if len(last.Tools) != 0 || last.Messages[len(last.Messages)-1].Content != agentBudgetPrompt {
+		t.Errorf("expected the final answer asked for without tools, got %d tools", len(last.Tools))
+	}
+	if explanation.Analysis == nil {
+		t.Errorf("expected the analysis after the budget")
+	}
+}
+
+func TestAgentDiagnosisRedaction(t *testing.T) {
+	stub := &stubLLM{replies: []openai.ChatCompletionMessage{
+		{ToolCalls: []openai.ToolCall{
+			toolCall("call-1", "get_pod_logs", `{"namespace": "default", "name": "[REDACTED_NAME_1]"}`),
+		}},
+		{Content: validAnalysis},
+	}}
+	d := newAgentDiagnosis(t, stub, nil)
+	d.AIProvider = "openai"
+	d.AnalyzerFactory["Pod"] = func(_ *diagnosisv1alpha1.AegisDiagnosis) common.IAnalyzer {
+		return imageAnalyzer{image: "registry/app:v2"}
+	}
+	redaction, err := redact.New(&redact.Config{Enable: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	d.Redaction = redaction
+
+	_, explanation, err := d.RunDiagnosis(context.Background(), agentDiagnosis())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// the tool is called with the restored name, and recorded unredacted
+	step := explanation.Agent.Steps[0]
+	if step.Error != "" || !strings.Contains(step.Arguments, `"trainer-worker-0"`) || !strings.Contains(step.Output, "fake logs") {
+		t.Errorf("expected the tool called on trainer-worker-0, got %+v", step)
+	}
+	for _, request := range stub.requests {
+		for _, message := range request.Messages {
+			if strings.Contains(message.Content, "trainer-worker-0") {
+				t.Errorf("expected the name redacted in the %s message:\n%s", message.Role, message.Content)
+			}
+		}
+	}
+}
+
+func TestAgentDiagnosisScope(t *testing.T) {
+	stub := &stubLLM{replies: []openai.ChatCompletionMessage{
+		{ToolCalls: []openai.ToolCall{
+			toolCall("call-1", "get_pod_logs", `{"namespace": "kube-system", "name": "etcd-0"}`),
+			toolCall("call-2", "list_node_pods", `{"node": "node-2"}`),
+			toolCall("call-3", "get_node_device_errors", `{"node": "node-2"}`),
+			toolCall("call-4", "get_events", `{"kind": "Node", "name": "node-2"}`),
+			toolCall("call-5", "get_events", `{"kind": "Deployment", "namespace": "kube-system", "name": "coredns"}`),
+			toolCall("call-6", "get_pod_logs", `{"namespace": "monitoring", "name": "exporter-0"}`),
+		}},
+		{Content: validAnalysis},
+	}}
+	d := newAgentDiagnosis(t, stub, &AgentConfig{Namespaces: []string{"monitoring"}})
+
+	_, explanation, err := d.RunDiagnosis(context.Background(), agentDiagnosis())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	steps := explanation.Agent.Steps
+	if len(steps) != 6 {
+		t.Fatalf("unexpected audit trail %+v", explanation.Agent)
+	}
+	for i, expected := range []string{
+		"pod kube-system/etcd-0 is out of the scope",
+		"node node-2 is out of the scope",
+		"node node-2 is out of the scope",
+		"node node-2 is out of the scope",
+		"namespace kube-system is out of the scope",
+	} {
+		if steps[i].Output != "" || !strings.Contains(steps[i].Error, expected) {
+			t.Errorf("expected step %d rejected with %q, got %+v", i, expected, steps[i])
+		}
+	}
+	// the allowed namespaces are in scope
+	if steps[5].Error != "" || !strings.Contains(steps[5].Output, "fake logs") {
+		t.Errorf("expected the logs of the allowed namespace, got %+v", steps[5])
+	}
+}
+
+func TestAgentScopeNode(t *testing.T) {
+	d := newAgentDiagnosis(t, &stubLLM{}, nil)
+	ctx := context.Background()
+	scope := d.newAgentScope(ctx, diagnosisv1alpha1.AegisDiagnosisObject{Kind: diagnosisv1alpha1.NodeKind, Name: "node-1"}, nil)
+
+	if err := scope.checkNode("node-1"); err != nil {
+		t.Errorf("expected the diagnosed node in scope: %v", err)
+	}
+	// the pods running on the node are in scope
+	if err := d.checkPod(ctx, scope, "default", "trainer-worker-0"); err != nil {
+		t.Errorf("expected the pod of the node in scope: %v", err)
+	}
+	if err := d.checkPod(ctx, scope, "default", "other"); err == nil {
+		t.Errorf("expected the pod of another node out of scope")
+	}
+}
@@ -0,0 +1,390 @@
+package diagnosis
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strings"
+	"unicode/utf8"
+
+	openai "github.com/sashabaranov/go-openai"
+	"github.com/sashabaranov/go-openai/jsonschema"
+	"github.com/scitix/aegis/pkg/analyzer"
+	diagnosisv1alpha1 "github.com/scitix/aegis/pkg/apis/diagnosis/v1alpha1"
+	"github.com/scitix/aegis/pkg/prom"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/klog/v2"
+)
+
+const (
+	// maxToolOutput bounds the output of a tool call sent to the LLM
+	maxToolOutput = 8192
+
+	defaultLogTailLines = 200
+	maxLogTailLines     = 2000
+)
+
+// agentTool is a read-only tool offered to the LLM in the agent mode
+type agentTool struct {
+	definition openai.FunctionDefinition
+	call       func(ctx context.Context, arguments string) (string, error)
+}
+
+// agentScope is what the tools of an agent run may read: the namespace of
+// the diagnosed object and the allowed namespaces, the nodes the object runs
+// on, and the pods running on the node of a node diagnosis
+type agentScope struct {
+	namespaces map[string]bool
+	nodes      map[string]bool
+	// node is the diagnosed node
+	node string
+}
+
+// newAgentScope returns the scope of the diagnosed object. The workloads and
+// jobs run their pods in their namespace, so the nodes of a namespaced object
+// are the ones running the pods of its namespace.
+func (d *Diagnosis) newAgentScope(ctx context.Context, object diagnosisv1alpha1.AegisDiagnosisObject, allowed []string) *agentScope {
+	scope := &agentScope{namespaces: make(map[string]bool), nodes: make(map[string]bool)}
+	for _, namespace := range allowed {
+		scope.namespaces[namespace] = true
+	}
+	if object.Kind == diagnosisv1alpha1.NodeKind {
+		scope.node = object.Name
+		scope.nodes[object.Name] = true
+		return scope
+	}
+	scope.namespaces[object.Namespace] = true
+
+	var pods []corev1.Pod
+	if object.Kind == diagnosisv1alpha1.PodKind {
+		pod, err := d.Client.GetClient().CoreV1().Pods(object.Namespace).Get(ctx, object.Name, metav1.GetOptions{})
+		if err != nil {
+			klog.Warningf("Failed to get the node of pod %s/%s: %v", object.Namespace, object.Name, err)
+			return scope
+		}
+		pods = append(pods, *pod)
+	} else {
+		list, err := d.Client.GetClient().CoreV1().Pods(object.Namespace).List(ctx, metav1.ListOptions{})
+		if err != nil {
+			klog.Warningf("Failed to list the pods of namespace %s: %v", object.Namespace, err)
+			return scope
+		}
+		pods = list.Items
+	}
+	for _, pod := range pods {
+		if pod.Spec.NodeName != "" {
+			scope.nodes[pod.Spec.NodeName] = true
+		}
+	}
+	return scope
+}
+
+func (s *agentScope) checkNode(node string) error {
+	if !s.nodes[node] {
+		return fmt.Errorf("node %s is out of the scope of the diagnosis", node)
+	}
+	return nil
+}
+
+func (s *agentScope) checkNamespace(namespace string) error {
+	if !s.namespaces[namespace] {
+		return fmt.Errorf("namespace %s is out of the scope of the diagnosis", namespace)
+	}
+	return nil
+}
+
+// checkPod allows the pods of the namespaces in scope, and the pods running
+// on the diagnosed node
+func (d *Diagnosis) checkPod(ctx context.Context, scope *agentScope, namespace, name string) error {
+	if scope.namespaces[namespace] {
+		return nil
+	}
+	if scope.node != "" {
+		pod, err := d.Client.GetClient().CoreV1().Pods(namespace).Get(ctx, name, metav1.GetOptions{})
+		if err == nil && pod.Spec.NodeName == scope.node {
+			return nil
+		}
+	}
+	return fmt.Errorf("pod %s/%s is out of the scope of the diagnosis", namespace, name)
+}
+
+func stringProperty(description string) jsonschema.Definition {
+	return jsonschema.Definition{Type: jsonschema.String, Description: description}
+}
+
+// agentTools returns the tools of the agent mode within the scope, the
+// Prometheus query is only offered with Prometheus enabled
+func (d *Diagnosis) agentTools(scope *agentScope) []agentTool {
+	scoped := func(call func(context.Context, *agentScope, string) (string, error)) func(context.Context, string) (string, error) {
+		return func(ctx context.Context, arguments string) (string, error) {
+			return call(ctx, scope, arguments)
+		}
+	}
+
+	tools := []agentTool{
+		{
+			definition: openai.FunctionDefinition{
+				Name:        "get_pod_logs",
+				Description: "获取 Pod 容器的最近日志，可用正则过滤日志行",
+				Parameters: jsonschema.Definition{
+					Type: jsonschema.Object,
+					Properties: map[string]jsonschema.Definition{
+						"namespace": stringProperty("Pod 所在命名空间"),
+						"name":      stringProperty("Pod 名称"),
+						"container": stringProperty("容器名称，多容器 Pod 时需要指定"),
+						"filter":    stringProperty("过滤日志行的 Go 正则表达式，如 (?i)nccl|error"),
+						"tailLines": {Type: jsonschema.Integer, Description: fmt.Sprintf("获取最后多少行日志，默认 %d，最大 %d", defaultLogTailLines, maxLogTailLines)},
+					},
+					Required: []string{"namespace", "name"},
+				},
+			},
+			call: scoped(d.getPodLogs),
+		},
+		{
+			definition: openai.FunctionDefinition{
+				Name:        "get_events",
+				Description: "获取 Kubernetes 对象的事件",
+				Parameters: jsonschema.Definition{
+					Type: jsonschema.Object,
+					Properties: map[string]jsonschema.Definition{
+						"kind":      stringProperty("对象类型，如 Pod、Node、PyTorchJob"),
+						"namespace": stringProperty("对象所在命名空间，Node 为空"),
+						"name":      stringProperty("对象名称"),
+					},
+					Required: []string{"kind", "name"},
+				},
+			},
+			call: scoped(d.getEvents),
+		},
+		{
+			definition: openai.FunctionDefinition{
+				Name:        "get_node_device_errors",
+				Description: "获取节点的设备异常（GPU、IB 网卡、磁盘等）及节点异常状态",
+				Parameters: jsonschema.Definition{
+					Type: jsonschema.Object,
+					Properties: map[string]jsonschema.Definition{
+						"node": stringProperty("节点名称"),
+					},
+					Required: []string{"node"},
+				},
+			},
+			call: scoped(d.getNodeDeviceErrors),
+		},
+		{
+			definition: openai.FunctionDefinition{
+				Name:        "list_node_pods",
+				Description: "列出节点上的 Pod 及其状态和重启次数",
+				Parameters: jsonschema.Definition{
+					Type: jsonschema.Object,
+					Properties: map[string]jsonschema.Definition{
+						"node": stringProperty("节点名称"),
+					},
+					Required: []string{"node"},
+				},
+			},
+			call: scoped(d.listNodePods),
+		},
+	}
+
+	if d.EnableProm && d.Prometheus != nil {
+		tools = append(tools, agentTool{
+			definition: openai.FunctionDefinition{
+				Name:        "query_prometheus",
+				Description: "执行 PromQL 即时查询，如 DCGM 指标、IB 网卡计数器、aegis_node_status_condition",
+				Parameters: jsonschema.Definition{
+					Type: jsonschema.Object,
+					Properties: map[string]jsonschema.Definition{
+						"query": stringProperty("PromQL 查询语句"),
+					},
+					Required: []string{"query"},
+				},
+			},
+			call: d.queryPrometheus,
+		})
+	}
+	return tools
+}
+
+func (d *Diagnosis) getPodLogs(ctx context.Context, scope *agentScope, arguments string) (string, error) {
+	var args struct {
+		Namespace string `json:"namespace"`
+		Name      string `json:"name"`
+		Container string `json:"container"`
+		Filter    string `json:"filter"`
+		TailLines int64  `json:"tailLines"`
+	}
+	if err := json.Unmarshal([]byte(arguments), &args); err != nil {
+		return "", fmt.Errorf("invalid arguments: %v", err)
+	}
+
+	if err := d.checkPod(ctx, scope, args.Namespace, args.Name); err != nil {
+		return "", err
+	}
+
+	var filter *regexp.Regexp
+	if args.Filter != "" {
+		var err error
+		if filter, err = regexp.Compile(args.Filter); err != nil {
+			return "", fmt.Errorf("invalid filter: %v", err)
+		}
+	}
+	if args.TailLines <= 0 {
+		args.TailLines = defaultLogTailLines
+	}
+	if args.TailLines > maxLogTailLines {
+		args.TailLines = maxLogTailLines
+	}
+
+	logs, err := d.Client.GetClient().CoreV1().Pods(args.Namespace).GetLogs(args.Name, &corev1.PodLogOptions{
+		Container: args.Container,
+		TailLines: &args.TailLines,
+	}).DoRaw(ctx)
+	if err != nil {
+		return "", err
+	}
+
+	lines := make([]string, 0)
+	for _, line := range strings.Split(string(logs), "\n") {
+		if line == "" || (filter != nil && !filter.MatchString(line)) {
+			continue
+		}
+		lines = append(lines, line)
+	}
+	if len(lines) == 0 {
+		return "no log lines", nil
+	}
+	return strings.Join(lines, "\n"), nil
+}
+
+func (d *Diagnosis) getEvents(ctx context.Context, scope *agentScope, arguments string) (string, error) {
+	var args struct {
+		Kind      string `json:"kind"`
+		Namespace string `json:"namespace"`
+		Name      string `json:"name"`
+	}
+	if err := json.Unmarshal([]byte(arguments), &args); err != nil {
+		return "", fmt.Errorf("invalid arguments: %v", err)
+	}
+
+	var err error
+	switch args.Kind {
+	case string(diagnosisv1alpha1.NodeKind):
+		err = scope.checkNode(args.Name)
+	case string(diagnosisv1alpha1.PodKind):
+		err = d.checkPod(ctx, scope, args.Namespace, args.Name)
+	default:
+		err = scope.checkNamespace(args.Namespace)
+	}
+	if err != nil {
+		return "", err
+	}
+
+	events, err := analyzer.FetchEvents(ctx, d.EnableProm, d.Prometheus, d.Client, args.Kind, args.Namespace, args.Name, "", "")
+	if err != nil {
+		return "", err
+	}
+
+	lines := make([]string, 0)
+	switch events := events.(type) {
+	case []prom.Event:
+		for _, event := range events {
+			lines = append(lines, fmt.Sprintf("%s %s %s(x%d): %s", event.TimeStamps, event.Type, event.Reason, event.Count, event.Message))
+		}
+	case []corev1.Event:
+		for _, event := range events {
+			lines = append(lines, fmt.Sprintf("%s %s %s(x%d): %s", event.LastTimestamp.Format("2006-01-02T15:04:05Z"), event.Type, event.Reason, event.Count, event.Message))
+		}
+	}
+	if len(lines) == 0 {
+		return "no events", nil
+	}
+	return strings.Join(lines, "\n"), nil
+}
+
+func (d *Diagnosis) getNodeDeviceErrors(ctx context.Context, scope *agentScope, arguments string) (string, error) {
+	var args struct {
+		Node string `json:"node"`
+	}
+	if err := json.Unmarshal([]byte(arguments), &args); err != nil {
+		return "", fmt.Errorf("invalid arguments: %v", err)
+	}
+
+	if err := scope.checkNode(args.Node); err != nil {
+		return "", err
+	}
+
+	failures, err := analyzer.FetchNodeFailures(ctx, d.EnableProm, d.Prometheus, d.Client, args.Node)
+	if err != nil {
+		return "", err
+	}
+	if len(failures) == 0 {
+		return "no device errors", nil
+	}
+	lines := make([]string, 0, len(failures))
+	for _, failure := range failures {
+		lines = append(lines, failure.Text)
+	}
+	return strings.Join(lines, "\n"), nil
+}
+
+func (d *Diagnosis) listNodePods(ctx context.Context, scope *agentScope, arguments string) (string, error) {
+	var args struct {
+		Node string `json:"node"`
+	}
+	if err := json.Unmarshal([]byte(arguments), &args); err != nil {
+		return "", fmt.Errorf("invalid arguments: %v", err)
+	}
+
+	if err := scope.checkNode(args.Node); err != nil {
+		return "", err
+	}
+
+	pods, err := d.Client.GetClient().CoreV1().Pods("").List(ctx, metav1.ListOptions{
+		FieldSelector: fmt.Sprintf("spec.nodeName=%s", args.Node),
+	})
+	if err != nil {
+		return "", err
+	}
+	if len(pods.Items) == 0 {
+		return "no pods", nil
+	}
+
+	lines := make([]string, 0, len(pods.Items))
+	for _, pod := range pods.Items {
+		var restarts int32
+		for _, status := range pod.Status.ContainerStatuses {
+			restarts += status.RestartCount
+		}
+		lines = append(lines, fmt.Sprintf("%s/%s %s restarts=%d", pod.Namespace, pod.Name, pod.Status.Phase, restarts))
+	}
+	return strings.Join(lines, "\n"), nil
+}
+
+func (d *Diagnosis) queryPrometheus(ctx context.Context, arguments string) (string, error) {
+	var args struct {
+		Query string `json:"query"`
+	}
+	if err := json.Unmarshal([]byte(arguments), &args); err != nil {
+		return "", fmt.Errorf("invalid arguments: %v", err)
+	}
+
+	value, err := d.Prometheus.Query(ctx, args.Query)
+	if err != nil {
+		return "", err
+	}
+	return value.String(), nil
+}
+
+// truncate keeps the tail of the output, the latest logs and events
+func truncate(output string, max int) string {
+	if len(output) <= max {
+		return output
+	}
+	start := len(output) - max
+	for start < len(output) && !utf8.RuneStart(output[start]) {
+		start++
+	}
+	return "...(truncated)\n" + output[start:]
+}
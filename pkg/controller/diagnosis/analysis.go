@@ -0,0 +1,180 @@
+package diagnosis
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"slices"
+	"strings"
+
+	kai "github.com/k8sgpt-ai/k8sgpt/pkg/ai"
+	diagnosisv1alpha1 "github.com/scitix/aegis/pkg/apis/diagnosis/v1alpha1"
+	"github.com/scitix/aegis/pkg/redact"
+	"k8s.io/klog/v2"
+)
+
+// maxAnalysisRetries bounds the completions retried on schema violations
+const maxAnalysisRetries = 2
+
+// analysisResponse is the JSON the LLM is asked for, the analysis is only
+// kept in the rendered explain
+type analysisResponse struct {
+	diagnosisv1alpha1.DiagnosisAnalysis `json:",inline"`
+	Analysis                            string `json:"analysis"`
+}
+
+// analysisPrompt appends the JSON output instruction, and the failures,
+// warnings and infos the evidence may reference, to the prompt
+func analysisPrompt(prompt string, result *diagnosisv1alpha1.DiagnosisResult) string {
+	categories := make([]string, 0, len(diagnosisv1alpha1.DiagnosisCategories))
+	for _, category := range diagnosisv1alpha1.DiagnosisCategories {
+		categories = append(categories, string(category))
+	}
+
+	var b strings.Builder
+	b.WriteString(prompt)
+	b.WriteString(`
+
+【输出格式要求】
+忽略上文的文本输出格式，只输出一个 JSON 对象，不要输出任何其他内容。上文的 Healthy、Error、Analysis、Solution 分别对应 healthy、rootCause、analysis、recommendedActions 字段：
+{
+  "healthy": true 或 false，是否健康,
+  "rootCause": "一句话总结最可能的根因，健康时为空",
+  "category": "根因分类，取值为 ` + strings.Join(categories, " / ") + `，健康时为 None",
+  "confidence": 对根因的置信度，0 到 100 的整数,
+  "evidence": [{"source": "failure / warning / info", "index": 下方列表中的序号, "reason": "该条目如何支持根因"}],
+  "recommendedActions": ["按优先级排列的处理建议"],
+  "analysis": "结合状态、事件、日志等的简要分析，可摘录关键日志片段"
+}
+evidence 只能引用以下条目：
+`)
+	for i, text := range result.Failures {
+		fmt.Fprintf(&b, "%s[%d]: %s\n", diagnosisv1alpha1.EvidenceFailure, i, text)
+	}
+	for i, text := range result.Warnings {
+		fmt.Fprintf(&b, "%s[%d]: %s\n", diagnosisv1alpha1.EvidenceWarning, i, text)
+	}
+	for i, text := range result.Infos {
+		fmt.Fprintf(&b, "%s[%d]: %s\n", diagnosisv1alpha1.EvidenceInfo, i, text)
+	}
+	return b.String()
+}
+
+const redactionPrompt = `
+注意：上文中形如 [REDACTED_IP_1] 的内容是脱敏占位符，输出中需要提及时请原样引用占位符，不要猜测其原值。
+`
+
+// redactPrompt replaces the sensitive values of the prompt by placeholders
+// before it leaves the cluster
+func redactPrompt(r *redact.Redactor, prompt string) string {
+	prompt = r.Redact(prompt)
+	if r.Redacted() > 0 {
+		prompt += redactionPrompt
+	}
+	return prompt
+}
+
+// parseAnalysis parses the JSON of the completion, and validates it against
+// the result
+func parseAnalysis(completion string, result *diagnosisv1alpha1.DiagnosisResult) (*analysisResponse, error) {
+	// models often wrap the JSON in a markdown code block
+	start, end := strings.Index(completion, "{"), strings.LastIndex(completion, "}")
+	if start < 0 || end < start {
+		return nil, fmt.Errorf("no JSON object found")
+	}
+
+	response := &analysisResponse{}
+	decoder := json.NewDecoder(strings.NewReader(completion[start : end+1]))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(response); err != nil {
+		return nil, fmt.Errorf("invalid JSON: %w", err)
+	}
+
+	if !response.Healthy && response.RootCause == "" {
+		return nil, fmt.Errorf("rootCause is required when not healthy")
+	}
+	if !slices.Contains(diagnosisv1alpha1.DiagnosisCategories, response.Category) {
+		return nil, fmt.Errorf("unknown category %q", response.Category)
+	}
+	if response.Confidence < 0 || response.Confidence > 100 {
+		return nil, fmt.Errorf("confidence %d is out of 0 to 100", response.Confidence)
+	}
+	for _, evidence := range response.Evidence {
+		var count int
+		switch evidence.Source {
+		case diagnosisv1alpha1.EvidenceFailure:
+			count = len(result.Failures)
+		case diagnosisv1alpha1.EvidenceWarning:
+			count = len(result.Warnings)
+		case diagnosisv1alpha1.EvidenceInfo:
+			count = len(result.Infos)
+		default:
+			return nil, fmt.Errorf("unknown evidence source %q", evidence.Source)
+		}
+		if evidence.Index < 0 || int(evidence.Index) >= count {
+			return nil, fmt.Errorf("evidence %s[%d] does not exist", evidence.Source, evidence.Index)
+		}
+	}
+	return response, nil
+}
+
+// getAnalysis asks the LLM for the structured explain, retrying with the
+// violation on invalid responses. The last completion is returned as is if
+// no response is valid.
+func getAnalysis(ctx context.Context, client kai.IAI, prompt string, result *diagnosisv1alpha1.DiagnosisResult, redactor *redact.Redactor) (string, *diagnosisv1alpha1.DiagnosisAnalysis, error) {
+	prompt = redactPrompt(redactor, analysisPrompt(prompt, result))
+
+	var completion string
+	for attempt := 0; attempt <= maxAnalysisRetries; attempt++ {
+		var err error
+		completion, err = client.GetCompletion(ctx, prompt)
+		if err != nil {
+			return "", nil, err
+		}
+
+		response, err := parseAnalysis(completion, result)
+		if err == nil {
+			return renderAnalysis(response, result), &response.DiagnosisAnalysis, nil
+		}
+		klog.Warningf("Invalid structured explain (attempt %d): %v", attempt+1, err)
+		prompt = fmt.Sprintf("%s\n上次输出不符合要求：%v。请严格按照要求重新输出 JSON。\n", prompt, err)
+	}
+	return completion, nil, nil
+}
+
+// renderAnalysis renders the structured explain in the text format of the
+// prompts, kept in Explain for compatibility
+func renderAnalysis(response *analysisResponse, result *diagnosisv1alpha1.DiagnosisResult) string {
+	lines := make([]string, 0)
+	if response.Healthy {
+		lines = append(lines, "Healthy: Yes")
+	} else {
+		lines = append(lines, "Healthy: No")
+		lines = append(lines, fmt.Sprintf("Error: %s", response.RootCause))
+	}
+	lines = append(lines, fmt.Sprintf("Category: %s (confidence %d%%)", response.Category, response.Confidence))
+	if response.Analysis != "" {
+		lines = append(lines, fmt.Sprintf("Analysis: %s", response.Analysis))
+	}
+	if len(response.Evidence) > 0 {
+		lines = append(lines, "Evidence:")
+		for _, evidence := range response.Evidence {
+			lines = append(lines, fmt.Sprintf("- %s: %s", evidenceText(evidence, result), evidence.Reason))
+		}
+	}
+	if len(response.RecommendedActions) > 0 {
+		lines = append(lines, fmt.Sprintf("Solution: %s", strings.Join(response.RecommendedActions, "; ")))
+	}
+	return strings.Join(lines, "\n")
+}
+
+func evidenceText(evidence diagnosisv1alpha1.DiagnosisEvidence, result *diagnosisv1alpha1.DiagnosisResult) string {
+	switch evidence.Source {
+	case diagnosisv1alpha1.EvidenceFailure:
+		return result.Failures[evidence.Index]
+	case diagnosisv1alpha1.EvidenceWarning:
+		return result.Warnings[evidence.Index]
+	default:
+		return result.Infos[evidence.Index]
+	}
+}
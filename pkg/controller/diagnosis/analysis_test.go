@@ -0,0 +1,273 @@
+package diagnosis
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+
+	kai "github.com/k8sgpt-ai/k8sgpt/pkg/ai"
+	kcommon "github.com/k8sgpt-ai/k8sgpt/pkg/common"
+	"github.com/scitix/aegis/pkg/analyzer/common"
+	diagnosisv1alpha1 "github.com/scitix/aegis/pkg/apis/diagnosis/v1alpha1"
+	"github.com/scitix/aegis/pkg/explaincache"
+	"github.com/scitix/aegis/pkg/redact"
+)
+
+// fakeAI replies the completions in order, and records the prompts
+type fakeAI struct {
+	kai.NoOpAIClient
+	completions []string
+	prompts     []string
+}
+
+func (f *fakeAI) GetCompletion(_ context.Context, prompt string) (string, error) {
+	f.prompts = append(f.prompts, prompt)
+	completion := f.completions[0]
+	if len(f.completions) > 1 {
+		f.completions = f.completions[1:]
+	}
+	return completion, nil
+}
+
+type fakeAnalyzer struct{}
+
+func (fakeAnalyzer) Analyze(_ common.Analyzer) (*common.Result, error) {
+	return &common.Result{
+		Result: kcommon.Result{
+			Error: []kcommon.Failure{{Text: "Back-off pulling image registry/app:v2"}},
+		},
+		Warning: []common.Warning{{Text: "Pod app has Warning event Failed(ErrImagePull)"}},
+	}, nil
+}
+
+func (fakeAnalyzer) Prompt(_ *common.Result) string {
+	return "prompt"
+}
+
+const validAnalysis = "```json\n" + `{
+  "healthy": false,
+  "rootCause": "image registry/app:v2 does not exist",
+  "category": "Image",
+  "confidence": 90,
+  "evidence": [{"source": "failure", "index": 0, "reason": "image pull back-off"}],
+  "recommendedActions": ["fix the image tag", "redeploy"],
+  "analysis": "the image tag is wrong"
+}` + "\n```"
+
+func TestParseAnalysis(t *testing.T) {
+	result := &diagnosisv1alpha1.DiagnosisResult{Failures: []string{"failure"}, Warnings: []string{"warning"}}
+
+	response, err := parseAnalysis(validAnalysis, result)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if response.Category != diagnosisv1alpha1.CategoryImage || response.Confidence != 90 || len(response.RecommendedActions) != 2 {
+		t.Errorf("unexpected analysis %+v", response)
+	}
+
+	for _, invalid := range []string{
+		"Healthy: No",
+		`{"healthy": false, "category": "Image"}`,
+		`{"healthy": false, "rootCause": "x", "category": "Cosmic"}`,
+		`{"healthy": false, "rootCause": "x", "category": "Image", "confidence": 0.9}`,
+		`{"healthy": false, "rootCause": "x", "category": "Image", "confidence": 120}`,
+		`{"healthy": false, "rootCause": "x", "category": "Image", "evidence": [{"source": "warning", "index": 1}]}`,
+		`{"healthy": false, "rootCause": "x", "category": "Image", "evidence": [{"source": "log", "index": 0}]}`,
+		`{"healthy": false, "rootCause": "x", "category": "Image", "severity": "high"}`,
+	} {
+		if _, err := parseAnalysis(invalid, result); err == nil {
+			t.Errorf("expected %s invalid", invalid)
+		}
+	}
+}
+
+func TestRunDiagnosisStructuredExplain(t *testing.T) {
+	client := &fakeAI{completions: []string{`{"healthy": false, "category": "Image"}`, validAnalysis}}
+	d := &Diagnosis{
+		AIClient: client,
+		Explain:  true,
+		Cache:    explaincache.NewWithStore(explaincache.Config{}, nil),
+		AnalyzerFactory: map[string]func(*diagnosisv1alpha1.AegisDiagnosis) common.IAnalyzer{
+			"Pod": func(_ *diagnosisv1alpha1.AegisDiagnosis) common.IAnalyzer { return fakeAnalyzer{} },
+		},
+	}
+	diagnosis := &diagnosisv1alpha1.AegisDiagnosis{
+		Spec: diagnosisv1alpha1.AegisDiagnosisSpec{
+			Object: diagnosisv1alpha1.AegisDiagnosisObject{Kind: "Pod", Name: "app", Namespace: "default"},
+		},
+	}
+
+	_, explanation, err := d.RunDiagnosis(context.Background(), diagnosis)
+	if err != nil {
+		t.Fatal(err)
+	}
+	explain, analysis := explanation.Text, explanation.Analysis
+	if len(client.prompts) != 2 || !strings.Contains(client.prompts[1], "rootCause is required when not healthy") {
+		t.Errorf("expected a retry with the violation, got %d prompts", len(client.prompts))
+	}
+	if !strings.Contains(client.prompts[0], "failure[0]: Back-off pulling image registry/app:v2") || !strings.Contains(client.prompts[0], "warning[0]: Pod app has Warning event") {
+		t.Errorf("expected the indexed result in the prompt:\n%s", client.prompts[0])
+	}
+	if analysis == nil || analysis.RootCause != "image registry/app:v2 does not exist" || analysis.Evidence[0].Source != diagnosisv1alpha1.EvidenceFailure {
+		t.Fatalf("unexpected analysis %+v", analysis)
+	}
+	for _, expected := range []string{"Healthy: No", "Error: image registry/app:v2 does not exist", "- Back-off pulling image registry/app:v2: image pull back-off", "Solution: fix the image tag; redeploy"} {
+		if !strings.Contains(explain, expected) {
+			t.Errorf("expected %q in the explain:\n%s", expected, explain)
+		}
+	}
+
+	// cached with the analysis
+	_, cached, err := d.RunDiagnosis(context.Background(), diagnosis)
+	if err != nil || cached.Text != explain || cached.Analysis.RootCause != analysis.RootCause || len(client.prompts) != 2 {
+		t.Errorf("expected the explain cached, got %v %+v", err, cached)
+	}
+
+	// falls back to the raw completion
+	client = &fakeAI{completions: []string{"Healthy: No\nError: something"}}
+	d.AIClient = client
+	d.NoCache = true
+	_, explanation, err = d.RunDiagnosis(context.Background(), diagnosis)
+	if err != nil || explanation.Analysis != nil || explanation.Text != "Healthy: No\nError: something" || len(client.prompts) != maxAnalysisRetries+1 {
+		t.Errorf("expected the raw completion after %d attempts, got %v %+v", maxAnalysisRetries+1, err, explanation)
+	}
+}
+
+// imageAnalyzer fails the object pulling the image
+type imageAnalyzer struct {
+	image string
+}
+
+func (i imageAnalyzer) Analyze(a common.Analyzer) (*common.Result, error) {
+	return &common.Result{
+		Result: kcommon.Result{
+			Error: []kcommon.Failure{{
+				Text:      fmt.Sprintf("Pod %s Back-off pulling image %s", a.Name, i.image),
+				Sensitive: []kcommon.Sensitive{{Unmasked: a.Name}},
+			}},
+		},
+	}, nil
+}
+
+func (imageAnalyzer) Prompt(_ *common.Result) string {
+	return "prompt"
+}
+
+func TestRunDiagnosisSharedExplain(t *testing.T) {
+	client := &fakeAI{completions: []string{`{
+  "healthy": false,
+  "rootCause": "trainer-worker-0 pulls a missing image",
+  "category": "Image",
+  "confidence": 90,
+  "evidence": [{"source": "failure", "index": 0, "reason": "image pull back-off"}]
+}`}}
+	image := "registry/app:v2"
+	d := &Diagnosis{
+		AIClient:   client,
+		AIProvider: "openai",
+		AIModel:    "gpt-4o",
+		Explain:    true,
+		Cache:      explaincache.NewWithStore(explaincache.Config{}, nil),
+		AnalyzerFactory: map[string]func(*diagnosisv1alpha1.AegisDiagnosis) common.IAnalyzer{
+			"Pod": func(_ *diagnosisv1alpha1.AegisDiagnosis) common.IAnalyzer { return imageAnalyzer{image: image} },
+		},
+	}
+	podDiagnosis := func(name string) *diagnosisv1alpha1.AegisDiagnosis {
+		return &diagnosisv1alpha1.AegisDiagnosis{
+			Spec: diagnosisv1alpha1.AegisDiagnosisSpec{
+				Object: diagnosisv1alpha1.AegisDiagnosisObject{Kind: "Pod", Name: name, Namespace: "default"},
+			},
+		}
+	}
+
+	if _, _, err := d.RunDiagnosis(context.Background(), podDiagnosis("trainer-worker-0")); err != nil {
+		t.Fatal(err)
+	}
+
+	// the pods of the same failure signature share the explain
+	_, explanation, err := d.RunDiagnosis(context.Background(), podDiagnosis("trainer-worker-1"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(client.prompts) != 1 {
+		t.Errorf("expected the explain reused, got %d prompts", len(client.prompts))
+	}
+	if explanation.Analysis.RootCause != "trainer-worker-1 pulls a missing image" || !strings.Contains(explanation.Text, "Pod trainer-worker-1 Back-off") {
+		t.Errorf("expected the explain of trainer-worker-1, got %+v", explanation)
+	}
+
+	// a changed failure is explained again
+	image = "registry/app:v3"
+	if _, _, err := d.RunDiagnosis(context.Background(), podDiagnosis("trainer-worker-1")); err != nil {
+		t.Fatal(err)
+	}
+	if len(client.prompts) != 2 {
+		t.Errorf("expected the changed failure explained, got %d prompts", len(client.prompts))
+	}
+
+	// another model does not share the explain
+	d.AIModel = "gpt-4o-mini"
+	if _, _, err := d.RunDiagnosis(context.Background(), podDiagnosis("trainer-worker-2")); err != nil {
+		t.Fatal(err)
+	}
+	if len(client.prompts) != 3 {
+		t.Errorf("expected the explain of another model not reused, got %d prompts", len(client.prompts))
+	}
+}
+
+func TestRunDiagnosisRedaction(t *testing.T) {
+	client := &fakeAI{completions: []string{`{
+  "healthy": false,
+  "rootCause": "[REDACTED_NAME_1] can't pull the image from [REDACTED_HOSTNAME_1]",
+  "category": "Image",
+  "confidence": 90,
+  "evidence": [{"source": "failure", "index": 0, "reason": "[REDACTED_NAME_1] image pull back-off"}],
+  "recommendedActions": ["check the network of [REDACTED_HOSTNAME_1]"]
+}`}}
+	redaction, err := redact.New(&redact.Config{Enable: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	d := &Diagnosis{
+		AIClient:   client,
+		AIProvider: "openai",
+		Explain:    true,
+		NoCache:    true,
+		Redaction:  redaction,
+		AnalyzerFactory: map[string]func(*diagnosisv1alpha1.AegisDiagnosis) common.IAnalyzer{
+			"Pod": func(_ *diagnosisv1alpha1.AegisDiagnosis) common.IAnalyzer {
+				return imageAnalyzer{image: "registry.corp.example.com/app:v2"}
+			},
+		},
+	}
+	diagnosis := &diagnosisv1alpha1.AegisDiagnosis{
+		Spec: diagnosisv1alpha1.AegisDiagnosisSpec{
+			Object: diagnosisv1alpha1.AegisDiagnosisObject{Kind: "Pod", Name: "trainer-worker-0", Namespace: "team-a"},
+		},
+	}
+
+	result, explanation, err := d.RunDiagnosis(context.Background(), diagnosis)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, value := range []string{"trainer-worker-0", "registry.corp.example.com"} {
+		if strings.Contains(client.prompts[0], value) {
+			t.Errorf("expected %q redacted in the prompt:\n%s", value, client.prompts[0])
+		}
+	}
+	if !strings.Contains(client.prompts[0], "failure[0]: Pod [REDACTED_NAME_1] Back-off pulling image [REDACTED_HOSTNAME_1]/app:v2") {
+		t.Errorf("expected the placeholders in the prompt:\n%s", client.prompts[0])
+	}
+
+	// the explain and the result are unredacted
+	analysis := explanation.Analysis
+	if analysis.RootCause != "trainer-worker-0 can't pull the image from registry.corp.example.com" ||
+		analysis.Evidence[0].Reason != "trainer-worker-0 image pull back-off" ||
+		analysis.RecommendedActions[0] != "check the network of registry.corp.example.com" {
+		t.Errorf("expected the analysis restored, got %+v", analysis)
+	}
+	if strings.Contains(explanation.Text, "[REDACTED_") || !strings.Contains(result.Failures[0], "trainer-worker-0") {
+		t.Errorf("expected the explain restored, got:\n%s", explanation.Text)
+	}
+}
@@ -1,44 +1,58 @@
 package diagnosis
 
 import (
+	"bytes"
 	"context"
+	"encoding/json"
 	"fmt"
-	"time"
+	"os"
+	"strings"
 
 	kai "github.com/k8sgpt-ai/k8sgpt/pkg/ai"
 	kcommon "github.com/k8sgpt-ai/k8sgpt/pkg/common"
 	"github.com/k8sgpt-ai/k8sgpt/pkg/kubernetes"
-	cache "github.com/patrickmn/go-cache"
 	"github.com/scitix/aegis/pkg/ai"
 	"github.com/scitix/aegis/pkg/analyzer"
 	"github.com/scitix/aegis/pkg/analyzer/common"
 	diagnosisv1alpha1 "github.com/scitix/aegis/pkg/apis/diagnosis/v1alpha1"
+	"github.com/scitix/aegis/pkg/explaincache"
 	"github.com/scitix/aegis/pkg/prom"
+	"github.com/scitix/aegis/pkg/redact"
+	"k8s.io/client-go/dynamic"
 	"k8s.io/klog/v2"
 
+	wfclientset "github.com/argoproj/argo-workflows/v3/pkg/client/clientset/versioned"
 	kfclientset "github.com/kubeflow/training-operator/pkg/client/clientset/versioned"
 )
 
 type Diagnosis struct {
-	Client           *kubernetes.Client
-	PytorchJobClient kfclientset.Interface
-	Language         string
-	CollectorImage   string
-	EnableProm       bool
-	Prometheus       *prom.PromAPI
-	AIClient         kai.IAI
-	AIFactory        ai.AIProviderFactory
-	Cache            *cache.Cache
-	NoCache          bool
-	Explain          bool
-	AIProvider       string
-	PodLogConfig     *common.PodLogConfig
-	AnalyzerFactory  map[string]func(*diagnosisv1alpha1.AegisDiagnosis) common.IAnalyzer
+	Client          *kubernetes.Client
+	KubeflowClient  kfclientset.Interface
+	WorkflowClient  wfclientset.Interface
+	DynamicClient   dynamic.Interface
+	Language        string
+	CollectorImage  string
+	EnableProm      bool
+	Prometheus      *prom.PromAPI
+	AIClient        kai.IAI
+	ChatClient      *ai.ChatClient
+	AgentConfig     *AgentConfig
+	AIFactory       ai.AIProviderFactory
+	Cache           *explaincache.Cache
+	Redaction       *redact.Pipeline
+	NoCache         bool
+	Explain         bool
+	AIProvider      string
+	AIModel         string
+	PodLogConfig    *common.PodLogConfig
+	AnalyzerFactory map[string]func(*diagnosisv1alpha1.AegisDiagnosis) common.IAnalyzer
 }
 
 func NewDiagnosis(
 	kubeClient *kubernetes.Client,
-	ptClient kfclientset.Interface,
+	kfClient kfclientset.Interface,
+	wfClient wfclientset.Interface,
+	dynClient dynamic.Interface,
 	backend string,
 	language string,
 	collectorImage string,
@@ -48,20 +62,34 @@ func NewDiagnosis(
 	explain bool,
 	httpHeaders []string,
 	podLogConfig *common.PodLogConfig,
+	agentConfig *AgentConfig,
+	cacheConfig *explaincache.Config,
+	redactionConfig *redact.Config,
 ) (*Diagnosis, error) {
-	c := cache.New(10*time.Minute, 20*time.Minute)
+	c, err := explaincache.New(cacheConfig, kubeClient.GetClient())
+	if err != nil {
+		return nil, err
+	}
+	redaction, err := redact.New(redactionConfig)
+	if err != nil {
+		return nil, err
+	}
 	d := &Diagnosis{
-		Client:           kubeClient,
-		PytorchJobClient: ptClient,
-		Language:         language,
-		CollectorImage:   collectorImage,
-		EnableProm:       enableProm,
-		Prometheus:       prometheus,
-		Explain:          explain,
-		Cache:            c,
-		NoCache:          noCache,
-		AIFactory:        &ai.DefaultFactory{},
-		PodLogConfig:     podLogConfig,
+		Client:         kubeClient,
+		KubeflowClient: kfClient,
+		WorkflowClient: wfClient,
+		DynamicClient:  dynClient,
+		Language:       language,
+		CollectorImage: collectorImage,
+		EnableProm:     enableProm,
+		Prometheus:     prometheus,
+		Explain:        explain,
+		Cache:          c,
+		Redaction:      redaction,
+		NoCache:        noCache,
+		AIFactory:      &ai.DefaultFactory{},
+		PodLogConfig:   podLogConfig,
+		AgentConfig:    agentConfig,
 	}
 
 	d.AnalyzerFactory = map[string]func(*diagnosisv1alpha1.AegisDiagnosis) common.IAnalyzer{
@@ -72,7 +100,37 @@ func NewDiagnosis(
 			return analyzer.NewNodeAnalyzer(d.Prometheus)
 		},
 		"PytorchJob": func(_ *diagnosisv1alpha1.AegisDiagnosis) common.IAnalyzer {
-			return analyzer.NewPytorchJobAnalyzer(d.Prometheus, d.PytorchJobClient)
+			return analyzer.NewPytorchJobAnalyzer(d.Prometheus, d.KubeflowClient)
+		},
+		"TFJob": func(_ *diagnosisv1alpha1.AegisDiagnosis) common.IAnalyzer {
+			return analyzer.NewTFJobAnalyzer(d.Prometheus, d.KubeflowClient)
+		},
+		"MPIJob": func(_ *diagnosisv1alpha1.AegisDiagnosis) common.IAnalyzer {
+			return analyzer.NewMPIJobAnalyzer(d.Prometheus, d.KubeflowClient)
+		},
+		"XGBoostJob": func(_ *diagnosisv1alpha1.AegisDiagnosis) common.IAnalyzer {
+			return analyzer.NewXGBoostJobAnalyzer(d.Prometheus, d.KubeflowClient)
+		},
+		"PaddleJob": func(_ *diagnosisv1alpha1.AegisDiagnosis) common.IAnalyzer {
+			return analyzer.NewPaddleJobAnalyzer(d.Prometheus, d.KubeflowClient)
+		},
+		"Workflow": func(_ *diagnosisv1alpha1.AegisDiagnosis) common.IAnalyzer {
+			return analyzer.NewWorkflowAnalyzer(d.Prometheus, d.WorkflowClient)
+		},
+		"Deployment": func(_ *diagnosisv1alpha1.AegisDiagnosis) common.IAnalyzer {
+			return analyzer.NewDeploymentAnalyzer(d.Prometheus)
+		},
+		"StatefulSet": func(_ *diagnosisv1alpha1.AegisDiagnosis) common.IAnalyzer {
+			return analyzer.NewStatefulSetAnalyzer(d.Prometheus)
+		},
+		"DaemonSet": func(_ *diagnosisv1alpha1.AegisDiagnosis) common.IAnalyzer {
+			return analyzer.NewDaemonSetAnalyzer(d.Prometheus)
+		},
+		"Job": func(_ *diagnosisv1alpha1.AegisDiagnosis) common.IAnalyzer {
+			return analyzer.NewJobAnalyzer(d.Prometheus)
+		},
+		"CronJob": func(_ *diagnosisv1alpha1.AegisDiagnosis) common.IAnalyzer {
+			return analyzer.NewCronJobAnalyzer(d.Prometheus)
 		},
 	}
 
@@ -83,14 +141,42 @@ func NewDiagnosis(
 		}
 		d.AIClient = AIClient
 		d.AIProvider = AIProvider
+
+		// the agent mode needs the tool calls of the chat completions API
+		provider, err := ai.LoadProvider(backend)
+		if err != nil {
+			return nil, err
+		}
+		d.AIModel = provider.Model
+		if d.ChatClient, err = ai.NewChatClient(provider, httpHeaders); err != nil {
+			klog.Warningf("Agent mode is unavailable, diagnoses fall back to the single mode: %v", err)
+		}
 	}
 
 	return d, nil
 }
 
-func (d *Diagnosis) RunDiagnosis(ctx context.Context, diagnosis *diagnosisv1alpha1.AegisDiagnosis) (*diagnosisv1alpha1.DiagnosisResult, string, error) {
+// newAnalyzer returns the analyzer of the diagnosis kind, falling back to the
+// GenericAnalyzer if the kind has a generic analyzer config
+func (d *Diagnosis) newAnalyzer(diagnosis *diagnosisv1alpha1.AegisDiagnosis) (common.IAnalyzer, error) {
+	kind := string(diagnosis.Spec.Object.Kind)
+	if factory, ok := d.AnalyzerFactory[kind]; ok {
+		return factory(diagnosis), nil
+	}
+
+	config, err := analyzer.LoadGenericConfig(kind)
+	if os.IsNotExist(err) {
+		return nil, fmt.Errorf("Unsupported diagnosis type: %s", kind)
+	} else if err != nil {
+		return nil, err
+	}
+	return analyzer.NewGenericAnalyzer(d.Prometheus, d.DynamicClient, config), nil
+}
+
+// RunDiagnosis analyzes the diagnosis object, and explains the result with
+// the structured analysis if explain is enabled
+func (d *Diagnosis) RunDiagnosis(ctx context.Context, diagnosis *diagnosisv1alpha1.AegisDiagnosis) (*diagnosisv1alpha1.DiagnosisResult, *Explanation, error) {
 	object := diagnosis.Spec.Object
-	kind := string(object.Kind)
 	name := object.Name
 	namespace := object.Namespace
 
@@ -108,15 +194,14 @@ func (d *Diagnosis) RunDiagnosis(ctx context.Context, diagnosis *diagnosisv1alph
 		Owner:          diagnosis,
 	}
 
-	factory, ok := d.AnalyzerFactory[kind]
-	if !ok {
-		return nil, "", fmt.Errorf("Unsupported diagnosis type: %s", kind)
+	analyzer, err := d.newAnalyzer(diagnosis)
+	if err != nil {
+		return nil, nil, err
 	}
-	analyzer := factory(diagnosis)
 
 	result, err := analyzer.Analyze(a)
 	if err != nil {
-		return nil, "", fmt.Errorf("Error running analyzer: %s", err)
+		return nil, nil, fmt.Errorf("Error running analyzer: %s", err)
 	}
 
 	failures, warnings, infos := make([]string, 0), make([]string, 0), make([]string, 0)
@@ -137,31 +222,189 @@ func (d *Diagnosis) RunDiagnosis(ctx context.Context, diagnosis *diagnosisv1alph
 	}
 
 	if !d.Explain {
-		return dresult, "", nil
+		return dresult, &Explanation{}, nil
 	}
 
-	inputKey := fmt.Sprintf("%s/%s/%s/%s", d.Language, kind, namespace, name)
-	if explain, found := d.Cache.Get(inputKey); found {
-		klog.V(4).Infof("Get explain from cache for %s", inputKey)
-		return dresult, explain.(string), nil
+	agent := diagnosis.Spec.Mode == diagnosisv1alpha1.ModeAgent
+	if agent && d.ChatClient == nil {
+		klog.Warningf("Agent mode is unavailable for %s/%s, fall back to the single mode", diagnosis.Namespace, diagnosis.Name)
+		agent = false
 	}
 
 	prompt := analyzer.Prompt(result)
 	if prompt == "" {
 		klog.Info("Do not need to get explain")
-		return dresult, "Healthy: Yes", nil
+		return dresult, &Explanation{
+			Text: "Healthy: Yes",
+			Analysis: &diagnosisv1alpha1.DiagnosisAnalysis{
+				Healthy:    true,
+				Category:   diagnosisv1alpha1.CategoryNone,
+				Confidence: 100,
+			},
+		}, nil
+	}
+
+	inputKey, placeholder := d.explainKey(diagnosis, analysisPrompt(prompt, dresult), agent)
+	if !d.NoCache {
+		if explanation := d.getCachedExplanation(ctx, inputKey, placeholder, name); explanation != nil {
+			klog.V(4).Infof("Get explain from cache for %s/%s: %s", namespace, name, inputKey)
+			return dresult, explanation, nil
+		}
 	}
 
 	klog.V(4).Infof("Prompt: %s", prompt)
-	response, err := d.AIClient.GetCompletion(ctx, prompt)
+	redactor := d.Redaction.Redactor(d.AIProvider, []string{namespace}, resultSensitive(result))
+	var explanation *Explanation
+	if agent {
+		explanation, err = d.getAgentAnalysis(ctx, object, prompt, dresult, redactor)
+	} else {
+		explanation = &Explanation{}
+		explanation.Text, explanation.Analysis, err = getAnalysis(ctx, d.AIClient, prompt, dresult, redactor)
+	}
+	explanation.restore(redactor)
 	if err != nil {
 		klog.Errorf("Failed to get AI completion: %v", err)
-		return dresult, "", fmt.Errorf("failed to get explain: %v", err)
+		return dresult, explanation, fmt.Errorf("failed to get explain: %v", err)
 	}
 
 	if !d.NoCache {
-		d.Cache.Set(inputKey, response, 10*time.Minute)
+		d.setCachedExplanation(ctx, inputKey, placeholder, name, explanation)
+	}
+
+	return dresult, explanation.DeepCopy(), nil
+}
+
+// objectPlaceholder stands for the object name in the cache, so that the
+// objects of the same failure signature share the explain
+const objectPlaceholder = "${OBJECT_NAME}"
+
+// minPlaceholderName is the shortest object name replaced by the placeholder,
+// shorter names are likely to be part of other words
+const minPlaceholderName = 4
+
+// explainKey hashes the rendered prompt and the model identity into the
+// cache key. The object name is replaced by the placeholder in the single
+// mode, the agent mode tool calls are specific to the object. Returns
+// whether the placeholder is used.
+func (d *Diagnosis) explainKey(diagnosis *diagnosisv1alpha1.AegisDiagnosis, input string, agent bool) (string, bool) {
+	object := diagnosis.Spec.Object
+	parts := []string{d.AIProvider, d.AIModel, d.Language}
+	if agent {
+		parts = append(parts, string(diagnosisv1alpha1.ModeAgent), string(object.Kind), object.Namespace, object.Name, input)
+		return explaincache.Key(parts...), false
+	}
+
+	placeholder := len(object.Name) >= minPlaceholderName
+	if placeholder {
+		input = replaceName(input, object.Name, objectPlaceholder)
+	}
+	parts = append(parts, string(diagnosisv1alpha1.ModeSingle), input)
+	return explaincache.Key(parts...), placeholder
+}
+
+func (d *Diagnosis) getCachedExplanation(ctx context.Context, key string, placeholder bool, name string) *Explanation {
+	data, found := d.Cache.Get(ctx, key)
+	if !found {
+		return nil
+	}
+	if placeholder {
+		data = bytes.ReplaceAll(data, []byte(objectPlaceholder), []byte(name))
+	}
+
+	explanation := &Explanation{}
+	if err := json.Unmarshal(data, explanation); err != nil {
+		klog.Warningf("Invalid cached explain %s: %v", key, err)
+		return nil
+	}
+	return explanation
+}
+
+func (d *Diagnosis) setCachedExplanation(ctx context.Context, key string, placeholder bool, name string, explanation *Explanation) {
+	data, err := json.Marshal(explanation)
+	if err != nil {
+		klog.Warningf("Failed to marshal explain %s: %v", key, err)
+		return
+	}
+	if placeholder {
+		data = []byte(replaceName(string(data), name, objectPlaceholder))
+	}
+	d.Cache.Set(ctx, key, data)
+}
+
+// replaceName replaces the whole occurrences of the object name, the name
+// inside a longer name or word is kept, e.g. test in latest or app in app-1.
+// A dot followed by a name character continues the name, a trailing dot
+// ends the sentence.
+func replaceName(s, name, replacement string) string {
+	var b strings.Builder
+	for {
+		i := strings.Index(s, name)
+		if i < 0 {
+			b.WriteString(s)
+			return b.String()
+		}
+		end := i + len(name)
+		whole := (i == 0 || !isNameChar(s[i-1]) && s[i-1] != '.') &&
+			(end == len(s) || !isNameChar(s[end]) && (s[end] != '.' || end+1 == len(s) || !isNameChar(s[end+1])))
+		if whole {
+			b.WriteString(s[:i])
+			b.WriteString(replacement)
+		} else {
+			b.WriteString(s[:i+1])
+			end = i + 1
+		}
+		s = s[end:]
+	}
+}
+
+func isNameChar(c byte) bool {
+	return c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' || c == '-' || c == '_'
+}
+
+// Explanation is the explain of a diagnosis object
+type Explanation struct {
+	Text     string                               `json:"text"`
+	Analysis *diagnosisv1alpha1.DiagnosisAnalysis `json:"analysis,omitempty"`
+
+	// Agent is the audit trail of the agent mode
+	Agent *diagnosisv1alpha1.DiagnosisAgentStatus `json:"agent,omitempty"`
+}
+
+// restore replaces the placeholders of the redacted prompt in the explain,
+// the agent steps are recorded unredacted
+func (e *Explanation) restore(r *redact.Redactor) {
+	e.Text = r.Restore(e.Text)
+	if e.Analysis == nil {
+		return
 	}
+	e.Analysis.RootCause = r.Restore(e.Analysis.RootCause)
+	for i := range e.Analysis.Evidence {
+		e.Analysis.Evidence[i].Reason = r.Restore(e.Analysis.Evidence[i].Reason)
+	}
+	for i := range e.Analysis.RecommendedActions {
+		e.Analysis.RecommendedActions[i] = r.Restore(e.Analysis.RecommendedActions[i])
+	}
+}
 
-	return dresult, response, nil
+// resultSensitive returns the sensitive values the analyzers marked
+func resultSensitive(result *common.Result) []kcommon.Sensitive {
+	sensitive := make([]kcommon.Sensitive, 0)
+	for _, failure := range result.Error {
+		sensitive = append(sensitive, failure.Sensitive...)
+	}
+	for _, warning := range result.Warning {
+		sensitive = append(sensitive, warning.Sensitive...)
+	}
+	for _, info := range result.Info {
+		sensitive = append(sensitive, info.Sensitive...)
+	}
+	return sensitive
+}
+
+func (e *Explanation) DeepCopy() *Explanation {
+	return &Explanation{
+		Text:     e.Text,
+		Analysis: e.Analysis.DeepCopy(),
+		Agent:    e.Agent.DeepCopy(),
+	}
 }
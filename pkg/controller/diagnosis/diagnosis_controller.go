@@ -2,6 +2,7 @@ package diagnosis
 
 import (
 	"context"
+	stderrors "errors"
 	"fmt"
 	"time"
 
@@ -10,6 +11,7 @@ import (
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
 	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/dynamic"
 	"k8s.io/client-go/kubernetes"
 	clientset "k8s.io/client-go/kubernetes"
 	"k8s.io/client-go/kubernetes/scheme"
@@ -21,8 +23,11 @@ import (
 
 	analyzercommon "github.com/scitix/aegis/pkg/analyzer/common"
 	"github.com/scitix/aegis/pkg/controller"
+	"github.com/scitix/aegis/pkg/explaincache"
 	"github.com/scitix/aegis/pkg/prom"
+	"github.com/scitix/aegis/pkg/redact"
 
+	wfclientset "github.com/argoproj/argo-workflows/v3/pkg/client/clientset/versioned"
 	kKubernetes "github.com/k8sgpt-ai/k8sgpt/pkg/kubernetes"
 	kfclientset "github.com/kubeflow/training-operator/pkg/client/clientset/versioned"
 	diagnosisv1alpha1 "github.com/scitix/aegis/pkg/apis/diagnosis/v1alpha1"
@@ -41,6 +46,8 @@ const (
 	FailedDiagnosis           = "Failed"
 	MessageSucceededDiagnosis = "Diagnosis successfully"
 	MessageFailededDiagnosis  = "Diagnosis Failed"
+	RemediationDiagnosis      = "Remediation"
+	FailedRemediation         = "FailedRemediation"
 )
 
 var controllerAgentName = "diagnosis-Controller"
@@ -68,6 +75,14 @@ type DiagnosisController struct {
 	// timeout for a diagnosis
 	timeout time.Duration
 
+	// callback of finished diagnosis, optional
+	callback controller.DiagnosisCallbackInterface
+
+	// remediation raises the alerts in auto remediation, optional
+	remediation controller.DiagnosisRemediationInterface
+	// minConfidence of the analysis to raise its alert in auto remediation
+	minConfidence int
+
 	logger klog.Logger
 }
 
@@ -108,6 +123,8 @@ func (c *DiagnosisController) enqueueDiagnosis(obj interface{}) {
 // diagnoseclient: diagnose resource controller
 // diagnoseinformer: diagnose informer
 // timeout: diagosis timeout
+// remediation: raises the alerts in auto remediation
+// minConfidence: of the analysis to raise its alert in auto remediation
 func NewController(kubeclient kubernetes.Interface,
 	diagnosiscleint diagnosisclientset.Interface,
 	diagnosisinformer diagnosisInformer.AegisDiagnosisInformer,
@@ -120,6 +137,12 @@ func NewController(kubeclient kubernetes.Interface,
 	explain bool,
 	noCache bool,
 	podLogConfig *analyzercommon.PodLogConfig,
+	agentConfig *AgentConfig,
+	cacheConfig *explaincache.Config,
+	redactionConfig *redact.Config,
+	callback controller.DiagnosisCallbackInterface,
+	remediation controller.DiagnosisRemediationInterface,
+	minConfidence int,
 ) (*DiagnosisController, error) {
 	eventBroadcaster := record.NewBroadcaster()
 	eventBroadcaster.StartStructuredLogging(0)
@@ -135,6 +158,9 @@ func NewController(kubeclient kubernetes.Interface,
 		recorder:           recorder,
 		diagnosisSynced:    diagnosisinformer.Informer().HasSynced,
 		timeout:            timeout,
+		callback:           callback,
+		remediation:        remediation,
+		minConfidence:      minConfidence,
 		logger:             klog.NewKlogr(),
 	}
 
@@ -146,12 +172,22 @@ func NewController(kubeclient kubernetes.Interface,
 		return nil, fmt.Errorf("initialising kubernetes client: %w", err)
 	}
 
-	ptClient, err := kfclientset.NewForConfig(client.Config)
+	kfClient, err := kfclientset.NewForConfig(client.Config)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create Kubeflow client: %w", err)
+	}
+
+	wfClient, err := wfclientset.NewForConfig(client.Config)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create Workflow client: %w", err)
+	}
+
+	dynClient, err := dynamic.NewForConfig(client.Config)
 	if err != nil {
-		return nil, fmt.Errorf("failed to create PyTorchJob client: %w", err)
+		return nil, fmt.Errorf("failed to create dynamic client: %w", err)
 	}
 
-	dignosis, err := NewDiagnosis(client, ptClient, backend, language, collectorImage, enableProm, prometheus, noCache, explain, nil, podLogConfig)
+	dignosis, err := NewDiagnosis(client, kfClient, wfClient, dynClient, backend, language, collectorImage, enableProm, prometheus, noCache, explain, nil, podLogConfig, agentConfig, cacheConfig, redactionConfig)
 	if err != nil {
 		return nil, err
 	}
@@ -258,6 +294,17 @@ func (c *DiagnosisController) syncHandler(key string) error {
 	diagnosis := sharedDiagnosis.DeepCopy()
 	// diagnosis has finished
 	if IsDiagnoseFinished(diagnosis) {
+		// retry the alerts failed to be raised
+		changed, err := c.syncRemediation(context.Background(), diagnosis)
+		if changed {
+			if err := c.updateStatus(context.Background(), diagnosis); err != nil {
+				return err
+			}
+		}
+		if err != nil {
+			return err
+		}
+
 		expired, ttl := CheckDiagnoseExpireTTL(diagnosis)
 		if expired && ttl > 0 {
 			klog.V(4).Infof("Diagnose %v ttl second: %d", key, ttl)
@@ -280,10 +327,14 @@ func (c *DiagnosisController) syncHandler(key string) error {
 	begin := metav1.Now()
 	diagnosis.Status.StartTime = &begin
 
-	result, explain, err := c.diagnosis.RunDiagnosis(ctx, diagnosis)
+	result, explanation, err := c.diagnosis.RunDiagnosis(ctx, diagnosis)
 
 	end := metav1.Now()
 	diagnosis.Status.CompletionTime = &end
+	if explanation != nil {
+		// the audit trail is kept on failures too
+		diagnosis.Status.Agent = explanation.Agent
+	}
 	if err != nil {
 		c.recorder.Event(diagnosis, v1.EventTypeWarning, FailedDiagnosis, MessageFailededDiagnosis)
 		errMsg := fmt.Sprintf("Dignosis fialed: %s", err)
@@ -292,11 +343,66 @@ func (c *DiagnosisController) syncHandler(key string) error {
 	} else {
 		c.recorder.Event(diagnosis, v1.EventTypeNormal, SucceededDiagnosis, MessageSucceededDiagnosis)
 		diagnosis.Status.Result = result
-		diagnosis.Status.Explain = &explain
+		diagnosis.Status.Explain = &explanation.Text
+		diagnosis.Status.Analysis = explanation.Analysis
 		diagnosis.Status.Phase = diagnosisv1alpha1.DiagnosisPhaseCompleted
+		diagnosis.Status.Remediation = getRemediation(diagnosis)
+		if remediation := diagnosis.Status.Remediation; remediation != nil {
+			c.recorder.Eventf(diagnosis, v1.EventTypeNormal, RemediationDiagnosis, "Category %s from the %s, alert %s (%s)", remediation.Category, remediation.Source, remediation.AlertType, diagnosis.Spec.Remediation)
+		}
 	}
 
-	return c.updateStatus(context.Background(), diagnosis)
+	// the diagnosis is requeued if the alert failed to be raised
+	_, remediationErr := c.syncRemediation(context.Background(), diagnosis)
+	if err := c.updateStatus(context.Background(), diagnosis); err != nil {
+		return err
+	}
+
+	if c.callback != nil {
+		go func() {
+			if err := c.callback.OnDiagnosisFinished(diagnosis); err != nil {
+				klog.Warningf("Diagnosis %s finished callback failed: %v", key, err)
+			}
+		}()
+	}
+	return remediationErr
+}
+
+// syncRemediation raises the alert of a completed diagnosis in auto
+// remediation, unless gated by the confidence, and records the outcome in
+// the status. Returns whether the status changed, and the error to retry.
+func (c *DiagnosisController) syncRemediation(ctx context.Context, diagnosis *diagnosisv1alpha1.AegisDiagnosis) (bool, error) {
+	remediation := diagnosis.Status.Remediation
+	if c.remediation == nil || diagnosis.Spec.Remediation != diagnosisv1alpha1.RemediationAuto || remediation == nil || remediation.AlertRaised {
+		return false, nil
+	}
+
+	var err error
+	eventType := v1.EventTypeNormal
+	message := remediationGate(diagnosis, c.minConfidence)
+	if message == "" {
+		if err = c.remediation.RaiseAlert(ctx, diagnosis); err == nil {
+			remediation.AlertRaised = true
+			message = fmt.Sprintf("Raised alert %s", remediation.AlertType)
+		} else {
+			eventType = v1.EventTypeWarning
+			message = fmt.Sprintf("Failed to raise alert %s: %v", remediation.AlertType, err)
+			if stderrors.Is(err, controller.ErrAlertDisabled) {
+				err = nil
+			}
+		}
+	}
+	if message == remediation.Message {
+		return false, err
+	}
+	remediation.Message = message
+
+	reason := RemediationDiagnosis
+	if eventType == v1.EventTypeWarning {
+		reason = FailedRemediation
+	}
+	c.recorder.Event(diagnosis, eventType, reason, message)
+	return true, err
 }
 
 func (c *DiagnosisController) updateStatus(ctx context.Context, diagnosis *diagnosisv1alpha1.AegisDiagnosis) error {
@@ -309,6 +415,9 @@ func (c *DiagnosisController) updateStatus(ctx context.Context, diagnosis *diagn
 		Result:         diagnosis.Status.Result,
 		Explain:        diagnosis.Status.Explain,
 		ErrorResult:    diagnosis.Status.ErrorResult,
+		Analysis:       diagnosis.Status.Analysis,
+		Remediation:    diagnosis.Status.Remediation,
+		Agent:          diagnosis.Status.Agent,
 		Phase:          diagnosis.Status.Phase,
 		StartTime:      diagnosis.Status.StartTime,
 		CompletionTime: diagnosis.Status.CompletionTime,
@@ -5,6 +5,7 @@ import (
 	"testing"
 
 	"github.com/k8sgpt-ai/k8sgpt/pkg/ai"
+	"github.com/scitix/aegis/pkg/explaincache"
 	"github.com/spf13/viper"
 )
 
@@ -47,4 +48,32 @@ func TestCreateAIClient(t *testing.T) {
 	}
 
 	t.Logf("response: %v", response)
-}
\ No newline at end of file
+}
+func TestReplaceName(t *testing.T) {
+	cases := []struct {
+		input, name, expected string
+	}{
+		{"pod test is pending", "test", "pod ${OBJECT_NAME} is pending"},
+		{"image latest of test", "test", "image latest of ${OBJECT_NAME}"},
+		{"pod test-1 and test", "test", "pod test-1 and ${OBJECT_NAME}"},
+		{"node test. node test.local", "test", "node ${OBJECT_NAME}. node test.local"},
+		{`{"text":"test test"}`, "test", `{"text":"${OBJECT_NAME} ${OBJECT_NAME}"}`},
+		{"ip-10-0-0-1.ec2.internal is down", "ip-10-0-0-1.ec2.internal", "${OBJECT_NAME} is down"},
+	}
+	for _, c := range cases {
+		if got := replaceName(c.input, c.name, objectPlaceholder); got != c.expected {
+			t.Errorf("replaceName(%q, %q) = %q, expected %q", c.input, c.name, got, c.expected)
+		}
+	}
+}
+
+func TestCachedExplanationName(t *testing.T) {
+	ctx := context.Background()
+	d := &Diagnosis{Cache: explaincache.NewWithStore(explaincache.Config{}, nil)}
+
+	d.setCachedExplanation(ctx, "key", true, "test", &Explanation{Text: "Pod test uses the latest image"})
+	explanation := d.getCachedExplanation(ctx, "key", true, "app-0")
+	if explanation == nil || explanation.Text != "Pod app-0 uses the latest image" {
+		t.Errorf("expected the object name replaced, got %+v", explanation)
+	}
+}
@@ -0,0 +1,121 @@
+package diagnosis
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"strings"
+
+	diagnosisv1alpha1 "github.com/scitix/aegis/pkg/apis/diagnosis/v1alpha1"
+)
+
+// DefaultRemediationMinConfidence is the confidence an analysis needs for its
+// category to raise the alert in auto remediation
+const DefaultRemediationMinConfidence = 60
+
+// failureSignature maps a well known failure reason of the analyzers to a
+// category, used when the analysis is absent or not conclusive
+type failureSignature struct {
+	reason   string
+	category diagnosisv1alpha1.DiagnosisCategory
+}
+
+// failureSignatures are matched in order against the failures
+var failureSignatures = []failureSignature{
+	{"OOMKilled", diagnosisv1alpha1.CategoryResource},
+	{"Evicted", diagnosisv1alpha1.CategoryResource},
+	{"ImagePullBackOff", diagnosisv1alpha1.CategoryImage},
+	{"ErrImagePull", diagnosisv1alpha1.CategoryImage},
+	{"InvalidImageName", diagnosisv1alpha1.CategoryImage},
+	{"Unschedulable", diagnosisv1alpha1.CategoryScheduling},
+	{"FailedScheduling", diagnosisv1alpha1.CategoryScheduling},
+	{"FailedMount", diagnosisv1alpha1.CategoryStorage},
+	{"FailedAttachVolume", diagnosisv1alpha1.CategoryStorage},
+	{"CreateContainerConfigError", diagnosisv1alpha1.CategoryConfiguration},
+	{"NetworkUnavailable", diagnosisv1alpha1.CategoryNetwork},
+	{"MemoryPressure", diagnosisv1alpha1.CategoryResource},
+	{"DiskPressure", diagnosisv1alpha1.CategoryResource},
+	{"PIDPressure", diagnosisv1alpha1.CategoryResource},
+	{"CrashLoopBackOff", diagnosisv1alpha1.CategoryApplication},
+}
+
+// diagnosisCategory returns the recognised category of the diagnosis, from
+// the analysis first, then from the failure signatures
+func diagnosisCategory(analysis *diagnosisv1alpha1.DiagnosisAnalysis, result *diagnosisv1alpha1.DiagnosisResult) (diagnosisv1alpha1.DiagnosisCategory, diagnosisv1alpha1.CategorySource, bool) {
+	if analysis != nil {
+		if analysis.Healthy {
+			return "", "", false
+		}
+		if analysis.Category != diagnosisv1alpha1.CategoryNone && analysis.Category != diagnosisv1alpha1.CategoryUnknown {
+			return analysis.Category, diagnosisv1alpha1.CategorySourceAnalysis, true
+		}
+	}
+
+	if result == nil {
+		return "", "", false
+	}
+	for _, signature := range failureSignatures {
+		for _, failure := range result.Failures {
+			if strings.Contains(failure, signature.reason) {
+				return signature.category, diagnosisv1alpha1.CategorySourceSignature, true
+			}
+		}
+	}
+	return "", "", false
+}
+
+// remediationAlertType is the alert type raised for the category, e.g.
+// DiagnosedImageIssue
+func remediationAlertType(category diagnosisv1alpha1.DiagnosisCategory) string {
+	return fmt.Sprintf("Diagnosed%sIssue", category)
+}
+
+// remediationFingerprint is the same for the diagnoses of an object with
+// the same alert type, so that they update one alert
+func remediationFingerprint(object diagnosisv1alpha1.AegisDiagnosisObject, alertType string) string {
+	hash := sha256.Sum256([]byte(fmt.Sprintf("%s:%s:%s:%s", object.Kind, object.Namespace, object.Name, alertType)))
+	fingerprint := hex.EncodeToString(hash[:])
+
+	// fingerprint is used as a label value
+	return fingerprint[:63]
+}
+
+// getRemediation maps the completed diagnosis to the alert to suggest or
+// create, nil if the remediation is none or the category not recognised
+func getRemediation(diagnosis *diagnosisv1alpha1.AegisDiagnosis) *diagnosisv1alpha1.DiagnosisRemediationStatus {
+	switch diagnosis.Spec.Remediation {
+	case diagnosisv1alpha1.RemediationSuggest, diagnosisv1alpha1.RemediationAuto:
+	default:
+		return nil
+	}
+
+	category, source, ok := diagnosisCategory(diagnosis.Status.Analysis, diagnosis.Status.Result)
+	if !ok {
+		return nil
+	}
+
+	alertType := remediationAlertType(category)
+	return &diagnosisv1alpha1.DiagnosisRemediationStatus{
+		Category:    category,
+		Source:      source,
+		AlertType:   alertType,
+		Fingerprint: remediationFingerprint(diagnosis.Spec.Object, alertType),
+	}
+}
+
+// remediationGate returns why the alert of the diagnosis is not raised in
+// auto remediation, empty if it is raised. The categories of the analysis
+// need the confidence, the failure signatures are trusted.
+func remediationGate(diagnosis *diagnosisv1alpha1.AegisDiagnosis, minConfidence int) string {
+	if diagnosis.Status.Remediation.Source != diagnosisv1alpha1.CategorySourceAnalysis {
+		return ""
+	}
+	var confidence int
+	if analysis := diagnosis.Status.Analysis; analysis != nil {
+		confidence = int(analysis.Confidence)
+	}
+	if confidence < minConfidence {
+		return fmt.Sprintf("Alert %s not raised: confidence %d of the analysis below %d", diagnosis.Status.Remediation.AlertType, confidence, minConfidence)
+	}
+	return ""
+}
@@ -0,0 +1,224 @@
+package diagnosis
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+
+	diagnosisv1alpha1 "github.com/scitix/aegis/pkg/apis/diagnosis/v1alpha1"
+	"github.com/scitix/aegis/pkg/controller"
+	"github.com/scitix/aegis/pkg/generated/diagnosis/clientset/versioned/fake"
+	diagnosisLister "github.com/scitix/aegis/pkg/generated/diagnosis/listers/diagnosis/v1alpha1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/client-go/tools/record"
+	"k8s.io/client-go/util/workqueue"
+)
+
+func TestGetRemediation(t *testing.T) {
+	object := diagnosisv1alpha1.AegisDiagnosisObject{Kind: "Pod", Name: "app", Namespace: "default"}
+	newDiagnosis := func(remediation diagnosisv1alpha1.DiagnosisRemediation, analysis *diagnosisv1alpha1.DiagnosisAnalysis, failures ...string) *diagnosisv1alpha1.AegisDiagnosis {
+		return &diagnosisv1alpha1.AegisDiagnosis{
+			Spec: diagnosisv1alpha1.AegisDiagnosisSpec{Object: object, Remediation: remediation},
+			Status: diagnosisv1alpha1.AegisDiagnosisStatus{
+				Result:   &diagnosisv1alpha1.DiagnosisResult{Failures: failures},
+				Analysis: analysis,
+			},
+		}
+	}
+
+	cases := []struct {
+		name      string
+		diagnosis *diagnosisv1alpha1.AegisDiagnosis
+		category  diagnosisv1alpha1.DiagnosisCategory
+		source    diagnosisv1alpha1.CategorySource
+	}{
+		{
+			name:      "none by default",
+			diagnosis: newDiagnosis("", nil, "container app OOMKilled"),
+		},
+		{
+			name:      "analysis",
+			diagnosis: newDiagnosis(diagnosisv1alpha1.RemediationAuto, &diagnosisv1alpha1.DiagnosisAnalysis{RootCause: "x", Category: diagnosisv1alpha1.CategoryHardware}, "container app OOMKilled"),
+			category:  diagnosisv1alpha1.CategoryHardware,
+			source:    diagnosisv1alpha1.CategorySourceAnalysis,
+		},
+		{
+			name:      "healthy analysis",
+			diagnosis: newDiagnosis(diagnosisv1alpha1.RemediationAuto, &diagnosisv1alpha1.DiagnosisAnalysis{Healthy: true, Category: diagnosisv1alpha1.CategoryNone}, "container app OOMKilled"),
+		},
+		{
+			name:      "signature of unknown analysis",
+			diagnosis: newDiagnosis(diagnosisv1alpha1.RemediationSuggest, &diagnosisv1alpha1.DiagnosisAnalysis{RootCause: "x", Category: diagnosisv1alpha1.CategoryUnknown}, "Back-off restarting: CrashLoopBackOff", "container app OOMKilled"),
+			category:  diagnosisv1alpha1.CategoryResource,
+			source:    diagnosisv1alpha1.CategorySourceSignature,
+		},
+		{
+			name:      "signature without analysis",
+			diagnosis: newDiagnosis(diagnosisv1alpha1.RemediationSuggest, nil, "Back-off pulling image: ImagePullBackOff"),
+			category:  diagnosisv1alpha1.CategoryImage,
+			source:    diagnosisv1alpha1.CategorySourceSignature,
+		},
+		{
+			name:      "no signature",
+			diagnosis: newDiagnosis(diagnosisv1alpha1.RemediationAuto, nil, "something wrong"),
+		},
+	}
+
+	for _, c := range cases {
+		remediation := getRemediation(c.diagnosis)
+		if c.category == "" {
+			if remediation != nil {
+				t.Errorf("%s: expected no remediation, got %+v", c.name, remediation)
+			}
+			continue
+		}
+		if remediation == nil || remediation.Category != c.category || remediation.Source != c.source {
+			t.Errorf("%s: expected %s from the %s, got %+v", c.name, c.category, c.source, remediation)
+			continue
+		}
+		if remediation.AlertType != "Diagnosed"+string(c.category)+"Issue" || len(remediation.Fingerprint) != 63 {
+			t.Errorf("%s: unexpected alert %+v", c.name, remediation)
+		}
+	}
+
+	// one alert per object and type
+	first := getRemediation(newDiagnosis(diagnosisv1alpha1.RemediationAuto, nil, "ImagePullBackOff"))
+	second := getRemediation(newDiagnosis(diagnosisv1alpha1.RemediationAuto, nil, "ErrImagePull"))
+	if first.Fingerprint != second.Fingerprint {
+		t.Errorf("expected the same fingerprint for the object")
+	}
+}
+
+// fakeRemediation fails the alerts with the errors in order
+type fakeRemediation struct {
+	errs  []error
+	calls int
+}
+
+func (f *fakeRemediation) RaiseAlert(ctx context.Context, diagnosis *diagnosisv1alpha1.AegisDiagnosis) error {
+	f.calls++
+	if len(f.errs) == 0 {
+		return nil
+	}
+	err := f.errs[0]
+	f.errs = f.errs[1:]
+	return err
+}
+
+func newRemediationTestController(t *testing.T, remediation *fakeRemediation, source diagnosisv1alpha1.CategorySource, confidence int32) (*DiagnosisController, *fake.Clientset, *record.FakeRecorder, func() *diagnosisv1alpha1.DiagnosisRemediationStatus) {
+	diagnosis := &diagnosisv1alpha1.AegisDiagnosis{
+		ObjectMeta: metav1.ObjectMeta{Name: "diagnose-app", Namespace: "default", CreationTimestamp: metav1.Now()},
+		Spec: diagnosisv1alpha1.AegisDiagnosisSpec{
+			Object:      diagnosisv1alpha1.AegisDiagnosisObject{Kind: "Pod", Name: "app", Namespace: "default"},
+			Remediation: diagnosisv1alpha1.RemediationAuto,
+		},
+		Status: diagnosisv1alpha1.AegisDiagnosisStatus{
+			Phase:    diagnosisv1alpha1.DiagnosisPhaseCompleted,
+			Result:   &diagnosisv1alpha1.DiagnosisResult{},
+			Analysis: &diagnosisv1alpha1.DiagnosisAnalysis{Category: diagnosisv1alpha1.CategoryImage, Confidence: confidence},
+			Remediation: &diagnosisv1alpha1.DiagnosisRemediationStatus{
+				Category:  diagnosisv1alpha1.CategoryImage,
+				Source:    source,
+				AlertType: "DiagnosedImageIssue",
+			},
+		},
+	}
+	client := fake.NewSimpleClientset()
+	if _, err := client.AegisV1alpha1().AegisDiagnosises("default").Create(context.Background(), diagnosis, metav1.CreateOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	recorder := record.NewFakeRecorder(10)
+
+	// the lister follows the updates of the status
+	indexer := cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{})
+	c := &DiagnosisController{
+		diagnosisclientset: client,
+		lister:             diagnosisLister.NewAegisDiagnosisLister(indexer),
+		workqueue:          workqueue.NewNamedRateLimitingQueue(workqueue.DefaultControllerRateLimiter(), "diagnosis"),
+		recorder:           recorder,
+		remediation:        remediation,
+		minConfidence:      DefaultRemediationMinConfidence,
+	}
+	status := func() *diagnosisv1alpha1.DiagnosisRemediationStatus {
+		current, err := client.AegisV1alpha1().AegisDiagnosises("default").Get(context.Background(), "diagnose-app", metav1.GetOptions{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		indexer.Update(current)
+		return current.Status.Remediation
+	}
+	status()
+	return c, client, recorder, status
+}
+
+func TestSyncRemediation(t *testing.T) {
+	remediation := &fakeRemediation{errs: []error{fmt.Errorf("connection refused")}}
+	c, _, recorder, status := newRemediationTestController(t, remediation, diagnosisv1alpha1.CategorySourceAnalysis, 80)
+
+	// a transient failure is recorded and retried
+	if err := c.syncHandler("default/diagnose-app"); err == nil {
+		t.Fatal("expected the diagnosis requeued")
+	}
+	if s := status(); s.AlertRaised || !strings.Contains(s.Message, "connection refused") {
+		t.Errorf("expected the failure recorded, got %+v", s)
+	}
+	if event := <-recorder.Events; !strings.HasPrefix(event, "Warning "+FailedRemediation) {
+		t.Errorf("expected a warning event, got %s", event)
+	}
+
+	if err := c.syncHandler("default/diagnose-app"); err != nil {
+		t.Fatal(err)
+	}
+	if s := status(); !s.AlertRaised || s.Message != "Raised alert DiagnosedImageIssue" {
+		t.Errorf("expected the alert raised, got %+v", s)
+	}
+
+	// the alert is raised once
+	if err := c.syncHandler("default/diagnose-app"); err != nil || remediation.calls != 2 {
+		t.Errorf("expected the alert raised once, got %d calls %v", remediation.calls, err)
+	}
+}
+
+func TestSyncRemediationNotRetried(t *testing.T) {
+	disabled := fmt.Errorf("%w, skip", controller.ErrAlertDisabled)
+	remediation := &fakeRemediation{errs: []error{disabled, disabled}}
+	c, client, _, status := newRemediationTestController(t, remediation, diagnosisv1alpha1.CategorySourceSignature, 0)
+
+	if err := c.syncHandler("default/diagnose-app"); err != nil {
+		t.Fatalf("expected the disabled alert not retried, got %v", err)
+	}
+	if s := status(); s.AlertRaised || !strings.Contains(s.Message, "alert is disabled") {
+		t.Errorf("expected the failure recorded, got %+v", s)
+	}
+
+	// the unchanged status is not updated again
+	client.ClearActions()
+	if err := c.syncHandler("default/diagnose-app"); err != nil {
+		t.Fatal(err)
+	}
+	for _, action := range client.Actions() {
+		if action.GetVerb() == "update" {
+			t.Errorf("expected no status update, got %v", action)
+		}
+	}
+}
+
+func TestSyncRemediationConfidence(t *testing.T) {
+	remediation := &fakeRemediation{}
+	c, _, recorder, status := newRemediationTestController(t, remediation, diagnosisv1alpha1.CategorySourceAnalysis, 40)
+
+	if err := c.syncHandler("default/diagnose-app"); err != nil {
+		t.Fatal(err)
+	}
+	if remediation.calls != 0 {
+		t.Errorf("expected no alert below the confidence, got %d calls", remediation.calls)
+	}
+	if s := status(); s.AlertRaised || s.Message != "Alert DiagnosedImageIssue not raised: confidence 40 of the analysis below 60" {
+		t.Errorf("expected the gate recorded, got %+v", s)
+	}
+	if event := <-recorder.Events; !strings.HasPrefix(event, "Normal "+RemediationDiagnosis) {
+		t.Errorf("expected a normal event, got %s", event)
+	}
+}
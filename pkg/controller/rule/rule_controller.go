@@ -43,7 +43,10 @@ const (
 )
 
 type RuleController struct {
-	mu         sync.Mutex
+	// mu guards the rule cache and index, lookups only take the read lock
+	mu sync.RWMutex
+	// templateMu serializes the template execute status updates
+	templateMu sync.Mutex
 	kubeClient kubernetes.Interface
 
 	// alert rule clientset
@@ -53,7 +56,12 @@ type RuleController struct {
 	lister         listers.AegisAlertOpsRuleLister
 	templateLister templateListers.AegisOpsTemplateLister
 
-	ruleCache map[string]*rulev1alpha1.AegisAlertOpsRule
+	ruleCache map[string]*compiledRule
+	index     ruleIndex
+
+	// maxEscalationWindow is the ttl of the failed alerts, the escalation
+	// windows are clamped to it as older failures are cleaned up
+	maxEscalationWindow time.Duration
 
 	synced cache.InformerSynced
 
@@ -62,19 +70,6 @@ type RuleController struct {
 	recorder record.EventRecorder
 }
 
-func (c *RuleController) addOrUpdateFromCache(key string, rule *rulev1alpha1.AegisAlertOpsRule) {
-	klog.V(4).Infof("Add or Update rule: %s", key)
-	c.mu.Lock()
-	defer c.mu.Unlock()
-	c.ruleCache[key] = rule.DeepCopy()
-}
-
-func (c *RuleController) deleteFromCache(key string) {
-	c.mu.Lock()
-	defer c.mu.Unlock()
-	delete(c.ruleCache, key)
-}
-
 // add reacts to an AegisAlertOpsRule creation
 func (c *RuleController) added(obj interface{}) {
 	c.enqueueRule(obj)
@@ -113,7 +108,8 @@ func NewController(
 	ruleclientset clientset.Interface,
 	templateclientset templateclientset.Interface,
 	ruleInformer informers.AegisAlertOpsRuleInformer,
-	templateInformer templateInformers.AegisOpsTemplateInformer) *RuleController {
+	templateInformer templateInformers.AegisOpsTemplateInformer,
+	maxEscalationWindow time.Duration) *RuleController {
 
 	// Create event broadcaster
 	klog.V(4).Info("Creating aegis rule event boradcaster")
@@ -123,15 +119,17 @@ func NewController(
 	recorder := eventBroadcaster.NewRecorder(scheme.Scheme, v1.EventSource{Component: controllerAgentName})
 
 	controller := &RuleController{
-		kubeClient:        kubeclientset,
-		ruleclinetset:     ruleclientset,
-		templateclientset: templateclientset,
-		lister:            ruleInformer.Lister(),
-		templateLister:    templateInformer.Lister(),
-		ruleCache:         make(map[string]*rulev1alpha1.AegisAlertOpsRule),
-		synced:            ruleInformer.Informer().HasSynced,
-		workqueue:         workqueue.NewNamedRateLimitingQueue(workqueue.DefaultControllerRateLimiter(), controllerAgentName),
-		recorder:          recorder,
+		kubeClient:          kubeclientset,
+		ruleclinetset:       ruleclientset,
+		templateclientset:   templateclientset,
+		lister:              ruleInformer.Lister(),
+		templateLister:      templateInformer.Lister(),
+		ruleCache:           make(map[string]*compiledRule),
+		index:               make(ruleIndex),
+		maxEscalationWindow: maxEscalationWindow,
+		synced:              ruleInformer.Informer().HasSynced,
+		workqueue:           workqueue.NewNamedRateLimitingQueue(workqueue.DefaultControllerRateLimiter(), controllerAgentName),
+		recorder:            recorder,
 	}
 
 	klog.Info("Setting up event handles")
@@ -3,72 +3,126 @@ package rule
 import (
 	"context"
 	"fmt"
-	"regexp"
+	"time"
 
 	ruleapi "github.com/scitix/aegis/pkg/apis/rule/v1alpha1"
 	templatev1alpha1 "github.com/scitix/aegis/pkg/apis/template/v1alpha1"
 	"github.com/scitix/aegis/pkg/controller"
 	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
-	"k8s.io/apimachinery/pkg/labels"
 	"k8s.io/klog/v2"
 )
 
 var templateControllerKind = templatev1alpha1.SchemeGroupVersion.WithKind("AegisOpsTemplate")
 
-func matchCondition(condition *controller.Condition, targets []ruleapi.AegisAlertCondition) bool {
-	if condition == nil {
-		return false
+func (c *RuleController) GetTemplateRefs(r *controller.MatchRule) ([]*corev1.ObjectReference, error) {
+	refs := make([]*corev1.ObjectReference, 0)
+	for _, rule := range c.matchRules(r) {
+		refs = append(refs, rule.rule.Spec.OpsTemplate)
 	}
-	for _, con := range targets {
-		if con.Status != condition.Status {
-			continue
+
+	return refs, nil
+}
+
+func (c *RuleController) GetOpsPolicy(r *controller.MatchRule) (*controller.OpsPolicy, error) {
+	rules := c.matchRules(r)
+	if len(rules) != 1 {
+		return nil, nil
+	}
+	spec := &rules[0].rule.Spec
+
+	policy := &controller.OpsPolicy{Rule: rules[0].key}
+	template := c.getOpsTemplate(spec.OpsTemplate)
+
+	var err error
+	if spec.RetryPolicy != nil {
+		policy.Retry, err = newRetryPolicyFromRule(spec.RetryPolicy)
+	} else if template != nil && template.Spec.RetryPolicy != nil {
+		policy.Retry, err = newRetryPolicyFromTemplate(template.Spec.RetryPolicy)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if spec.Verify != nil {
+		if policy.Verify, err = controller.NewVerifyPolicy(spec.Verify.Query, spec.Verify.Wait, spec.Verify.Timeout, spec.Verify.Interval); err != nil {
+			return nil, err
 		}
+	}
 
-		if match, _ := regexp.MatchString(con.Type, condition.Type); !match {
-			continue
+	if spec.Escalation != nil {
+		if policy.Escalation, err = newEscalationPolicyFromRule(spec.Escalation); err != nil {
+			return nil, err
 		}
+		if c.escalationWindowExceeded(policy.Escalation) {
+			policy.Escalation.Window = c.maxEscalationWindow
+		}
+	}
 
-		return true
+	if spec.ActiveDeadlineSeconds != nil {
+		policy.ActiveDeadline = time.Duration(*spec.ActiveDeadlineSeconds) * time.Second
+	} else if template != nil && template.Spec.ActiveDeadlineSeconds != nil {
+		policy.ActiveDeadline = time.Duration(*template.Spec.ActiveDeadlineSeconds) * time.Second
 	}
 
-	return false
+	return policy, nil
 }
 
-func (c *RuleController) GetTemplateRefs(r *controller.MatchRule) ([]*corev1.ObjectReference, error) {
-	c.mu.Lock()
-	defer c.mu.Unlock()
-
-	// selector := &metav1.LabelSelector{
-	// 	MatchLabels:      make(map[string]string),
-	// 	MatchExpressions: make([]metav1.LabelSelectorRequirement, 0),
-	// }
-
-	// err := metav1.Convert_Map_string_To_string_To_v1_LabelSelector(&r.Labels, selector, nil)
-	// if err != nil {
-	// 	return nil, fmt.Errorf("fail to convert rule labels(%+v) to LabelSelector: %v", r.Labels, err)
-	// }
-
-	// labels, err := metav1.LabelSelectorAsSelector(selector)
-	// if err != nil {
-	// 	return nil, fmt.Errorf("fail to convert selector(%v) to labels: %v", selector, err)
-	// }
-	refs := make([]*corev1.ObjectReference, 0)
-	for key, rule := range c.ruleCache {
-		selector, err := metav1.LabelSelectorAsSelector(rule.Spec.Selector)
-		if err != nil {
-			klog.Errorf("Error when deal with rule(%s) labelselector: %v, ignore", key, err)
-		}
+// getOpsTemplate returns the referenced template, nil if it can't be found
+func (c *RuleController) getOpsTemplate(ref *corev1.ObjectReference) *templatev1alpha1.AegisOpsTemplate {
+	if ref == nil || ref.Kind != templateControllerKind.Kind {
+		return nil
+	}
 
-		if matchCondition(r.Condition, rule.Spec.AlertConditions) && (len(selector.String()) == 0 || selector.Matches(labels.Set(r.Labels))) {
-			klog.V(6).Infof("rule %s match condition: %v", key, r)
-			refs = append(refs, rule.Spec.OpsTemplate)
-		} else {
-			klog.V(6).Infof("rule %s don't match condition: %v", key, r)
-		}
+	template, err := c.templateLister.AegisOpsTemplates(ref.Namespace).Get(ref.Name)
+	if err != nil {
+		klog.V(4).Infof("Get template %s/%s failed: %v", ref.Namespace, ref.Name, err)
+		return nil
 	}
+	return template
+}
 
-	return refs, nil
+func newRetryPolicyFromRule(p *ruleapi.RetryPolicy) (*controller.RetryPolicy, error) {
+	if p.Backoff == nil {
+		return controller.NewRetryPolicy(p.MaxRetries, "", nil, "", p.RetryOn)
+	}
+	return controller.NewRetryPolicy(p.MaxRetries, p.Backoff.Duration, p.Backoff.Factor, p.Backoff.MaxDuration, p.RetryOn)
+}
+
+func newRetryPolicyFromTemplate(p *templatev1alpha1.RetryPolicy) (*controller.RetryPolicy, error) {
+	if p.Backoff == nil {
+		return controller.NewRetryPolicy(p.MaxRetries, "", nil, "", p.RetryOn)
+	}
+	return controller.NewRetryPolicy(p.MaxRetries, p.Backoff.Duration, p.Backoff.Factor, p.Backoff.MaxDuration, p.RetryOn)
+}
+
+func newEscalationPolicyFromRule(p *ruleapi.EscalationPolicy) (*controller.EscalationPolicy, error) {
+	actions := make([]string, 0, len(p.Actions))
+	for _, action := range p.Actions {
+		actions = append(actions, string(action))
+	}
+	return controller.NewEscalationPolicy(p.MaxFailures, p.Window, string(p.GroupBy), actions, p.Severity)
+}
+
+// escalationWindowExceeded reports whether the escalation window is longer than
+// the ttl of the failed alerts, the failures before the ttl are never counted
+func (c *RuleController) escalationWindowExceeded(policy *controller.EscalationPolicy) bool {
+	return c.maxEscalationWindow > 0 && policy.Window > c.maxEscalationWindow
+}
+
+// checkEscalationWindow warns of the rule whose escalation window is clamped
+func (c *RuleController) checkEscalationWindow(key string, rule *ruleapi.AegisAlertOpsRule) {
+	if rule.Spec.Escalation == nil {
+		return
+	}
+	policy, err := newEscalationPolicyFromRule(rule.Spec.Escalation)
+	if err != nil {
+		klog.Warningf("Invalid escalation of rule %s: %v", key, err)
+		return
+	}
+	if c.escalationWindowExceeded(policy) {
+		klog.Warningf("Escalation window %v of rule %s exceeds the ttl %v of the failed alerts, clamped to %v", policy.Window, key, c.maxEscalationWindow, c.maxEscalationWindow)
+	}
 }
 
 func (c *RuleController) GetTemplateContentByRefs(ref *corev1.ObjectReference) (string, error) {
@@ -85,8 +139,8 @@ func (c *RuleController) GetTemplateContentByRefs(ref *corev1.ObjectReference) (
 }
 
 func (c *RuleController) SucceedExecuteTemplateCallback(ref *corev1.ObjectReference) {
-	c.mu.Lock()
-	defer c.mu.Unlock()
+	c.templateMu.Lock()
+	defer c.templateMu.Unlock()
 	klog.V(6).Infof("increase template %s/%s succeed status field", ref.Namespace, ref.Name)
 
 	template, err := c.templateLister.AegisOpsTemplates(ref.Namespace).Get(ref.Name)
@@ -104,8 +158,8 @@ func (c *RuleController) SucceedExecuteTemplateCallback(ref *corev1.ObjectRefere
 }
 
 func (c *RuleController) FailedExecuteTemplateCallback(ref *corev1.ObjectReference) {
-	c.mu.Lock()
-	defer c.mu.Unlock()
+	c.templateMu.Lock()
+	defer c.templateMu.Unlock()
 	klog.V(6).Infof("increase template %s/%s failed status field", ref.Namespace, ref.Name)
 
 	template, err := c.templateLister.AegisOpsTemplates(ref.Namespace).Get(ref.Name)
@@ -2,9 +2,15 @@ package rule
 
 import (
 	"testing"
+	"time"
 
 	ruleapi "github.com/scitix/aegis/pkg/apis/rule/v1alpha1"
+	templatev1alpha1 "github.com/scitix/aegis/pkg/apis/template/v1alpha1"
 	"github.com/scitix/aegis/pkg/controller"
+	templatelisters "github.com/scitix/aegis/pkg/generated/template/listers/template/v1alpha1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/tools/cache"
 )
 
 func TestMatchRule(t *testing.T) {
@@ -22,9 +28,101 @@ func TestMatchRule(t *testing.T) {
 		}: true,
 	}
 
+	conditions := compileConditions("monitoring/rule", rules)
 	for condition, expected := range condMap {
-		if expected != matchCondition(condition, rules) {
+		if expected != matchCondition(condition, conditions) {
 			t.Fatalf("Condition: %v match expected: %v, but got: %v", condition, expected, !expected)
 		}
 	}
 }
+
+func TestGetOpsPolicy(t *testing.T) {
+	indexer := cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{})
+	indexer.Add(&templatev1alpha1.AegisOpsTemplate{
+		ObjectMeta: metav1.ObjectMeta{Name: "restart", Namespace: "monitoring"},
+		Spec: templatev1alpha1.AegisOpsTemplateSpec{
+			RetryPolicy:           &templatev1alpha1.RetryPolicy{MaxRetries: 1},
+			ActiveDeadlineSeconds: &[]int64{600}[0],
+		},
+	})
+
+	newRule := func(name, alertType string, policy *ruleapi.RetryPolicy) *ruleapi.AegisAlertOpsRule {
+		return &ruleapi.AegisAlertOpsRule{
+			ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "monitoring"},
+			Spec: ruleapi.AegisAlertOpsRuleSpec{
+				AlertConditions: []ruleapi.AegisAlertCondition{{Type: alertType, Status: "Firing"}},
+				OpsTemplate: &corev1.ObjectReference{
+					Kind:      "AegisOpsTemplate",
+					Namespace: "monitoring",
+					Name:      "restart",
+				},
+				RetryPolicy: policy,
+			},
+		}
+	}
+
+	c := &RuleController{
+		templateLister: templatelisters.NewAegisOpsTemplateLister(indexer),
+		ruleCache:      make(map[string]*compiledRule),
+		index:          make(ruleIndex),
+	}
+	rule := newRule("rule", "NodeOutOfDiskSpace", &ruleapi.RetryPolicy{MaxRetries: 3})
+	rule.Spec.ActiveDeadlineSeconds = &[]int64{60}[0]
+	c.addOrUpdateFromCache("monitoring/rule", rule)
+	c.addOrUpdateFromCache("monitoring/template", newRule("template", "NodeNotReady", nil))
+
+	cases := map[string]struct {
+		rule     string
+		retries  int32
+		deadline time.Duration
+	}{
+		"NodeOutOfDiskSpace": {"monitoring/rule", 3, time.Minute},
+		"NodeNotReady":       {"monitoring/template", 1, 10 * time.Minute},
+	}
+	for alertType, expected := range cases {
+		policy, err := c.GetOpsPolicy(&controller.MatchRule{Condition: &controller.Condition{Type: alertType, Status: "Firing"}})
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if policy == nil || policy.Retry == nil || policy.Retry.MaxRetries != expected.retries {
+			t.Errorf("alert %s: expected max retries %d, got %+v", alertType, expected.retries, policy)
+		}
+		if policy != nil && policy.Rule != expected.rule {
+			t.Errorf("alert %s: expected rule %s, got %s", alertType, expected.rule, policy.Rule)
+		}
+		if policy != nil && policy.ActiveDeadline != expected.deadline {
+			t.Errorf("alert %s: expected deadline %v, got %v", alertType, expected.deadline, policy.ActiveDeadline)
+		}
+	}
+
+	policy, err := c.GetOpsPolicy(&controller.MatchRule{Condition: &controller.Condition{Type: "GpuDown", Status: "Firing"}})
+	if err != nil || policy != nil {
+		t.Errorf("expected no policy for unmatched alert, got %+v, %v", policy, err)
+	}
+}
+
+func TestGetOpsPolicyEscalationWindow(t *testing.T) {
+	c := newIndexTestController()
+	c.maxEscalationWindow = 72 * time.Hour
+
+	for name, window := range map[string]string{"week": "168h", "day": "24h"} {
+		rule := newIndexTestRule(name, name, nil)
+		rule.Spec.Escalation = &ruleapi.EscalationPolicy{MaxFailures: 3, Window: window, Actions: []ruleapi.EscalationActionType{ruleapi.EscalationActionNotify}}
+		c.addOrUpdateFromCache("monitoring/"+name, rule)
+	}
+
+	// failures older than the ttl of the failed alerts are cleaned up
+	cases := map[string]time.Duration{
+		"week": 72 * time.Hour,
+		"day":  24 * time.Hour,
+	}
+	for alertType, expected := range cases {
+		policy, err := c.GetOpsPolicy(&controller.MatchRule{Condition: &controller.Condition{Type: alertType, Status: "Firing"}})
+		if err != nil || policy == nil || policy.Escalation == nil {
+			t.Fatalf("alert %s: expected escalation, got %+v, %v", alertType, policy, err)
+		}
+		if policy.Escalation.Window != expected {
+			t.Errorf("alert %s: expected window %v, got %v", alertType, expected, policy.Escalation.Window)
+		}
+	}
+}
@@ -0,0 +1,199 @@
+package rule
+
+import (
+	"regexp"
+	"sort"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/klog/v2"
+
+	ruleapi "github.com/scitix/aegis/pkg/apis/rule/v1alpha1"
+	"github.com/scitix/aegis/pkg/controller"
+)
+
+// maxIndexSize bounds the conditions indexed, alert types come from the
+// alert sources and aren't known in advance
+const maxIndexSize = 10000
+
+// ruleCondition is an alert condition of a rule, its type is a regexp
+type ruleCondition struct {
+	status string
+	typ    *regexp.Regexp
+}
+
+// compileConditions compiles the conditions of the rule of key, the invalid
+// ones are dropped as they never match any alert
+func compileConditions(key string, targets []ruleapi.AegisAlertCondition) []ruleCondition {
+	conditions := make([]ruleCondition, 0, len(targets))
+	for _, target := range targets {
+		typ, err := regexp.Compile(target.Type)
+		if err != nil {
+			klog.Errorf("Error when deal with rule(%s) alert condition %q: %v, ignore", key, target.Type, err)
+			continue
+		}
+		conditions = append(conditions, ruleCondition{status: target.Status, typ: typ})
+	}
+	return conditions
+}
+
+func matchCondition(condition *controller.Condition, targets []ruleCondition) bool {
+	if condition == nil {
+		return false
+	}
+	for _, con := range targets {
+		if con.status == condition.Status && con.typ.MatchString(condition.Type) {
+			return true
+		}
+	}
+	return false
+}
+
+// compiledRule is a cached rule with its selector and conditions compiled
+// once, it must not be modified once cached.
+type compiledRule struct {
+	key        string
+	rule       *ruleapi.AegisAlertOpsRule
+	selector   labels.Selector
+	conditions []ruleCondition
+}
+
+// compileRule returns the compiled rule, nil if its selector or all its
+// conditions are invalid as it never matches any alert then
+func compileRule(key string, rule *ruleapi.AegisAlertOpsRule) *compiledRule {
+	selector, err := metav1.LabelSelectorAsSelector(rule.Spec.Selector)
+	if err != nil {
+		klog.Errorf("Error when deal with rule(%s) labelselector: %v, ignore", key, err)
+		return nil
+	}
+	// a rule without selector matches all the alerts of its conditions
+	if len(selector.String()) == 0 {
+		selector = labels.Everything()
+	}
+
+	conditions := compileConditions(key, rule.Spec.AlertConditions)
+	if len(conditions) == 0 && len(rule.Spec.AlertConditions) > 0 {
+		klog.Errorf("Error when deal with rule(%s): no valid alert condition, ignore", key)
+		return nil
+	}
+
+	return &compiledRule{
+		key:        key,
+		rule:       rule,
+		selector:   selector,
+		conditions: conditions,
+	}
+}
+
+// ruleIndex indexes the rules by the alert conditions they match. The
+// conditions are added on their first lookup and kept up to date as rules
+// change. Indexed rule slices are replaced, never modified, so they can be
+// read once the lock is released. The caller must hold the lock of the
+// controller.
+type ruleIndex map[controller.Condition][]*compiledRule
+
+// insertRule returns the rules of the condition with the rule, sorted by key
+func insertRule(rules []*compiledRule, rule *compiledRule) []*compiledRule {
+	i := sort.Search(len(rules), func(i int) bool { return rules[i].key >= rule.key })
+	updated := make([]*compiledRule, 0, len(rules)+1)
+	updated = append(updated, rules[:i]...)
+	updated = append(updated, rule)
+	return append(updated, rules[i:]...)
+}
+
+// removeRule returns the rules of the condition without the rule of key
+func removeRule(rules []*compiledRule, key string) []*compiledRule {
+	for i, rule := range rules {
+		if rule.key == key {
+			updated := make([]*compiledRule, 0, len(rules)-1)
+			updated = append(updated, rules[:i]...)
+			return append(updated, rules[i+1:]...)
+		}
+	}
+	return rules
+}
+
+// update replaces the rule of key in the indexed conditions, rule is nil if deleted
+func (index ruleIndex) update(key string, rule *compiledRule) {
+	for condition, rules := range index {
+		rules = removeRule(rules, key)
+		if rule != nil && matchCondition(&condition, rule.conditions) {
+			rules = insertRule(rules, rule)
+		}
+		index[condition] = rules
+	}
+}
+
+func (c *RuleController) addOrUpdateFromCache(key string, rule *ruleapi.AegisAlertOpsRule) {
+	klog.V(4).Infof("Add or Update rule: %s", key)
+	compiled := compileRule(key, rule.DeepCopy())
+	c.checkEscalationWindow(key, rule)
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if compiled == nil {
+		delete(c.ruleCache, key)
+	} else {
+		c.ruleCache[key] = compiled
+	}
+	c.index.update(key, compiled)
+}
+
+func (c *RuleController) deleteFromCache(key string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	delete(c.ruleCache, key)
+	c.index.update(key, nil)
+}
+
+// conditionRules returns the rules matching the condition, sorted by key
+func (c *RuleController) conditionRules(condition controller.Condition) []*compiledRule {
+	c.mu.RLock()
+	rules, ok := c.index[condition]
+	c.mu.RUnlock()
+	if ok {
+		return rules
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if rules, ok := c.index[condition]; ok {
+		return rules
+	}
+
+	rules = make([]*compiledRule, 0)
+	for _, rule := range c.ruleCache {
+		if matchCondition(&condition, rule.conditions) {
+			rules = append(rules, rule)
+		}
+	}
+	sort.Slice(rules, func(i, j int) bool {
+		return rules[i].key < rules[j].key
+	})
+
+	if len(c.index) >= maxIndexSize {
+		klog.Warningf("Rule index reached %d alert conditions, reset it", len(c.index))
+		c.index = make(ruleIndex)
+	}
+	c.index[condition] = rules
+	return rules
+}
+
+// matchRules returns the cached rules matching r, sorted by key
+func (c *RuleController) matchRules(r *controller.MatchRule) []*compiledRule {
+	if r.Condition == nil {
+		return nil
+	}
+
+	set := labels.Set(r.Labels)
+	rules := make([]*compiledRule, 0)
+	for _, rule := range c.conditionRules(*r.Condition) {
+		if rule.selector.Matches(set) {
+			klog.V(6).Infof("rule %s match condition: %v", rule.key, r)
+			rules = append(rules, rule)
+		} else {
+			klog.V(6).Infof("rule %s don't match condition: %v", rule.key, r)
+		}
+	}
+	return rules
+}
@@ -0,0 +1,227 @@
+package rule
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/tools/cache"
+
+	ruleapi "github.com/scitix/aegis/pkg/apis/rule/v1alpha1"
+	"github.com/scitix/aegis/pkg/controller"
+	templatelisters "github.com/scitix/aegis/pkg/generated/template/listers/template/v1alpha1"
+)
+
+func newIndexTestController() *RuleController {
+	return &RuleController{
+		templateLister: templatelisters.NewAegisOpsTemplateLister(cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{})),
+		ruleCache:      make(map[string]*compiledRule),
+		index:          make(ruleIndex),
+	}
+}
+
+func newIndexTestRule(name, alertType string, matchLabels map[string]string) *ruleapi.AegisAlertOpsRule {
+	rule := &ruleapi.AegisAlertOpsRule{
+		ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "monitoring"},
+		Spec: ruleapi.AegisAlertOpsRuleSpec{
+			AlertConditions: []ruleapi.AegisAlertCondition{{Type: alertType, Status: "Firing"}},
+			OpsTemplate:     &corev1.ObjectReference{Kind: "AegisOpsTemplate", Namespace: "monitoring", Name: name},
+		},
+	}
+	if matchLabels != nil {
+		rule.Spec.Selector = &metav1.LabelSelector{MatchLabels: matchLabels}
+	}
+	return rule
+}
+
+func matchedTemplates(t *testing.T, c *RuleController, alertType string, labels map[string]string) string {
+	t.Helper()
+	refs, err := c.GetTemplateRefs(&controller.MatchRule{
+		Labels:    labels,
+		Condition: &controller.Condition{Type: alertType, Status: "Firing"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	names := make([]string, 0, len(refs))
+	for _, ref := range refs {
+		names = append(names, ref.Name)
+	}
+	return fmt.Sprint(names)
+}
+
+func TestRuleIndex(t *testing.T) {
+	c := newIndexTestController()
+	c.addOrUpdateFromCache("monitoring/gpu", newIndexTestRule("gpu", "Gpu.*", nil))
+	c.addOrUpdateFromCache("monitoring/down", newIndexTestRule("down", "GpuDown", map[string]string{"cluster": "a"}))
+
+	if got := matchedTemplates(t, c, "GpuDown", map[string]string{"cluster": "a"}); got != "[down gpu]" {
+		t.Errorf("expected [down gpu], got %s", got)
+	}
+	if got := matchedTemplates(t, c, "GpuDown", map[string]string{"cluster": "b"}); got != "[gpu]" {
+		t.Errorf("expected selector filtered, got %s", got)
+	}
+
+	// indexed conditions follow the rule updates
+	c.addOrUpdateFromCache("monitoring/down", newIndexTestRule("down", "NodeNotReady", nil))
+	if got := matchedTemplates(t, c, "GpuDown", map[string]string{"cluster": "a"}); got != "[gpu]" {
+		t.Errorf("expected updated rule removed from the condition, got %s", got)
+	}
+	if got := matchedTemplates(t, c, "NodeNotReady", nil); got != "[down]" {
+		t.Errorf("expected updated rule matched, got %s", got)
+	}
+
+	c.deleteFromCache("monitoring/gpu")
+	if got := matchedTemplates(t, c, "GpuDown", nil); got != "[]" {
+		t.Errorf("expected deleted rule removed, got %s", got)
+	}
+
+	// invalid rules never match
+	c.addOrUpdateFromCache("monitoring/regexp", newIndexTestRule("regexp", "Gpu(", nil))
+	invalid := newIndexTestRule("selector", "GpuDown", nil)
+	invalid.Spec.Selector = &metav1.LabelSelector{MatchExpressions: []metav1.LabelSelectorRequirement{{Key: "cluster", Operator: "Near"}}}
+	c.addOrUpdateFromCache("monitoring/selector", invalid)
+	if got := matchedTemplates(t, c, "GpuDown", nil); got != "[]" {
+		t.Errorf("expected invalid rules ignored, got %s", got)
+	}
+
+	// only the invalid conditions of a rule are dropped
+	partial := newIndexTestRule("partial", "Gpu(", nil)
+	partial.Spec.AlertConditions = append(partial.Spec.AlertConditions, ruleapi.AegisAlertCondition{Type: "GpuDown", Status: "Firing"})
+	c.addOrUpdateFromCache("monitoring/partial", partial)
+	if got := matchedTemplates(t, c, "GpuDown", nil); got != "[partial]" {
+		t.Errorf("expected the valid condition matched, got %s", got)
+	}
+}
+
+const (
+	benchmarkRules      = 5000
+	benchmarkAlertTypes = 500
+	benchmarkBurst      = 10000
+)
+
+// newBenchmarkController caches 5k rules: 10 per alert type with a cluster
+// selector, and a few regexp rules matching many types
+func newBenchmarkController() *RuleController {
+	c := newIndexTestController()
+	for i := 0; i < benchmarkRules; i++ {
+		name := fmt.Sprintf("rule-%d", i)
+		alertType := fmt.Sprintf("AlertType%03d", i%benchmarkAlertTypes)
+		labels := map[string]string{"cluster": fmt.Sprintf("c%d", i/benchmarkAlertTypes)}
+		if i%1000 == 0 {
+			alertType = fmt.Sprintf("AlertType%d.*", i/1000)
+			labels = nil
+		}
+		c.addOrUpdateFromCache("monitoring/"+name, newIndexTestRule(name, alertType, labels))
+	}
+	return c
+}
+
+// newBenchmarkAlerts returns a burst of alerts, some of types without rules
+func newBenchmarkAlerts() []*controller.MatchRule {
+	alerts := make([]*controller.MatchRule, 0, benchmarkBurst)
+	for i := 0; i < benchmarkBurst; i++ {
+		alerts = append(alerts, &controller.MatchRule{
+			Labels: map[string]string{
+				"cluster": fmt.Sprintf("c%d", i%10),
+				"node":    fmt.Sprintf("node-%d", i%2000),
+			},
+			Condition: &controller.Condition{
+				Type:   fmt.Sprintf("AlertType%03d", i%(benchmarkAlertTypes+100)),
+				Status: "Firing",
+			},
+		})
+	}
+	return alerts
+}
+
+func reportAlertsPerMinute(b *testing.B, alerts int) {
+	if elapsed := b.Elapsed(); elapsed > 0 {
+		b.ReportMetric(float64(alerts)/elapsed.Minutes(), "alerts/min")
+	}
+}
+
+func BenchmarkGetTemplateRefs(b *testing.B) {
+	c := newBenchmarkController()
+	alerts := newBenchmarkAlerts()
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		if _, err := c.GetTemplateRefs(alerts[i%len(alerts)]); err != nil {
+			b.Fatal(err)
+		}
+	}
+	reportAlertsPerMinute(b, b.N)
+}
+
+func BenchmarkGetTemplateRefsParallel(b *testing.B) {
+	c := newBenchmarkController()
+	alerts := newBenchmarkAlerts()
+	b.ResetTimer()
+
+	b.RunParallel(func(pb *testing.PB) {
+		i := 0
+		for pb.Next() {
+			if _, err := c.GetTemplateRefs(alerts[i%len(alerts)]); err != nil {
+				b.Error(err)
+				return
+			}
+			i++
+		}
+	})
+	reportAlertsPerMinute(b, b.N)
+}
+
+// BenchmarkAlertBurst matches bursts of 10k alerts with 8 workers, as the
+// alert controller does, while a rule changes every millisecond
+func BenchmarkAlertBurst(b *testing.B) {
+	c := newBenchmarkController()
+	alerts := newBenchmarkAlerts()
+	workers := 8
+
+	stop := make(chan struct{})
+	updated := make(chan struct{})
+	go func() {
+		defer close(updated)
+		ticker := time.NewTicker(time.Millisecond)
+		defer ticker.Stop()
+		for i := 0; ; i++ {
+			select {
+			case <-stop:
+				return
+			case <-ticker.C:
+			}
+			name := fmt.Sprintf("rule-%d", i%benchmarkRules)
+			c.addOrUpdateFromCache("monitoring/"+name, newIndexTestRule(name, fmt.Sprintf("AlertType%03d", i%benchmarkAlertTypes), nil))
+		}
+	}()
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		var wg sync.WaitGroup
+		for w := 0; w < workers; w++ {
+			wg.Add(1)
+			go func(w int) {
+				defer wg.Done()
+				for j := w; j < len(alerts); j += workers {
+					if _, err := c.GetTemplateRefs(alerts[j]); err != nil {
+						b.Error(err)
+						return
+					}
+					if _, err := c.GetOpsPolicy(alerts[j]); err != nil {
+						b.Error(err)
+						return
+					}
+				}
+			}(w)
+		}
+		wg.Wait()
+	}
+	b.StopTimer()
+	close(stop)
+	<-updated
+	reportAlertsPerMinute(b, b.N*len(alerts))
+}
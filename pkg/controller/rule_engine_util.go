@@ -1,9 +1,36 @@
 package controller
 
 import (
+	"fmt"
+	"strings"
+	"time"
+
 	corev1 "k8s.io/api/core/v1"
 )
 
+const (
+	DefaultRetryBackoff       = 10 * time.Second
+	DefaultRetryBackoffFactor = 2
+	// DefaultRetryMaxBackoff caps the backoff of the policies without a max duration
+	DefaultRetryMaxBackoff = time.Hour
+
+	DefaultVerifyTimeout  = 5 * time.Minute
+	DefaultVerifyInterval = 30 * time.Second
+
+	DefaultEscalationWindow   = 24 * time.Hour
+	DefaultEscalationSeverity = "critical"
+)
+
+const (
+	EscalationGroupByFingerprint    = "Fingerprint"
+	EscalationGroupByInvolvedObject = "InvolvedObject"
+
+	EscalationActionRaiseSeverity = "RaiseSeverity"
+	EscalationActionDisableNode   = "DisableNode"
+	EscalationActionNotify        = "Notify"
+	EscalationActionCreateTicket  = "CreateTicket"
+)
+
 type Condition struct {
 	Type   string
 	Status string
@@ -14,11 +41,220 @@ type MatchRule struct {
 	Condition *Condition        // match any
 }
 
+// OpsPolicy gathers the ops behaviors declared by the matched rule and its template
+type OpsPolicy struct {
+	// Rule is the namespace/name of the matched rule
+	Rule string
+
+	Retry      *RetryPolicy
+	Verify     *VerifyPolicy
+	Escalation *EscalationPolicy
+
+	// ActiveDeadline bounds the ops duration of an alert, zero if unbounded
+	ActiveDeadline time.Duration
+}
+
+// RetryPolicy is the resolved retry policy of ops workflows
+type RetryPolicy struct {
+	MaxRetries int32
+	Backoff    time.Duration
+	Factor     int32
+	MaxBackoff time.Duration
+	RetryOn    []string
+}
+
+// NewRetryPolicy parses the retry policy fields declared on a rule or template
+func NewRetryPolicy(maxRetries int32, duration string, factor *int32, maxDuration string, retryOn []string) (*RetryPolicy, error) {
+	policy := &RetryPolicy{
+		MaxRetries: maxRetries,
+		Backoff:    DefaultRetryBackoff,
+		Factor:     DefaultRetryBackoffFactor,
+		RetryOn:    retryOn,
+	}
+
+	var err error
+	if len(duration) > 0 {
+		if policy.Backoff, err = time.ParseDuration(duration); err != nil {
+			return nil, fmt.Errorf("invalid backoff duration %q: %v", duration, err)
+		}
+	}
+
+	if len(maxDuration) > 0 {
+		if policy.MaxBackoff, err = time.ParseDuration(maxDuration); err != nil {
+			return nil, fmt.Errorf("invalid backoff max duration %q: %v", maxDuration, err)
+		}
+	}
+
+	if factor != nil {
+		if *factor < 1 {
+			return nil, fmt.Errorf("invalid backoff factor %d", *factor)
+		}
+		policy.Factor = *factor
+	}
+
+	return policy, nil
+}
+
+// BackoffFor returns the delay before the given retry attempt, starting from 1.
+// The delay is capped by MaxBackoff, or if unset by DefaultRetryMaxBackoff or
+// the initial backoff, whichever is longer.
+func (p *RetryPolicy) BackoffFor(attempt int32) time.Duration {
+	max := p.MaxBackoff
+	if max <= 0 {
+		max = DefaultRetryMaxBackoff
+		if p.Backoff > max {
+			max = p.Backoff
+		}
+	}
+
+	delay := p.Backoff
+	for i := int32(1); i < attempt && delay < max; i++ {
+		// check before multiplying, the delay would overflow
+		if delay > max/time.Duration(p.Factor) {
+			return max
+		}
+		delay *= time.Duration(p.Factor)
+	}
+
+	if delay > max {
+		return max
+	}
+	return delay
+}
+
+// ShouldRetryOn reports whether a failure with the given phase and message is worth a retry
+func (p *RetryPolicy) ShouldRetryOn(phase, message string) bool {
+	if len(p.RetryOn) == 0 {
+		return true
+	}
+
+	for _, on := range p.RetryOn {
+		if on == phase || (len(message) > 0 && strings.Contains(message, on)) {
+			return true
+		}
+	}
+	return false
+}
+
+// VerifyPolicy is the resolved post-remediation verification of ops workflows
+type VerifyPolicy struct {
+	Query    string
+	Wait     time.Duration
+	Timeout  time.Duration
+	Interval time.Duration
+}
+
+// NewVerifyPolicy parses the verification fields declared on a rule
+func NewVerifyPolicy(query, wait, timeout, interval string) (*VerifyPolicy, error) {
+	if len(strings.TrimSpace(query)) == 0 {
+		return nil, fmt.Errorf("empty verify query")
+	}
+
+	policy := &VerifyPolicy{
+		Query:    query,
+		Timeout:  DefaultVerifyTimeout,
+		Interval: DefaultVerifyInterval,
+	}
+
+	var err error
+	if len(wait) > 0 {
+		if policy.Wait, err = time.ParseDuration(wait); err != nil {
+			return nil, fmt.Errorf("invalid verify wait %q: %v", wait, err)
+		}
+	}
+
+	if len(timeout) > 0 {
+		if policy.Timeout, err = time.ParseDuration(timeout); err != nil {
+			return nil, fmt.Errorf("invalid verify timeout %q: %v", timeout, err)
+		}
+	}
+
+	if len(interval) > 0 {
+		if policy.Interval, err = time.ParseDuration(interval); err != nil {
+			return nil, fmt.Errorf("invalid verify interval %q: %v", interval, err)
+		}
+		if policy.Interval <= 0 {
+			return nil, fmt.Errorf("invalid verify interval %q", interval)
+		}
+	}
+
+	return policy, nil
+}
+
+// EscalationPolicy is the resolved escalation of repeatedly failing ops
+type EscalationPolicy struct {
+	MaxFailures int32
+	Window      time.Duration
+	GroupBy     string
+	Actions     []string
+	Severity    string
+}
+
+// NewEscalationPolicy parses the escalation fields declared on a rule
+func NewEscalationPolicy(maxFailures int32, window, groupBy string, actions []string, severity string) (*EscalationPolicy, error) {
+	if maxFailures < 1 {
+		return nil, fmt.Errorf("invalid escalation max failures %d", maxFailures)
+	}
+
+	policy := &EscalationPolicy{
+		MaxFailures: maxFailures,
+		Window:      DefaultEscalationWindow,
+		GroupBy:     EscalationGroupByFingerprint,
+		Actions:     actions,
+		Severity:    DefaultEscalationSeverity,
+	}
+
+	var err error
+	if len(window) > 0 {
+		if policy.Window, err = time.ParseDuration(window); err != nil {
+			return nil, fmt.Errorf("invalid escalation window %q: %v", window, err)
+		}
+		if policy.Window <= 0 {
+			return nil, fmt.Errorf("invalid escalation window %q", window)
+		}
+	}
+
+	switch groupBy {
+	case "":
+	case EscalationGroupByFingerprint, EscalationGroupByInvolvedObject:
+		policy.GroupBy = groupBy
+	default:
+		return nil, fmt.Errorf("invalid escalation group by %q", groupBy)
+	}
+
+	for _, action := range actions {
+		switch action {
+		case EscalationActionRaiseSeverity, EscalationActionDisableNode, EscalationActionNotify, EscalationActionCreateTicket:
+		default:
+			return nil, fmt.Errorf("invalid escalation action %q", action)
+		}
+	}
+
+	if len(severity) > 0 {
+		policy.Severity = severity
+	}
+
+	return policy, nil
+}
+
+// HasAction reports whether the action is taken on escalation
+func (p *EscalationPolicy) HasAction(action string) bool {
+	for _, a := range p.Actions {
+		if a == action {
+			return true
+		}
+	}
+	return false
+}
+
 type RuleEngineInterface interface {
 	GetTemplateRefs(r *MatchRule) ([]*corev1.ObjectReference, error)
 
 	GetTemplateContentByRefs(ref *corev1.ObjectReference) (string, error)
 
+	// GetOpsPolicy returns the ops policy of the single rule matching r, nil if none.
+	GetOpsPolicy(r *MatchRule) (*OpsPolicy, error)
+
 	SucceedExecuteTemplateCallback(ref *corev1.ObjectReference)
 
 	FailedExecuteTemplateCallback(ref *corev1.ObjectReference)
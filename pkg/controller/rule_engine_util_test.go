@@ -0,0 +1,126 @@
+package controller
+
+import (
+	"testing"
+	"time"
+)
+
+func TestRetryPolicyBackoffFor(t *testing.T) {
+	factor := int32(3)
+	policy, err := NewRetryPolicy(5, "10s", &factor, "2m", nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	expected := map[int32]time.Duration{
+		1: 10 * time.Second,
+		2: 30 * time.Second,
+		3: 90 * time.Second,
+		4: 2 * time.Minute,
+		5: 2 * time.Minute,
+	}
+	for attempt, delay := range expected {
+		if got := policy.BackoffFor(attempt); got != delay {
+			t.Errorf("attempt %d: expected backoff %v, got %v", attempt, delay, got)
+		}
+	}
+}
+
+func TestRetryPolicyBackoffForUnbounded(t *testing.T) {
+	factor := int32(1 << 30)
+	policy, err := NewRetryPolicy(1000, "", &factor, "", nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	// without a max duration the delay is capped instead of overflowing
+	for _, attempt := range []int32{2, 64, 1000} {
+		if got := policy.BackoffFor(attempt); got != DefaultRetryMaxBackoff {
+			t.Errorf("attempt %d: expected backoff %v, got %v", attempt, DefaultRetryMaxBackoff, got)
+		}
+	}
+
+	policy, _ = NewRetryPolicy(1000, "", nil, "", nil)
+	if got := policy.BackoffFor(1000); got != DefaultRetryMaxBackoff {
+		t.Errorf("expected backoff %v, got %v", DefaultRetryMaxBackoff, got)
+	}
+
+	// a longer initial backoff is kept
+	policy, _ = NewRetryPolicy(1000, "2h", nil, "", nil)
+	if got := policy.BackoffFor(1000); got != 2*time.Hour {
+		t.Errorf("expected backoff %v, got %v", 2*time.Hour, got)
+	}
+}
+
+func TestRetryPolicyShouldRetryOn(t *testing.T) {
+	policy, err := NewRetryPolicy(1, "", nil, "", []string{"Error", "ImagePullBackOff"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	cases := []struct {
+		phase    string
+		message  string
+		expected bool
+	}{
+		{"Error", "", true},
+		{"Failed", "Back-off pulling image: ImagePullBackOff", true},
+		{"Failed", "exit code 1", false},
+	}
+	for _, c := range cases {
+		if got := policy.ShouldRetryOn(c.phase, c.message); got != c.expected {
+			t.Errorf("phase %s message %q: expected %v, got %v", c.phase, c.message, c.expected, got)
+		}
+	}
+
+	if _, err := NewRetryPolicy(1, "soon", nil, "", nil); err == nil {
+		t.Errorf("expected error for invalid duration")
+	}
+}
+
+func TestNewVerifyPolicy(t *testing.T) {
+	policy, err := NewVerifyPolicy(`up{node="{{.node}}"} == 1`, "1m", "", "")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if policy.Wait != time.Minute || policy.Timeout != DefaultVerifyTimeout || policy.Interval != DefaultVerifyInterval {
+		t.Errorf("unexpected policy: %+v", policy)
+	}
+
+	if _, err := NewVerifyPolicy("", "", "", ""); err == nil {
+		t.Errorf("expected error for empty query")
+	}
+	if _, err := NewVerifyPolicy("up", "", "", "0s"); err == nil {
+		t.Errorf("expected error for zero interval")
+	}
+}
+
+func TestNewEscalationPolicy(t *testing.T) {
+	policy, err := NewEscalationPolicy(3, "", "", []string{EscalationActionNotify, EscalationActionDisableNode}, "")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if policy.Window != DefaultEscalationWindow || policy.GroupBy != EscalationGroupByFingerprint || policy.Severity != DefaultEscalationSeverity {
+		t.Errorf("unexpected policy: %+v", policy)
+	}
+	if !policy.HasAction(EscalationActionNotify) || policy.HasAction(EscalationActionCreateTicket) {
+		t.Errorf("unexpected actions: %v", policy.Actions)
+	}
+
+	invalid := []struct {
+		maxFailures int32
+		window      string
+		groupBy     string
+		action      string
+	}{
+		{0, "", "", EscalationActionNotify},
+		{1, "1d", "", EscalationActionNotify},
+		{1, "", "Node", EscalationActionNotify},
+		{1, "", "", "Reboot"},
+	}
+	for _, c := range invalid {
+		if _, err := NewEscalationPolicy(c.maxFailures, c.window, c.groupBy, []string{c.action}, ""); err == nil {
+			t.Errorf("expected error for %+v", c)
+		}
+	}
+}
@@ -0,0 +1,135 @@
+package explaincache
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+const (
+	DefaultPath = "/aegis/cache/explain.db"
+
+	openTimeout = time.Minute
+)
+
+var entriesBucket = []byte("entries")
+
+// entry is a persisted value with its expiration
+type entry struct {
+	Expire time.Time `json:"expire"`
+	Value  []byte    `json:"value"`
+}
+
+// BoltStore is a bbolt database of cache entries, on a persistent volume
+type BoltStore struct {
+	db         *bolt.DB
+	maxEntries int
+}
+
+// OpenBoltStore opens or creates the cache database at path, holding at most
+// maxEntries entries
+func OpenBoltStore(path string, maxEntries int) (*BoltStore, error) {
+	if len(path) == 0 {
+		path = DefaultPath
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return nil, fmt.Errorf("explaincache: create directory of %s: %v", path, err)
+	}
+
+	db, err := bolt.Open(path, 0600, &bolt.Options{Timeout: openTimeout})
+	if err != nil {
+		return nil, fmt.Errorf("explaincache: open %s: %v", path, err)
+	}
+
+	err = db.Update(func(tx *bolt.Tx) error {
+		_, err := tx.CreateBucketIfNotExists(entriesBucket)
+		return err
+	})
+	if err != nil {
+		db.Close()
+		return nil, fmt.Errorf("explaincache: init %s: %v", path, err)
+	}
+
+	return &BoltStore{db: db, maxEntries: maxEntries}, nil
+}
+
+func (s *BoltStore) Close() error {
+	return s.db.Close()
+}
+
+func (s *BoltStore) Get(_ context.Context, key string) ([]byte, bool, error) {
+	var value []byte
+	err := s.db.View(func(tx *bolt.Tx) error {
+		data := tx.Bucket(entriesBucket).Get([]byte(key))
+		if data == nil {
+			return nil
+		}
+		var e entry
+		if err := json.Unmarshal(data, &e); err != nil {
+			return err
+		}
+		if time.Now().Before(e.Expire) {
+			value = e.Value
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, false, fmt.Errorf("explaincache: get %s: %v", key, err)
+	}
+	return value, value != nil, nil
+}
+
+// Put stores the entry, evicting the expired entries and then the oldest
+// ones if the store is full
+func (s *BoltStore) Put(_ context.Context, key string, value []byte, expire time.Time) error {
+	data, err := json.Marshal(entry{Expire: expire, Value: value})
+	if err != nil {
+		return fmt.Errorf("explaincache: marshal %s: %v", key, err)
+	}
+
+	err = s.db.Update(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket(entriesBucket)
+		if bucket.Get([]byte(key)) == nil && s.maxEntries > 0 && bucket.Stats().KeyN >= s.maxEntries {
+			if err := s.evict(bucket); err != nil {
+				return err
+			}
+		}
+		return bucket.Put([]byte(key), data)
+	})
+	if err != nil {
+		return fmt.Errorf("explaincache: put %s: %v", key, err)
+	}
+	return nil
+}
+
+// evict makes room for an entry
+func (s *BoltStore) evict(bucket *bolt.Bucket) error {
+	expires := make(map[string]time.Time)
+	err := bucket.ForEach(func(k, v []byte) error {
+		var e entry
+		if err := json.Unmarshal(v, &e); err != nil {
+			// undecodable entries are the first to go
+			expires[string(k)] = time.Time{}
+			return nil
+		}
+		expires[string(k)] = e.Expire
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	for _, key := range evictionOrder(expires, len(expires)-s.maxEntries+1) {
+		if err := bucket.Delete([]byte(key)); err != nil {
+			return err
+		}
+		evictions.WithLabelValues(BackendBbolt).Inc()
+	}
+	return nil
+}
@@ -0,0 +1,236 @@
+package explaincache
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"time"
+
+	cache "github.com/patrickmn/go-cache"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/klog/v2"
+)
+
+const (
+	BackendMemory    = "memory"
+	BackendConfigMap = "configmap"
+	BackendBbolt     = "bbolt"
+
+	DefaultTTL             = 24 * time.Hour
+	DefaultMaxEntries      = 1000
+	DefaultMaxEntrySize    = 64 * 1024
+	DefaultConfigMapPrefix = "aegis-explain-cache"
+	DefaultShards          = 16
+
+	// storeTimeout bounds a call to the persistent store
+	storeTimeout = 10 * time.Second
+)
+
+var (
+	lookups = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "aegis_diagnosis",
+		Subsystem: "explain_cache",
+		Name:      "lookups_total",
+		Help:      "Lookups of the explain cache by tier, memory or the persistent backend",
+	}, []string{"tier", "result"})
+
+	evictions = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "aegis_diagnosis",
+		Subsystem: "explain_cache",
+		Name:      "evictions_total",
+		Help:      "Entries evicted from the explain cache to stay within the size limits",
+	}, []string{"tier"})
+
+	rejections = promauto.NewCounter(prometheus.CounterOpts{
+		Namespace: "aegis_diagnosis",
+		Subsystem: "explain_cache",
+		Name:      "rejected_total",
+		Help:      "Explains not cached for exceeding the max entry size",
+	})
+)
+
+// Config of the explain cache
+type Config struct {
+	// Backend persisting the entries behind the memory tier: memory (none),
+	// configmap or bbolt. Default: memory.
+	Backend string
+	// TTL of the entries. Default: 24h.
+	TTL time.Duration
+	// MaxEntries bounds the entries of each tier, the oldest are evicted.
+	// Default: 1000.
+	MaxEntries int
+	// MaxEntrySize bounds the size of an entry in bytes, larger ones are not
+	// cached. Default: 64KiB.
+	MaxEntrySize int
+	// Path of the bbolt database. Default: /aegis/cache/explain.db.
+	Path string
+	// Namespace of the ConfigMap shards
+	Namespace string
+	// ConfigMapPrefix names the ConfigMap shards <prefix>-<i>. Default:
+	// aegis-explain-cache.
+	ConfigMapPrefix string
+	// Shards is the number of ConfigMaps. Default: 16.
+	Shards int
+}
+
+func (c *Config) withDefaults() Config {
+	config := Config{}
+	if c != nil {
+		config = *c
+	}
+	if config.Backend == "" {
+		config.Backend = BackendMemory
+	}
+	if config.TTL <= 0 {
+		config.TTL = DefaultTTL
+	}
+	if config.MaxEntries <= 0 {
+		config.MaxEntries = DefaultMaxEntries
+	}
+	if config.MaxEntrySize <= 0 {
+		config.MaxEntrySize = DefaultMaxEntrySize
+	}
+	if config.Path == "" {
+		config.Path = DefaultPath
+	}
+	if config.ConfigMapPrefix == "" {
+		config.ConfigMapPrefix = DefaultConfigMapPrefix
+	}
+	if config.Shards <= 0 {
+		config.Shards = DefaultShards
+	}
+	return config
+}
+
+// Store persists the entries across restarts
+type Store interface {
+	Get(ctx context.Context, key string) ([]byte, bool, error)
+	Put(ctx context.Context, key string, value []byte, expire time.Time) error
+}
+
+// Cache is a content-addressed cache of explains, an in-process memory tier
+// in front of an optional persistent store. The cache is best effort, the
+// errors of the store are logged and taken as misses.
+type Cache struct {
+	config Config
+	memory *cache.Cache
+	store  Store
+}
+
+// New returns the cache of the config backend, the client is only used by
+// the configmap backend
+func New(c *Config, client kubernetes.Interface) (*Cache, error) {
+	config := c.withDefaults()
+
+	var store Store
+	switch config.Backend {
+	case BackendMemory:
+	case BackendBbolt:
+		s, err := OpenBoltStore(config.Path, config.MaxEntries)
+		if err != nil {
+			return nil, err
+		}
+		store = s
+	case BackendConfigMap:
+		if client == nil || config.Namespace == "" {
+			return nil, fmt.Errorf("explaincache: configmap backend needs a client and a namespace")
+		}
+		store = NewConfigMapStore(client, config.Namespace, config.ConfigMapPrefix, config.Shards, config.MaxEntries)
+	default:
+		return nil, fmt.Errorf("explaincache: unknown backend %q", config.Backend)
+	}
+
+	return NewWithStore(config, store), nil
+}
+
+// NewWithStore returns the cache in front of the store, nil for memory only
+func NewWithStore(config Config, store Store) *Cache {
+	config = config.withDefaults()
+	return &Cache{
+		config: config,
+		memory: cache.New(config.TTL, 2*config.TTL),
+		store:  store,
+	}
+}
+
+// Key hashes the parts into a cache key
+func Key(parts ...string) string {
+	h := sha256.New()
+	for _, part := range parts {
+		h.Write([]byte(part))
+		h.Write([]byte{0})
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// Get returns the cached value of the key
+func (c *Cache) Get(ctx context.Context, key string) ([]byte, bool) {
+	if value, found := c.memory.Get(key); found {
+		lookups.WithLabelValues(BackendMemory, "hit").Inc()
+		return value.([]byte), true
+	}
+	lookups.WithLabelValues(BackendMemory, "miss").Inc()
+
+	if c.store == nil {
+		return nil, false
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, storeTimeout)
+	defer cancel()
+	value, found, err := c.store.Get(ctx, key)
+	if err != nil {
+		klog.Warningf("Failed to get explain %s from the %s cache: %v", key, c.config.Backend, err)
+	}
+	if err != nil || !found {
+		lookups.WithLabelValues(c.config.Backend, "miss").Inc()
+		return nil, false
+	}
+	lookups.WithLabelValues(c.config.Backend, "hit").Inc()
+
+	// the entry expires in memory no later than in the store
+	c.setMemory(key, value)
+	return value, true
+}
+
+// Set caches the value of the key in both tiers, unless it exceeds the max
+// entry size
+func (c *Cache) Set(ctx context.Context, key string, value []byte) {
+	if len(value) > c.config.MaxEntrySize {
+		klog.V(4).Infof("Explain %s of %d bytes exceeds the max entry size, not cached", key, len(value))
+		rejections.Inc()
+		return
+	}
+	c.setMemory(key, value)
+
+	if c.store == nil {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, storeTimeout)
+	defer cancel()
+	if err := c.store.Put(ctx, key, value, time.Now().Add(c.config.TTL)); err != nil {
+		klog.Warningf("Failed to put explain %s to the %s cache: %v", key, c.config.Backend, err)
+	}
+}
+
+// setMemory sets the entry in memory, evicting the oldest entry if full.
+// All the entries share the TTL, the first to expire is the oldest.
+func (c *Cache) setMemory(key string, value []byte) {
+	if _, found := c.memory.Get(key); !found && c.memory.ItemCount() >= c.config.MaxEntries {
+		c.memory.DeleteExpired()
+		if items := c.memory.Items(); len(items) >= c.config.MaxEntries {
+			oldest, expiration := "", int64(0)
+			for k, item := range items {
+				if oldest == "" || item.Expiration < expiration {
+					oldest, expiration = k, item.Expiration
+				}
+			}
+			c.memory.Delete(oldest)
+			evictions.WithLabelValues(BackendMemory).Inc()
+		}
+	}
+	c.memory.SetDefault(key, value)
+}
@@ -0,0 +1,117 @@
+package explaincache
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"testing"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func TestCacheLimits(t *testing.T) {
+	ctx := context.Background()
+	c := NewWithStore(Config{MaxEntries: 2, MaxEntrySize: 8}, nil)
+
+	c.Set(ctx, "a", []byte("1"))
+	time.Sleep(time.Millisecond)
+	c.Set(ctx, "b", []byte("2"))
+	c.Set(ctx, "c", []byte("3"))
+	if _, found := c.Get(ctx, "a"); found {
+		t.Errorf("expected the oldest entry evicted")
+	}
+	for _, key := range []string{"b", "c"} {
+		if value, found := c.Get(ctx, key); !found || len(value) != 1 {
+			t.Errorf("expected %s cached, got %q", key, value)
+		}
+	}
+
+	c.Set(ctx, "d", []byte("too large value"))
+	if _, found := c.Get(ctx, "d"); found {
+		t.Errorf("expected the large entry not cached")
+	}
+}
+
+func TestCacheBoltStore(t *testing.T) {
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "explain.db")
+	store, err := OpenBoltStore(path, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	c := NewWithStore(Config{Backend: BackendBbolt}, store)
+	key := Key("openai", "gpt-4o", "prompt")
+	c.Set(ctx, key, []byte("explain"))
+	store.Close()
+
+	// survives a restart
+	store, err = OpenBoltStore(path, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer store.Close()
+	c = NewWithStore(Config{Backend: BackendBbolt}, store)
+	if value, found := c.Get(ctx, key); !found || string(value) != "explain" {
+		t.Fatalf("expected the entry persisted, got %q", value)
+	}
+
+	now := time.Now()
+	for i, expire := range []time.Time{now.Add(-time.Minute), now.Add(48 * time.Hour), now.Add(time.Hour)} {
+		if err := store.Put(ctx, fmt.Sprintf("k%d", i), []byte("v"), expire); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, found, _ := store.Get(ctx, "k0"); found {
+		t.Errorf("expected the expired entry missed")
+	}
+	for key, expected := range map[string]bool{key: false, "k1": true, "k2": true} {
+		if _, found, err := store.Get(ctx, key); err != nil || found != expected {
+			t.Errorf("expected %s found %t, got %t %v", key, expected, found, err)
+		}
+	}
+}
+
+func TestCacheConfigMapStore(t *testing.T) {
+	ctx := context.Background()
+	client := fake.NewSimpleClientset()
+	store := NewConfigMapStore(client, "aegis", DefaultConfigMapPrefix, 2, 4)
+
+	keys := make([]string, 0)
+	for i := 0; i < 8; i++ {
+		key := Key(fmt.Sprintf("prompt-%d", i))
+		keys = append(keys, key)
+		if err := store.Put(ctx, key, []byte(key[:4]), time.Now().Add(time.Duration(i+1)*time.Hour)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	cms, err := client.CoreV1().ConfigMaps("aegis").List(ctx, metav1.ListOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(cms.Items) > 2 {
+		t.Errorf("expected at most 2 shards, got %d", len(cms.Items))
+	}
+	for _, cm := range cms.Items {
+		if len(cm.Data) > 2 {
+			t.Errorf("expected at most 2 entries in %s, got %d", cm.Name, len(cm.Data))
+		}
+	}
+
+	// the latest entry of each shard is kept
+	value, found, err := store.Get(ctx, keys[7])
+	if err != nil || !found || string(value) != keys[7][:4] {
+		t.Errorf("expected the latest entry found, got %q %t %v", value, found, err)
+	}
+
+	c, err := New(&Config{Backend: BackendConfigMap, Namespace: "aegis", Shards: 2, MaxEntries: 4}, client)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, found := c.Get(ctx, keys[7]); !found {
+		t.Errorf("expected the entry found through the cache")
+	}
+}
@@ -0,0 +1,172 @@
+package explaincache
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/util/retry"
+)
+
+// maxShardSize keeps a shard below the 1MiB object limit of etcd
+const maxShardSize = 900 * 1024
+
+// ConfigMapStore shards the cache entries into ConfigMaps by key, so that
+// the entries survive a restart without a persistent volume
+type ConfigMapStore struct {
+	client     kubernetes.Interface
+	namespace  string
+	prefix     string
+	shards     int
+	maxEntries int
+}
+
+// NewConfigMapStore returns the store of the shards <prefix>-<i> in the
+// namespace, holding at most maxEntries entries altogether
+func NewConfigMapStore(client kubernetes.Interface, namespace, prefix string, shards, maxEntries int) *ConfigMapStore {
+	if shards <= 0 {
+		shards = DefaultShards
+	}
+	if maxEntries <= 0 {
+		maxEntries = DefaultMaxEntries
+	}
+	return &ConfigMapStore{
+		client:     client,
+		namespace:  namespace,
+		prefix:     prefix,
+		shards:     shards,
+		maxEntries: maxEntries,
+	}
+}
+
+// shard names the ConfigMap of the key, the keys are hex hashes
+func (s *ConfigMapStore) shard(key string) string {
+	var index uint64
+	if len(key) >= 8 {
+		index, _ = strconv.ParseUint(key[:8], 16, 64)
+	}
+	return fmt.Sprintf("%s-%d", s.prefix, index%uint64(s.shards))
+}
+
+func (s *ConfigMapStore) Get(ctx context.Context, key string) ([]byte, bool, error) {
+	cm, err := s.client.CoreV1().ConfigMaps(s.namespace).Get(ctx, s.shard(key), metav1.GetOptions{})
+	if apierrors.IsNotFound(err) {
+		return nil, false, nil
+	} else if err != nil {
+		return nil, false, fmt.Errorf("explaincache: get %s: %v", key, err)
+	}
+
+	data, ok := cm.Data[key]
+	if !ok {
+		return nil, false, nil
+	}
+	var e entry
+	if err := json.Unmarshal([]byte(data), &e); err != nil {
+		return nil, false, fmt.Errorf("explaincache: decode %s: %v", key, err)
+	}
+	if !time.Now().Before(e.Expire) {
+		return nil, false, nil
+	}
+	return e.Value, true, nil
+}
+
+// Put stores the entry in its shard, evicting the expired entries and then
+// the oldest ones if the shard is full
+func (s *ConfigMapStore) Put(ctx context.Context, key string, value []byte, expire time.Time) error {
+	data, err := json.Marshal(entry{Expire: expire, Value: value})
+	if err != nil {
+		return fmt.Errorf("explaincache: marshal %s: %v", key, err)
+	}
+	if len(data) > maxShardSize {
+		return fmt.Errorf("explaincache: entry %s of %d bytes exceeds the shard size", key, len(data))
+	}
+
+	maxEntries := (s.maxEntries + s.shards - 1) / s.shards
+	name := s.shard(key)
+	err = retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		cm, err := s.client.CoreV1().ConfigMaps(s.namespace).Get(ctx, name, metav1.GetOptions{})
+		if apierrors.IsNotFound(err) {
+			cm = &corev1.ConfigMap{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      name,
+					Namespace: s.namespace,
+					Labels:    map[string]string{"app.kubernetes.io/component": "explain-cache"},
+				},
+				Data: map[string]string{key: string(data)},
+			}
+			_, err = s.client.CoreV1().ConfigMaps(s.namespace).Create(ctx, cm, metav1.CreateOptions{})
+			if apierrors.IsAlreadyExists(err) {
+				return apierrors.NewConflict(corev1.Resource("configmaps"), name, err)
+			}
+			return err
+		} else if err != nil {
+			return err
+		}
+
+		if cm.Data == nil {
+			cm.Data = make(map[string]string)
+		}
+		delete(cm.Data, key)
+		evictShard(cm.Data, maxEntries-1, maxShardSize-len(key)-len(data))
+		cm.Data[key] = string(data)
+
+		_, err = s.client.CoreV1().ConfigMaps(s.namespace).Update(ctx, cm, metav1.UpdateOptions{})
+		return err
+	})
+	if err != nil {
+		return fmt.Errorf("explaincache: put %s: %v", key, err)
+	}
+	return nil
+}
+
+// evictShard drops the expired entries, and then the oldest ones until the
+// shard holds at most maxEntries entries of maxSize bytes
+func evictShard(data map[string]string, maxEntries, maxSize int) {
+	now := time.Now()
+	expires := make(map[string]time.Time, len(data))
+	size := 0
+	for k, v := range data {
+		var e entry
+		if err := json.Unmarshal([]byte(v), &e); err != nil || !now.Before(e.Expire) {
+			delete(data, k)
+			evictions.WithLabelValues(BackendConfigMap).Inc()
+			continue
+		}
+		expires[k] = e.Expire
+		size += len(k) + len(v)
+	}
+
+	for _, k := range evictionOrder(expires, len(expires)) {
+		if len(data) <= maxEntries && size <= maxSize {
+			break
+		}
+		size -= len(k) + len(data[k])
+		delete(data, k)
+		evictions.WithLabelValues(BackendConfigMap).Inc()
+	}
+}
+
+// evictionOrder returns the first n keys to expire
+func evictionOrder(expires map[string]time.Time, n int) []string {
+	keys := make([]string, 0, len(expires))
+	for k := range expires {
+		keys = append(keys, k)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		return expires[keys[i]].Before(expires[keys[j]])
+	})
+	if n < 0 {
+		n = 0
+	}
+	if n > len(keys) {
+		n = len(keys)
+	}
+	return keys[:n]
+}
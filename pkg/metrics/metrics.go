@@ -5,6 +5,7 @@ import (
 
 	"github.com/prometheus/client_golang/prometheus"
 	"github.com/prometheus/client_golang/prometheus/promauto"
+	corev1 "k8s.io/api/core/v1"
 	"k8s.io/klog/v2"
 
 	alertv1alpha1 "github.com/scitix/aegis/pkg/apis/alert/v1alpha1"
@@ -68,6 +69,13 @@ var (
 		Help:      "Ops succeed of aegis alert",
 	}, []string{"name", "type", "sub_type", "namespace"})
 
+	alertOpsStatusResolved = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: "aegis_alert",
+		Subsystem: "ops",
+		Name:      "status_resolved",
+		Help:      "Ops verified resolved (1) or not resolved (0) of aegis alert",
+	}, []string{"name", "type", "sub_type", "namespace"})
+
 	alertOpsExecuteSeconds = promauto.NewGaugeVec(prometheus.GaugeOpts{
 		Namespace: "aegis_alert",
 		Subsystem: "ops",
@@ -213,6 +221,13 @@ func (m *MetricsController) OnDelete(alert *alertv1alpha1.AegisAlert) error {
 		"namespace": alert.Namespace,
 	})
 
+	alertOpsStatusResolved.DeletePartialMatch(prometheus.Labels{
+		"name":      alert.Name,
+		"type":      alert.Spec.Type,
+		"sub_type":  subType,
+		"namespace": alert.Namespace,
+	})
+
 	alertOpsExecuteSeconds.DeletePartialMatch(prometheus.Labels{
 		"name":      alert.Name,
 		"type":      alert.Spec.Type,
@@ -220,6 +235,9 @@ func (m *MetricsController) OnDelete(alert *alertv1alpha1.AegisAlert) error {
 		"namespace": alert.Namespace,
 	})
 
+	clearOpsSLOOverrun(alert)
+	observedAlerts.Delete(alert.UID)
+
 	return nil
 }
 
@@ -264,6 +282,7 @@ func (m *MetricsController) OnFailedCreateOpsWorkflow(alert *alertv1alpha1.Aegis
 
 func (m *MetricsController) OnSucceedCreateOpsWorkflow(alert *alertv1alpha1.AegisAlert) error {
 	subType := getSubType(alert)
+	observeWorkflowCreated(alert)
 	alertOpsStatusRunning.With(prometheus.Labels{
 		"name":      alert.Name,
 		"type":      alert.Spec.Type,
@@ -286,8 +305,27 @@ func opsSpendTime(alert *alertv1alpha1.AegisAlert) (int, error) {
 	return int(alert.Status.OpsStatus.CompletionTime.Time.Sub(alert.Status.OpsStatus.StartTime.Time).Seconds()), nil
 }
 
+// opsResolved returns the post-remediation verification result, verified is false if not verified
+func opsResolved(alert *alertv1alpha1.AegisAlert) (resolved bool, verified bool) {
+	for _, c := range alert.Status.Conditions {
+		if c.Status != corev1.ConditionTrue {
+			continue
+		}
+		switch c.Type {
+		case alertv1alpha1.AlertVerified:
+			return true, true
+		case alertv1alpha1.AlertVerificationFailed:
+			return false, true
+		}
+	}
+	return false, false
+}
+
 func (m *MetricsController) OnOpsWorkflowSucceed(alert *alertv1alpha1.AegisAlert) error {
 	subType := getSubType(alert)
+	clearOpsSLOOverrun(alert)
+	observeOpsFinished(alert, "Succeed")
+
 	alertOpsStatusRunning.With(prometheus.Labels{
 		"name":      alert.Name,
 		"type":      alert.Spec.Type,
@@ -302,6 +340,20 @@ func (m *MetricsController) OnOpsWorkflowSucceed(alert *alertv1alpha1.AegisAlert
 		"namespace": alert.Namespace,
 	}).Set(float64(1))
 
+	// workflow succeeded doesn't mean the problem is resolved
+	if resolved, verified := opsResolved(alert); verified {
+		value := float64(0)
+		if resolved {
+			value = 1
+		}
+		alertOpsStatusResolved.With(prometheus.Labels{
+			"name":      alert.Name,
+			"type":      alert.Spec.Type,
+			"sub_type":  subType,
+			"namespace": alert.Namespace,
+		}).Set(value)
+	}
+
 	diff, err := opsSpendTime(alert)
 	if err != nil {
 		return err
@@ -320,6 +372,9 @@ func (m *MetricsController) OnOpsWorkflowSucceed(alert *alertv1alpha1.AegisAlert
 
 func (m *MetricsController) OnOpsWorkflowFailed(alert *alertv1alpha1.AegisAlert) error {
 	subType := getSubType(alert)
+	clearOpsSLOOverrun(alert)
+	observeOpsFinished(alert, "Failed")
+
 	alertOpsStatusRunning.With(prometheus.Labels{
 		"name":      alert.Name,
 		"type":      alert.Spec.Type,
@@ -327,7 +382,22 @@ func (m *MetricsController) OnOpsWorkflowFailed(alert *alertv1alpha1.AegisAlert)
 		"namespace": alert.Namespace,
 	}).Set(float64(0))
 
-	alertOpsStatusFailed.With(prometheus.Labels{
+	// a failed verification follows a succeeded workflow, the workflow status
+	// is kept apart from the problem not being resolved
+	workflowStatus := "Failed"
+	workflowGauge := alertOpsStatusFailed
+	if resolved, verified := opsResolved(alert); verified && !resolved {
+		workflowStatus = "Succeed"
+		workflowGauge = alertOpsStatuSucceed
+		alertOpsStatusResolved.With(prometheus.Labels{
+			"name":      alert.Name,
+			"type":      alert.Spec.Type,
+			"sub_type":  subType,
+			"namespace": alert.Namespace,
+		}).Set(float64(0))
+	}
+
+	workflowGauge.With(prometheus.Labels{
 		"name":      alert.Name,
 		"type":      alert.Spec.Type,
 		"sub_type":  subType,
@@ -344,7 +414,7 @@ func (m *MetricsController) OnOpsWorkflowFailed(alert *alertv1alpha1.AegisAlert)
 		"type":      alert.Spec.Type,
 		"sub_type":  subType,
 		"namespace": alert.Namespace,
-		"status":    "Failed",
+		"status":    workflowStatus,
 	}).Set(float64(diff))
 
 	return nil
@@ -0,0 +1,50 @@
+package metrics
+
+import (
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	corev1 "k8s.io/api/core/v1"
+
+	alertv1alpha1 "github.com/scitix/aegis/pkg/apis/alert/v1alpha1"
+)
+
+func TestOpsWorkflowFailedVerification(t *testing.T) {
+	m := NewMetricsController()
+	// keep the ops totals of TestObserveOpsFinished apart
+	newAlert := func(name string) *alertv1alpha1.AegisAlert {
+		alert := newFinishedAlert(name, false)
+		alert.Status.OpsStatus.Rule = ""
+		alert.Status.OpsStatus.Template = ""
+		return alert
+	}
+
+	// the workflow succeeded while the problem persists
+	unresolved := newAlert("unresolved")
+	unresolved.Status.Conditions = []alertv1alpha1.AlertOpsCondition{{
+		Type:   alertv1alpha1.AlertVerificationFailed,
+		Status: corev1.ConditionTrue,
+	}}
+	m.OnOpsWorkflowFailed(unresolved)
+
+	labels := []string{"unresolved", "SLOTest", "SLOTest", "monitoring"}
+	if got := testutil.ToFloat64(alertOpsStatuSucceed.WithLabelValues(labels...)); got != 1 {
+		t.Errorf("expected the workflow succeeded, got %v", got)
+	}
+	if got := testutil.ToFloat64(alertOpsStatusResolved.WithLabelValues(labels...)); got != 0 {
+		t.Errorf("expected the problem not resolved, got %v", got)
+	}
+	if alertOpsStatusFailed.DeleteLabelValues(labels...) {
+		t.Error("expected no failed workflow of a failed verification")
+	}
+
+	// a failed workflow is never verified
+	m.OnOpsWorkflowFailed(newAlert("crashed"))
+	labels = []string{"crashed", "SLOTest", "SLOTest", "monitoring"}
+	if got := testutil.ToFloat64(alertOpsStatusFailed.WithLabelValues(labels...)); got != 1 {
+		t.Errorf("expected the workflow failed, got %v", got)
+	}
+	if alertOpsStatusResolved.DeleteLabelValues(labels...) || alertOpsStatuSucceed.DeleteLabelValues(labels...) {
+		t.Error("expected no resolved or succeeded status of a failed workflow")
+	}
+}
@@ -0,0 +1,50 @@
+package metrics
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+
+	alertv1alpha1 "github.com/scitix/aegis/pkg/apis/alert/v1alpha1"
+)
+
+var (
+	alertOpsSLOOverrun = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: "aegis_alert",
+		Subsystem: "ops",
+		Name:      "slo_overrun",
+		Help:      "Ops of aegis alert running longer than the SLO",
+	}, []string{"name", "type", "sub_type", "namespace"})
+
+	alertOpsDeadlineExceeded = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "aegis_alert",
+		Subsystem: "ops",
+		Name:      "deadline_exceeded_total",
+		Help:      "Count of aegis alert ops terminated after exceeding their active deadline",
+	}, []string{"type", "sub_type", "namespace"})
+)
+
+func alertOpsLabels(alert *alertv1alpha1.AegisAlert) prometheus.Labels {
+	return prometheus.Labels{
+		"name":      alert.Name,
+		"type":      alert.Spec.Type,
+		"sub_type":  getSubType(alert),
+		"namespace": alert.Namespace,
+	}
+}
+
+func (m *MetricsController) RecordOpsSLOOverrun(alert *alertv1alpha1.AegisAlert) {
+	alertOpsSLOOverrun.With(alertOpsLabels(alert)).Set(float64(1))
+}
+
+func (m *MetricsController) RecordOpsDeadlineExceeded(alert *alertv1alpha1.AegisAlert) {
+	alertOpsDeadlineExceeded.With(prometheus.Labels{
+		"type":      alert.Spec.Type,
+		"sub_type":  getSubType(alert),
+		"namespace": alert.Namespace,
+	}).Inc()
+}
+
+// clearOpsSLOOverrun drops the overrun of finished or deleted alerts
+func clearOpsSLOOverrun(alert *alertv1alpha1.AegisAlert) {
+	alertOpsSLOOverrun.Delete(alertOpsLabels(alert))
+}
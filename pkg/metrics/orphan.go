@@ -0,0 +1,32 @@
+package metrics
+
+import (
+	"strconv"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+var (
+	orphanWorkflows = promauto.NewGauge(prometheus.GaugeOpts{
+		Namespace: "aegis_alert",
+		Subsystem: "orphan",
+		Name:      "workflows",
+		Help:      "Number of ops workflows whose alert is gone, found by the last scan",
+	})
+
+	orphanWorkflowActions = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "aegis_alert",
+		Subsystem: "orphan",
+		Name:      "workflow_actions_total",
+		Help:      "Actions taken on orphan ops workflows",
+	}, []string{"action", "dry_run"})
+)
+
+func (m *MetricsController) RecordOrphanWorkflows(count int) {
+	orphanWorkflows.Set(float64(count))
+}
+
+func (m *MetricsController) RecordOrphanWorkflowAction(action string, dryRun bool) {
+	orphanWorkflowActions.WithLabelValues(action, strconv.FormatBool(dryRun)).Inc()
+}
@@ -0,0 +1,112 @@
+package metrics
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/prometheus/common/model"
+)
+
+var (
+	DefaultRuleWindows   = []string{"1h", "1d", "7d"}
+	DefaultRuleQuantiles = []float64{0.5, 0.9, 0.99}
+)
+
+// RuleGroups is a prometheus rules file
+type RuleGroups struct {
+	Groups []RuleGroup `yaml:"groups" json:"groups"`
+}
+
+type RuleGroup struct {
+	Name     string          `yaml:"name" json:"name"`
+	Interval string          `yaml:"interval,omitempty" json:"interval,omitempty"`
+	Rules    []RecordingRule `yaml:"rules" json:"rules"`
+}
+
+type RecordingRule struct {
+	Record string `yaml:"record" json:"record"`
+	Expr   string `yaml:"expr" json:"expr"`
+}
+
+// remediationHistograms are the remediation duration histograms and the labels
+// they are aggregated by
+var remediationHistograms = []struct {
+	name string
+	by   string
+}{
+	{"aegis_alert_ops_time_to_workflow_seconds", "type"},
+	{"aegis_alert_ops_workflow_duration_seconds", "type, status"},
+	{"aegis_alert_time_to_resolve_seconds", "type"},
+}
+
+// NewRecordingRules generates the recording rules of the remediation SLOs: the
+// quantiles and mean of the remediation durations, and the ops success ratios
+// per rule and template, over each window.
+func NewRecordingRules(windows []string, quantiles []float64) (*RuleGroups, error) {
+	if len(windows) == 0 {
+		windows = DefaultRuleWindows
+	}
+	if len(quantiles) == 0 {
+		quantiles = DefaultRuleQuantiles
+	}
+
+	for _, window := range windows {
+		if _, err := model.ParseDuration(window); err != nil {
+			return nil, fmt.Errorf("invalid window %q: %v", window, err)
+		}
+	}
+	for _, q := range quantiles {
+		if q <= 0 || q >= 1 {
+			return nil, fmt.Errorf("invalid quantile %v, must be in (0, 1)", q)
+		}
+	}
+
+	groups := &RuleGroups{}
+	for _, window := range windows {
+		group := RuleGroup{Name: "aegis-remediation-slo-" + window}
+
+		for _, h := range remediationHistograms {
+			level := strings.ReplaceAll(strings.ReplaceAll(h.by, ", ", "_"), " ", "")
+			for _, q := range quantiles {
+				group.Rules = append(group.Rules, RecordingRule{
+					Record: fmt.Sprintf("%s:%s:p%s_%s", level, h.name, quantileName(q), window),
+					Expr:   fmt.Sprintf("histogram_quantile(%v, sum by (%s, le) (rate(%s_bucket[%s])))", q, h.by, h.name, window),
+				})
+			}
+			group.Rules = append(group.Rules, RecordingRule{
+				Record: fmt.Sprintf("%s:%s:mean_%s", level, h.name, window),
+				Expr:   fmt.Sprintf("sum by (%s) (rate(%s_sum[%s])) / sum by (%s) (rate(%s_count[%s]))", h.by, h.name, window, h.by, h.name, window),
+			})
+		}
+
+		for _, by := range []string{"rule", "template"} {
+			counter := fmt.Sprintf("aegis_alert_ops_%s_total", by)
+			group.Rules = append(group.Rules, RecordingRule{
+				Record: fmt.Sprintf("%s:aegis_alert_ops_success:ratio_%s", by, window),
+				Expr: fmt.Sprintf(`sum by (%s) (increase(%s{status="Succeed"}[%s])) / sum by (%s) (increase(%s[%s]))`,
+					by, counter, window, by, counter, window),
+			})
+		}
+
+		groups.Groups = append(groups.Groups, group)
+	}
+
+	groups.Groups = append(groups.Groups, RuleGroup{
+		Name: "aegis-remediation-unmatched",
+		Rules: []RecordingRule{{
+			Record: "aegis_alert_unmatched:sum",
+			Expr:   "sum(aegis_alert_unmatched)",
+		}},
+	})
+	return groups, nil
+}
+
+// quantileName formats 0.5 as 50, 0.99 as 99 and 0.995 as 995
+func quantileName(q float64) string {
+	name := strings.TrimPrefix(strconv.FormatFloat(q, 'f', -1, 64), "0.")
+	if len(name) < 2 {
+		name += "0"
+	}
+	return name
+}
@@ -0,0 +1,168 @@
+package metrics
+
+import (
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/klog/v2"
+
+	alertv1alpha1 "github.com/scitix/aegis/pkg/apis/alert/v1alpha1"
+	alertlisters "github.com/scitix/aegis/pkg/generated/alert/listers/alert/v1alpha1"
+)
+
+// remediationBuckets range from a second to a day and a half
+var remediationBuckets = prometheus.ExponentialBuckets(1, 2, 18)
+
+var (
+	alertTimeToWorkflow = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: "aegis_alert",
+		Subsystem: "ops",
+		Name:      "time_to_workflow_seconds",
+		Help:      "Seconds from aegis alert received to its ops workflow created",
+		Buckets:   remediationBuckets,
+	}, []string{"type", "sub_type"})
+
+	alertWorkflowDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: "aegis_alert",
+		Subsystem: "ops",
+		Name:      "workflow_duration_seconds",
+		Help:      "Seconds from aegis alert ops workflow created to completed",
+		Buckets:   remediationBuckets,
+	}, []string{"type", "sub_type", "status"})
+
+	alertTimeToResolve = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: "aegis_alert",
+		Subsystem: "",
+		Name:      "time_to_resolve_seconds",
+		Help:      "Seconds from aegis alert received to resolved, verified or not, by its ops",
+		Buckets:   remediationBuckets,
+	}, []string{"type", "sub_type", "verified"})
+
+	alertOpsRuleTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "aegis_alert",
+		Subsystem: "ops",
+		Name:      "rule_total",
+		Help:      "Count of finished aegis alert ops per rule",
+	}, []string{"rule", "status"})
+
+	alertOpsTemplateTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "aegis_alert",
+		Subsystem: "ops",
+		Name:      "template_total",
+		Help:      "Count of finished aegis alert ops per template",
+	}, []string{"template", "status"})
+
+	alertUnmatchedDesc = prometheus.NewDesc(
+		"aegis_alert_unmatched",
+		"Number of aegis alerts currently without a matching ops rule",
+		[]string{"type"}, nil,
+	)
+)
+
+var (
+	// startTime of the process, alerts finished before were observed by the previous one
+	startTime = time.Now()
+
+	// observedAlerts are the uids of the finished alerts already observed, the
+	// finish callbacks fire on every sync of a finished alert
+	observedAlerts sync.Map
+)
+
+// observeOpsFinished records the remediation duration and the rule and
+// template outcome of a finished alert, once.
+func observeOpsFinished(alert *alertv1alpha1.AegisAlert, status string) {
+	finishTime := opsFinishTime(alert)
+	if finishTime.IsZero() || finishTime.Before(startTime) {
+		return
+	}
+	if _, loaded := observedAlerts.LoadOrStore(alert.UID, struct{}{}); loaded {
+		return
+	}
+
+	subType := getSubType(alert)
+	if alert.Status.OpsStatus.StartTime != nil && alert.Status.OpsStatus.CompletionTime != nil {
+		alertWorkflowDuration.WithLabelValues(alert.Spec.Type, subType, status).
+			Observe(alert.Status.OpsStatus.CompletionTime.Sub(alert.Status.OpsStatus.StartTime.Time).Seconds())
+	}
+
+	if resolved, verified := opsResolved(alert); status == "Succeed" && (resolved || !verified) {
+		alertTimeToResolve.With(prometheus.Labels{
+			"type":     alert.Spec.Type,
+			"sub_type": subType,
+			"verified": boolLabel(verified),
+		}).Observe(finishTime.Sub(alert.CreationTimestamp.Time).Seconds())
+	}
+
+	if len(alert.Status.OpsStatus.Rule) > 0 {
+		alertOpsRuleTotal.WithLabelValues(alert.Status.OpsStatus.Rule, status).Inc()
+	}
+	if len(alert.Status.OpsStatus.Template) > 0 {
+		alertOpsTemplateTotal.WithLabelValues(alert.Status.OpsStatus.Template, status).Inc()
+	}
+}
+
+// opsFinishTime is when the problem was verified, or the ops completed
+func opsFinishTime(alert *alertv1alpha1.AegisAlert) time.Time {
+	for _, c := range alert.Status.Conditions {
+		if c.Status == corev1.ConditionTrue && (c.Type == alertv1alpha1.AlertVerified || c.Type == alertv1alpha1.AlertVerificationFailed) {
+			return c.LastTransitionTime.Time
+		}
+	}
+	if alert.Status.OpsStatus.CompletionTime != nil {
+		return alert.Status.OpsStatus.CompletionTime.Time
+	}
+	return time.Time{}
+}
+
+func boolLabel(b bool) string {
+	if b {
+		return "true"
+	}
+	return "false"
+}
+
+// observeWorkflowCreated records the time the first ops workflow of the alert took to be created
+func observeWorkflowCreated(alert *alertv1alpha1.AegisAlert) {
+	if alert.Status.OpsStatus.Retries > 0 {
+		return
+	}
+	alertTimeToWorkflow.WithLabelValues(alert.Spec.Type, getSubType(alert)).
+		Observe(time.Since(alert.CreationTimestamp.Time).Seconds())
+}
+
+// unmatchedAlertsCollector counts the alerts of the lister without a matching ops rule
+type unmatchedAlertsCollector struct {
+	lister alertlisters.AegisAlertLister
+}
+
+func (c *unmatchedAlertsCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- alertUnmatchedDesc
+}
+
+func (c *unmatchedAlertsCollector) Collect(ch chan<- prometheus.Metric) {
+	alerts, err := c.lister.List(labels.Everything())
+	if err != nil {
+		klog.Errorf("fail to list alerts for unmatched metrics: %v", err)
+		return
+	}
+
+	counts := make(map[string]int)
+	for _, alert := range alerts {
+		if alert.Status.OpsStatus.TriggerStatus == alertv1alpha1.OpsTriggerStatusRuleNotFound {
+			counts[alert.Spec.Type]++
+		}
+	}
+
+	for alertType, count := range counts {
+		ch <- prometheus.MustNewConstMetric(alertUnmatchedDesc, prometheus.GaugeValue, float64(count), alertType)
+	}
+}
+
+// RegisterUnmatchedAlerts reports the alerts of the lister currently without a matching ops rule
+func (m *MetricsController) RegisterUnmatchedAlerts(lister alertlisters.AegisAlertLister) error {
+	return prometheus.Register(&unmatchedAlertsCollector{lister: lister})
+}
@@ -0,0 +1,141 @@
+package metrics
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/cache"
+
+	alertv1alpha1 "github.com/scitix/aegis/pkg/apis/alert/v1alpha1"
+	alertlisters "github.com/scitix/aegis/pkg/generated/alert/listers/alert/v1alpha1"
+)
+
+func newFinishedAlert(name string, verified bool) *alertv1alpha1.AegisAlert {
+	now := time.Now()
+	alert := &alertv1alpha1.AegisAlert{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:              name,
+			Namespace:         "monitoring",
+			UID:               types.UID("uid-" + name),
+			CreationTimestamp: metav1.NewTime(now.Add(-8 * time.Minute)),
+		},
+		Spec: alertv1alpha1.AegisAlertSpec{Type: "SLOTest"},
+	}
+	alert.Status.OpsStatus.StartTime = &metav1.Time{Time: now.Add(-6 * time.Minute)}
+	alert.Status.OpsStatus.CompletionTime = &metav1.Time{Time: now}
+	alert.Status.OpsStatus.Rule = "monitoring/rule"
+	alert.Status.OpsStatus.Template = "monitoring/template"
+	if verified {
+		alert.Status.Conditions = []alertv1alpha1.AlertOpsCondition{{
+			Type:               alertv1alpha1.AlertVerified,
+			Status:             corev1.ConditionTrue,
+			LastTransitionTime: metav1.NewTime(now),
+		}}
+	}
+	return alert
+}
+
+func TestObserveOpsFinished(t *testing.T) {
+	m := NewMetricsController()
+
+	succeeded := newFinishedAlert("succeeded", true)
+	// the finish callbacks fire on every sync
+	for i := 0; i < 3; i++ {
+		m.OnOpsWorkflowSucceed(succeeded)
+	}
+	m.OnOpsWorkflowFailed(newFinishedAlert("failed", false))
+
+	if got := testutil.ToFloat64(alertOpsRuleTotal.WithLabelValues("monitoring/rule", "Succeed")); got != 1 {
+		t.Errorf("expected 1 succeeded rule ops, got %v", got)
+	}
+	if got := testutil.ToFloat64(alertOpsTemplateTotal.WithLabelValues("monitoring/template", "Failed")); got != 1 {
+		t.Errorf("expected 1 failed template ops, got %v", got)
+	}
+	if got := testutil.CollectAndCount(alertWorkflowDuration); got != 2 {
+		t.Errorf("expected workflow duration of succeeded and failed ops, got %d series", got)
+	}
+
+	// failed ops never resolve the problem
+	if got := testutil.CollectAndCount(alertTimeToResolve); got != 1 {
+		t.Errorf("expected time to resolve of the succeeded alert only, got %d series", got)
+	}
+	if !alertTimeToResolve.DeleteLabelValues("SLOTest", "SLOTest", "true") {
+		t.Error("expected time to resolve of a verified alert")
+	}
+
+	// deleted alerts are forgotten
+	m.OnDelete(succeeded)
+	if _, ok := observedAlerts.Load(succeeded.UID); ok {
+		t.Error("expected deleted alert forgotten")
+	}
+
+	// alerts finished before the process started were observed by the previous one
+	old := newFinishedAlert("old", false)
+	old.Status.OpsStatus.CompletionTime = &metav1.Time{Time: startTime.Add(-time.Minute)}
+	m.OnOpsWorkflowSucceed(old)
+	if got := testutil.ToFloat64(alertOpsRuleTotal.WithLabelValues("monitoring/rule", "Succeed")); got != 1 {
+		t.Errorf("expected alert finished before start ignored, got %v", got)
+	}
+}
+
+func TestUnmatchedAlertsCollector(t *testing.T) {
+	indexer := cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{})
+	for i, triggerStatus := range []alertv1alpha1.AlertOpsTriggerStatusType{
+		alertv1alpha1.OpsTriggerStatusRuleNotFound,
+		alertv1alpha1.OpsTriggerStatusRuleNotFound,
+		alertv1alpha1.OpsTriggerStatusTriggered,
+	} {
+		alert := &alertv1alpha1.AegisAlert{
+			ObjectMeta: metav1.ObjectMeta{Name: string(rune('a' + i)), Namespace: "monitoring"},
+			Spec:       alertv1alpha1.AegisAlertSpec{Type: "NodeNotReady"},
+		}
+		alert.Status.OpsStatus.TriggerStatus = triggerStatus
+		indexer.Add(alert)
+	}
+
+	collector := &unmatchedAlertsCollector{lister: alertlisters.NewAegisAlertLister(indexer)}
+	expected := `
+# HELP aegis_alert_unmatched Number of aegis alerts currently without a matching ops rule
+# TYPE aegis_alert_unmatched gauge
+aegis_alert_unmatched{type="NodeNotReady"} 2
+`
+	if err := testutil.CollectAndCompare(collector, strings.NewReader(expected)); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestNewRecordingRules(t *testing.T) {
+	groups, err := NewRecordingRules([]string{"1d"}, []float64{0.5, 0.99})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	records := make(map[string]string)
+	for _, group := range groups.Groups {
+		for _, rule := range group.Rules {
+			records[rule.Record] = rule.Expr
+		}
+	}
+	for record, expr := range map[string]string{
+		"type:aegis_alert_time_to_resolve_seconds:p50_1d":              "histogram_quantile(0.5, sum by (type, le) (rate(aegis_alert_time_to_resolve_seconds_bucket[1d])))",
+		"type_status:aegis_alert_ops_workflow_duration_seconds:p99_1d": "histogram_quantile(0.99, sum by (type, status, le) (rate(aegis_alert_ops_workflow_duration_seconds_bucket[1d])))",
+		"rule:aegis_alert_ops_success:ratio_1d":                        `sum by (rule) (increase(aegis_alert_ops_rule_total{status="Succeed"}[1d])) / sum by (rule) (increase(aegis_alert_ops_rule_total[1d]))`,
+		"aegis_alert_unmatched:sum":                                    "sum(aegis_alert_unmatched)",
+	} {
+		if records[record] != expr {
+			t.Errorf("rule %s: expected %q, got %q", record, expr, records[record])
+		}
+	}
+
+	if _, err := NewRecordingRules([]string{"1 day"}, nil); err == nil {
+		t.Error("expected invalid window error")
+	}
+	if _, err := NewRecordingRules(nil, []float64{1}); err == nil {
+		t.Error("expected invalid quantile error")
+	}
+}
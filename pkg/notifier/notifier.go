@@ -0,0 +1,398 @@
+package notifier
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"text/template"
+	"time"
+
+	cache "github.com/patrickmn/go-cache"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/klog/v2"
+
+	alertv1alpha1 "github.com/scitix/aegis/pkg/apis/alert/v1alpha1"
+	nodecheckv1alpha1 "github.com/scitix/aegis/pkg/apis/nodecheck/v1alpha1"
+)
+
+// Event is an alert lifecycle event a notifier can subscribe to
+type Event string
+
+const (
+	EventNoOpsRule               Event = "OnNoOpsRule"
+	EventFailedCreateOpsWorkflow Event = "OnFailedCreateOpsWorkflow"
+	EventOpsWorkflowFailed       Event = "OnOpsWorkflowFailed"
+	EventOpsWorkflowSucceed      Event = "OnOpsWorkflowSucceed"
+	EventOpsEscalated            Event = "OnOpsEscalated"
+	EventNodeCheckUpdate         Event = "OnNodeCheckUpdate"
+)
+
+const (
+	TypeWebhook  = "webhook"
+	TypeSlack    = "slack"
+	TypeFeishu   = "feishu"
+	TypeDingTalk = "dingtalk"
+	TypeSMTP     = "smtp"
+)
+
+const (
+	defaultRetryBackoff = 2 * time.Second
+	defaultSendTimeout  = 10 * time.Second
+	// lifecycle callbacks fire on every sync of a finished alert, remember
+	// what has been sent for a while so on-call is notified once.
+	sentExpiration = 24 * time.Hour
+
+	defaultTemplate = `[Aegis] {{.Title}}
+{{.Text}}`
+	defaultSubject = `[Aegis] {{.Title}}`
+)
+
+// Config describes a notification sink, loaded from the notifiers section of the config file
+type Config struct {
+	Name string `mapstructure:"name"`
+	// Type is one of webhook, slack, feishu, dingtalk and smtp
+	Type string `mapstructure:"type"`
+	// URL is the webhook or bot endpoint
+	URL string `mapstructure:"url"`
+	// Secret signs feishu and dingtalk bot requests if set
+	Secret string `mapstructure:"secret"`
+	// Headers are extra headers of webhook requests
+	Headers map[string]string `mapstructure:"headers"`
+	// Events subscribed, empty means all supported events
+	Events []string `mapstructure:"events"`
+	// Severities accepted, empty means all
+	Severities []string `mapstructure:"severities"`
+	// Template is the go template of the message body, rendered with Message
+	Template string `mapstructure:"template"`
+	// Subject is the go template of the email subject
+	Subject string     `mapstructure:"subject"`
+	SMTP    SMTPConfig `mapstructure:"smtp"`
+	Retry   Retry      `mapstructure:"retry"`
+}
+
+type SMTPConfig struct {
+	Host     string   `mapstructure:"host"`
+	Port     int      `mapstructure:"port"`
+	Username string   `mapstructure:"username"`
+	Password string   `mapstructure:"password"`
+	From     string   `mapstructure:"from"`
+	To       []string `mapstructure:"to"`
+}
+
+// Retry is the retry with exponential backoff of failed sends
+type Retry struct {
+	MaxRetries int           `mapstructure:"maxRetries"`
+	Backoff    time.Duration `mapstructure:"backoff"`
+}
+
+// Message is the data the message templates are rendered with
+type Message struct {
+	Event     Event
+	Title     string
+	Text      string
+	Severity  string
+	Alert     *alertv1alpha1.AegisAlert
+	NodeCheck *nodecheckv1alpha1.AegisNodeHealthCheck
+
+	severities []string
+}
+
+type sender interface {
+	send(ctx context.Context, subject, body string) error
+}
+
+type notifier struct {
+	cfg        Config
+	sender     sender
+	body       *template.Template
+	subject    *template.Template
+	events     sets.String
+	severities sets.String
+}
+
+func newNotifier(cfg Config) (*notifier, error) {
+	if len(cfg.Name) == 0 {
+		cfg.Name = cfg.Type
+	}
+
+	var s sender
+	switch cfg.Type {
+	case TypeWebhook:
+		s = &webhookSender{url: cfg.URL, headers: cfg.Headers, raw: len(cfg.Template) > 0}
+	case TypeSlack:
+		s = &slackSender{url: cfg.URL}
+	case TypeFeishu:
+		s = &feishuSender{url: cfg.URL, secret: cfg.Secret}
+	case TypeDingTalk:
+		s = &dingtalkSender{url: cfg.URL, secret: cfg.Secret}
+	case TypeSMTP:
+		if len(cfg.SMTP.Host) == 0 || len(cfg.SMTP.To) == 0 {
+			return nil, fmt.Errorf("notifier %s: smtp host and recipients are required", cfg.Name)
+		}
+		s = &smtpSender{cfg: cfg.SMTP}
+	default:
+		return nil, fmt.Errorf("notifier %s: unsupported type %q", cfg.Name, cfg.Type)
+	}
+
+	if cfg.Type != TypeSMTP && len(cfg.URL) == 0 {
+		return nil, fmt.Errorf("notifier %s: url is required", cfg.Name)
+	}
+
+	body := cfg.Template
+	if len(body) == 0 {
+		body = defaultTemplate
+	}
+	bodyTmpl, err := template.New(cfg.Name).Parse(body)
+	if err != nil {
+		return nil, fmt.Errorf("notifier %s: invalid template: %v", cfg.Name, err)
+	}
+
+	subject := cfg.Subject
+	if len(subject) == 0 {
+		subject = defaultSubject
+	}
+	subjectTmpl, err := template.New(cfg.Name + "-subject").Parse(subject)
+	if err != nil {
+		return nil, fmt.Errorf("notifier %s: invalid subject: %v", cfg.Name, err)
+	}
+
+	if cfg.Retry.Backoff <= 0 {
+		cfg.Retry.Backoff = defaultRetryBackoff
+	}
+
+	return &notifier{
+		cfg:        cfg,
+		sender:     s,
+		body:       bodyTmpl,
+		subject:    subjectTmpl,
+		events:     sets.NewString(cfg.Events...),
+		severities: sets.NewString(lower(cfg.Severities)...),
+	}, nil
+}
+
+// accept applies the event and severity filters
+func (n *notifier) accept(msg *Message) bool {
+	if n.events.Len() > 0 && !n.events.Has(string(msg.Event)) {
+		return false
+	}
+
+	if n.severities.Len() == 0 {
+		return true
+	}
+	return n.severities.HasAny(lower(msg.severities)...)
+}
+
+// notify renders and sends the message, retrying with exponential backoff
+func (n *notifier) notify(ctx context.Context, msg *Message) error {
+	var body, subject strings.Builder
+	if err := n.body.Execute(&body, msg); err != nil {
+		return fmt.Errorf("notifier %s: render template: %v", n.cfg.Name, err)
+	}
+	if err := n.subject.Execute(&subject, msg); err != nil {
+		return fmt.Errorf("notifier %s: render subject: %v", n.cfg.Name, err)
+	}
+
+	var err error
+	backoff := n.cfg.Retry.Backoff
+	for attempt := 0; ; attempt++ {
+		sendCtx, cancel := context.WithTimeout(ctx, defaultSendTimeout)
+		err = n.sender.send(sendCtx, subject.String(), body.String())
+		cancel()
+		if err == nil || attempt >= n.cfg.Retry.MaxRetries {
+			break
+		}
+
+		klog.V(4).Infof("notifier %s: send %s failed, retry in %v: %v", n.cfg.Name, msg.Event, backoff, err)
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(backoff):
+		}
+		backoff *= 2
+	}
+
+	if err != nil {
+		return fmt.Errorf("notifier %s: send %s: %v", n.cfg.Name, msg.Event, err)
+	}
+	return nil
+}
+
+// NotifyController sends alert lifecycle events to the configured notifiers
+type NotifyController struct {
+	notifiers []*notifier
+	sent      *cache.Cache
+}
+
+func NewNotifyController(configs []Config) (*NotifyController, error) {
+	notifiers := make([]*notifier, 0, len(configs))
+	for _, cfg := range configs {
+		n, err := newNotifier(cfg)
+		if err != nil {
+			return nil, err
+		}
+		notifiers = append(notifiers, n)
+	}
+
+	return &NotifyController{
+		notifiers: notifiers,
+		sent:      cache.New(sentExpiration, time.Hour),
+	}, nil
+}
+
+// dispatch sends the message once per object and event to every accepting notifier
+func (c *NotifyController) dispatch(key string, msg *Message) {
+	if err := c.sent.Add(fmt.Sprintf("%s/%s", key, msg.Event), struct{}{}, cache.DefaultExpiration); err != nil {
+		return
+	}
+
+	for _, n := range c.notifiers {
+		if !n.accept(msg) {
+			continue
+		}
+
+		go func(n *notifier) {
+			if err := n.notify(context.Background(), msg); err != nil {
+				klog.Errorf("%v", err)
+			} else {
+				klog.V(4).Infof("notifier %s: sent %s for %s", n.cfg.Name, msg.Event, key)
+			}
+		}(n)
+	}
+}
+
+func (c *NotifyController) notifyAlert(event Event, title string, alert *alertv1alpha1.AegisAlert) {
+	msg := &Message{
+		Event:      event,
+		Title:      fmt.Sprintf("%s for alert %s/%s", title, alert.Namespace, alert.Name),
+		Text:       alertText(alert),
+		Severity:   alert.Spec.Severity,
+		Alert:      alert,
+		severities: []string{alert.Spec.Severity},
+	}
+	c.dispatch(string(alert.UID), msg)
+}
+
+func (c *NotifyController) OnCreate(alert *alertv1alpha1.AegisAlert) error {
+	return nil
+}
+
+func (c *NotifyController) OnUpdate(alert *alertv1alpha1.AegisAlert) error {
+	return nil
+}
+
+func (c *NotifyController) OnDelete(alert *alertv1alpha1.AegisAlert) error {
+	return nil
+}
+
+func (c *NotifyController) OnNoOpsRule(alert *alertv1alpha1.AegisAlert) error {
+	c.notifyAlert(EventNoOpsRule, "No ops rule found", alert)
+	return nil
+}
+
+func (c *NotifyController) OnNoOpsTemplate(alert *alertv1alpha1.AegisAlert) error {
+	return nil
+}
+
+func (c *NotifyController) OnFailedCreateOpsWorkflow(alert *alertv1alpha1.AegisAlert) error {
+	c.notifyAlert(EventFailedCreateOpsWorkflow, "Failed to create ops workflow", alert)
+	return nil
+}
+
+func (c *NotifyController) OnSucceedCreateOpsWorkflow(alert *alertv1alpha1.AegisAlert) error {
+	return nil
+}
+
+func (c *NotifyController) OnOpsWorkflowSucceed(alert *alertv1alpha1.AegisAlert) error {
+	c.notifyAlert(EventOpsWorkflowSucceed, "Ops workflow succeeded", alert)
+	return nil
+}
+
+func (c *NotifyController) OnOpsWorkflowFailed(alert *alertv1alpha1.AegisAlert) error {
+	c.notifyAlert(EventOpsWorkflowFailed, "Ops workflow failed", alert)
+	return nil
+}
+
+func (c *NotifyController) OnOpsEscalated(alert *alertv1alpha1.AegisAlert) error {
+	c.notifyAlert(EventOpsEscalated, "Ops escalated", alert)
+	return nil
+}
+
+// OnNodeCheckUpdate notifies the abnormal items of a finished node check
+func (c *NotifyController) OnNodeCheckUpdate(nodecheck *nodecheckv1alpha1.AegisNodeHealthCheck) error {
+	if nodecheck.Status.Status != nodecheckv1alpha1.CheckStatusSucceeded {
+		return nil
+	}
+
+	levels := sets.NewString()
+	lines := make([]string, 0)
+	modules := make([]string, 0, len(nodecheck.Status.Results))
+	for module := range nodecheck.Status.Results {
+		modules = append(modules, module)
+	}
+	sort.Strings(modules)
+
+	for _, module := range modules {
+		for _, info := range nodecheck.Status.Results[module] {
+			if !info.Status {
+				continue
+			}
+			levels.Insert(info.Level)
+			lines = append(lines, fmt.Sprintf("- [%s] %s/%s %s: %s", info.Level, module, info.Item, info.Condition, info.Message))
+		}
+	}
+
+	if len(lines) == 0 {
+		return nil
+	}
+
+	msg := &Message{
+		Event:      EventNodeCheckUpdate,
+		Title:      fmt.Sprintf("Node %s health check found %d issues", nodecheck.Spec.Node, len(lines)),
+		Text:       strings.Join(lines, "\n"),
+		Severity:   strings.Join(levels.List(), ","),
+		NodeCheck:  nodecheck,
+		severities: levels.List(),
+	}
+	c.dispatch(string(nodecheck.UID), msg)
+	return nil
+}
+
+// alertText summarizes the alert and its ops result
+func alertText(alert *alertv1alpha1.AegisAlert) string {
+	lines := []string{
+		fmt.Sprintf("Type: %s (%s)", alert.Spec.Type, alert.Spec.Status),
+		fmt.Sprintf("Object: %s %s", alert.Spec.InvolvedObject.Kind, objectName(alert)),
+	}
+	if len(alert.Spec.InvolvedObject.Node) > 0 {
+		lines = append(lines, fmt.Sprintf("Node: %s", alert.Spec.InvolvedObject.Node))
+	}
+	if len(alert.Status.OpsStatus.TriggerStatus) > 0 {
+		lines = append(lines, fmt.Sprintf("Trigger: %s", alert.Status.OpsStatus.TriggerStatus))
+	}
+	if n := len(alert.Status.History); n > 0 {
+		last := alert.Status.History[n-1]
+		if len(last.Message) > 0 {
+			lines = append(lines, fmt.Sprintf("%s: %s", last.Type, last.Message))
+		}
+	}
+	if result, ok := alert.Status.OpsStatus.Outputs[alertv1alpha1.OpsResultOutputName]; ok {
+		lines = append(lines, fmt.Sprintf("Result: %s", result))
+	}
+	return strings.Join(lines, "\n")
+}
+
+func objectName(alert *alertv1alpha1.AegisAlert) string {
+	if len(alert.Spec.InvolvedObject.Namespace) == 0 {
+		return alert.Spec.InvolvedObject.Name
+	}
+	return alert.Spec.InvolvedObject.Namespace + "/" + alert.Spec.InvolvedObject.Name
+}
+
+func lower(values []string) []string {
+	result := make([]string, 0, len(values))
+	for _, v := range values {
+		result = append(result, strings.ToLower(v))
+	}
+	return result
+}
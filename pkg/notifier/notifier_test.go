@@ -0,0 +1,192 @@
+package notifier
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	alertv1alpha1 "github.com/scitix/aegis/pkg/apis/alert/v1alpha1"
+	nodecheckv1alpha1 "github.com/scitix/aegis/pkg/apis/nodecheck/v1alpha1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func newTestAlert(severity string) *alertv1alpha1.AegisAlert {
+	return &alertv1alpha1.AegisAlert{
+		ObjectMeta: metav1.ObjectMeta{Name: "alert", Namespace: "monitoring", UID: "uid-1"},
+		Spec: alertv1alpha1.AegisAlertSpec{
+			Type:     "NodeNotReady",
+			Status:   "Firing",
+			Severity: severity,
+			InvolvedObject: alertv1alpha1.AegisAlertObject{
+				Kind: "Node",
+				Name: "node-1",
+				Node: "node-1",
+			},
+		},
+	}
+}
+
+func TestNotifierFilters(t *testing.T) {
+	n, err := newNotifier(Config{
+		Type:       TypeSlack,
+		URL:        "http://localhost",
+		Events:     []string{string(EventOpsWorkflowFailed)},
+		Severities: []string{"Critical"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cases := []struct {
+		event    Event
+		severity string
+		expected bool
+	}{
+		{EventOpsWorkflowFailed, "critical", true},
+		{EventOpsWorkflowFailed, "warning", false},
+		{EventOpsWorkflowSucceed, "critical", false},
+	}
+	for _, c := range cases {
+		msg := &Message{Event: c.event, severities: []string{c.severity}}
+		if got := n.accept(msg); got != c.expected {
+			t.Errorf("%s/%s: expected %v, got %v", c.event, c.severity, c.expected, got)
+		}
+	}
+}
+
+func TestNotifierInvalidConfig(t *testing.T) {
+	configs := []Config{
+		{Type: "pager", URL: "http://localhost"},
+		{Type: TypeWebhook},
+		{Type: TypeSMTP, SMTP: SMTPConfig{Host: "smtp"}},
+		{Type: TypeWebhook, URL: "http://localhost", Template: "{{.Title"},
+	}
+	for _, cfg := range configs {
+		if _, err := newNotifier(cfg); err == nil {
+			t.Errorf("expected error for %+v", cfg)
+		}
+	}
+}
+
+func TestNotifierRetry(t *testing.T) {
+	var calls int32
+	var body []byte
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&calls, 1) < 3 {
+			w.WriteHeader(http.StatusBadGateway)
+			return
+		}
+		body, _ = io.ReadAll(r.Body)
+	}))
+	defer server.Close()
+
+	n, err := newNotifier(Config{
+		Type:     TypeWebhook,
+		URL:      server.URL,
+		Template: `{"alert":"{{.Alert.Name}}","event":"{{.Event}}"}`,
+		Retry:    Retry{MaxRetries: 2, Backoff: time.Millisecond},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	msg := &Message{Event: EventOpsWorkflowFailed, Alert: newTestAlert("critical")}
+	if err := n.notify(t.Context(), msg); err != nil {
+		t.Fatal(err)
+	}
+	if calls != 3 {
+		t.Errorf("expected 3 calls, got %d", calls)
+	}
+	if string(body) != `{"alert":"alert","event":"OnOpsWorkflowFailed"}` {
+		t.Errorf("unexpected body %s", body)
+	}
+
+	n.cfg.Retry.MaxRetries = 0
+	atomic.StoreInt32(&calls, 0)
+	if err := n.notify(t.Context(), msg); err == nil {
+		t.Error("expected error without retries")
+	}
+}
+
+func TestNotifyControllerDedup(t *testing.T) {
+	received := make(chan map[string]interface{}, 10)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		payload := make(map[string]interface{})
+		json.NewDecoder(r.Body).Decode(&payload)
+		received <- payload
+	}))
+	defer server.Close()
+
+	c, err := NewNotifyController([]Config{{Type: TypeFeishu, URL: server.URL, Secret: "secret"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	alert := newTestAlert("critical")
+	c.OnOpsWorkflowSucceed(alert)
+	c.OnOpsWorkflowSucceed(alert)
+
+	select {
+	case payload := <-received:
+		if payload["msg_type"] != "text" || payload["sign"] == nil {
+			t.Errorf("unexpected payload %v", payload)
+		}
+		content := payload["content"].(map[string]interface{})
+		if !strings.Contains(content["text"].(string), "Ops workflow succeeded for alert monitoring/alert") {
+			t.Errorf("unexpected text %v", content["text"])
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("notification not sent")
+	}
+
+	select {
+	case payload := <-received:
+		t.Errorf("duplicated notification %v", payload)
+	case <-time.After(100 * time.Millisecond):
+	}
+}
+
+func TestNodeCheckUpdate(t *testing.T) {
+	received := make(chan map[string]interface{}, 10)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		payload := make(map[string]interface{})
+		json.NewDecoder(r.Body).Decode(&payload)
+		received <- payload
+	}))
+	defer server.Close()
+
+	c, err := NewNotifyController([]Config{{Type: TypeSlack, URL: server.URL, Severities: []string{"fatal"}}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	nodecheck := &nodecheckv1alpha1.AegisNodeHealthCheck{
+		ObjectMeta: metav1.ObjectMeta{Name: "check", UID: "uid-2"},
+		Spec:       nodecheckv1alpha1.AegisNodeHealthCheckSpec{Node: "node-1"},
+		Status: nodecheckv1alpha1.AegisNodeHealthCheckStatus{
+			Status: nodecheckv1alpha1.CheckStatusSucceeded,
+			Results: nodecheckv1alpha1.ResultInfos{
+				"gpu": {
+					{Item: "xid", Condition: "GpuXid79", Level: "Fatal", Status: true, Message: "fallen off the bus"},
+					{Item: "ecc", Condition: "GpuEcc", Level: "Warning", Status: false},
+				},
+			},
+		},
+	}
+	c.OnNodeCheckUpdate(nodecheck)
+
+	select {
+	case payload := <-received:
+		text := payload["text"].(string)
+		if !strings.Contains(text, "[Fatal] gpu/xid GpuXid79: fallen off the bus") || strings.Contains(text, "ecc") {
+			t.Errorf("unexpected text %s", text)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("notification not sent")
+	}
+}
@@ -0,0 +1,201 @@
+package notifier
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/smtp"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// postJSON posts the payload and treats non 2xx responses as failure
+func postJSON(ctx context.Context, endpoint string, headers map[string]string, payload []byte) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, bytes.NewReader(payload))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for k, v := range headers {
+		req.Header.Set(k, v)
+	}
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return fmt.Errorf("unexpected status %d: %s", resp.StatusCode, strings.TrimSpace(string(body)))
+	}
+	return nil
+}
+
+// webhookSender posts the rendered body as is when a template is configured,
+// otherwise a json object with the title and text.
+type webhookSender struct {
+	url     string
+	headers map[string]string
+	raw     bool
+}
+
+func (s *webhookSender) send(ctx context.Context, subject, body string) error {
+	if s.raw {
+		return postJSON(ctx, s.url, s.headers, []byte(body))
+	}
+
+	payload, err := json.Marshal(map[string]string{
+		"title": subject,
+		"text":  body,
+	})
+	if err != nil {
+		return err
+	}
+	return postJSON(ctx, s.url, s.headers, payload)
+}
+
+type slackSender struct {
+	url string
+}
+
+func (s *slackSender) send(ctx context.Context, subject, body string) error {
+	payload, err := json.Marshal(map[string]string{"text": body})
+	if err != nil {
+		return err
+	}
+	return postJSON(ctx, s.url, nil, payload)
+}
+
+type feishuSender struct {
+	url    string
+	secret string
+}
+
+// feishuSign signs timestamp and secret as documented by feishu custom bots
+func feishuSign(secret string, timestamp int64) (string, error) {
+	key := fmt.Sprintf("%d\n%s", timestamp, secret)
+	h := hmac.New(sha256.New, []byte(key))
+	if _, err := h.Write(nil); err != nil {
+		return "", err
+	}
+	return base64.StdEncoding.EncodeToString(h.Sum(nil)), nil
+}
+
+func (s *feishuSender) send(ctx context.Context, subject, body string) error {
+	msg := map[string]interface{}{
+		"msg_type": "text",
+		"content":  map[string]string{"text": body},
+	}
+	if len(s.secret) > 0 {
+		timestamp := time.Now().Unix()
+		sign, err := feishuSign(s.secret, timestamp)
+		if err != nil {
+			return err
+		}
+		msg["timestamp"] = strconv.FormatInt(timestamp, 10)
+		msg["sign"] = sign
+	}
+
+	payload, err := json.Marshal(msg)
+	if err != nil {
+		return err
+	}
+	return postJSON(ctx, s.url, nil, payload)
+}
+
+type dingtalkSender struct {
+	url    string
+	secret string
+}
+
+// dingtalkURL appends the timestamp and signature required by signed dingtalk robots
+func dingtalkURL(endpoint, secret string, timestamp int64) (string, error) {
+	u, err := url.Parse(endpoint)
+	if err != nil {
+		return "", err
+	}
+
+	h := hmac.New(sha256.New, []byte(secret))
+	if _, err := h.Write([]byte(fmt.Sprintf("%d\n%s", timestamp, secret))); err != nil {
+		return "", err
+	}
+
+	query := u.Query()
+	query.Set("timestamp", strconv.FormatInt(timestamp, 10))
+	query.Set("sign", base64.StdEncoding.EncodeToString(h.Sum(nil)))
+	u.RawQuery = query.Encode()
+	return u.String(), nil
+}
+
+func (s *dingtalkSender) send(ctx context.Context, subject, body string) error {
+	endpoint := s.url
+	if len(s.secret) > 0 {
+		var err error
+		endpoint, err = dingtalkURL(s.url, s.secret, time.Now().UnixMilli())
+		if err != nil {
+			return err
+		}
+	}
+
+	payload, err := json.Marshal(map[string]interface{}{
+		"msgtype": "text",
+		"text":    map[string]string{"content": body},
+	})
+	if err != nil {
+		return err
+	}
+	return postJSON(ctx, endpoint, nil, payload)
+}
+
+type smtpSender struct {
+	cfg SMTPConfig
+}
+
+func (s *smtpSender) send(ctx context.Context, subject, body string) error {
+	port := s.cfg.Port
+	if port == 0 {
+		port = 25
+	}
+	addr := net.JoinHostPort(s.cfg.Host, strconv.Itoa(port))
+
+	var auth smtp.Auth
+	if len(s.cfg.Username) > 0 {
+		auth = smtp.PlainAuth("", s.cfg.Username, s.cfg.Password, s.cfg.Host)
+	}
+
+	from := s.cfg.From
+	if len(from) == 0 {
+		from = s.cfg.Username
+	}
+
+	var msg bytes.Buffer
+	fmt.Fprintf(&msg, "From: %s\r\n", from)
+	fmt.Fprintf(&msg, "To: %s\r\n", strings.Join(s.cfg.To, ", "))
+	fmt.Fprintf(&msg, "Subject: %s\r\n", strings.ReplaceAll(subject, "\n", " "))
+	fmt.Fprintf(&msg, "MIME-Version: 1.0\r\n")
+	fmt.Fprintf(&msg, "Content-Type: text/plain; charset=UTF-8\r\n\r\n")
+	msg.WriteString(body)
+
+	errCh := make(chan error, 1)
+	go func() {
+		errCh <- smtp.SendMail(addr, auth, from, s.cfg.To, msg.Bytes())
+	}()
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case err := <-errCh:
+		return err
+	}
+}
@@ -0,0 +1,191 @@
+package redact
+
+import (
+	"math"
+	"net"
+	"regexp"
+	"sort"
+	"strings"
+	"unicode"
+)
+
+// Built-in detectors
+const (
+	DetectorToken     = "token"
+	DetectorIP        = "ip"
+	DetectorHostname  = "hostname"
+	DetectorEmail     = "email"
+	DetectorNamespace = "namespace"
+	DetectorSensitive = "sensitive"
+	DetectorEntropy   = "entropy"
+)
+
+// Detectors are the built-in detectors, in the order they are applied. The
+// exact values go first, the entropy catches what the patterns missed.
+var Detectors = []string{
+	DetectorSensitive,
+	DetectorNamespace,
+	DetectorToken,
+	DetectorEmail,
+	DetectorIP,
+	DetectorHostname,
+	DetectorEntropy,
+}
+
+var (
+	// the secret is the last group, the key names are kept for the LLM
+	tokenPatterns = []*regexp.Regexp{
+		regexp.MustCompile(`(?i)\b(?:bearer|basic)\s+([A-Za-z0-9\-._~+/]{8,}=*)`),
+		regexp.MustCompile(`(?i)\b(?:password|passwd|pwd|secret|token|api[_-]?key|access[_-]?key|secret[_-]?key|credential)s?["']?\s*[:=]\s*["']?([^\s"',;]+)`),
+		regexp.MustCompile(`\beyJ[A-Za-z0-9_-]+\.[A-Za-z0-9_-]+\.[A-Za-z0-9_-]+`),
+		regexp.MustCompile(`\b(?:AKIA|ASIA)[0-9A-Z]{16}\b`),
+		regexp.MustCompile(`\b(?:sk|pk|rk)-[A-Za-z0-9_-]{20,}`),
+		regexp.MustCompile(`\bgh[pousr]_[A-Za-z0-9]{36,}\b`),
+		regexp.MustCompile(`\bxox[abpr]-[A-Za-z0-9-]{10,}`),
+	}
+
+	emailPattern = regexp.MustCompile(`[A-Za-z0-9._%+-]+@[A-Za-z0-9.-]+\.[A-Za-z]{2,}`)
+
+	ipv4Pattern = regexp.MustCompile(`\b(?:\d{1,3}\.){3}\d{1,3}\b`)
+	// candidates of IPv6, validated by net.ParseIP
+	ipv6Pattern = regexp.MustCompile(`[0-9A-Fa-f]{0,4}(?::[0-9A-Fa-f]{0,4}){2,7}`)
+
+	// host names of the usual and the cluster internal suffixes, so that the
+	// dotted module and file names of the logs are kept
+	hostnamePattern = regexp.MustCompile(`(?i)\b(?:[a-z0-9](?:[a-z0-9-]{0,61}[a-z0-9])?\.)+(?:com|net|org|io|ai|cn|local|internal|cluster|lan|corp|cloud|dev)\b`)
+
+	entropyPattern = regexp.MustCompile(`[A-Za-z0-9+/=_-]+`)
+)
+
+// span is the byte range of a detected value
+type span struct {
+	start, end int
+}
+
+// detector finds the values to redact in a text
+type detector struct {
+	kind string
+	find func(text string) []span
+}
+
+// patternDetector redacts the last group of the matches, or the whole match
+// without groups
+func patternDetector(kind string, patterns []*regexp.Regexp) detector {
+	return detector{
+		kind: kind,
+		find: func(text string) []span {
+			spans := make([]span, 0)
+			for _, pattern := range patterns {
+				for _, match := range pattern.FindAllStringSubmatchIndex(text, -1) {
+					s := span{match[0], match[1]}
+					if n := len(match); n > 2 && match[n-2] >= 0 {
+						s = span{match[n-2], match[n-1]}
+					}
+					spans = append(spans, s)
+				}
+			}
+			return spans
+		},
+	}
+}
+
+// ipDetector redacts the IPv4 and IPv6 addresses, the IPv6 candidates are
+// validated so that the times and the C++ scopes are kept
+func ipDetector() detector {
+	return detector{
+		kind: DetectorIP,
+		find: func(text string) []span {
+			spans := make([]span, 0)
+			for _, match := range ipv4Pattern.FindAllStringIndex(text, -1) {
+				if net.ParseIP(text[match[0]:match[1]]) != nil {
+					spans = append(spans, span{match[0], match[1]})
+				}
+			}
+			for _, match := range ipv6Pattern.FindAllStringIndex(text, -1) {
+				value := text[match[0]:match[1]]
+				ip := net.ParseIP(value)
+				if ip == nil || ip.To4() != nil || ip.IsUnspecified() || nameByte(text, match[0]-1) || nameByte(text, match[1]) {
+					continue
+				}
+				if strings.Contains(value, "::") || strings.Count(value, ":") == 7 {
+					spans = append(spans, span{match[0], match[1]})
+				}
+			}
+			return spans
+		},
+	}
+}
+
+// valuesDetector redacts the exact values, where they are not part of a
+// longer name
+func valuesDetector(kind string, values []string) detector {
+	values = append([]string(nil), values...)
+	sort.Slice(values, func(i, j int) bool { return len(values[i]) > len(values[j]) })
+
+	quoted := make([]string, 0, len(values))
+	for _, value := range values {
+		if value != "" {
+			quoted = append(quoted, regexp.QuoteMeta(value))
+		}
+	}
+	if len(quoted) == 0 {
+		return detector{kind: kind, find: func(string) []span { return nil }}
+	}
+	pattern := regexp.MustCompile(strings.Join(quoted, "|"))
+
+	return detector{
+		kind: kind,
+		find: func(text string) []span {
+			spans := make([]span, 0)
+			for _, match := range pattern.FindAllStringIndex(text, -1) {
+				if nameByte(text, match[0]-1) || nameByte(text, match[1]) {
+					continue
+				}
+				spans = append(spans, span{match[0], match[1]})
+			}
+			return spans
+		},
+	}
+}
+
+// nameByte returns whether the byte at i continues a Kubernetes name
+func nameByte(text string, i int) bool {
+	if i < 0 || i >= len(text) {
+		return false
+	}
+	c := text[i]
+	return c == '-' || c == '_' || c < 0x80 && (unicode.IsLetter(rune(c)) || unicode.IsDigit(rune(c)))
+}
+
+// entropyDetector redacts the random looking words, mixing letters and
+// digits, of at least minLength bytes and minEntropy bits per byte
+func entropyDetector(minEntropy float64, minLength int) detector {
+	return detector{
+		kind: DetectorEntropy,
+		find: func(text string) []span {
+			spans := make([]span, 0)
+			for _, match := range entropyPattern.FindAllStringIndex(text, -1) {
+				word := text[match[0]:match[1]]
+				if len(word) >= minLength && strings.ContainsAny(word, "0123456789") &&
+					strings.IndexFunc(word, unicode.IsLetter) >= 0 && entropy(word) >= minEntropy {
+					spans = append(spans, span{match[0], match[1]})
+				}
+			}
+			return spans
+		},
+	}
+}
+
+// entropy is the Shannon entropy of the bytes of the word, in bits per byte
+func entropy(word string) float64 {
+	counts := make(map[byte]int)
+	for i := 0; i < len(word); i++ {
+		counts[word[i]]++
+	}
+	var bits float64
+	for _, count := range counts {
+		p := float64(count) / float64(len(word))
+		bits -= p * math.Log2(p)
+	}
+	return bits
+}
@@ -0,0 +1,295 @@
+package redact
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+
+	kcommon "github.com/k8sgpt-ai/k8sgpt/pkg/common"
+)
+
+const (
+	// DefaultPolicy applies to the providers without a policy
+	DefaultPolicy = "default"
+
+	DefaultMinEntropy       = 4.2
+	DefaultMinEntropyLength = 24
+
+	placeholderPrefix = "[REDACTED_"
+)
+
+var (
+	placeholderPattern = regexp.MustCompile(`\[REDACTED_[A-Z0-9_]+_[0-9]+\]`)
+	patternName        = regexp.MustCompile(`^[A-Za-z0-9_]+$`)
+)
+
+// systemNamespaces are not worth hiding and are kept for the LLM
+var systemNamespaces = map[string]bool{
+	"default":         true,
+	"kube-system":     true,
+	"kube-public":     true,
+	"kube-node-lease": true,
+}
+
+// Config of the redaction, loaded from the diagnosis.redaction section of
+// the config file
+type Config struct {
+	Enable bool `mapstructure:"enable"`
+	// Policies by AI provider name, the default policy applies to the
+	// providers without one. All the detectors apply without any policy.
+	Policies map[string]Policy `mapstructure:"policies"`
+}
+
+// Policy selects the detectors of an AI provider
+type Policy struct {
+	// Disable sends the prompts verbatim, e.g. to an in-cluster provider
+	Disable bool `mapstructure:"disable"`
+	// Detectors are the built-in detectors applied, all if empty
+	Detectors []string `mapstructure:"detectors"`
+	// Patterns are extra regular expression detectors, applied first
+	Patterns []Pattern `mapstructure:"patterns"`
+	// MinEntropy of the entropy detector in bits per byte. Default: 4.2.
+	MinEntropy float64 `mapstructure:"min-entropy"`
+	// MinEntropyLength of the entropy detector in bytes. Default: 24.
+	MinEntropyLength int `mapstructure:"min-entropy-length"`
+}
+
+// Pattern is a regular expression detector, the last group is redacted if
+// any, the whole match otherwise
+type Pattern struct {
+	Name  string `mapstructure:"name"`
+	Regex string `mapstructure:"regex"`
+}
+
+// policy is a compiled policy
+type policy struct {
+	detectors []string
+	patterns  []detector
+	static    map[string]detector
+}
+
+// Pipeline holds the compiled policies, and returns a redactor per prompt
+type Pipeline struct {
+	policies map[string]*policy
+}
+
+// New compiles the policies of the config, nil if disabled
+func New(config *Config) (*Pipeline, error) {
+	if config == nil || !config.Enable {
+		return nil, nil
+	}
+
+	p := &Pipeline{policies: make(map[string]*policy)}
+	for name, c := range config.Policies {
+		compiled, err := compile(c)
+		if err != nil {
+			return nil, fmt.Errorf("redact: policy %s: %v", name, err)
+		}
+		p.policies[name] = compiled
+	}
+	if _, ok := p.policies[DefaultPolicy]; !ok {
+		p.policies[DefaultPolicy], _ = compile(Policy{})
+	}
+	return p, nil
+}
+
+func compile(c Policy) (*policy, error) {
+	if c.Disable {
+		return nil, nil
+	}
+
+	known := make(map[string]bool, len(Detectors))
+	for _, name := range Detectors {
+		known[name] = true
+	}
+	enabled := make(map[string]bool, len(c.Detectors))
+	for _, name := range c.Detectors {
+		if !known[name] {
+			return nil, fmt.Errorf("unknown detector %q", name)
+		}
+		enabled[name] = true
+	}
+
+	p := &policy{static: make(map[string]detector)}
+	for _, name := range Detectors {
+		if len(enabled) == 0 || enabled[name] {
+			p.detectors = append(p.detectors, name)
+		}
+	}
+
+	for _, pattern := range c.Patterns {
+		if !patternName.MatchString(pattern.Name) {
+			return nil, fmt.Errorf("pattern %q needs a name of letters, digits and underscores", pattern.Regex)
+		}
+		re, err := regexp.Compile(pattern.Regex)
+		if err != nil {
+			return nil, fmt.Errorf("pattern %s: %v", pattern.Name, err)
+		}
+		p.patterns = append(p.patterns, patternDetector(strings.ToUpper(pattern.Name), []*regexp.Regexp{re}))
+	}
+
+	minEntropy, minLength := c.MinEntropy, c.MinEntropyLength
+	if minEntropy <= 0 {
+		minEntropy = DefaultMinEntropy
+	}
+	if minLength <= 0 {
+		minLength = DefaultMinEntropyLength
+	}
+	p.static[DetectorToken] = patternDetector(DetectorToken, tokenPatterns)
+	p.static[DetectorEmail] = patternDetector(DetectorEmail, []*regexp.Regexp{emailPattern})
+	p.static[DetectorIP] = ipDetector()
+	p.static[DetectorHostname] = patternDetector(DetectorHostname, []*regexp.Regexp{hostnamePattern})
+	p.static[DetectorEntropy] = entropyDetector(minEntropy, minLength)
+	return p, nil
+}
+
+// Redactor returns the redactor of a prompt to the provider, with the
+// namespaces and the sensitive values of the analyzers as exact values.
+// Returns nil, a no-op redactor, if the pipeline or the policy is disabled.
+func (p *Pipeline) Redactor(provider string, namespaces []string, sensitive []kcommon.Sensitive) *Redactor {
+	if p == nil {
+		return nil
+	}
+	policy, ok := p.policies[provider]
+	if !ok {
+		policy = p.policies[DefaultPolicy]
+	}
+	if policy == nil {
+		return nil
+	}
+
+	values := make([]string, 0, len(sensitive))
+	for _, s := range sensitive {
+		values = append(values, s.Unmasked)
+	}
+	names := make([]string, 0, len(namespaces))
+	for _, namespace := range namespaces {
+		if !systemNamespaces[namespace] {
+			names = append(names, namespace)
+		}
+	}
+
+	detectors := append([]detector(nil), policy.patterns...)
+	for _, name := range policy.detectors {
+		switch name {
+		case DetectorSensitive:
+			// the analyzers mark the object and node names
+			detectors = append(detectors, valuesDetector("name", values))
+		case DetectorNamespace:
+			detectors = append(detectors, valuesDetector(name, names))
+		default:
+			detectors = append(detectors, policy.static[name])
+		}
+	}
+
+	return &Redactor{
+		detectors:    detectors,
+		placeholders: make(map[string]string),
+		values:       make(map[string]string),
+		counts:       make(map[string]int),
+	}
+}
+
+// Redactor replaces the detected values by placeholders, the same value by
+// the same placeholder, and restores them. The nil redactor is a no-op.
+type Redactor struct {
+	detectors []detector
+
+	mu sync.Mutex
+	// placeholders of the values, and values of the placeholders
+	placeholders map[string]string
+	values       map[string]string
+	counts       map[string]int
+}
+
+// Redact replaces the detected values of the text by placeholders
+func (r *Redactor) Redact(text string) string {
+	if r == nil {
+		return text
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, d := range r.detectors {
+		spans := d.find(text)
+		if len(spans) == 0 {
+			continue
+		}
+
+		// the placeholders of the previous detectors are kept
+		placeholders := placeholderPattern.FindAllStringIndex(text, -1)
+		overlaps := func(s span) bool {
+			for _, p := range placeholders {
+				if s.start < p[1] && p[0] < s.end {
+					return true
+				}
+			}
+			return false
+		}
+
+		var b strings.Builder
+		last := 0
+		for _, s := range sortSpans(spans) {
+			// the values detected twice are replaced once
+			if s.start < last || s.start == s.end || overlaps(s) {
+				continue
+			}
+			b.WriteString(text[last:s.start])
+			b.WriteString(r.placeholder(d.kind, text[s.start:s.end]))
+			last = s.end
+		}
+		b.WriteString(text[last:])
+		text = b.String()
+	}
+	return text
+}
+
+func (r *Redactor) placeholder(kind, value string) string {
+	if placeholder, ok := r.placeholders[value]; ok {
+		return placeholder
+	}
+	kind = strings.ToUpper(kind)
+	r.counts[kind]++
+	placeholder := fmt.Sprintf("%s%s_%d]", placeholderPrefix, kind, r.counts[kind])
+	r.placeholders[value] = placeholder
+	r.values[placeholder] = value
+	return placeholder
+}
+
+// Restore replaces the placeholders of the text by their values
+func (r *Redactor) Restore(text string) string {
+	if r == nil || !strings.Contains(text, placeholderPrefix) {
+		return text
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	pairs := make([]string, 0, 2*len(r.values))
+	for placeholder, value := range r.values {
+		pairs = append(pairs, placeholder, value)
+	}
+	return strings.NewReplacer(pairs...).Replace(text)
+}
+
+// Redacted returns the number of distinct values redacted
+func (r *Redactor) Redacted() int {
+	if r == nil {
+		return 0
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return len(r.values)
+}
+
+// sortSpans orders the spans by start, the longest first
+func sortSpans(spans []span) []span {
+	sort.Slice(spans, func(i, j int) bool {
+		if spans[i].start != spans[j].start {
+			return spans[i].start < spans[j].start
+		}
+		return spans[i].end > spans[j].end
+	})
+	return spans
+}
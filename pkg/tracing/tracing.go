@@ -0,0 +1,197 @@
+package tracing
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracegrpc"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
+	"k8s.io/klog/v2"
+)
+
+const (
+	ExporterOTLP     = "otlp"
+	ExporterOTLPHTTP = "otlphttp"
+	ExporterStdout   = "stdout"
+
+	DefaultServiceName = "aegis"
+
+	// TraceParentAnnotation and TraceStateAnnotation carry the w3c trace
+	// context on the alerts and the workflows they trigger
+	TraceParentAnnotation = "aegis.io/traceparent"
+	TraceStateAnnotation  = "aegis.io/tracestate"
+
+	instrumentationName = "github.com/scitix/aegis"
+)
+
+// Config of the tracing, loaded from the tracing section of the config file
+type Config struct {
+	Enable bool `mapstructure:"enable"`
+	// Exporter is otlp (grpc), otlphttp or stdout
+	Exporter string `mapstructure:"exporter"`
+	// Endpoint of the otlp collector, host:port. OTEL_EXPORTER_OTLP_* env
+	// vars are used if empty
+	Endpoint string `mapstructure:"endpoint"`
+	Insecure bool   `mapstructure:"insecure"`
+	// SampleRatio of the root spans, all if zero
+	SampleRatio float64 `mapstructure:"sample-ratio"`
+	ServiceName string  `mapstructure:"service-name"`
+}
+
+// traceContext propagates the w3c trace context, whatever the global propagator
+var traceContext = propagation.TraceContext{}
+
+// Setup installs the global tracer provider exporting spans as configured and
+// the w3c trace context propagator. The returned function flushes and stops the
+// exporter.
+func Setup(ctx context.Context, cfg Config) (func(context.Context) error, error) {
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(traceContext, propagation.Baggage{}))
+	if !cfg.Enable {
+		return func(context.Context) error { return nil }, nil
+	}
+
+	exporter, err := newExporter(ctx, cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	serviceName := cfg.ServiceName
+	if len(serviceName) == 0 {
+		serviceName = DefaultServiceName
+	}
+	res, err := resource.Merge(resource.Default(), resource.NewWithAttributes(semconv.SchemaURL, semconv.ServiceName(serviceName)))
+	if err != nil {
+		return nil, fmt.Errorf("tracing: build resource: %v", err)
+	}
+
+	sampler := sdktrace.AlwaysSample()
+	if cfg.SampleRatio > 0 && cfg.SampleRatio < 1 {
+		sampler = sdktrace.TraceIDRatioBased(cfg.SampleRatio)
+	}
+
+	provider := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithResource(res),
+		sdktrace.WithSampler(sdktrace.ParentBased(sampler)),
+	)
+	otel.SetTracerProvider(provider)
+	otel.SetErrorHandler(otel.ErrorHandlerFunc(func(err error) {
+		klog.V(4).Infof("tracing: %v", err)
+	}))
+
+	klog.Infof("Tracing enabled, exporting %s spans with %s", serviceName, cfg.Exporter)
+	return provider.Shutdown, nil
+}
+
+func newExporter(ctx context.Context, cfg Config) (sdktrace.SpanExporter, error) {
+	switch cfg.Exporter {
+	case ExporterOTLP, "":
+		opts := []otlptracegrpc.Option{}
+		if len(cfg.Endpoint) > 0 {
+			opts = append(opts, otlptracegrpc.WithEndpoint(cfg.Endpoint))
+		}
+		if cfg.Insecure {
+			opts = append(opts, otlptracegrpc.WithInsecure())
+		}
+		return otlptracegrpc.New(ctx, opts...)
+	case ExporterOTLPHTTP:
+		opts := []otlptracehttp.Option{}
+		if len(cfg.Endpoint) > 0 {
+			opts = append(opts, otlptracehttp.WithEndpoint(cfg.Endpoint))
+		}
+		if cfg.Insecure {
+			opts = append(opts, otlptracehttp.WithInsecure())
+		}
+		return otlptracehttp.New(ctx, opts...)
+	case ExporterStdout:
+		return stdouttrace.New(stdouttrace.WithWriter(os.Stdout))
+	default:
+		return nil, fmt.Errorf("tracing: unknown exporter %q, must be one of %s, %s and %s", cfg.Exporter, ExporterOTLP, ExporterOTLPHTTP, ExporterStdout)
+	}
+}
+
+// Tracer of the aegis spans
+func Tracer() trace.Tracer {
+	return otel.Tracer(instrumentationName)
+}
+
+// End records the error, if any, and ends the span
+func End(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
+
+// annotationCarrier maps the trace context headers to the aegis annotations
+type annotationCarrier map[string]string
+
+var carrierKeys = map[string]string{
+	"traceparent": TraceParentAnnotation,
+	"tracestate":  TraceStateAnnotation,
+}
+
+func (c annotationCarrier) Get(key string) string {
+	return c[carrierKeys[key]]
+}
+
+func (c annotationCarrier) Set(key, value string) {
+	if annotation, ok := carrierKeys[key]; ok {
+		c[annotation] = value
+	}
+}
+
+func (c annotationCarrier) Keys() []string {
+	return []string{"traceparent", "tracestate"}
+}
+
+// InjectAnnotations adds the trace context of ctx to the annotations, it
+// returns them unchanged if ctx carries no valid span.
+func InjectAnnotations(ctx context.Context, annotations map[string]string) map[string]string {
+	if !trace.SpanContextFromContext(ctx).IsValid() {
+		return annotations
+	}
+	if annotations == nil {
+		annotations = make(map[string]string)
+	}
+	traceContext.Inject(ctx, annotationCarrier(annotations))
+	return annotations
+}
+
+// ExtractAnnotations returns ctx with the remote span of the annotations as parent, if any
+func ExtractAnnotations(ctx context.Context, annotations map[string]string) context.Context {
+	if len(annotations[TraceParentAnnotation]) == 0 {
+		return ctx
+	}
+	return traceContext.Extract(ctx, annotationCarrier(annotations))
+}
+
+// TraceParent returns the w3c traceparent of the span of ctx, empty if none
+func TraceParent(ctx context.Context) string {
+	carrier := propagation.MapCarrier{}
+	traceContext.Inject(ctx, carrier)
+	return carrier.Get("traceparent")
+}
+
+// ContextFromEnv returns ctx with the remote span of the TRACEPARENT and
+// TRACESTATE environment variables as parent, as set by workflow templates.
+func ContextFromEnv(ctx context.Context) context.Context {
+	traceParent := os.Getenv("TRACEPARENT")
+	if len(traceParent) == 0 {
+		return ctx
+	}
+	return traceContext.Extract(ctx, propagation.MapCarrier{
+		"traceparent": traceParent,
+		"tracestate":  os.Getenv("TRACESTATE"),
+	})
+}
@@ -0,0 +1,88 @@
+package tracing
+
+import (
+	"context"
+	"testing"
+
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+func newTestTracer() (*tracetest.SpanRecorder, *sdktrace.TracerProvider) {
+	recorder := tracetest.NewSpanRecorder()
+	return recorder, sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+}
+
+func TestAnnotationsRoundTrip(t *testing.T) {
+	recorder, provider := newTestTracer()
+	tracer := provider.Tracer("test")
+
+	// no span, no annotations
+	if annotations := InjectAnnotations(context.Background(), nil); annotations != nil {
+		t.Errorf("expected no annotations without a span, got %v", annotations)
+	}
+
+	ctx, parent := tracer.Start(context.Background(), "parent")
+	annotations := InjectAnnotations(ctx, map[string]string{"foo": "bar"})
+	parent.End()
+	if len(annotations[TraceParentAnnotation]) == 0 || annotations["foo"] != "bar" {
+		t.Fatalf("expected traceparent injected, got %v", annotations)
+	}
+	if annotations[TraceParentAnnotation] != TraceParent(ctx) {
+		t.Errorf("expected annotation %q equal to trace parent %q", annotations[TraceParentAnnotation], TraceParent(ctx))
+	}
+
+	_, child := tracer.Start(ExtractAnnotations(context.Background(), annotations), "child")
+	child.End()
+
+	spans := recorder.Ended()
+	if len(spans) != 2 {
+		t.Fatalf("expected 2 spans, got %d", len(spans))
+	}
+	if spans[1].Parent().SpanID() != spans[0].SpanContext().SpanID() {
+		t.Error("expected child span continuing the annotation trace")
+	}
+	if spans[1].SpanContext().TraceID() != spans[0].SpanContext().TraceID() {
+		t.Error("expected child span in the same trace")
+	}
+}
+
+func TestContextFromEnv(t *testing.T) {
+	recorder, provider := newTestTracer()
+	tracer := provider.Tracer("test")
+
+	t.Setenv("TRACEPARENT", "")
+	if ctx := ContextFromEnv(context.Background()); len(TraceParent(ctx)) > 0 {
+		t.Error("expected no remote span without TRACEPARENT")
+	}
+
+	traceParent := "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01"
+	t.Setenv("TRACEPARENT", traceParent)
+	_, span := tracer.Start(ContextFromEnv(context.Background()), "sop")
+	span.End()
+
+	spans := recorder.Ended()
+	if len(spans) != 1 {
+		t.Fatalf("expected 1 span, got %d", len(spans))
+	}
+	if got := spans[0].SpanContext().TraceID().String(); got != "4bf92f3577b34da6a3ce929d0e0e4736" {
+		t.Errorf("expected span in the TRACEPARENT trace, got %s", got)
+	}
+	if got := spans[0].Parent().SpanID().String(); got != "00f067aa0ba902b7" {
+		t.Errorf("expected TRACEPARENT span as parent, got %s", got)
+	}
+}
+
+func TestSetup(t *testing.T) {
+	shutdown, err := Setup(context.Background(), Config{})
+	if err != nil {
+		t.Fatalf("expected disabled tracing set up, got %v", err)
+	}
+	if err := shutdown(context.Background()); err != nil {
+		t.Error(err)
+	}
+
+	if _, err := Setup(context.Background(), Config{Enable: true, Exporter: "zipkin"}); err == nil {
+		t.Error("expected unknown exporter error")
+	}
+}
@@ -13,10 +13,14 @@ import (
 	nodesop "github.com/scitix/aegis/internal/selfhealing/node_sop"
 	"github.com/scitix/aegis/internal/selfhealing/sop"
 	"github.com/scitix/aegis/internal/selfhealing/sop/basic"
+	"github.com/scitix/aegis/pkg/cloudevents"
 	"github.com/scitix/aegis/pkg/prom"
+	"github.com/scitix/aegis/pkg/tracing"
 	"github.com/scitix/aegis/selfhealing/config"
 	"github.com/scitix/aegis/tools"
 	"github.com/spf13/cobra"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
 	v1 "k8s.io/api/core/v1"
 	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
@@ -73,12 +77,26 @@ func NewCommand(config *config.SelfHealingConfig, use string) *cobra.Command {
 				os.Exit(code)
 			})
 
-			// just return
-			if !o.precheck(ctx) {
-				os.Exit(0)
+			shutdownTracing, err := tracing.Setup(ctx, o.tracing)
+			if err != nil {
+				klog.Fatalf("Invalid tracing option: %s", err)
 			}
 
-			if err := o.catchLockAndRun(ctx); err != nil {
+			// continue the trace of the alert ops workflow
+			ctx, span := tracing.Tracer().Start(tracing.ContextFromEnv(ctx), "SelfHealing.node", trace.WithAttributes(
+				attribute.String("aegis.node", o.name),
+				attribute.String("aegis.alert", Alert),
+				attribute.String("aegis.alert.type", AlertType),
+			))
+
+			// just return if precheck fails
+			if o.precheck(ctx) {
+				err = o.catchLockAndRun(ctx)
+			}
+
+			tracing.End(span, err)
+			shutdownTracing(context.Background())
+			if err != nil {
 				klog.Fatalf("Selfhealing failed: %v", err)
 			}
 		},
@@ -95,6 +113,12 @@ func NewCommand(config *config.SelfHealingConfig, use string) *cobra.Command {
 	c.PersistentFlags().StringVar(&o.promEndpoint, "prometheus.endpoint", "", "Prometheus server endpoint, e.g. http://localhost:9090")
 	c.PersistentFlags().StringVar(&o.promToken, "prometheus.token", "", "Prometheus API access token")
 	c.PersistentFlags().StringVar(&o.opsImage, "ops.image", "", "selfhealing ops image")
+	c.PersistentFlags().StringVar(&o.cloudevents.Sink, "cloudevents.sink", "", "http endpoint to emit sop cloudevents to")
+	c.PersistentFlags().StringVar(&o.cloudevents.File, "cloudevents.file", "", "file to append sop cloudevents to")
+	c.PersistentFlags().StringVar(&o.cloudevents.Source, "cloudevents.source", "", "cloudevents source, default /aegis")
+	c.PersistentFlags().StringVar(&o.tracing.Exporter, "tracing.exporter", "", "export spans with otlp, otlphttp or stdout, disabled if empty")
+	c.PersistentFlags().StringVar(&o.tracing.Endpoint, "tracing.endpoint", "", "otlp collector endpoint, OTEL_EXPORTER_OTLP_* env vars are used if empty")
+	c.PersistentFlags().BoolVar(&o.tracing.Insecure, "tracing.insecure", false, "disable tls to the otlp collector")
 	return c
 }
 
@@ -126,6 +150,11 @@ type nodeOptions struct {
 
 	opsImage string
 
+	cloudevents cloudevents.Config
+	emitter     *cloudevents.Emitter
+
+	tracing tracing.Config
+
 	node   *v1.Node
 	pod    *v1.Pod
 	bridge *sop.ApiBridge
@@ -231,6 +260,16 @@ func (o *nodeOptions) complete(cmd *cobra.Command, args []string) (err error) {
 	}
 	o.gatekeeper = gatekeeper
 
+	if len(o.cloudevents.Sink) > 0 || len(o.cloudevents.File) > 0 {
+		o.emitter, err = cloudevents.NewEmitter(o.cloudevents)
+		if err != nil {
+			return fmt.Errorf("fail to create cloudevents emitter: %s", err)
+		}
+	}
+
+	o.tracing.Enable = len(o.tracing.Exporter) > 0
+	o.tracing.ServiceName = "aegis-selfhealing"
+
 	return err
 }
 
@@ -452,6 +491,7 @@ func (o *nodeOptions) run(ctx context.Context) error {
 	}
 
 	err = sop.Execute(ctx, o.name, status)
+	o.emitSOPResult(ctx, status, err)
 	if err != nil {
 		klog.Errorf("Error execute sop: %s", err)
 		return fmt.Errorf("Error execute sop: %s", err)
@@ -460,6 +500,23 @@ func (o *nodeOptions) run(ctx context.Context) error {
 	return nil
 }
 
+// emitSOPResult reports the sop execution as a cloudevent if enabled
+func (o *nodeOptions) emitSOPResult(ctx context.Context, status *prom.AegisNodeStatus, err error) {
+	if o.emitter == nil {
+		return
+	}
+
+	result := &cloudevents.SOPResult{
+		Node:      o.name,
+		Condition: status.Condition,
+		Type:      o.tpe,
+		Alert:     Alert,
+	}
+	if emitErr := o.emitter.EmitSOP(ctx, result, err); emitErr != nil {
+		klog.Warningf("Failed to emit sop event: %s", emitErr)
+	}
+}
+
 // find proper issue
 // fisrt: Node not ready
 // second: Node cordon with no emergency issues
@@ -87,6 +87,22 @@ func RenderWorkflowTemplate(tmp string, parameters map[string]interface{}) (stri
 	return buf.String(), nil
 }
 
+// RenderTemplate renders a go template with the given parameters, unlike
+// RenderWorkflowTemplate it returns parse errors instead of panicking and
+// fails on missing parameters.
+func RenderTemplate(tmp string, parameters map[string]interface{}) (string, error) {
+	tmpl, err := template.New("template.tmp").Option("missingkey=error").Parse(tmp)
+	if err != nil {
+		return "", err
+	}
+
+	buf := &bytes.Buffer{}
+	if err := tmpl.Execute(buf, parameters); err != nil {
+		return "", err
+	}
+	return buf.String(), nil
+}
+
 func LoadFromFile(file string) (string, error) {
 	bytes, err := ioutil.ReadFile(file)
 	return string(bytes), err